@@ -0,0 +1,49 @@
+package cmd
+
+import (
+  "context"
+  "fmt"
+  "net/http"
+  "time"
+
+  "github.com/xanzy/go-gitlab"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/audit"
+  gl "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/gitlab"
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/storage"
+)
+
+// auditTimeout limits the time a remote audit sink may take per entry
+const auditTimeout = 30 * time.Second
+
+// openAuditLog makes the manager record every mutating API call in the sinks of the audit_log
+// config, attributed to the user of the token. It returns nil if no audit log is configured.
+func openAuditLog(ctx context.Context, client *gitlab.Client, manager *gl.ProjectManager) (audit.Sink, error) {
+  if cfg.AuditLog == nil {
+    return nil, nil
+  }
+
+  user, _, err := client.Users.CurrentUser(gitlab.WithContext(ctx))
+  if err != nil {
+    return nil, fmt.Errorf("failed to resolve the token identity for the audit log: %v", err)
+  }
+
+  var sinks audit.MultiSink
+  if storage.IsRemote(cfg.AuditLog.File) {
+    // Remote objects can't be appended to, every run writes its own
+    sinks = append(sinks, audit.NewObjectSink(instanceFile(cfg.AuditLog.File, time.Now().UTC().Format(rollbackTimeFormat))))
+  } else if cfg.AuditLog.File != "" {
+    sink, err := audit.NewFileSink(cfg.AuditLog.File)
+    if err != nil {
+      return nil, err
+    }
+    sinks = append(sinks, sink)
+  }
+  if cfg.AuditLog.URL != "" {
+    sinks = append(sinks, audit.NewHTTPSink(&http.Client{Timeout: auditTimeout}, cfg.AuditLog.URL))
+  }
+
+  manager.SetAuditLog(sinks, fmt.Sprintf("%s (%d)", user.Username, user.ID))
+
+  return sinks, nil
+}
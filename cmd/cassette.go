@@ -0,0 +1,47 @@
+package cmd
+
+import (
+  "errors"
+  "net/http"
+
+  gl "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/gitlab"
+)
+
+var (
+  recordDir string
+  replayDir string
+)
+
+var errCassetteModeAmbiguous = errors.New("only one is allowed: --record / --replay")
+
+func init() {
+  rootCmd.PersistentFlags().StringVar(&recordDir, "record", "", "Record all GitLab API responses as cassette files to this directory")
+  rootCmd.PersistentFlags().StringVar(&replayDir, "replay", "", "Answer all GitLab API requests from the cassette files in this directory instead of talking to GitLab")
+}
+
+// replaying returns whether GitLab API requests are answered from cassette files (--replay)
+func replaying() bool {
+  return replayDir != ""
+}
+
+// wrapCassette records the responses of the transport (--record) or replaces it by the recorded
+// ones (--replay), if either is set
+func wrapCassette(transport http.RoundTripper) http.RoundTripper {
+  if recordDir != "" && replayDir != "" {
+    logger.Fatal(errCassetteModeAmbiguous)
+  }
+
+  mode, dir := gl.CassetteRecord, recordDir
+  if replaying() {
+    mode, dir = gl.CassetteReplay, replayDir
+  } else if recordDir == "" {
+    return transport
+  }
+
+  cassette, err := gl.NewCassetteTransport(transport, mode, dir)
+  if err != nil {
+    logger.Fatal(err)
+  }
+
+  return cassette
+}
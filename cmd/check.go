@@ -0,0 +1,163 @@
+package cmd
+
+import (
+  "fmt"
+  "io"
+  "os"
+  "sort"
+
+  "github.com/spf13/cobra"
+
+  gl "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/gitlab"
+)
+
+var (
+  checkProjects  []string
+  checkCIProject bool
+)
+
+// checkCmd represents the check command
+var checkCmd = &cobra.Command{
+  Use:   "check",
+  Short: "Check the projects against the config without changing anything, for CI gates",
+  Long: `Check the projects against the config without changing anything, for CI gates.
+
+Runs like a dryrun of sync, without notifications, metrics or state. Exits with 2 listing the
+violating projects and settings if any setting drifted or a policy is violated, with 1 on errors
+(e.g. an invalid config) and with 0 if all projects comply.`,
+  RunE: func(cmd *cobra.Command, args []string) error {
+    ctx, cancel := newSignalContext()
+    defer cancel()
+
+    if err := applySubsystemFlags(cmd); err != nil {
+      logger.Fatal(err)
+    }
+
+    // A check never changes anything
+    env.Dryrun = true
+
+    syncProjects = checkProjects
+    if checkCIProject {
+      project := os.Getenv("CI_PROJECT_PATH")
+      if project == "" {
+        logger.Fatal("--ci-project requires CI_PROJECT_PATH, which is set in GitLab CI pipelines")
+      }
+      syncProjects = append(syncProjects, project)
+    }
+
+    renderer, err := gl.NewChangeLogRenderer(outputFormat, gl.RenderOptions{Planned: true, Color: outputColor})
+    if err != nil {
+      logger.Fatal(err)
+    }
+
+    reports, err := openReports()
+    if err != nil {
+      logger.Fatal(err)
+    }
+    defer reports.Close()
+
+    var results []*syncResult
+    var failed bool
+    for _, instance := range syncInstances() {
+      if ctx.Err() != nil {
+        break
+      }
+
+      if instance.Name != "" {
+        logger.Infof("Checking instance %s at %s.", instance.Name, instance.Endpoint)
+      }
+      reports.section = instance.Name
+
+      result := syncInstance(ctx, instance, renderer, reports)
+      results = append(results, result)
+      failed = failed || result.failed
+    }
+    reports.section = ""
+
+    if err := checkInterrupted(ctx); err != nil {
+      return err
+    }
+
+    if failed || runErrors.Count() > 0 {
+      if err := reports.write("errors", gl.OutputFormatText, runErrors.GenerateReport); err != nil {
+        logger.Errorf("failed to create errors report: %v", err)
+      }
+      logger.Fatalf("%d error(s) encountered, the check is incomplete.", runErrors.Count())
+    }
+
+    changes := mergeChangeLogs(results)
+    violations := mergeViolations(results)
+    if len(changes) == 0 && len(violations) == 0 {
+      logger.Infof("All projects comply with the config.")
+      return nil
+    }
+
+    if err := writeCheckFailures(os.Stderr, changes, violations); err != nil {
+      logger.Errorf("failed to list violating projects: %v", err)
+    }
+    os.Exit(exitCodeDrift)
+    return nil
+  },
+}
+
+func init() {
+  rootCmd.AddCommand(checkCmd)
+
+  checkCmd.Flags().StringArrayVar(&checkProjects, "project", nil, "Only check this project path or glob, ignoring the project whitelist, blacklist and filters (repeatable)")
+  checkCmd.Flags().BoolVar(&checkCIProject, "ci-project", false, "Only check the project running the GitLab CI pipeline (CI_PROJECT_PATH)")
+  checkCmd.Flags().StringSliceVar(&syncOnly, "only", nil, "Only check these subsystems, e.g. branches,approvals (overrides subsystems.only)")
+  checkCmd.Flags().StringSliceVar(&syncSkip, "skip", nil, "Don't check these subsystems, e.g. project-settings (overrides subsystems.skip)")
+}
+
+// mergeViolations merges the policy violations of all instances, prefixing the projects with the
+// name of their instance if there are several
+func mergeViolations(results []*syncResult) map[string][]string {
+  violations := make(map[string][]string)
+  for _, result := range results {
+    if result.manager == nil {
+      continue
+    }
+
+    for project, found := range result.manager.ProjectViolations() {
+      if len(results) > 1 {
+        project = result.instance.Name + ":" + project
+      }
+      violations[project] = append(violations[project], found...)
+    }
+  }
+
+  return violations
+}
+
+// writeCheckFailures lists the drifted settings and policy violations per project
+func writeCheckFailures(w io.Writer, changes gl.ChangeLog, violations map[string][]string) error {
+  failures := make(map[string][]string)
+  for project, subsections := range changes {
+    for subsection, settings := range subsections {
+      for setting := range settings {
+        failures[project] = append(failures[project], subsection+"."+setting)
+      }
+    }
+  }
+  for project, found := range violations {
+    failures[project] = append(failures[project], found...)
+  }
+
+  var projects []string
+  for project := range failures {
+    projects = append(projects, project)
+  }
+  sort.Strings(projects)
+
+  fmt.Fprintf(w, "\nCHECK FAILED: %d project(s) violate the config\n", len(projects))
+  for _, project := range projects {
+    sort.Strings(failures[project])
+
+    fmt.Fprintf(w, "  %s\n", project)
+    for _, failure := range failures[project] {
+      fmt.Fprintf(w, "    %s\n", failure)
+    }
+  }
+  _, err := fmt.Fprintf(w, "\n")
+  return err
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+  "crypto/tls"
+  "crypto/x509"
+  "fmt"
+  "io/ioutil"
+  "net"
+  "net/http"
+  "net/url"
+  "time"
+
+  "github.com/xanzy/go-gitlab"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/config"
+  gl "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/gitlab"
+)
+
+// httpClient is shared by the REST and GraphQL clients of the last created GitLab client,
+// so they are paced together
+var httpClient *http.Client
+
+// gitlabTransport paces the requests of httpClient and counts them
+var gitlabTransport *gl.Transport
+
+// newHTTPClient creates the HTTP client used to talk to GitLab from the http config and flags,
+// paced and retrying by the rate_limit and retry config
+func newHTTPClient() *http.Client {
+  settings, rateLimit, retry := config.DefaultHTTP(), config.DefaultRateLimit(), config.DefaultRetry()
+  if cfg != nil {
+    settings, rateLimit, retry = cfg.HTTP, cfg.RateLimit, cfg.Retry
+  }
+  applyHTTPFlags(&settings)
+
+  base, err := newHTTPTransport(settings)
+  if err != nil {
+    logger.Fatal(err)
+  }
+
+  gitlabTransport = gl.NewTransport(runMetrics.InstrumentTransport(base), rateLimit, retry, logger.WithField("module", "transport"))
+
+  return &http.Client{
+    Transport: wrapCassette(gitlabTransport),
+  }
+}
+
+// newHTTPTransport creates the HTTP transport from the given settings.
+// The proxy of the environment (HTTPS_PROXY, NO_PROXY) is used unless a proxy URL is set.
+func newHTTPTransport(settings config.HTTP) (*http.Transport, error) {
+  proxy := http.ProxyFromEnvironment
+  if settings.ProxyURL != "" {
+    proxyURL, err := url.Parse(settings.ProxyURL)
+    if err != nil {
+      return nil, err
+    }
+    proxy = http.ProxyURL(proxyURL)
+  }
+
+  tlsConfig, err := newTLSConfig(settings)
+  if err != nil {
+    return nil, err
+  }
+
+  dialer := &net.Dialer{
+    Timeout:   time.Duration(settings.DialTimeoutSeconds) * time.Second,
+    KeepAlive: time.Duration(settings.KeepAliveSeconds) * time.Second,
+  }
+
+  return &http.Transport{
+    Proxy:                 proxy,
+    DialContext:           dialer.DialContext,
+    TLSClientConfig:       tlsConfig,
+    MaxIdleConns:          settings.MaxIdleConns,
+    MaxIdleConnsPerHost:   settings.MaxIdleConns,
+    IdleConnTimeout:       90 * time.Second,
+    TLSHandshakeTimeout:   time.Duration(settings.DialTimeoutSeconds) * time.Second,
+    ResponseHeaderTimeout: time.Duration(settings.RequestTimeoutSeconds) * time.Second,
+    ExpectContinueTimeout: time.Second,
+  }, nil
+}
+
+// newTLSConfig creates the TLS config trusting the system and the configured CA certificates,
+// presenting the configured client certificate
+func newTLSConfig(settings config.HTTP) (*tls.Config, error) {
+  if (settings.ClientCertFile == "") != (settings.ClientKeyFile == "") {
+    return nil, fmt.Errorf("client certificate and key must be given together")
+  }
+
+  tlsConfig := &tls.Config{}
+
+  if settings.CACertFile != "" {
+    pem, err := ioutil.ReadFile(settings.CACertFile)
+    if err != nil {
+      return nil, fmt.Errorf("failed to read CA certificates: %v", err)
+    }
+
+    pool, err := x509.SystemCertPool()
+    if err != nil || pool == nil {
+      pool = x509.NewCertPool()
+    }
+    if !pool.AppendCertsFromPEM(pem) {
+      return nil, fmt.Errorf("no CA certificates found in %s", settings.CACertFile)
+    }
+    tlsConfig.RootCAs = pool
+  }
+
+  if settings.ClientCertFile != "" {
+    cert, err := tls.LoadX509KeyPair(settings.ClientCertFile, settings.ClientKeyFile)
+    if err != nil {
+      return nil, fmt.Errorf("failed to load client certificate: %v", err)
+    }
+    tlsConfig.Certificates = []tls.Certificate{cert}
+  }
+
+  if settings.InsecureSkipVerify {
+    logger.Warn("TLS certificate verification is disabled, connections to GitLab are insecure")
+    tlsConfig.InsecureSkipVerify = true
+  }
+
+  return tlsConfig, nil
+}
+
+// newGitlabClient creates the GitLab API client from the env config
+func newGitlabClient() *gitlab.Client {
+  // Replayed responses don't require a token
+  if err := checkToken(); err != nil && !replaying() {
+    logger.Fatal(err)
+  }
+
+  httpClient = newHTTPClient()
+
+  var client *gitlab.Client
+  if env.GitlabTokenType == tokenTypeOAuth {
+    client = gitlab.NewOAuthClient(httpClient, env.GitlabToken)
+  } else {
+    client = gitlab.NewClient(httpClient, env.GitlabToken)
+  }
+  if env.GitlabEndpoint != "" {
+    if err := client.SetBaseURL(env.GitlabEndpoint); err != nil {
+      logger.Fatal(err)
+    }
+  }
+
+  // The token endpoint is only known once the base URL is set
+  if env.GitlabTokenType == tokenTypeOAuth {
+    httpClient.Transport = gl.NewOAuthTransport(httpClient.Transport, client.BaseURL(), env.GitlabToken, oauthRefresh())
+  }
+
+  return client
+}
+
+// newProjectManager creates a ProjectManager using the services of the given client
+func newProjectManager(client *gitlab.Client) *gl.ProjectManager {
+  return gl.NewProjectManager(
+    logger.WithField("module", "project_manager"),
+    client.Groups,
+    client.Projects,
+    client.ProtectedBranches,
+    client.Branches,
+    client.RepositoryFiles,
+    client.Commits,
+    client.MergeRequests,
+    client,
+    gl.NewGraphQLClient(httpClient, client.BaseURL(), env.GitlabToken),
+    cfg,
+  )
+}
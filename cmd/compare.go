@@ -0,0 +1,99 @@
+package cmd
+
+import (
+  "context"
+  "io"
+  "os"
+  "time"
+
+  "github.com/spf13/cobra"
+
+  gl "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/gitlab"
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/storage"
+)
+
+// compareCmd represents the compare command
+var compareCmd = &cobra.Command{
+  Use:   "compare <source> <target>",
+  Short: "Compare the project settings of two groups, or of a group and a snapshot file",
+  Long: `Compare the project settings of two groups, or of a group and a snapshot file.
+
+Projects are matched by their path relative to the group, or to the namespace shared by all
+projects of a snapshot. Exits with 2 if any setting differs.`,
+  Args: cobra.ExactArgs(2),
+  RunE: func(cmd *cobra.Command, args []string) error {
+    ctx, cancel := newSignalContext()
+    defer cancel()
+
+    if outputFormat != gl.OutputFormatText && outputFormat != gl.OutputFormatJSON {
+      logger.Fatalf("unsupported output format %q, compare supports text and json", outputFormat)
+    }
+
+    reports, err := openReports()
+    if err != nil {
+      logger.Fatal(err)
+    }
+    defer reports.Close()
+
+    manager := newProjectManager(newGitlabClient())
+
+    source, sourcePrefix := compareSide(ctx, manager, args[0])
+    target, targetPrefix := compareSide(ctx, manager, args[1])
+
+    if err := checkInterrupted(ctx); err != nil {
+      return err
+    }
+
+    if manager.GetError() {
+      logger.Fatalf("%d error(s) encountered.", runErrors.Count())
+    }
+
+    differences, err := gl.CompareSnapshots(source, sourcePrefix, target, targetPrefix)
+    if err != nil {
+      logger.Fatal(err)
+    }
+
+    report := func(w io.Writer) error {
+      return gl.RenderDifferences(w, outputFormat, args[0], args[1], differences)
+    }
+    if err := reports.write("differences", outputFormat, report); err != nil {
+      logger.Fatalf("failed to create differences report: %v", err)
+    }
+
+    if len(differences) > 0 {
+      logger.Warnf("%d setting(s) differ.", len(differences))
+      os.Exit(exitCodeDrift)
+    }
+
+    return nil
+  },
+}
+
+func init() {
+  rootCmd.AddCommand(compareCmd)
+}
+
+// compareSide returns the snapshot of a compared side and the prefix of its project paths.
+// Existing files and remote objects are read as snapshot, everything else is taken as group path.
+func compareSide(ctx context.Context, manager *gl.ProjectManager, side string) (*gl.Snapshot, string) {
+  if info, err := os.Stat(side); storage.IsRemote(side) || (err == nil && !info.IsDir()) {
+    snapshot, err := gl.LoadSnapshot(side)
+    if err != nil {
+      logger.Fatal(err)
+    }
+
+    return snapshot, snapshot.CommonNamespace()
+  }
+
+  projects, err := manager.GetGroupProjects(ctx, side)
+  if err != nil {
+    // Interrupted runs are reported by the caller
+    if ctx.Err() != nil {
+      return nil, side
+    }
+    logger.Fatal(err)
+  }
+
+  logger.Infof("Identified %d project(s) in group %s.", len(projects), side)
+  return takeSnapshot(ctx, manager, projects, time.Now()), side
+}
@@ -2,7 +2,6 @@ package cmd
 
 import (
   "github.com/spf13/cobra"
-  "github.com/xanzy/go-gitlab"
 
   gl "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/gitlab"
 )
@@ -11,41 +10,52 @@ import (
 var complianceCmd = &cobra.Command{
   Use:   "compliance",
   Short: "Compare gitlab's project settings with desired state",
-  Run: func(cmd *cobra.Command, args []string) {
-    client := gitlab.NewClient(nil, env.GitlabToken)
-    if env.GitlabEndpoint != "" {
-      if err := client.SetBaseURL(env.GitlabEndpoint); err != nil {
-        logger.Fatal(err)
-      }
+  RunE: func(cmd *cobra.Command, args []string) error {
+    ctx, cancel := newSignalContext()
+    defer cancel()
+
+    if outputFormat != gl.OutputFormatText && outputFormat != gl.OutputFormatSARIF {
+      logger.Fatalf("unsupported output format %q, compliance supports text and sarif", outputFormat)
     }
+
+    reports, err := openReports()
+    if err != nil {
+      logger.Fatal(err)
+    }
+    defer reports.Close()
+
+    client := newGitlabClient()
     if env.Dryrun {
       logger.Infof("DRYRUN: No changes will be implemented.")
     }
 
-    manager := gl.NewProjectManager(
-      logger.WithField("module", "project_manager"),
-      client.Groups,
-      client.Projects,
-      client.ProtectedBranches,
-      client.Branches,
-      cfg,
-    )
+    manager := newProjectManager(client)
 
     if ! manager.ComplianceReady() {
       logger.Fatal("No compliance configuration.")
     }
 
-    projects, err := manager.GetProjects()
+    projects, err := manager.GetProjects(ctx)
     if err != nil {
+      if err := checkInterrupted(ctx); err != nil {
+        return err
+      }
       logger.Fatal(err)
     }
 
     logger.Infof("Identified %d valid project(s).", len(projects))
+    prefetchProjectSettings(ctx, manager, projects)
+
     for index, project := range projects {
+      if ctx.Err() != nil {
+        logger.Warnf("Skipping remaining %d project(s).", len(projects)-index)
+        break
+      }
+
       logger.Infof("Processing project #%d: %s", index + 1, project.PathWithNamespace)
 
       // Get current approval settings
-      approvalSettings, err := manager.GetProjectApprovalSettings(project)
+      approvalSettings, err := manager.GetProjectApprovalSettings(ctx, project)
       if err != nil {
         logger.Errorf("failed to get current project settings of project %s: %v", project.PathWithNamespace, err)
         manager.SetError(true)
@@ -55,7 +65,7 @@ var complianceCmd = &cobra.Command{
       manager.ApprovalSettingsOriginal[project.PathWithNamespace] = approvalSettings
 
       // Get current settings states
-      projectSettings, err := manager.GetProjectSettings(project)
+      projectSettings, err := manager.GetProjectSettings(ctx, project)
       if err != nil {
         logger.Errorf("failed to get current project settings of project %s: %v", project.PathWithNamespace, err)
         manager.SetError(true)
@@ -63,10 +73,20 @@ var complianceCmd = &cobra.Command{
 
       // Record current settings states
       manager.ProjectSettingsOriginal[project.PathWithNamespace] = projectSettings
+
+      // Audit presence of required files
+      if err := manager.AuditRequiredFiles(ctx, project); err != nil {
+        logger.Errorf("failed to audit required files of project %s: %v", project.PathWithNamespace, err)
+        manager.SetError(true)
+      }
     }
 
-    if err := manager.GenerateComplianceReport(); err != nil {
-      logger.Errorf("failed to create changelog report: %v", err)
+    report := manager.GenerateComplianceReport
+    if outputFormat == gl.OutputFormatSARIF {
+      report = manager.GenerateComplianceSARIF
+    }
+    if err := reports.write("compliance", outputFormat, report); err != nil {
+      logger.Errorf("failed to create compliance report: %v", err)
       manager.SetError(true)
     }
 
@@ -75,9 +95,15 @@ var complianceCmd = &cobra.Command{
       manager.SetError(true)
     }
 
+    if err := checkInterrupted(ctx); err != nil {
+      return err
+    }
+
     if manager.GetError() {
       logger.Fatal("Error(s) encountered.")
     }
+
+    return nil
   },
 }
 
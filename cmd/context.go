@@ -0,0 +1,51 @@
+package cmd
+
+import (
+  "context"
+  "errors"
+  "os"
+  "os/signal"
+  "syscall"
+)
+
+// exitCodeInterrupted is the exit code of runs canceled by SIGINT or SIGTERM
+const exitCodeInterrupted = 130
+
+// errInterrupted is returned by commands whose run was canceled by SIGINT or SIGTERM
+var errInterrupted = errors.New("run interrupted, results are incomplete")
+
+// newSignalContext returns a context which is canceled on the first SIGINT or SIGTERM,
+// so in-flight API calls are aborted. A second signal exits immediately.
+func newSignalContext() (context.Context, context.CancelFunc) {
+  ctx, cancel := context.WithCancel(context.Background())
+
+  signals := make(chan os.Signal, 2)
+  signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+  go func() {
+    select {
+    case <-signals:
+      logger.Warnf("Interrupted, finishing up. Interrupt again to exit immediately.")
+      cancel()
+    case <-ctx.Done():
+      return
+    }
+
+    <-signals
+    os.Exit(exitCodeInterrupted)
+  }()
+
+  return ctx, func() {
+    signal.Stop(signals)
+    cancel()
+  }
+}
+
+// checkInterrupted returns errInterrupted if the context has been canceled. Commands return it,
+// so Execute exits with exitCodeInterrupted once their deferred cleanups have run.
+func checkInterrupted(ctx context.Context) error {
+  if ctx.Err() != nil {
+    return errInterrupted
+  }
+  return nil
+}
@@ -0,0 +1,279 @@
+package cmd
+
+import (
+  "context"
+  "encoding/json"
+  "errors"
+  "net/http"
+  "os"
+  "os/exec"
+  "sync"
+  "syscall"
+  "time"
+
+  "github.com/robfig/cron"
+  "github.com/spf13/cobra"
+  "github.com/spf13/pflag"
+)
+
+// daemonShutdownTimeout limits the time the health server may take to shut down
+const daemonShutdownTimeout = 5 * time.Second
+
+var (
+  daemonInterval    time.Duration
+  daemonSchedule    string
+  daemonListen      string
+  daemonSystemHooks bool
+)
+
+var errDaemonScheduleRequired = errors.New("exactly one of --interval and --schedule must be set")
+
+// daemonCmd represents the daemon command
+var daemonCmd = &cobra.Command{
+  Use:   "daemon [-- sync flags]",
+  Short: "Keep running and re-enforce the config on an interval or cron schedule",
+  Long: `Keep running and re-enforce the config on an interval or cron schedule.
+
+Every run is a separate sync process, started with the root flags (like --config), the given
+sync flags and the environment of the daemon, so the config is reloaded for every run.`,
+  Run: func(cmd *cobra.Command, args []string) {
+    next, err := daemonScheduler()
+    if err != nil {
+      logger.Fatal(err)
+    }
+
+    executable, err := os.Executable()
+    if err != nil {
+      logger.Fatal(err)
+    }
+
+    ctx, cancel := newSignalContext()
+    defer cancel()
+
+    status := &daemonStatus{}
+    handler := status.handler()
+
+    var hooks *systemHookListener
+    if daemonSystemHooks {
+      if env.SystemHookSecret == "" {
+        logger.Fatal(errSystemHookSecretRequired)
+      }
+
+      hooks = newSystemHookListener(newProjectManager(newGitlabClient()), env.SystemHookSecret)
+      handler.Handle(systemHookPath, hooks)
+      logger.Infof("Accepting system hooks on %s.", systemHookPath)
+    }
+
+    server := &http.Server{Addr: daemonListen, Handler: handler}
+    go func() {
+      if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+        logger.Fatalf("failed to serve health endpoints: %v", err)
+      }
+    }()
+    logger.Infof("Serving health endpoints on %s.", daemonListen)
+
+    syncArgs := childSyncArgs(args...)
+
+    // The first run starts immediately
+    at := time.Now()
+    for ctx.Err() == nil {
+      select {
+      case <-time.After(time.Until(at)):
+        status.run(ctx, executable, syncArgs, true)
+
+        at = next(time.Now())
+        status.setNextRun(at)
+        logger.Infof("Next run at %s.", at.Format(time.RFC3339))
+      case project := <-hooks.Projects():
+        if hooks.matches(ctx, project) {
+          logger.Infof("Enforcing project %s announced by a system hook.", project)
+          status.run(ctx, executable, append(syncArgs, "--project", project), false)
+        }
+      case <-ctx.Done():
+      }
+    }
+
+    shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), daemonShutdownTimeout)
+    defer shutdownCancel()
+    if err := server.Shutdown(shutdownCtx); err != nil {
+      logger.Warnf("failed to shut down health endpoints: %v", err)
+    }
+  },
+}
+
+func init() {
+  rootCmd.AddCommand(daemonCmd)
+
+  daemonCmd.Flags().DurationVar(&daemonInterval, "interval", 0, "Time between the start of runs, e.g. 1h")
+  daemonCmd.Flags().StringVar(&daemonSchedule, "schedule", "", "Cron expression of the runs, e.g. \"0 */6 * * *\"")
+  daemonCmd.Flags().StringVar(&daemonListen, "listen", ":8080", "Address of the health (/healthz) and readiness (/readyz) endpoints")
+  daemonCmd.Flags().BoolVar(&daemonSystemHooks, "system-hooks", false, "Enforce new and transferred projects announced by GitLab system hooks on "+systemHookPath+" (requires SYSTEM_HOOK_SECRET)")
+}
+
+// daemonScheduler returns the function computing the start of the next run from --interval or --schedule
+func daemonScheduler() (func(time.Time) time.Time, error) {
+  if (daemonInterval > 0) == (daemonSchedule != "") {
+    return nil, errDaemonScheduleRequired
+  }
+
+  if daemonInterval > 0 {
+    return func(now time.Time) time.Time {
+      return now.Add(daemonInterval)
+    }, nil
+  }
+
+  schedule, err := cron.ParseStandard(daemonSchedule)
+  if err != nil {
+    return nil, err
+  }
+
+  return schedule.Next, nil
+}
+
+// daemonStatus records the runs of the daemon and serves them on the health endpoints
+type daemonStatus struct {
+  mu sync.Mutex
+
+  LastRunStarted  time.Time `json:"last_run_started"`
+  LastRunFinished time.Time `json:"last_run_finished"`
+  LastExitCode    int       `json:"last_exit_code"`
+  NextRun         time.Time `json:"next_run"`
+  Running         bool      `json:"running"`
+}
+
+// childSyncArgs returns the arguments of a sync process started by the daemon or server: the root
+// flags given on their command line (e.g. --config, --values or --token-file), so it enforces the
+// config its parent checked, followed by the given sync flags, which take precedence
+func childSyncArgs(args ...string) []string {
+  flags := rootCmd.PersistentFlags()
+
+  syncArgs := []string{"sync"}
+  flags.VisitAll(func(flag *pflag.Flag) {
+    if !flag.Changed {
+      return
+    }
+
+    var values []string
+    switch flag.Value.Type() {
+    case "stringArray":
+      values, _ = flags.GetStringArray(flag.Name)
+    case "stringSlice":
+      values, _ = flags.GetStringSlice(flag.Name)
+    default:
+      values = []string{flag.Value.String()}
+    }
+
+    for _, value := range values {
+      syncArgs = append(syncArgs, "--"+flag.Name+"="+value)
+    }
+  })
+
+  return append(syncArgs, args...)
+}
+
+// run runs a single sync process, forwarding the cancellation of ctx as SIGTERM, and returns
+// its exit code (-1 if it couldn't be started). Only recorded runs are reported by the health
+// endpoints.
+func (s *daemonStatus) run(ctx context.Context, executable string, args []string, record bool) int {
+  if record {
+    s.mu.Lock()
+    s.LastRunStarted = time.Now()
+    s.Running = true
+    s.mu.Unlock()
+  }
+
+  logger.Infof("Starting run.")
+
+  process := exec.Command(executable, args...)
+  process.Stdout = os.Stdout
+  process.Stderr = os.Stderr
+
+  exitCode := -1
+  if err := process.Start(); err != nil {
+    logger.Errorf("failed to start run: %v", err)
+  } else {
+    done := make(chan error, 1)
+    go func() {
+      done <- process.Wait()
+    }()
+
+    var err error
+    select {
+    case err = <-done:
+    case <-ctx.Done():
+      process.Process.Signal(syscall.SIGTERM)
+      err = <-done
+    }
+
+    exitCode = process.ProcessState.ExitCode()
+    if err != nil {
+      logger.Warnf("Run finished with exit code %d.", exitCode)
+    } else {
+      logger.Infof("Run finished.")
+    }
+  }
+
+  if !record {
+    return exitCode
+  }
+
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  s.LastRunFinished = time.Now()
+  s.LastExitCode = exitCode
+  s.Running = false
+
+  return exitCode
+}
+
+// setNextRun records the start of the next run
+func (s *daemonStatus) setNextRun(at time.Time) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  s.NextRun = at
+}
+
+// ready returns whether the last run finished successfully. Finding drift (--fail-on-drift)
+// doesn't count as failure.
+func (s *daemonStatus) ready() bool {
+  return !s.LastRunFinished.IsZero() && (s.LastExitCode == 0 || s.LastExitCode == exitCodeDrift)
+}
+
+// handler serves /healthz, answering while the daemon is running, and /readyz, answering
+// with 503 until a run finished successfully and after failed runs
+func (s *daemonStatus) handler() *http.ServeMux {
+  mux := http.NewServeMux()
+  mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+    s.writeJSON(w, http.StatusOK)
+  })
+  mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+    s.mu.Lock()
+    ready := s.ready()
+    s.mu.Unlock()
+
+    code := http.StatusOK
+    if !ready {
+      code = http.StatusServiceUnavailable
+    }
+    s.writeJSON(w, code)
+  })
+
+  return mux
+}
+
+// writeJSON writes the status as JSON with the given status code
+func (s *daemonStatus) writeJSON(w http.ResponseWriter, code int) {
+  s.mu.Lock()
+  body, err := json.Marshal(s)
+  s.mu.Unlock()
+  if err != nil {
+    http.Error(w, err.Error(), http.StatusInternalServerError)
+    return
+  }
+
+  w.Header().Set("Content-Type", "application/json")
+  w.WriteHeader(code)
+  w.Write(body)
+}
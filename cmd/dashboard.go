@@ -0,0 +1,261 @@
+package cmd
+
+import (
+  "html/template"
+  "net/http"
+  "sort"
+  "strings"
+  "time"
+
+  gl "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/gitlab"
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/history"
+)
+
+// dashboardHistoryLimit limits the changes listed on the page of a project
+const dashboardHistoryLimit = 50
+
+// dashboardTemplates render the pages of the dashboard, without external resources
+var dashboardTemplates = template.Must(template.New("layout").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>GitLab Settings Enforcer{{with .Title}} - {{.}}{{end}}</title>
+<style>
+  body { font-family: sans-serif; margin: 2em; color: #333; }
+  table { border-collapse: collapse; margin-bottom: 2em; }
+  th, td { border: 1px solid #ccc; padding: 0.3em 0.8em; text-align: left; }
+  th { background: #f0f0f0; }
+  td.value { font-family: monospace; }
+  .summary td:first-child { font-weight: bold; }
+  .compliant { color: #1a7f37; }
+  .violation { color: #cf222e; font-weight: bold; }
+</style>
+</head>
+<body>
+<p><a href="/">GitLab Settings Enforcer</a></p>
+{{template "content" .}}
+</body>
+</html>
+{{define "outcome"}}{{if .Compliant}}<span class="compliant">{{.Outcome}}</span>{{else}}<span class="violation">{{.Outcome}}{{if .Dryrun}} (dryrun){{end}}</span>{{end}}{{end}}
+`))
+
+var dashboardOverviewTemplate = template.Must(template.Must(dashboardTemplates.Clone()).Parse(`{{define "content"}}
+<h1>Compliance</h1>
+<table class="summary">
+  <tr><td>Last run</td><td>{{.LastRun}}{{if .Running}} (running){{end}}</td></tr>
+  <tr><td>Status updated</td><td>{{.Updated}}</td></tr>
+  <tr><td>Projects</td><td>{{len .Projects}}</td></tr>
+  <tr><td>Compliant</td><td>{{.Compliant}}</td></tr>
+  <tr><td>Non-compliant</td><td>{{.NonCompliant}}</td></tr>
+</table>
+{{if .Projects}}
+<table>
+  <thead><tr><th>Project</th><th>Outcome</th><th>Drifted settings</th><th>Checked</th></tr></thead>
+  <tbody>
+  {{range .Projects}}<tr><td><a href="/projects/{{.Project}}">{{.Project}}</a></td><td>{{template "outcome" .}}</td><td>{{len .Changes}}</td><td>{{.CheckedAt.Format "2006-01-02 15:04:05 MST"}}</td></tr>
+  {{end}}</tbody>
+</table>
+{{else}}
+<p>No project enforced yet.</p>
+{{end}}
+{{end}}`))
+
+var dashboardProjectTemplate = template.Must(template.Must(dashboardTemplates.Clone()).Parse(`{{define "content"}}
+<h1>{{.Title}}</h1>
+<table class="summary">
+  <tr><td>Outcome</td><td>{{template "outcome" .Project}}</td></tr>
+  <tr><td>Checked</td><td>{{.Project.CheckedAt.Format "2006-01-02 15:04:05 MST"}}</td></tr>
+</table>
+<h2>Drifted settings</h2>
+{{if .Changes}}
+<table>
+  <thead><tr><th>Section</th><th>Setting</th><th>From</th><th>To</th></tr></thead>
+  <tbody>
+  {{range .Changes}}<tr><td>{{.Subsection}}</td><td>{{.Setting}}</td><td class="value">{{.From}}</td><td class="value">{{.To}}</td></tr>
+  {{end}}</tbody>
+</table>
+{{else}}
+<p>No drifted settings found by the latest run.</p>
+{{end}}
+{{if .HistoryEnabled}}
+<h2>History</h2>
+{{if .History}}
+<table>
+  <thead><tr><th>Run</th><th>Started</th><th>Section</th><th>Setting</th><th>From</th><th>To</th></tr></thead>
+  <tbody>
+  {{range .History}}<tr><td>{{.Run}}{{if .Dryrun}} (dryrun){{end}}</td><td>{{.StartedAt}}</td><td>{{.Subsection}}</td><td>{{.Setting}}</td><td class="value">{{.From}}</td><td class="value">{{.To}}</td></tr>
+  {{end}}</tbody>
+</table>
+{{else}}
+<p>No changes recorded.</p>
+{{end}}
+{{end}}
+{{end}}`))
+
+type dashboardOverview struct {
+  Title        string
+  LastRun      string
+  Running      bool
+  Updated      string
+  Compliant    int
+  NonCompliant int
+  Projects     []projectStatus
+}
+
+type dashboardProject struct {
+  Title          string
+  Project        projectStatus
+  Changes        []dashboardChange
+  HistoryEnabled bool
+  History        []dashboardChange
+}
+
+type dashboardChange struct {
+  Run        int64
+  Dryrun     bool
+  StartedAt  string
+  Subsection string
+  Setting    string
+  From       string
+  To         string
+}
+
+// dashboard serves read-only pages on the project outcomes recorded in the status file and, if
+// given, the changes recorded in the history database
+type dashboard struct {
+  status     *daemonStatus
+  statusFile string
+  historyDB  string
+}
+
+// handler serves the overview on / and the page of each project on /projects/<path>
+func (d *dashboard) handler() http.Handler {
+  mux := http.NewServeMux()
+  mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+    if r.URL.Path != "/" {
+      http.NotFound(w, r)
+      return
+    }
+    d.serveOverview(w, r)
+  })
+  mux.HandleFunc("/projects/", d.serveProject)
+
+  return mux
+}
+
+// serveOverview lists all projects with their latest outcome
+func (d *dashboard) serveOverview(w http.ResponseWriter, r *http.Request) {
+  status, err := loadComplianceStatus(d.statusFile)
+  if err != nil {
+    d.fail(w, r, err)
+    return
+  }
+
+  page := dashboardOverview{
+    LastRun:  "never",
+    Updated:  "never",
+    Projects: status.sorted(),
+  }
+
+  d.status.mu.Lock()
+  if !d.status.LastRunFinished.IsZero() {
+    page.LastRun = d.status.LastRunFinished.Format(time.RFC1123)
+  }
+  page.Running = d.status.Running
+  d.status.mu.Unlock()
+
+  if !status.UpdatedAt.IsZero() {
+    page.Updated = status.UpdatedAt.Format(time.RFC1123)
+  }
+  for _, project := range page.Projects {
+    if project.Compliant {
+      page.Compliant++
+    } else {
+      page.NonCompliant++
+    }
+  }
+
+  d.render(w, r, dashboardOverviewTemplate, page)
+}
+
+// serveProject shows the drifted settings and the recorded changes of a single project
+func (d *dashboard) serveProject(w http.ResponseWriter, r *http.Request) {
+  status, err := loadComplianceStatus(d.statusFile)
+  if err != nil {
+    d.fail(w, r, err)
+    return
+  }
+
+  name := strings.TrimPrefix(r.URL.Path, "/projects/")
+  project, ok := status.Projects[name]
+  if !ok {
+    http.NotFound(w, r)
+    return
+  }
+
+  page := dashboardProject{Title: name, Project: project, HistoryEnabled: d.historyDB != ""}
+
+  for subsection, settings := range project.Changes {
+    for setting, values := range settings {
+      page.Changes = append(page.Changes, dashboardChange{
+        Subsection: subsection,
+        Setting:    setting,
+        From:       gl.FormatValue(values["From"]),
+        To:         gl.FormatValue(values["To"]),
+      })
+    }
+  }
+  sort.Slice(page.Changes, func(i, j int) bool {
+    if page.Changes[i].Subsection != page.Changes[j].Subsection {
+      return page.Changes[i].Subsection < page.Changes[j].Subsection
+    }
+    return page.Changes[i].Setting < page.Changes[j].Setting
+  })
+
+  if page.HistoryEnabled {
+    store, err := history.Open(d.historyDB)
+    if err != nil {
+      d.fail(w, r, err)
+      return
+    }
+    defer store.Close()
+
+    changes, err := store.Changes(history.ChangeFilter{Project: name, Limit: dashboardHistoryLimit})
+    if err != nil {
+      d.fail(w, r, err)
+      return
+    }
+
+    for _, change := range changes {
+      page.History = append(page.History, dashboardChange{
+        Run:        change.RunID,
+        Dryrun:     change.Dryrun,
+        StartedAt:  change.StartedAt.Format(time.RFC1123),
+        Subsection: change.Subsection,
+        Setting:    change.Setting,
+        From:       gl.FormatValue(change.From),
+        To:         gl.FormatValue(change.To),
+      })
+    }
+  }
+
+  d.render(w, r, dashboardProjectTemplate, page)
+}
+
+// render executes the page template, failing before anything is written
+func (d *dashboard) render(w http.ResponseWriter, r *http.Request, page *template.Template, data interface{}) {
+  var body strings.Builder
+  if err := page.Execute(&body, data); err != nil {
+    d.fail(w, r, err)
+    return
+  }
+
+  w.Header().Set("Content-Type", "text/html; charset=utf-8")
+  w.Write([]byte(body.String()))
+}
+
+// fail logs the error and answers with 500, without exposing the error
+func (d *dashboard) fail(w http.ResponseWriter, r *http.Request, err error) {
+  logger.Errorf("failed to serve %s: %v", r.URL.Path, err)
+  http.Error(w, "failed to render the page, see the logs of the server", http.StatusInternalServerError)
+}
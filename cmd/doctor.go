@@ -0,0 +1,70 @@
+package cmd
+
+import (
+  "io"
+
+  "github.com/spf13/cobra"
+
+  gl "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/gitlab"
+)
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+  Use:   "doctor",
+  Short: "Verify the connection to GitLab and that the token suffices to enforce the config",
+  Long: `Verify the connection to GitLab and that the token suffices to enforce the config.
+
+Checks that the endpoint is reachable and the token valid, reports the scopes of the token and
+its access level on the configured groups, and checks the GitLab version and edition against the
+features used by the config. Exits with 1 if any check failed.`,
+  RunE: func(cmd *cobra.Command, args []string) error {
+    ctx, cancel := newSignalContext()
+    defer cancel()
+
+    if outputFormat != gl.OutputFormatText && outputFormat != gl.OutputFormatJSON {
+      logger.Fatalf("unsupported output format %q, doctor supports text and json", outputFormat)
+    }
+
+    reports, err := openReports()
+    if err != nil {
+      logger.Fatal(err)
+    }
+    defer reports.Close()
+
+    var failed bool
+    for _, instance := range syncInstances() {
+      reports.section = instance.Name
+
+      if err := useInstance(instance); err != nil {
+        logger.Errorf("failed to check instance %s: %v", instance.Name, err)
+        failed = true
+        continue
+      }
+
+      checks := newProjectManager(newGitlabClient()).Doctor(ctx)
+      if err := checkInterrupted(ctx); err != nil {
+        return err
+      }
+
+      report := func(w io.Writer) error {
+        return gl.RenderDoctorChecks(w, outputFormat, checks)
+      }
+      if err := reports.write("doctor", outputFormat, report); err != nil {
+        logger.Errorf("failed to create doctor report: %v", err)
+        failed = true
+      }
+
+      failed = failed || gl.DoctorChecksFailed(checks)
+    }
+
+    if failed {
+      logger.Fatal("Doctor found problems.")
+    }
+
+    return nil
+  },
+}
+
+func init() {
+  rootCmd.AddCommand(doctorCmd)
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+  "fmt"
+  "io"
+  "sync"
+
+  "github.com/sirupsen/logrus"
+)
+
+// runErrors collects every error logged during the run, so they can be reported together at its end
+var runErrors = &errorCollector{}
+
+func init() {
+  logger.AddHook(runErrors)
+}
+
+// errorCollector is a logrus.Hook recording the messages of all logged errors
+type errorCollector struct {
+  mu       sync.Mutex
+  messages []string
+}
+
+// Levels implements logrus.Hook
+func (c *errorCollector) Levels() []logrus.Level {
+  return []logrus.Level{logrus.ErrorLevel}
+}
+
+// Fire implements logrus.Hook
+func (c *errorCollector) Fire(entry *logrus.Entry) error {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+
+  c.messages = append(c.messages, entry.Message)
+  return nil
+}
+
+// Count returns the number of errors logged so far
+func (c *errorCollector) Count() int {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+
+  return len(c.messages)
+}
+
+// GenerateReport writes all logged errors to w, in the order they occurred
+func (c *errorCollector) GenerateReport(w io.Writer) error {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+
+  if len(c.messages) == 0 {
+    return nil
+  }
+
+  fmt.Fprintf(w, "\nERRORS\n")
+  for _, message := range c.messages {
+    fmt.Fprintf(w, "  %s\n", message)
+  }
+
+  _, err := fmt.Fprintf(w, "\n")
+  return err
+}
@@ -0,0 +1,23 @@
+package cmd
+
+import (
+  "os"
+
+  gl "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/gitlab"
+)
+
+// exitCodeDrift is the exit code of runs finding drifted settings with --fail-on-drift.
+// Runs in sync exit with 0, runs with errors with 1 (logger.Fatal).
+const exitCodeDrift = 2
+
+// exitIfDrifted exits with exitCodeDrift if drifted settings were found and failOnDrift is set
+func exitIfDrifted(changelog gl.ChangeLog, failOnDrift bool) {
+  if !failOnDrift {
+    return
+  }
+
+  if len(changelog) > 0 {
+    logger.Warnf("Drift detected in %d project(s).", len(changelog))
+    os.Exit(exitCodeDrift)
+  }
+}
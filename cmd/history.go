@@ -0,0 +1,211 @@
+package cmd
+
+import (
+  "encoding/json"
+  "fmt"
+  "io"
+  "text/tabwriter"
+  "time"
+
+  "github.com/spf13/cobra"
+
+  gl "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/gitlab"
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/history"
+)
+
+var (
+  historyDB      string
+  historyRun     int64
+  historyProject string
+  historySetting string
+  historyLimit   int
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+  Use:   "history",
+  Short: "Query the runs and changes recorded with sync --history-db",
+  Long: `Query the runs and changes recorded with sync --history-db.
+
+Without flags, the latest runs are listed. --run lists the project outcomes and changes of a run,
+--project and --setting list the changes of a project or setting across runs, e.g.
+
+  history --history-db runs.db --project example/repo --setting merge_method`,
+  Annotations: map[string]string{annotationNoConfig: "true"},
+  Run: func(cmd *cobra.Command, args []string) {
+    if outputFormat != gl.OutputFormatText && outputFormat != gl.OutputFormatJSON {
+      logger.Fatalf("unsupported output format %q, history supports text and json", outputFormat)
+    }
+    if historyDB == "" {
+      logger.Fatal("--history-db is required")
+    }
+
+    store, err := history.Open(historyDB)
+    if err != nil {
+      logger.Fatal(err)
+    }
+    defer store.Close()
+
+    reports, err := openReports()
+    if err != nil {
+      logger.Fatal(err)
+    }
+    defer reports.Close()
+
+    var report func(io.Writer) error
+    switch {
+    case historyRun != 0:
+      projects, err := store.ProjectResults(historyRun)
+      if err != nil {
+        logger.Fatal(err)
+      }
+      changes, err := store.Changes(history.ChangeFilter{RunID: historyRun})
+      if err != nil {
+        logger.Fatal(err)
+      }
+      report = func(w io.Writer) error {
+        return renderHistoryRun(w, projects, changes)
+      }
+    case historyProject != "" || historySetting != "":
+      changes, err := store.Changes(history.ChangeFilter{Project: historyProject, Setting: historySetting, Limit: historyLimit})
+      if err != nil {
+        logger.Fatal(err)
+      }
+      report = func(w io.Writer) error {
+        return renderHistoryChanges(w, changes)
+      }
+    default:
+      runs, err := store.Runs(historyLimit)
+      if err != nil {
+        logger.Fatal(err)
+      }
+      report = func(w io.Writer) error {
+        return renderHistoryRuns(w, runs)
+      }
+    }
+
+    if err := reports.write("history", outputFormat, report); err != nil {
+      logger.Fatalf("failed to create history report: %v", err)
+    }
+  },
+}
+
+func init() {
+  rootCmd.AddCommand(historyCmd)
+
+  historyCmd.Flags().StringVar(&historyDB, "history-db", "", "SQLite database the runs were recorded in")
+  historyCmd.Flags().Int64Var(&historyRun, "run", 0, "List the project outcomes and changes of this run")
+  historyCmd.Flags().StringVar(&historyProject, "project", "", "List the changes of this project")
+  historyCmd.Flags().StringVar(&historySetting, "setting", "", "List the changes of this setting, e.g. merge_method or project_settings.merge_method")
+  historyCmd.Flags().IntVar(&historyLimit, "limit", 20, "Maximum number of runs or changes to list")
+}
+
+// recordHistory records the run in the database given by --history-db, if any
+func recordHistory(results []*syncResult, changes gl.ChangeLog, failed bool, started time.Time) error {
+  if historyDB == "" {
+    return nil
+  }
+
+  run := &history.Run{
+    Command:   "sync",
+    StartedAt: started,
+    Duration:  time.Since(started),
+    Dryrun:    env.Dryrun,
+    Failed:    failed,
+  }
+
+  for _, result := range results {
+    for _, project := range result.projects {
+      if len(results) > 1 {
+        project.Project = result.instance.Name + ":" + project.Project
+      }
+      run.Projects = append(run.Projects, project)
+    }
+  }
+
+  for project, subsections := range changes {
+    for subsection, settings := range subsections {
+      for setting, values := range settings {
+        run.Changes = append(run.Changes, history.Change{
+          Project:    project,
+          Subsection: subsection,
+          Setting:    setting,
+          From:       values["From"],
+          To:         values["To"],
+        })
+      }
+    }
+  }
+
+  store, err := history.Open(historyDB)
+  if err != nil {
+    return err
+  }
+  defer store.Close()
+
+  if err := store.Record(run); err != nil {
+    return err
+  }
+
+  logger.Infof("Recorded run #%d in %s.", run.ID, historyDB)
+  return nil
+}
+
+// renderHistoryRuns writes the runs as table, or as JSON for the json output format
+func renderHistoryRuns(w io.Writer, runs []history.Run) error {
+  if outputFormat == gl.OutputFormatJSON {
+    return renderHistoryJSON(w, runs)
+  }
+
+  tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+  fmt.Fprintf(tw, "RUN\tSTARTED\tCOMMAND\tDRYRUN\tFAILED\tDURATION\tPROJECTS\tCHANGED\tFAILED PROJECTS\n")
+  for _, r := range runs {
+    fmt.Fprintf(tw, "%d\t%s\t%s\t%t\t%t\t%s\t%d\t%d\t%d\n", r.ID, r.StartedAt.Format(time.RFC3339), r.Command, r.Dryrun, r.Failed, r.Duration, r.ProjectCount, r.ChangedCount, r.FailedCount)
+  }
+  return tw.Flush()
+}
+
+// renderHistoryRun writes the project outcomes and changes of a run as tables, or as JSON for
+// the json output format
+func renderHistoryRun(w io.Writer, projects []history.ProjectResult, changes []history.Change) error {
+  if outputFormat == gl.OutputFormatJSON {
+    return renderHistoryJSON(w, map[string]interface{}{"projects": projects, "changes": changes})
+  }
+
+  tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+  fmt.Fprintf(tw, "PROJECT\tOUTCOME\tDURATION\n")
+  for _, p := range projects {
+    fmt.Fprintf(tw, "%s\t%s\t%s\n", p.Project, p.Outcome, p.Duration)
+  }
+  if err := tw.Flush(); err != nil {
+    return err
+  }
+
+  fmt.Fprintf(w, "\n")
+  return renderHistoryChanges(w, changes)
+}
+
+// renderHistoryChanges writes the changes as table, or as JSON for the json output format
+func renderHistoryChanges(w io.Writer, changes []history.Change) error {
+  if outputFormat == gl.OutputFormatJSON {
+    return renderHistoryJSON(w, changes)
+  }
+
+  tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+  fmt.Fprintf(tw, "RUN\tSTARTED\tDRYRUN\tPROJECT\tSETTING\tFROM\tTO\n")
+  for _, c := range changes {
+    fmt.Fprintf(tw, "%d\t%s\t%t\t%s\t%s.%s\t%s\t%s\n", c.RunID, c.StartedAt.Format(time.RFC3339), c.Dryrun, c.Project, c.Subsection, c.Setting, gl.FormatValue(c.From), gl.FormatValue(c.To))
+  }
+  return tw.Flush()
+}
+
+// renderHistoryJSON writes v as indented JSON
+func renderHistoryJSON(w io.Writer, v interface{}) error {
+  body, err := json.MarshalIndent(v, "", "  ")
+  if err != nil {
+    return fmt.Errorf("failed to marshal history: %v", err)
+  }
+
+  _, err = fmt.Fprintf(w, "%s\n", body)
+  return err
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+  "context"
+  "crypto/subtle"
+  "encoding/json"
+  "errors"
+  "net/http"
+
+  gl "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/gitlab"
+)
+
+// systemHookPath is the endpoint of the daemon receiving GitLab system hooks
+const systemHookPath = "/hooks/system"
+
+// systemHookQueueSize limits the number of announced projects waiting to be enforced
+const systemHookQueueSize = 100
+
+var errSystemHookSecretRequired = errors.New("--system-hooks requires SYSTEM_HOOK_SECRET to be set")
+
+// systemHookEvents are the system hook events announcing projects to enforce
+var systemHookEvents = map[string]bool{
+  "project_create":   true,
+  "project_transfer": true,
+}
+
+// systemHookListener receives GitLab system hooks, queueing the paths of new and transferred projects
+type systemHookListener struct {
+  manager  *gl.ProjectManager
+  secret   string
+  projects chan string
+}
+
+type systemHookEvent struct {
+  EventName         string `json:"event_name"`
+  PathWithNamespace string `json:"path_with_namespace"`
+}
+
+// newSystemHookListener returns a listener accepting hooks sent with the given secret token,
+// checking announced projects against the config with the manager
+func newSystemHookListener(manager *gl.ProjectManager, secret string) *systemHookListener {
+  return &systemHookListener{
+    manager:  manager,
+    secret:   secret,
+    projects: make(chan string, systemHookQueueSize),
+  }
+}
+
+// Projects returns the queue of announced project paths. A nil listener never announces any.
+func (l *systemHookListener) Projects() <-chan string {
+  if l == nil {
+    return nil
+  }
+
+  return l.projects
+}
+
+// ServeHTTP implements http.Handler
+func (l *systemHookListener) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+  if r.Method != http.MethodPost {
+    http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+    return
+  }
+
+  if subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Gitlab-Token")), []byte(l.secret)) != 1 {
+    logger.Warnf("Rejected system hook with invalid secret token from %s.", r.RemoteAddr)
+    http.Error(w, "invalid token", http.StatusUnauthorized)
+    return
+  }
+
+  var event systemHookEvent
+  if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+    http.Error(w, "invalid payload", http.StatusBadRequest)
+    return
+  }
+
+  // GitLab sends all events to system hooks, the others are ignored
+  if !systemHookEvents[event.EventName] || event.PathWithNamespace == "" {
+    w.WriteHeader(http.StatusNoContent)
+    return
+  }
+
+  select {
+  case l.projects <- event.PathWithNamespace:
+    logger.Debugf("Queued project %s of system hook %s.", event.PathWithNamespace, event.EventName)
+    w.WriteHeader(http.StatusAccepted)
+  default:
+    logger.Warnf("Dropped project %s of system hook %s, too many projects are queued.", event.PathWithNamespace, event.EventName)
+    http.Error(w, "queue full", http.StatusServiceUnavailable)
+  }
+}
+
+// matches returns whether the project at path is matched by the groups, project whitelist,
+// blacklist and filters of the config
+func (l *systemHookListener) matches(ctx context.Context, path string) bool {
+  l.manager.ResolveGroupID()
+  // Looked up per event, a renamed group keeps being matched
+  if err := l.manager.ResolveGroupPath(ctx); err != nil {
+    logger.Errorf("failed to check project %s of system hook: %v", path, err)
+    return false
+  }
+  if err := l.manager.DiscoverGroups(ctx); err != nil {
+    logger.Errorf("failed to check project %s of system hook: %v", path, err)
+    return false
+  }
+
+  projects, err := l.manager.GetProjectsByPattern(ctx, []string{path})
+  if err != nil {
+    logger.Errorf("failed to check project %s of system hook: %v", path, err)
+    return false
+  }
+
+  for _, p := range projects {
+    if l.manager.IsProjectMatched(p) {
+      return true
+    }
+  }
+
+  logger.Infof("Ignoring project %s of system hook, it isn't matched by the config.", path)
+  return false
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+  "io"
+  "os"
+  "time"
+
+  "github.com/spf13/cobra"
+  "github.com/xanzy/go-gitlab"
+)
+
+var (
+  initFile  string
+  initForce bool
+)
+
+// initCmd represents the init command
+var initCmd = &cobra.Command{
+  Use:   "init <project>",
+  Short: "Generate a starter config from the settings of an exemplar project",
+  Long: `Generate a starter config from the settings of an exemplar project.
+
+The config enforces the project settings, approval settings and protected branches of the given
+project on the group containing it. Settings which are specific to the project, like its name or
+description, are written commented out. Review the config before enforcing it.`,
+  Args:        cobra.ExactArgs(1),
+  Annotations: map[string]string{annotationNoConfig: "true"},
+  RunE: func(cmd *cobra.Command, args []string) error {
+    ctx, cancel := newSignalContext()
+    defer cancel()
+
+    client := newGitlabClient()
+    manager := newProjectManager(client)
+
+    project, _, err := client.Projects.GetProject(args[0], &gitlab.GetProjectOptions{}, gitlab.WithContext(ctx))
+    if err != nil {
+      if err := checkInterrupted(ctx); err != nil {
+        return err
+      }
+      logger.Fatalf("failed to get project %s: %v", args[0], err)
+    }
+
+    var w io.Writer = os.Stdout
+    if initFile != "" {
+      flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+      if initForce {
+        flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+      }
+
+      f, err := os.OpenFile(initFile, flags, 0644)
+      if os.IsExist(err) {
+        logger.Fatalf("%s already exists, use --force to overwrite it", initFile)
+      }
+      if err != nil {
+        logger.Fatalf("failed to create config file: %v", err)
+      }
+      defer f.Close()
+      w = f
+    }
+
+    if err := manager.Scaffold(ctx, w, *project, time.Now()); err != nil {
+      if err := checkInterrupted(ctx); err != nil {
+        return err
+      }
+      logger.Fatal(err)
+    }
+
+    if initFile != "" {
+      logger.Infof("Wrote the config of project %s to %s, review it and try it with: DRYRUN=true sync --config %s", project.PathWithNamespace, initFile, initFile)
+    }
+
+    return nil
+  },
+}
+
+func init() {
+  rootCmd.AddCommand(initCmd)
+
+  initCmd.Flags().StringVar(&initFile, "file", "", "File to write the config to (default: stdout)")
+  initCmd.Flags().BoolVar(&initForce, "force", false, "Overwrite the file if it exists")
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+  "fmt"
+  "os"
+  "path/filepath"
+  "strings"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/config"
+  gl "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/gitlab"
+)
+
+// syncInstances returns the instances of the config. Without any, the instance given by the
+// env config is returned, which has no name.
+func syncInstances() []config.Instance {
+  if len(cfg.Instances) > 0 {
+    return cfg.Instances
+  }
+
+  return []config.Instance{{}}
+}
+
+// useInstance points the env config and the groups of the config at the given instance,
+// so the next GitLab client and project manager talk to it
+func useInstance(instance config.Instance) error {
+  if instance.Name == "" {
+    return nil
+  }
+
+  token := os.Getenv(instance.TokenEnv)
+  if instance.TokenFile != "" {
+    var err error
+    if token, err = readToken(instance.TokenFile); err != nil {
+      return err
+    }
+  }
+  if token == "" {
+    return fmt.Errorf("no GitLab token given for instance %s, set %s", instance.Name, instance.TokenEnv)
+  }
+
+  env.GitlabEndpoint = instance.Endpoint
+  env.GitlabToken = token
+  env.GitlabTokenType = tokenTypePrivate
+  env.GitlabOauthRefreshToken = ""
+
+  cfg.GroupName = instance.GroupName
+  cfg.GroupID = instance.GroupID
+  cfg.Groups = instance.Groups
+  cfg.Users = instance.Users
+  cfg.ProjectList = instance.ProjectList
+  cfg.AllGroups = instance.AllGroups
+
+  return nil
+}
+
+// instanceFile returns the path of the file of the named instance, inserting its name before
+// the extension of path. Unnamed instances use path as is.
+func instanceFile(path string, name string) string {
+  if name == "" || path == "" {
+    return path
+  }
+
+  ext := filepath.Ext(path)
+  return strings.TrimSuffix(path, ext) + "." + name + ext
+}
+
+// mergeChangeLogs merges the change logs of all instances, prefixing the projects with the name
+// of their instance
+func mergeChangeLogs(results []*syncResult) gl.ChangeLog {
+  if len(results) == 1 && results[0].manager != nil {
+    return results[0].manager.ChangeLog()
+  }
+
+  changes := gl.ChangeLog{}
+  for _, result := range results {
+    if result.manager == nil {
+      continue
+    }
+
+    for project, subsections := range result.manager.ChangeLog() {
+      changes[result.instance.Name+":"+project] = subsections
+    }
+  }
+
+  return changes
+}
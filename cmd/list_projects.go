@@ -0,0 +1,74 @@
+package cmd
+
+import (
+  "io"
+
+  "github.com/spf13/cobra"
+
+  gl "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/gitlab"
+)
+
+// listProjectsCmd represents the list-projects command
+var listProjectsCmd = &cobra.Command{
+  Use:   "list-projects",
+  Short: "List the projects matched by the config",
+  Long: `List the projects matched by the config, after applying the project whitelist, blacklist,
+topics filter and project filters, to preview which projects sync would enforce.`,
+  RunE: func(cmd *cobra.Command, args []string) error {
+    ctx, cancel := newSignalContext()
+    defer cancel()
+
+    if outputFormat != gl.OutputFormatText && outputFormat != gl.OutputFormatJSON {
+      logger.Fatalf("unsupported output format %q, list-projects supports text and json", outputFormat)
+    }
+
+    reports, err := openReports()
+    if err != nil {
+      logger.Fatal(err)
+    }
+    defer reports.Close()
+
+    var failed bool
+    for _, instance := range syncInstances() {
+      reports.section = instance.Name
+
+      if err := useInstance(instance); err != nil {
+        logger.Errorf("failed to list projects of instance %s: %v", instance.Name, err)
+        failed = true
+        continue
+      }
+
+      projects, err := newProjectManager(newGitlabClient()).GetProjects(ctx)
+      if err != nil {
+        if err := checkInterrupted(ctx); err != nil {
+          return err
+        }
+        logger.Errorf("failed to get projects: %v", err)
+        failed = true
+        continue
+      }
+
+      report := func(w io.Writer) error {
+        return gl.RenderProjects(w, outputFormat, projects)
+      }
+      if err := reports.write("projects", outputFormat, report); err != nil {
+        logger.Errorf("failed to create projects report: %v", err)
+        failed = true
+      }
+    }
+
+    if err := checkInterrupted(ctx); err != nil {
+      return err
+    }
+
+    if failed {
+      logger.Fatalf("%d error(s) encountered.", runErrors.Count())
+    }
+
+    return nil
+  },
+}
+
+func init() {
+  rootCmd.AddCommand(listProjectsCmd)
+}
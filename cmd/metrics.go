@@ -0,0 +1,73 @@
+package cmd
+
+import (
+  gl "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/gitlab"
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/metrics"
+)
+
+var (
+  runMetrics = metrics.New()
+
+  metricsPushgatewayURL string
+  metricsJob            string
+)
+
+func init() {
+  rootCmd.PersistentFlags().StringVar(&metricsPushgatewayURL, "pushgateway-url", "", "Push the metrics of the run to the Prometheus Pushgateway at this URL")
+  rootCmd.PersistentFlags().StringVar(&metricsJob, "metrics-job", "gitlab-settings-enforcer", "Job name of the pushed metrics")
+
+  // Count logged errors
+  logger.AddHook(runMetrics)
+}
+
+// pushMetrics records the results of the run and pushes them, if --pushgateway-url is set.
+// Settings GitLab didn't apply drifted, but weren't remediated.
+func pushMetrics(results []*syncResult, changelog gl.ChangeLog, scanned int, failed bool) {
+  if metricsPushgatewayURL == "" {
+    return
+  }
+
+  remediated := countSettings(changelog)
+  drifted := remediated
+  driftedProjects := len(changelog)
+  for _, result := range results {
+    if result.manager == nil {
+      continue
+    }
+
+    notApplied := result.manager.NotApplied()
+    drifted += countSettings(notApplied)
+    for project := range notApplied {
+      // Keyed like the merged change log
+      if len(results) > 1 {
+        project = result.instance.Name + ":" + project
+      }
+      if _, ok := changelog[project]; !ok {
+        driftedProjects++
+      }
+    }
+  }
+
+  // The change log of a dryrun holds the planned changes, nothing was remediated
+  if env.Dryrun {
+    remediated = 0
+  }
+
+  runMetrics.ObserveRun(scanned, driftedProjects, drifted, remediated, failed)
+
+  if err := runMetrics.Push(metricsPushgatewayURL, metricsJob); err != nil {
+    logger.Warnf("%v", err)
+  }
+}
+
+// countSettings returns the number of settings in the change log
+func countSettings(changelog gl.ChangeLog) int {
+  var settings int
+  for _, subsections := range changelog {
+    for _, changes := range subsections {
+      settings += len(changes)
+    }
+  }
+
+  return settings
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+  "errors"
+  "io"
+
+  "github.com/spf13/cobra"
+
+  gl "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/gitlab"
+)
+
+var errDefaultBranchMigrationRequired = errors.New("default_branch_migration must be configured")
+
+// migrateDefaultBranchCmd represents the migrate-default-branch command
+var migrateDefaultBranchCmd = &cobra.Command{
+  Use:   "migrate-default-branch",
+  Short: "Move the default branch of all matched projects as configured in default_branch_migration",
+  Long: `Move the default branch of all matched projects as configured in default_branch_migration.
+
+For every project whose default branch is default_branch_migration.from, the branch
+default_branch_migration.to is created from it, takes over its protection and becomes the default
+branch. Open merge requests into the old branch are retargeted, and with delete_old_branch the old
+branch is deleted.`,
+  RunE: func(cmd *cobra.Command, args []string) error {
+    if cfg.DefaultBranchMigration == nil {
+      logger.Fatal(errDefaultBranchMigrationRequired)
+    }
+
+    ctx, cancel := newSignalContext()
+    defer cancel()
+
+    renderer, err := gl.NewChangeLogRenderer(outputFormat, gl.RenderOptions{Planned: env.Dryrun, Color: outputColor})
+    if err != nil {
+      logger.Fatal(err)
+    }
+
+    reports, err := openReports()
+    if err != nil {
+      logger.Fatal(err)
+    }
+    defer reports.Close()
+
+    if env.Dryrun {
+      logger.Infof("DRYRUN: No changes will be implemented.")
+    }
+
+    client := newGitlabClient()
+    manager := newProjectManager(client)
+
+    // Dryruns don't mutate anything to audit
+    if !env.Dryrun {
+      auditLog, err := openAuditLog(ctx, client, manager)
+      if err != nil {
+        logger.Fatal(err)
+      }
+      if auditLog != nil {
+        defer auditLog.Close()
+      }
+    }
+
+    projects, err := getSyncProjects(ctx, manager)
+    if err != nil {
+      if err := checkInterrupted(ctx); err != nil {
+        return err
+      }
+      logger.Fatal(err)
+    }
+
+    logger.Infof("Migrating the default branch of %d project(s) from %s to %s.", len(projects), cfg.DefaultBranchMigration.From, cfg.DefaultBranchMigration.To)
+
+    for index, project := range projects {
+      if ctx.Err() != nil {
+        logger.Warnf("Skipping remaining %d project(s).", len(projects)-index)
+        break
+      }
+
+      logger.Infof("Processing project #%d: %s", index + 1, project.PathWithNamespace)
+
+      if err := manager.MigrateDefaultBranch(ctx, project, env.Dryrun); err != nil {
+        logger.Errorf("failed to migrate default branch of repo %v: %v", project.PathWithNamespace, err)
+        manager.AddFailure(project.PathWithNamespace, err)
+        manager.SetError(true)
+      }
+    }
+
+    changelog := func(w io.Writer) error {
+      return manager.GenerateChangeLogReport(w, renderer)
+    }
+    if err := reports.write("changelog", outputFormat, changelog); err != nil {
+      logger.Errorf("failed to create changelog report: %v", err)
+      manager.SetError(true)
+    }
+
+    if err := reports.write("failures", gl.OutputFormatText, manager.GenerateFailureReport); err != nil {
+      logger.Errorf("failed to create failure report: %v", err)
+      manager.SetError(true)
+    }
+
+    if err := checkInterrupted(ctx); err != nil {
+      return err
+    }
+
+    if manager.GetError() {
+      logger.Fatalf("%d error(s) encountered.", runErrors.Count())
+    }
+
+    return nil
+  },
+}
+
+func init() {
+  rootCmd.AddCommand(migrateDefaultBranchCmd)
+
+  migrateDefaultBranchCmd.Flags().StringArrayVar(&syncProjects, "project", nil, "Only migrate this project path or glob, ignoring the project whitelist, blacklist and filters (repeatable)")
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+  "context"
+  "net/http"
+  "time"
+
+  gl "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/gitlab"
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/notify"
+)
+
+// notifyTimeout limits the time each notification backend may take
+const notifyTimeout = 30 * time.Second
+
+// notifyRun sends the digest of the run to all configured notification backends
+func notifyRun(changes gl.ChangeLog, failed bool, command string, started time.Time, summary *gl.RunSummary) {
+  notifiers := notify.New(cfg.Notifications, &http.Client{Timeout: notifyTimeout})
+  if len(notifiers) == 0 {
+    return
+  }
+
+  digest := notify.Digest{
+    Command:  command,
+    Dryrun:   env.Dryrun,
+    Failed:   failed,
+    Started:  started,
+    Finished: time.Now(),
+    Changes:  changes,
+    Summary:  summary,
+  }
+
+  // The run may have been interrupted already, the digest is sent nevertheless
+  for _, notifier := range notifiers {
+    ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+    if err := notifier.Notify(ctx, digest); err != nil {
+      logger.Warnf("%v", err)
+    }
+    cancel()
+  }
+}
@@ -0,0 +1,224 @@
+package cmd
+
+import (
+  "context"
+  "encoding/json"
+  "fmt"
+  "io/ioutil"
+  "net/http"
+  "os"
+  "path/filepath"
+  "time"
+
+  "github.com/spf13/cobra"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/config"
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/kubernetes"
+)
+
+var (
+  operatorInterval      time.Duration
+  operatorPollInterval  time.Duration
+  operatorNamespace     string
+  operatorAllNamespaces bool
+  operatorListen        string
+)
+
+// operatorCmd represents the operator command
+var operatorCmd = &cobra.Command{
+  Use:   "operator [-- sync flags]",
+  Short: "Run as Kubernetes operator enforcing GitLabSettingsPolicy resources",
+  Long: `Run as Kubernetes operator enforcing GitLabSettingsPolicy resources.
+
+The spec of every policy is a config. Each policy is enforced by a separate sync process on the
+given interval and whenever its spec changes, with the given sync flags and the environment of the
+operator. The outcome is reported in the status of the policy.`,
+  Annotations: map[string]string{annotationNoConfig: "true"},
+  Run: func(cmd *cobra.Command, args []string) {
+    client, err := kubernetes.NewInClusterClient()
+    if err != nil {
+      logger.Fatal(err)
+    }
+
+    namespace := operatorNamespace
+    if namespace == "" && !operatorAllNamespaces {
+      if namespace, err = client.Namespace(); err != nil {
+        logger.Fatal(err)
+      }
+    }
+
+    executable, err := os.Executable()
+    if err != nil {
+      logger.Fatal(err)
+    }
+
+    ctx, cancel := newSignalContext()
+    defer cancel()
+
+    status := &daemonStatus{}
+    server := &http.Server{Addr: operatorListen, Handler: status.handler()}
+    go func() {
+      if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+        logger.Fatalf("failed to serve health endpoints: %v", err)
+      }
+    }()
+
+    if namespace == "" {
+      logger.Infof("Watching GitLabSettingsPolicies of all namespaces.")
+    } else {
+      logger.Infof("Watching GitLabSettingsPolicies of namespace %s.", namespace)
+    }
+
+    for ctx.Err() == nil {
+      reconcilePolicies(ctx, client, namespace, executable, args, status)
+
+      select {
+      case <-time.After(operatorPollInterval):
+      case <-ctx.Done():
+      }
+    }
+
+    shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), daemonShutdownTimeout)
+    defer shutdownCancel()
+    if err := server.Shutdown(shutdownCtx); err != nil {
+      logger.Warnf("failed to shut down health endpoints: %v", err)
+    }
+  },
+}
+
+func init() {
+  rootCmd.AddCommand(operatorCmd)
+
+  operatorCmd.Flags().DurationVar(&operatorInterval, "interval", time.Hour, "Time between the runs of each policy")
+  operatorCmd.Flags().DurationVar(&operatorPollInterval, "poll-interval", 30*time.Second, "Time between checks for changed or due policies")
+  operatorCmd.Flags().StringVar(&operatorNamespace, "namespace", "", "Namespace of the policies (default: the namespace of the operator)")
+  operatorCmd.Flags().BoolVar(&operatorAllNamespaces, "all-namespaces", false, "Enforce the policies of all namespaces")
+  operatorCmd.Flags().StringVar(&operatorListen, "listen", ":8080", "Address of the health (/healthz) and readiness (/readyz) endpoints")
+}
+
+// reconcilePolicies enforces the policies which changed or are due
+func reconcilePolicies(ctx context.Context, client *kubernetes.Client, namespace string, executable string, args []string, status *daemonStatus) {
+  policies, err := client.ListPolicies(ctx, namespace)
+  if err != nil {
+    logger.Errorf("failed to list policies: %v", err)
+    return
+  }
+
+  for _, policy := range policies {
+    if ctx.Err() != nil {
+      return
+    }
+
+    last := policy.Status.LastRunTime
+    if policy.Status.ObservedGeneration == policy.Metadata.Generation && last != nil && time.Since(*last) < operatorInterval {
+      continue
+    }
+
+    enforcePolicy(ctx, client, policy, executable, args, status)
+  }
+}
+
+// enforcePolicy runs sync with the spec of the policy as config and records the outcome in its status
+func enforcePolicy(ctx context.Context, client *kubernetes.Client, policy kubernetes.Policy, executable string, args []string, status *daemonStatus) {
+  name := policy.Metadata.Namespace + "/" + policy.Metadata.Name
+  logger.Infof("Enforcing policy %s.", name)
+
+  // Anyone allowed to create policies would otherwise be able to commit files of the operator
+  if err := config.CheckNoLocalFiles(policy.Spec); err != nil {
+    logger.Errorf("refusing to enforce policy %s: %v", name, err)
+    rejectPolicy(ctx, client, policy, name, err)
+    return
+  }
+
+  dir, err := ioutil.TempDir("", "gitlab-settings-policy-")
+  if err != nil {
+    logger.Errorf("failed to enforce policy %s: %v", name, err)
+    return
+  }
+  defer os.RemoveAll(dir)
+
+  configPath := filepath.Join(dir, "config.json")
+  if err := ioutil.WriteFile(configPath, policy.Spec, 0600); err != nil {
+    logger.Errorf("failed to enforce policy %s: %v", name, err)
+    return
+  }
+
+  // Runs are stateless, the reports are only read for the status
+  reportDir := filepath.Join(dir, "reports") + "/"
+  syncArgs := append([]string{
+    "sync",
+    "--config", configPath,
+    "--output", "json",
+    "--report-file", reportDir,
+    "--state-file", filepath.Join(dir, "state.json"),
+    "--fail-on-drift",
+  }, args...)
+
+  started := time.Now()
+  exitCode := status.run(ctx, executable, syncArgs, true)
+  if ctx.Err() != nil {
+    return
+  }
+
+  policy.Status.ObservedGeneration = policy.Metadata.Generation
+  policy.Status.LastRunTime = &started
+  policy.Status.Summary = readPolicySummary(filepath.Join(reportDir, "summary.json"))
+
+  ready := kubernetes.Condition{Type: kubernetes.ConditionReady, LastTransitionTime: started}
+  compliant := kubernetes.Condition{Type: kubernetes.ConditionCompliant, LastTransitionTime: started}
+  switch exitCode {
+  case 0:
+    ready.Status, ready.Reason, ready.Message = "True", "RunSucceeded", "The policy was enforced"
+    compliant.Status, compliant.Reason, compliant.Message = "True", "InSync", "All projects complied with the policy"
+  case exitCodeDrift:
+    ready.Status, ready.Reason, ready.Message = "True", "RunSucceeded", "The policy was enforced"
+    compliant.Status, compliant.Reason, compliant.Message = "False", "DriftCorrected", "Settings had drifted"
+    if changed, ok := policy.Status.Summary["changed"]; ok {
+      compliant.Message = fmt.Sprintf("%v project(s) had drifted settings", changed)
+    }
+    if env.Dryrun {
+      compliant.Reason = "DriftDetected"
+    }
+  default:
+    ready.Status, ready.Reason, ready.Message = "False", "RunFailed", fmt.Sprintf("sync exited with code %d, see the logs of the operator", exitCode)
+    compliant.Status, compliant.Reason, compliant.Message = "Unknown", "RunFailed", "The compliance couldn't be determined"
+  }
+  policy.Status.SetCondition(ready)
+  policy.Status.SetCondition(compliant)
+
+  if err := client.UpdatePolicyStatus(ctx, policy); err != nil {
+    logger.Errorf("failed to update status of policy %s: %v", name, err)
+  }
+}
+
+// rejectPolicy records in the status of the policy that its spec was refused
+func rejectPolicy(ctx context.Context, client *kubernetes.Client, policy kubernetes.Policy, name string, err error) {
+  now := time.Now()
+  policy.Status.ObservedGeneration = policy.Metadata.Generation
+  policy.Status.LastRunTime = &now
+  policy.Status.Summary = nil
+  policy.Status.SetCondition(kubernetes.Condition{Type: kubernetes.ConditionReady, Status: "False", Reason: "SpecRejected", Message: err.Error(), LastTransitionTime: now})
+  policy.Status.SetCondition(kubernetes.Condition{Type: kubernetes.ConditionCompliant, Status: "Unknown", Reason: "SpecRejected", Message: "The compliance couldn't be determined", LastTransitionTime: now})
+
+  if err := client.UpdatePolicyStatus(ctx, policy); err != nil {
+    logger.Errorf("failed to update status of policy %s: %v", name, err)
+  }
+}
+
+// readPolicySummary returns the summary report of a run, nil if it wasn't written
+func readPolicySummary(path string) map[string]interface{} {
+  b, err := ioutil.ReadFile(path)
+  if err != nil {
+    return nil
+  }
+
+  var report struct {
+    Summary map[string]interface{} `json:"summary"`
+  }
+  if err := json.Unmarshal(b, &report); err != nil {
+    logger.Warnf("failed to read summary report %s: %v", path, err)
+    return nil
+  }
+
+  return report.Summary
+}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+  "fmt"
+  "io"
+  "os"
+  "path/filepath"
+  "strings"
+
+  gl "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/gitlab"
+)
+
+var (
+  outputFormat   string
+  reportFile     string
+  htmlReportFile string
+  outputColor    bool
+)
+
+// reportExtensions maps the output formats to the file extension of their reports
+var reportExtensions = map[string]string{
+  gl.OutputFormatText:     "txt",
+  gl.OutputFormatJSON:     "json",
+  gl.OutputFormatJUnit:    "xml",
+  gl.OutputFormatCSV:      "csv",
+  gl.OutputFormatMarkdown: "md",
+  gl.OutputFormatHTML:     "html",
+  gl.OutputFormatSARIF:    "sarif",
+}
+
+func init() {
+  rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", gl.OutputFormatText, "Output format: text, json, junit, csv, markdown or html for sync, text or sarif for compliance")
+  rootCmd.PersistentFlags().StringVar(&reportFile, "report-file", "", "Write all reports to this file, or a file per report to this directory (trailing slash or existing directory), instead of stdout")
+  rootCmd.PersistentFlags().StringVar(&htmlReportFile, "html-report", "", "Additionally write the change log as standalone HTML report to this file")
+  rootCmd.PersistentFlags().BoolVar(&outputColor, "color", false, "Color the previous and new values of the text change log")
+}
+
+// reportOutput writes the reports of a run to stdout, a single file or a directory holding
+// a file per report
+type reportOutput struct {
+  dir  string
+  file *os.File
+
+  // section is the name of the instance the following reports belong to
+  section string
+  // header is the section whose header was written last
+  header string
+}
+
+// openReports prepares the output of the reports given by --report-file
+func openReports() (*reportOutput, error) {
+  r := &reportOutput{}
+  if reportFile == "" {
+    return r, nil
+  }
+
+  if info, err := os.Stat(reportFile); strings.HasSuffix(reportFile, "/") || (err == nil && info.IsDir()) {
+    if err := os.MkdirAll(reportFile, 0755); err != nil {
+      return nil, fmt.Errorf("failed to create report directory %q: %v", reportFile, err)
+    }
+
+    r.dir = reportFile
+    return r, nil
+  }
+
+  file, err := os.Create(reportFile)
+  if err != nil {
+    return nil, fmt.Errorf("failed to create report file %q: %v", reportFile, err)
+  }
+
+  r.file = file
+  return r, nil
+}
+
+// write renders a single report of the given format. In directory mode the report is written
+// to its own file, named after the report and its section. Otherwise a header is written
+// before the first report of each section.
+func (r *reportOutput) write(name string, format string, render func(io.Writer) error) error {
+  if r.section != "" {
+    name = r.section + "-" + name
+  }
+
+  if r.dir == "" && r.section != r.header {
+    r.header = r.section
+    if r.section != "" {
+      fmt.Fprintf(r.writer(), "\n=== INSTANCE %s ===\n", r.section)
+    }
+  }
+
+  switch {
+  case r.dir != "":
+    path := filepath.Join(r.dir, name+"."+reportExtensions[format])
+    file, err := os.Create(path)
+    if err != nil {
+      return fmt.Errorf("failed to create report file %q: %v", path, err)
+    }
+
+    if err := render(file); err != nil {
+      file.Close()
+      return err
+    }
+
+    return file.Close()
+  default:
+    return render(r.writer())
+  }
+}
+
+// writer returns the single file or stdout the reports are written to
+func (r *reportOutput) writer() io.Writer {
+  if r.file != nil {
+    return r.file
+  }
+
+  return os.Stdout
+}
+
+// Close closes the report file, if any
+func (r *reportOutput) Close() error {
+  if r.file == nil {
+    return nil
+  }
+
+  return r.file.Close()
+}
+
+// writeHTMLReport renders the change log of the manager as HTML report, if --html-report is set.
+// The reports of named instances are written to their own files.
+func writeHTMLReport(manager *gl.ProjectManager, instance string) error {
+  if htmlReportFile == "" {
+    return nil
+  }
+
+  path := instanceFile(htmlReportFile, instance)
+
+  renderer, err := gl.NewChangeLogRenderer(gl.OutputFormatHTML, gl.RenderOptions{Planned: env.Dryrun})
+  if err != nil {
+    return err
+  }
+
+  file, err := os.Create(path)
+  if err != nil {
+    return fmt.Errorf("failed to create html report %q: %v", path, err)
+  }
+
+  if err := manager.GenerateChangeLogReport(file, renderer); err != nil {
+    file.Close()
+    return err
+  }
+
+  return file.Close()
+}
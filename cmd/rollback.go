@@ -0,0 +1,71 @@
+package cmd
+
+import (
+  "fmt"
+  "os"
+  "time"
+
+  "github.com/spf13/cobra"
+
+  gl "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/gitlab"
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/storage"
+)
+
+// rollbackTimeFormat formats the time of a run in the names of rollback files
+const rollbackTimeFormat = "20060102T150405Z"
+
+var (
+  rollbackDir  string
+  rollbackFrom string
+)
+
+// rollbackCmd represents the rollback command
+var rollbackCmd = &cobra.Command{
+  Use:   "rollback",
+  Short: "Re-apply the original settings recorded by a previous sync",
+  RunE: func(cmd *cobra.Command, args []string) error {
+    rollback, err := gl.LoadSnapshot(rollbackFrom)
+    if err != nil {
+      logger.Fatal(err)
+    }
+
+    logger.Infof("Rolling back %d project(s) to their settings before %s.", len(rollback.Projects), rollback.CreatedAt.Format(time.RFC3339))
+    return restoreSnapshot(rollback)
+  },
+}
+
+func init() {
+  rootCmd.AddCommand(rollbackCmd)
+
+  rollbackCmd.Flags().StringVar(&rollbackFrom, "from", "", "Rollback file written by sync")
+  rollbackCmd.MarkFlagRequired("from")
+  rollbackCmd.Flags().BoolVar(&restoreAllowDestructive, "allow-destructive", false, "Apply destructive changes (see sync), which are refused otherwise")
+}
+
+// writeRollback records the original values of the settings changed by the manager in a
+// timestamped file of --rollback-dir, if it is set and anything was changed
+func writeRollback(manager *gl.ProjectManager, instance string, started time.Time) error {
+  if rollbackDir == "" {
+    return nil
+  }
+
+  rollback := gl.NewRollback(manager.ChangeLog(), started)
+  if len(rollback.Projects) == 0 {
+    return nil
+  }
+
+  // Remote prefixes need no directory
+  if !storage.IsRemote(rollbackDir) {
+    if err := os.MkdirAll(rollbackDir, 0755); err != nil {
+      return fmt.Errorf("failed to create rollback directory %q: %v", rollbackDir, err)
+    }
+  }
+
+  path := instanceFile(storage.Join(rollbackDir, "rollback-"+started.UTC().Format(rollbackTimeFormat)+".json"), instance)
+  if err := rollback.Save(path); err != nil {
+    return err
+  }
+
+  logger.Infof("Recorded the original settings of %d project(s), undo with: rollback --from %s", len(rollback.Projects), path)
+  return nil
+}
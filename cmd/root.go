@@ -1,61 +1,254 @@
 package cmd
 
 import (
+  "context"
   "fmt"
   "os"
+  "path"
+  "strings"
 
   "github.com/sirupsen/logrus"
   "github.com/kelseyhightower/envconfig"
   "github.com/spf13/cobra"
+  "github.com/xanzy/go-gitlab"
 
   "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/config"
+  gl "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/gitlab"
 )
 
+// annotationNoConfig marks commands which run without loading a config
+const annotationNoConfig = "no-config"
+
 type envCfg struct {
-  ConfigFile     string `split_words:"true" default:"./config.json"`
-  Dryrun         bool
-  GitlabEndpoint string `split_words:"true"`
-  GitlabToken    string `split_words:"true" required:"true"`
-  Verbose        bool
+  ConfigFile      string `split_words:"true" default:"./config.json"`
+  ConfigProject   string `split_words:"true"`
+  ConfigRef       string `split_words:"true" default:"HEAD"`
+  Dryrun          bool
+  GitlabEndpoint  string `split_words:"true"`
+  GitlabToken     string `split_words:"true"`
+  GitlabTokenFile string `split_words:"true"`
+  GitlabTokenType string `split_words:"true" default:"private"`
+  Verbose         bool
+
+  GitlabOauthRefreshToken     string `split_words:"true"`
+  GitlabOauthRefreshTokenFile string `split_words:"true"`
+  GitlabOauthClientID         string `split_words:"true"`
+  GitlabOauthClientSecret     string `split_words:"true"`
+
+  SystemHookSecret string `split_words:"true"`
+  APIToken         string `split_words:"true"`
 }
 
 var (
-  env    = &envCfg{}
-  logger = logrus.New()
-  cfg    *config.Config
+  env          = &envCfg{}
+  logger       = logrus.New()
+  cfg          *config.Config
+  configFile   string
+  configFormat string
+  configValues []string
+  valuesFile   string
+
+  filterSkipArchived    bool
+  filterVisibility      []string
+  filterMaxInactiveDays int
+
+  httpRequestTimeout int
+  httpDialTimeout    int
+  httpProxy          string
+  httpMaxIdleConns   int
+  httpCACert         string
+  httpClientCert     string
+  httpClientKey      string
+  httpInsecure       bool
+
+  graphqlPrefetch bool
 )
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
   Use:   "gitlab-setting-enforcer",
   Short: "Enforces the settings of configured GitLab repos",
-  PersistentPreRun: func(cmd *cobra.Command, args []string) {
-    err := envconfig.Process("", env)
-    if err != nil {
-      logger.Fatal(err)
-    }
+}
+
+func init() {
+  // Assigned here, as loading the config refers back to rootCmd for the http flags
+  rootCmd.PersistentPreRun = persistentPreRun
+
+  rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Config file, or directory of config files to merge (overrides CONFIG_FILE)")
+  rootCmd.PersistentFlags().StringVar(&configFormat, "config-format", "", "Format of the config file(s), not of included files: json, yaml or toml (default: detected by file extension)")
+  rootCmd.PersistentFlags().StringVar(&valuesFile, "values", "", "Values file (json, yaml or toml) rendering the config file(s) as Go templates")
+  rootCmd.PersistentFlags().StringArrayVar(&configValues, "set", nil, "Template value as key=value, overriding the values file (repeatable)")
+  rootCmd.PersistentFlags().BoolVar(&filterSkipArchived, "skip-archived", false, "Skip archived projects (overrides project_filters.skip_archived)")
+  rootCmd.PersistentFlags().StringSliceVar(&filterVisibility, "visibility", nil, "Only enforce projects of these visibility levels (overrides project_filters.visibility)")
+  rootCmd.PersistentFlags().IntVar(&filterMaxInactiveDays, "max-inactive-days", 0, "Skip projects without activity in this many days (overrides project_filters.max_inactive_days)")
+  rootCmd.PersistentFlags().IntVar(&httpRequestTimeout, "http-request-timeout", 0, "Seconds to wait for the response of a request (overrides http.request_timeout_seconds)")
+  rootCmd.PersistentFlags().IntVar(&httpDialTimeout, "http-dial-timeout", 0, "Seconds to wait for a connection (overrides http.dial_timeout_seconds)")
+  rootCmd.PersistentFlags().StringVar(&httpProxy, "http-proxy", "", "Proxy URL used to talk to GitLab (overrides http.proxy_url)")
+  rootCmd.PersistentFlags().IntVar(&httpMaxIdleConns, "http-max-idle-conns", 0, "Maximum number of idle connections kept alive (overrides http.max_idle_conns)")
+  rootCmd.PersistentFlags().StringVar(&httpCACert, "http-ca-cert", "", "PEM file of CA certificates trusted in addition to the system ones (overrides http.ca_cert_file)")
+  rootCmd.PersistentFlags().StringVar(&httpClientCert, "http-client-cert", "", "PEM file of the client certificate presented to GitLab (overrides http.client_cert_file)")
+  rootCmd.PersistentFlags().StringVar(&httpClientKey, "http-client-key", "", "PEM file of the client certificate's private key (overrides http.client_key_file)")
+  rootCmd.PersistentFlags().BoolVar(&httpInsecure, "http-insecure-skip-verify", false, "Do not verify the TLS certificate of GitLab. Insecure, for testing only (overrides http.insecure_skip_verify)")
+  rootCmd.PersistentFlags().BoolVar(&graphqlPrefetch, "graphql-prefetch", false, "Fetch the project settings of all projects in bulk using the GraphQL API")
+}
+
+// persistentPreRun processes the environment and loads the config before any command runs
+func persistentPreRun(cmd *cobra.Command, args []string) {
+  // Errors returned by the command are reported by Execute, not as usage errors
+  cmd.SilenceErrors = true
+  cmd.SilenceUsage = true
+
+  err := envconfig.Process("", env)
+  if err != nil {
+    logger.Fatal(err)
+  }
+  if configFile != "" {
+    env.ConfigFile = configFile
+  }
 
+  // Replayed changes never reach GitLab, so they must not be recorded as if they did
+  if replaying() {
+    env.Dryrun = true
+  }
+
+  if err := resolveToken(); err != nil {
+    logger.Fatal(err)
+  }
+
+  // Commands creating a config can't load one
+  if cmd.Annotations[annotationNoConfig] == "true" {
+    cfg = &config.Config{}
+  } else if cfg, err = loadConfig(); err != nil {
+    logger.Fatal(err)
+  }
+
+  if err := applyFilterFlags(cmd); err != nil {
+    logger.Fatal(err)
+  }
+
+  if env.Verbose {
+    logger.SetLevel(logrus.DebugLevel)
+  } else {
+    logger.SetLevel(logrus.InfoLevel)
+  }
+}
+
+// loadConfig reads the config from a GitLab repository, a URL or the local file system
+func loadConfig() (*config.Config, error) {
+  opts, err := configOptions()
+  if err != nil {
+    return nil, err
+  }
+
+  switch {
+  case env.ConfigProject != "":
+    configFile := strings.TrimPrefix(path.Clean(env.ConfigFile), "/")
+    logger.Infof("Loading config file %v from project %v at %v", configFile, env.ConfigProject, env.ConfigRef)
+
+    source := gl.NewRepositoryConfigSource(newGitlabClient().RepositoryFiles, env.ConfigProject, env.ConfigRef)
+    return config.ParseFrom(source, configFile, opts)
+  case strings.HasPrefix(env.ConfigFile, "http://") || strings.HasPrefix(env.ConfigFile, "https://"):
     logger.Infof("Loading config file from %v", env.ConfigFile)
 
-    cfg, err = config.Parse(env.ConfigFile)
+    return config.ParseFrom(config.NewURLSource(nil), env.ConfigFile, opts)
+  default:
+    logger.Infof("Loading config file from %v", env.ConfigFile)
+
+    return config.Parse(env.ConfigFile, opts)
+  }
+}
+
+// applyFilterFlags overrides the project filters of the config with the flags given on the command
+// line and validates the result
+func applyFilterFlags(cmd *cobra.Command) error {
+  if cmd.Flags().Changed("skip-archived") {
+    cfg.ProjectFilters.SkipArchived = filterSkipArchived
+  }
+  if cmd.Flags().Changed("visibility") {
+    cfg.ProjectFilters.Visibility = filterVisibility
+  }
+  if cmd.Flags().Changed("max-inactive-days") {
+    cfg.ProjectFilters.MaxInactiveDays = filterMaxInactiveDays
+  }
+
+  return config.CheckProjectFilters(cfg.ProjectFilters)
+}
+
+// applyHTTPFlags overrides the given http settings with the flags given on the command line
+func applyHTTPFlags(settings *config.HTTP) {
+  flags := rootCmd.PersistentFlags()
+  if flags.Changed("http-request-timeout") {
+    settings.RequestTimeoutSeconds = httpRequestTimeout
+  }
+  if flags.Changed("http-dial-timeout") {
+    settings.DialTimeoutSeconds = httpDialTimeout
+  }
+  if flags.Changed("http-proxy") {
+    settings.ProxyURL = httpProxy
+  }
+  if flags.Changed("http-max-idle-conns") {
+    settings.MaxIdleConns = httpMaxIdleConns
+  }
+  if flags.Changed("http-ca-cert") {
+    settings.CACertFile = httpCACert
+  }
+  if flags.Changed("http-client-cert") {
+    settings.ClientCertFile = httpClientCert
+  }
+  if flags.Changed("http-client-key") {
+    settings.ClientKeyFile = httpClientKey
+  }
+  if flags.Changed("http-insecure-skip-verify") {
+    settings.InsecureSkipVerify = httpInsecure
+  }
+}
+
+// configOptions builds the config options from the command line flags
+func configOptions() (config.Options, error) {
+  opts := config.Options{Format: configFormat}
+
+  if valuesFile != "" {
+    values, err := config.ParseValues(config.NewFileSource(), valuesFile)
     if err != nil {
-      logger.Fatal(err)
+      return opts, err
     }
+    opts.Values = values
+  }
 
-    if env.Verbose {
-      logger.SetLevel(logrus.DebugLevel)
-    } else {
-      logger.SetLevel(logrus.InfoLevel)
+  for _, assignment := range configValues {
+    if opts.Values == nil {
+      opts.Values = make(map[string]interface{})
+    }
+    if err := config.SetValue(opts.Values, assignment); err != nil {
+      return opts, err
     }
+  }
 
-  },
+  return opts, nil
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-  if err := rootCmd.Execute(); err != nil {
+  err := rootCmd.Execute()
+  if err == errInterrupted {
+    logger.Error(err)
+    os.Exit(exitCodeInterrupted)
+  }
+  if err != nil {
     fmt.Println(err)
     os.Exit(1)
   }
 }
+
+// prefetchProjectSettings fetches the project settings in bulk if --graphql-prefetch is set.
+// On failure the settings are fetched per project using the REST API.
+func prefetchProjectSettings(ctx context.Context, manager *gl.ProjectManager, projects []gitlab.Project) {
+  if !graphqlPrefetch {
+    return
+  }
+
+  if err := manager.PrefetchProjectSettings(ctx, projects); err != nil {
+    logger.Warnf("Falling back to fetching project settings per project: %v", err)
+  }
+}
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+  "encoding/json"
+  "fmt"
+
+  "github.com/spf13/cobra"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/config"
+)
+
+// schemaCmd represents the schema command
+var schemaCmd = &cobra.Command{
+  Use:   "schema",
+  Short: "Print the JSON Schema of the config file",
+  // The schema does not depend on any config, env var or token
+  PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+  Run: func(cmd *cobra.Command, args []string) {
+    body, err := json.MarshalIndent(config.GenerateSchema(), "", "  ")
+    if err != nil {
+      logger.Fatal(err)
+    }
+
+    fmt.Println(string(body))
+  },
+}
+
+func init() {
+  rootCmd.AddCommand(schemaCmd)
+}
@@ -0,0 +1,272 @@
+package cmd
+
+import (
+  "context"
+  "crypto/subtle"
+  "encoding/json"
+  "errors"
+  "io"
+  "net/http"
+  "os"
+  "strings"
+  "sync"
+
+  "github.com/spf13/cobra"
+)
+
+// apiPrefix is the path prefix of the REST API
+const apiPrefix = "/api/v1/"
+
+var (
+  serveAPI                      bool
+  serveDashboard                bool
+  serveDashboardUnauthenticated bool
+  serveListen                   string
+  serveStatusFile               string
+)
+
+var (
+  errServeModeRequired      = errors.New("at least one of --api and --dashboard must be set")
+  errAPITokenRequired       = errors.New("API_TOKEN must be set to serve the API")
+  errDashboardTokenRequired = errors.New("API_TOKEN must be set to serve the dashboard, or --dashboard-unauthenticated")
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+  Use:   "serve [--api] [--dashboard] [-- sync flags]",
+  Short: "Serve an API to trigger syncs and a dashboard on the compliance of projects",
+  Long: `Serve an API to trigger syncs and a dashboard on the compliance of projects.
+
+Every sync is a separate sync process, started with the root flags (like --config), the given
+sync flags and the environment of the server, recording the outcome of its projects in
+--status-file. Requests to the API must present API_TOKEN as bearer token. So do requests to the
+read-only dashboard, unless --dashboard-unauthenticated is set.`,
+  Run: func(cmd *cobra.Command, args []string) {
+    if !serveAPI && !serveDashboard {
+      logger.Fatal(errServeModeRequired)
+    }
+    if serveAPI && env.APIToken == "" {
+      logger.Fatal(errAPITokenRequired)
+    }
+    if serveDashboard && !serveDashboardUnauthenticated && env.APIToken == "" {
+      logger.Fatal(errDashboardTokenRequired)
+    }
+
+    executable, err := os.Executable()
+    if err != nil {
+      logger.Fatal(err)
+    }
+
+    ctx, cancel := newSignalContext()
+    defer cancel()
+
+    syncArgs := []string{"--status-file", serveStatusFile}
+    if historyDB != "" {
+      syncArgs = append(syncArgs, "--history-db", historyDB)
+    }
+
+    api := &apiServer{
+      ctx:        ctx,
+      status:     &daemonStatus{},
+      executable: executable,
+      syncArgs:   childSyncArgs(append(syncArgs, args...)...),
+      statusFile: serveStatusFile,
+      runs:       make(chan struct{}, 1),
+    }
+
+    handler := api.status.handler()
+    if serveAPI {
+      handler.Handle(apiPrefix, api.authenticate(env.APIToken, api.handler()))
+      logger.Infof("Serving the API on %s%s.", serveListen, apiPrefix)
+    }
+    if serveDashboard {
+      board := &dashboard{status: api.status, statusFile: serveStatusFile, historyDB: historyDB}
+      if serveDashboardUnauthenticated {
+        handler.Handle("/", board.handler())
+      } else {
+        handler.Handle("/", api.authenticate(env.APIToken, board.handler()))
+      }
+      logger.Infof("Serving the dashboard on %s/.", serveListen)
+    }
+
+    server := &http.Server{Addr: serveListen, Handler: handler}
+    go func() {
+      if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+        logger.Fatalf("failed to serve: %v", err)
+      }
+    }()
+
+    <-ctx.Done()
+
+    shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), daemonShutdownTimeout)
+    defer shutdownCancel()
+    if err := server.Shutdown(shutdownCtx); err != nil {
+      logger.Warnf("failed to shut down the server: %v", err)
+    }
+
+    // A running sync received SIGTERM with the cancellation of ctx
+    api.wg.Wait()
+  },
+}
+
+func init() {
+  rootCmd.AddCommand(serveCmd)
+
+  serveCmd.Flags().BoolVar(&serveAPI, "api", false, "Serve the REST API on "+apiPrefix+" (requires API_TOKEN)")
+  serveCmd.Flags().BoolVar(&serveDashboard, "dashboard", false, "Serve a read-only dashboard of the compliance of the projects on / (requires API_TOKEN)")
+  serveCmd.Flags().BoolVar(&serveDashboardUnauthenticated, "dashboard-unauthenticated", false, "Serve the dashboard without requiring API_TOKEN, e.g. behind an authenticating proxy")
+  serveCmd.Flags().StringVar(&serveListen, "listen", ":8080", "Address of the API, the dashboard and the health (/healthz) and readiness (/readyz) endpoints")
+  serveCmd.Flags().StringVar(&serveStatusFile, "status-file", ".gitlab-settings-enforcer.status.json", "File the syncs record the outcome of every project in")
+  serveCmd.Flags().StringVar(&historyDB, "history-db", "", "SQLite database the syncs record their changes in, listed per project by the dashboard")
+}
+
+// apiServer triggers syncs and serves the project outcomes they recorded
+type apiServer struct {
+  ctx        context.Context
+  status     *daemonStatus
+  executable string
+  syncArgs   []string
+  statusFile string
+
+  // runs holds a token while a sync is running, as syncs must not overlap
+  runs chan struct{}
+  wg   sync.WaitGroup
+}
+
+// syncRequest is the optional body of POST /api/v1/sync
+type syncRequest struct {
+  Projects []string `json:"projects"`
+}
+
+// authenticate rejects requests not presenting the token as bearer token
+func (s *apiServer) authenticate(token string, next http.Handler) http.Handler {
+  return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    presented := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+    if subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+      w.Header().Set("WWW-Authenticate", "Bearer")
+      writeAPIError(w, http.StatusUnauthorized, "invalid or missing bearer token")
+      return
+    }
+
+    next.ServeHTTP(w, r)
+  })
+}
+
+// handler serves the endpoints of the API:
+//
+//   POST /api/v1/sync               start a sync, optionally of the given projects only
+//   GET  /api/v1/sync               state of the latest sync
+//   GET  /api/v1/drift              projects with drifted settings found by their latest sync
+//   GET  /api/v1/projects           latest outcome of all projects, ?compliant=false for violations
+//   GET  /api/v1/projects/<path>    latest outcome of a single project
+func (s *apiServer) handler() http.Handler {
+  mux := http.NewServeMux()
+  mux.HandleFunc(apiPrefix+"sync", func(w http.ResponseWriter, r *http.Request) {
+    switch r.Method {
+    case http.MethodGet:
+      s.status.writeJSON(w, http.StatusOK)
+    case http.MethodPost:
+      s.startSync(w, r)
+    default:
+      writeAPIError(w, http.StatusMethodNotAllowed, "method not allowed")
+    }
+  })
+  mux.HandleFunc(apiPrefix+"drift", s.getOnly(func(w http.ResponseWriter, r *http.Request, status *complianceStatus) {
+    drifted := []projectStatus{}
+    for _, project := range status.sorted() {
+      if len(project.Changes) > 0 {
+        drifted = append(drifted, project)
+      }
+    }
+    writeAPIJSON(w, http.StatusOK, map[string]interface{}{"updated_at": status.UpdatedAt, "projects": drifted})
+  }))
+  mux.HandleFunc(apiPrefix+"projects", s.getOnly(func(w http.ResponseWriter, r *http.Request, status *complianceStatus) {
+    filter := r.URL.Query().Get("compliant")
+
+    projects := []projectStatus{}
+    for _, project := range status.sorted() {
+      if filter == "" || (filter == "true" && project.Compliant) || (filter == "false" && !project.Compliant) {
+        projects = append(projects, project)
+      }
+    }
+    writeAPIJSON(w, http.StatusOK, map[string]interface{}{"updated_at": status.UpdatedAt, "projects": projects})
+  }))
+  mux.HandleFunc(apiPrefix+"projects/", s.getOnly(func(w http.ResponseWriter, r *http.Request, status *complianceStatus) {
+    project, ok := status.Projects[strings.TrimPrefix(r.URL.Path, apiPrefix+"projects/")]
+    if !ok {
+      writeAPIError(w, http.StatusNotFound, "project not enforced yet")
+      return
+    }
+    writeAPIJSON(w, http.StatusOK, project)
+  }))
+
+  return mux
+}
+
+// getOnly serves GET requests with the current status file
+func (s *apiServer) getOnly(serve func(http.ResponseWriter, *http.Request, *complianceStatus)) http.HandlerFunc {
+  return func(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodGet {
+      writeAPIError(w, http.StatusMethodNotAllowed, "method not allowed")
+      return
+    }
+
+    status, err := loadComplianceStatus(s.statusFile)
+    if err != nil {
+      logger.Errorf("failed to serve %s: %v", r.URL.Path, err)
+      writeAPIError(w, http.StatusInternalServerError, "failed to read the project status")
+      return
+    }
+
+    serve(w, r, status)
+  }
+}
+
+// startSync starts a sync in the background, answering with 409 while another one is running
+func (s *apiServer) startSync(w http.ResponseWriter, r *http.Request) {
+  var request syncRequest
+  if err := json.NewDecoder(r.Body).Decode(&request); err != nil && err != io.EOF {
+    writeAPIError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+    return
+  }
+
+  select {
+  case s.runs <- struct{}{}:
+  default:
+    writeAPIError(w, http.StatusConflict, "a sync is running already")
+    return
+  }
+
+  args := append([]string{}, s.syncArgs...)
+  for _, project := range request.Projects {
+    args = append(args, "--project", project)
+  }
+
+  s.wg.Add(1)
+  go func() {
+    defer s.wg.Done()
+    defer func() { <-s.runs }()
+
+    s.status.run(s.ctx, s.executable, args, true)
+  }()
+
+  writeAPIJSON(w, http.StatusAccepted, map[string]interface{}{"started": true, "projects": request.Projects})
+}
+
+// writeAPIJSON writes the value as JSON with the given status code
+func writeAPIJSON(w http.ResponseWriter, code int, v interface{}) {
+  body, err := json.Marshal(v)
+  if err != nil {
+    http.Error(w, err.Error(), http.StatusInternalServerError)
+    return
+  }
+
+  w.Header().Set("Content-Type", "application/json")
+  w.WriteHeader(code)
+  w.Write(body)
+}
+
+// writeAPIError writes the error message as JSON with the given status code
+func writeAPIError(w http.ResponseWriter, code int, message string) {
+  writeAPIJSON(w, code, map[string]string{"error": message})
+}
@@ -0,0 +1,200 @@
+package cmd
+
+import (
+  "context"
+  "io"
+  "sort"
+  "time"
+
+  "github.com/spf13/cobra"
+  "github.com/xanzy/go-gitlab"
+
+  gl "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/gitlab"
+)
+
+var (
+  snapshotFile            string
+  restoreFrom             string
+  restoreAllowDestructive bool
+)
+
+// snapshotCmd represents the snapshot command
+var snapshotCmd = &cobra.Command{
+  Use:   "snapshot",
+  Short: "Save the current settings of all matched projects to a file",
+  RunE: func(cmd *cobra.Command, args []string) error {
+    ctx, cancel := newSignalContext()
+    defer cancel()
+
+    started := time.Now()
+    client := newGitlabClient()
+    manager := newProjectManager(client)
+
+    projects, err := manager.GetProjects(ctx)
+    if err != nil {
+      if err := checkInterrupted(ctx); err != nil {
+        return err
+      }
+      logger.Fatal(err)
+    }
+
+    logger.Infof("Identified %d valid project(s).", len(projects))
+
+    snapshot := takeSnapshot(ctx, manager, projects, started)
+    if err := checkInterrupted(ctx); err != nil {
+      return err
+    }
+
+    // An incomplete snapshot is no reliable backup
+    if manager.GetError() {
+      logger.Fatalf("%d error(s) encountered.", runErrors.Count())
+    }
+
+    path := snapshotFile
+    if path == "" {
+      path = "snapshot-" + started.UTC().Format(rollbackTimeFormat) + ".json"
+    }
+    if err := snapshot.Save(path); err != nil {
+      logger.Fatal(err)
+    }
+
+    logger.Infof("Saved the settings of %d project(s) to %s, restore with: restore --from %s", len(snapshot.Projects), path, path)
+
+    return nil
+  },
+}
+
+// restoreCmd represents the restore command
+var restoreCmd = &cobra.Command{
+  Use:   "restore",
+  Short: "Re-apply the settings of a snapshot",
+  RunE: func(cmd *cobra.Command, args []string) error {
+    snapshot, err := gl.LoadSnapshot(restoreFrom)
+    if err != nil {
+      logger.Fatal(err)
+    }
+
+    logger.Infof("Restoring %d project(s) to their settings of %s.", len(snapshot.Projects), snapshot.CreatedAt.Format(time.RFC3339))
+    return restoreSnapshot(snapshot)
+  },
+}
+
+func init() {
+  rootCmd.AddCommand(snapshotCmd)
+  rootCmd.AddCommand(restoreCmd)
+
+  snapshotCmd.Flags().StringVar(&snapshotFile, "file", "", "File to save the snapshot to (default: snapshot-<timestamp>.json)")
+  restoreCmd.Flags().StringVar(&restoreFrom, "from", "", "Snapshot file to restore")
+  restoreCmd.MarkFlagRequired("from")
+  restoreCmd.Flags().BoolVar(&restoreAllowDestructive, "allow-destructive", false, "Apply destructive changes (see sync), which are refused otherwise")
+}
+
+// takeSnapshot returns the snapshot of the current settings of the given projects. Failures are
+// logged and recorded by the manager, leaving the project out.
+func takeSnapshot(ctx context.Context, manager *gl.ProjectManager, projects []gitlab.Project, started time.Time) *gl.Snapshot {
+  prefetchProjectSettings(ctx, manager, projects)
+
+  snapshot := &gl.Snapshot{
+    CreatedAt: started,
+    Projects:  make(map[string]gl.ProjectSnapshot),
+  }
+  for index, project := range projects {
+    if ctx.Err() != nil {
+      logger.Warnf("Skipping remaining %d project(s).", len(projects)-index)
+      break
+    }
+
+    logger.Infof("Processing project #%d: %s", index + 1, project.PathWithNamespace)
+
+    settings, err := manager.SnapshotProject(ctx, project)
+    if err != nil {
+      logger.Errorf("failed to snapshot repo %v: %v", project.PathWithNamespace, err)
+      manager.SetError(true)
+      continue
+    }
+    snapshot.Projects[project.PathWithNamespace] = settings
+  }
+
+  return snapshot
+}
+
+// restoreSnapshot re-applies the settings of the snapshot to its projects, independent of the
+// config, and reports the changes. It returns errInterrupted if the restore was interrupted.
+func restoreSnapshot(snapshot *gl.Snapshot) error {
+  ctx, cancel := newSignalContext()
+  defer cancel()
+
+  renderer, err := gl.NewChangeLogRenderer(outputFormat, gl.RenderOptions{Planned: env.Dryrun, Color: outputColor})
+  if err != nil {
+    logger.Fatal(err)
+  }
+
+  reports, err := openReports()
+  if err != nil {
+    logger.Fatal(err)
+  }
+  defer reports.Close()
+
+  client := newGitlabClient()
+  if env.Dryrun {
+    logger.Infof("DRYRUN: No changes will be implemented.")
+  }
+
+  manager := newProjectManager(client)
+  manager.SetAllowDestructive(restoreAllowDestructive)
+
+  // Dryruns don't mutate anything to audit
+  if !env.Dryrun {
+    auditLog, err := openAuditLog(ctx, client, manager)
+    if err != nil {
+      logger.Fatal(err)
+    }
+    if auditLog != nil {
+      defer auditLog.Close()
+    }
+  }
+
+  var projects []string
+  for project := range snapshot.Projects {
+    projects = append(projects, project)
+  }
+  sort.Strings(projects)
+
+  for index, project := range projects {
+    if ctx.Err() != nil {
+      logger.Warnf("Skipping remaining %d project(s).", len(projects)-index)
+      break
+    }
+
+    logger.Infof("Processing project #%d: %s", index + 1, project)
+
+    if err := manager.RestoreProject(ctx, project, snapshot.Projects[project], env.Dryrun); err != nil {
+      logger.Errorf("failed to restore repo %v: %v", project, err)
+      manager.SetError(true)
+    }
+  }
+
+  // Destructive changes precede the change log, so they aren't overlooked
+  if err := reports.write("destructive", gl.OutputFormatText, manager.GenerateDestructiveReport); err != nil {
+    logger.Errorf("failed to create destructive changes report: %v", err)
+    manager.SetError(true)
+  }
+
+  changelog := func(w io.Writer) error {
+    return manager.GenerateChangeLogReport(w, renderer)
+  }
+  if err := reports.write("changelog", outputFormat, changelog); err != nil {
+    logger.Errorf("failed to create changelog report: %v", err)
+    manager.SetError(true)
+  }
+
+  if err := checkInterrupted(ctx); err != nil {
+    return err
+  }
+
+  if manager.GetError() {
+    logger.Fatalf("%d error(s) encountered.", runErrors.Count())
+  }
+
+  return nil
+}
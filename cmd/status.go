@@ -0,0 +1,115 @@
+package cmd
+
+import (
+  "encoding/json"
+  "fmt"
+  "sort"
+  "time"
+
+  gl "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/gitlab"
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/history"
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/storage"
+)
+
+// statusFile is the file recording the latest outcome of every project (sync --status-file)
+var statusFile string
+
+// complianceStatus is the latest outcome of every enforced project, merged across runs so
+// runs of single projects don't forget the others
+type complianceStatus struct {
+  UpdatedAt time.Time                `json:"updated_at"`
+  Projects  map[string]projectStatus `json:"projects"`
+}
+
+// projectStatus is the outcome of the latest run enforcing a project
+type projectStatus struct {
+  Project   string    `json:"project"`
+  Outcome   string    `json:"outcome"`
+  Compliant bool      `json:"compliant"`
+  Dryrun    bool      `json:"dryrun"`
+  CheckedAt time.Time `json:"checked_at"`
+
+  // Changes holds the drifted settings found by the run, keyed by subsection and setting
+  Changes map[string]map[string]map[string]interface{} `json:"changes,omitempty"`
+}
+
+// loadComplianceStatus reads the status file at the given path, which may be remote. A missing
+// file results in an empty status.
+func loadComplianceStatus(path string) (*complianceStatus, error) {
+  status := &complianceStatus{Projects: make(map[string]projectStatus)}
+
+  b, err := storage.ReadFile(path)
+  if storage.IsNotExist(err) {
+    return status, nil
+  } else if err != nil {
+    return nil, fmt.Errorf("failed to read status file %q: %v", path, err)
+  }
+
+  if err := json.Unmarshal(b, status); err != nil {
+    return nil, fmt.Errorf("failed to unmarshal status file %q: %v", path, err)
+  }
+  if status.Projects == nil {
+    status.Projects = make(map[string]projectStatus)
+  }
+
+  return status, nil
+}
+
+// sorted returns the project statuses ordered by project
+func (s *complianceStatus) sorted() []projectStatus {
+  var projects []projectStatus
+  for _, project := range s.Projects {
+    projects = append(projects, project)
+  }
+  sort.Slice(projects, func(i, j int) bool {
+    return projects[i].Project < projects[j].Project
+  })
+
+  return projects
+}
+
+// writeComplianceStatus merges the project outcomes of the run into the file given by
+// --status-file, if any. Projects of several instances are prefixed with the instance name.
+func writeComplianceStatus(results []*syncResult, changes gl.ChangeLog, now time.Time) error {
+  if statusFile == "" {
+    return nil
+  }
+
+  status, err := loadComplianceStatus(statusFile)
+  if err != nil {
+    return err
+  }
+
+  for _, result := range results {
+    for _, outcome := range result.projects {
+      // The change log of several instances is keyed the same way
+      name := outcome.Project
+      if len(results) > 1 {
+        name = result.instance.Name + ":" + name
+      }
+
+      status.Projects[name] = projectStatus{
+        Project:   name,
+        Outcome:   outcome.Outcome,
+        Dryrun:    env.Dryrun,
+        CheckedAt: now,
+        Changes:   changes[name],
+
+        // Drift corrected by the run complies now, unlike drift only found by a dryrun
+        Compliant: outcome.Outcome == history.OutcomeInSync || (outcome.Outcome == history.OutcomeChanged && !env.Dryrun),
+      }
+    }
+  }
+  status.UpdatedAt = now
+
+  b, err := json.MarshalIndent(status, "", "  ")
+  if err != nil {
+    return err
+  }
+
+  if err := storage.WriteFile(statusFile, b, 0644); err != nil {
+    return fmt.Errorf("failed to write status file %q: %v", statusFile, err)
+  }
+
+  return nil
+}
@@ -1,73 +1,490 @@
 package cmd
 
 import (
+  "context"
+  "fmt"
+  "io"
+  "strings"
+  "time"
+
   "github.com/spf13/cobra"
   "github.com/xanzy/go-gitlab"
 
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/config"
   gl "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/gitlab"
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/history"
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/state"
+)
+
+var (
+  syncAdmin              bool
+  syncResume             bool
+  syncStateFile          string
+  syncSince              string
+  syncIncremental        bool
+  syncFullSweepInterval  time.Duration
+  syncFailOnDrift        bool
+  syncOnly               []string
+  syncSkip               []string
+  syncProjects           []string
+  syncFailFast           bool
+  syncAllowDestructive   bool
+  syncSkipPreflight      bool
+  syncPruneStaleBranches bool
+  syncAllowTransfer      bool
 )
 
 // syncCmd represents the sync command
 var syncCmd = &cobra.Command{
   Use:   "sync",
   Short: "Sync gitlab's project settings with the config",
-  Run: func(cmd *cobra.Command, args []string) {
-    client := gitlab.NewClient(nil, env.GitlabToken)
-    if env.GitlabEndpoint != "" {
-      if err := client.SetBaseURL(env.GitlabEndpoint); err != nil {
-        logger.Fatal(err)
-      }
+  RunE: func(cmd *cobra.Command, args []string) error {
+    started := time.Now()
+
+    ctx, cancel := newSignalContext()
+    defer cancel()
+
+    if err := applySubsystemFlags(cmd); err != nil {
+      logger.Fatal(err)
+    }
+
+    renderer, err := gl.NewChangeLogRenderer(outputFormat, gl.RenderOptions{Planned: env.Dryrun, Color: outputColor})
+    if err != nil {
+      logger.Fatal(err)
     }
+
+    reports, err := openReports()
+    if err != nil {
+      logger.Fatal(err)
+    }
+    defer reports.Close()
+
     if env.Dryrun {
       logger.Infof("DRYRUN: No changes will be implemented.")
     }
 
-    manager := gl.NewProjectManager(
-      logger.WithField("module", "project_manager"),
-      client.Groups,
-      client.Projects,
-      client.ProtectedBranches,
-      client.Branches,
-      cfg,
-    )
+    instances := syncInstances()
 
-    projects, err := manager.GetProjects()
-    if err != nil {
-      logger.Fatal(err)
+    var results []*syncResult
+    var summary gl.RunSummary
+    var failed bool
+    for index, instance := range instances {
+      if ctx.Err() != nil {
+        logger.Warnf("Skipping remaining %d instance(s).", len(instances)-index)
+        break
+      }
+      if syncFailFast && failed {
+        logger.Warnf("Aborting on the first error (--fail-fast), skipping remaining %d instance(s).", len(instances)-index)
+        break
+      }
+
+      if instance.Name != "" {
+        logger.Infof("Enforcing instance %s at %s.", instance.Name, instance.Endpoint)
+      }
+      reports.section = instance.Name
+
+      result := syncInstance(ctx, instance, renderer, reports)
+      results = append(results, result)
+      summary.Add(result.summary)
+      failed = failed || result.failed
+    }
+    reports.section = ""
+
+    // The summary of a single instance was reported already
+    if len(results) > 1 {
+      summary.Elapsed = time.Since(started)
+      if err := writeSummary(reports, summary); err != nil {
+        logger.Errorf("failed to create summary report: %v", err)
+        failed = true
+      }
     }
 
-    logger.Infof("Identified %d valid project(s).", len(projects))
-    for index, project := range projects {
-      logger.Infof("Processing project #%d: %s", index + 1, project.PathWithNamespace)
+    var scanned int
+    for _, result := range results {
+      scanned += result.scanned
+    }
+
+    changes := mergeChangeLogs(results)
+    pushMetrics(results, changes, scanned, failed)
+    notifyRun(changes, failed, "sync", started, &summary)
+
+    if err := recordHistory(results, changes, failed, started); err != nil {
+      logger.Errorf("failed to record run history: %v", err)
+      failed = true
+    }
+
+    if err := writeComplianceStatus(results, changes, time.Now()); err != nil {
+      logger.Errorf("failed to record project status: %v", err)
+      failed = true
+    }
 
-      // Update branches
-      if err := manager.EnsureBranchesAndProtection(project, env.Dryrun); err != nil {
-        logger.Errorf("failed to ensure branches of repo %v: %v", project.PathWithNamespace, err)
-        manager.SetError(true)
+    if err := checkInterrupted(ctx); err != nil {
+      return err
+    }
+
+    if failed || runErrors.Count() > 0 {
+      if err := reports.write("errors", gl.OutputFormatText, runErrors.GenerateReport); err != nil {
+        logger.Errorf("failed to create errors report: %v", err)
       }
+      logger.Fatalf("%d error(s) encountered.", runErrors.Count())
+    }
 
-      // Update general settings
-      if err := manager.UpdateProjectSettings(project, env.Dryrun); err != nil {
-        logger.Errorf("failed to update project settings of repo %v: %v", project.PathWithNamespace, err)
-        manager.SetError(true)
+    if !env.Dryrun {
+      for _, result := range results {
+        if err := result.runState.Finish(result.fullSweep); err != nil {
+          logger.Fatalf("failed to record finished run: %v", err)
+        }
       }
+    }
+
+    exitIfDrifted(changes, syncFailOnDrift)
+
+    return nil
+  },
+}
+
+// syncResult holds the outcome of enforcing a single GitLab instance
+type syncResult struct {
+  instance  config.Instance
+  manager   *gl.ProjectManager
+  summary   gl.RunSummary
+  scanned   int
+  failed    bool
+  runState  *state.State
+  fullSweep bool
+  projects  []history.ProjectResult
+}
+
+// syncInstance enforces the config on all projects of the given instance and writes its reports
+func syncInstance(ctx context.Context, instance config.Instance, renderer gl.ChangeLogRenderer, reports *reportOutput) *syncResult {
+  started := time.Now()
+  result := &syncResult{instance: instance, failed: true}
+
+  if err := useInstance(instance); err != nil {
+    logger.Errorf("failed to enforce instance %s: %v", instance.Name, err)
+    return result
+  }
+
+  client := newGitlabClient()
+  manager := newProjectManager(client)
+  manager.SetAllowDestructive(syncAllowDestructive)
+  result.manager = manager
+
+  manager.ResolveGroupID()
+
+  // Discovered groups are checked by the preflight and enforced like the configured ones
+  if err := manager.DiscoverGroups(ctx); err != nil {
+    if ctx.Err() == nil {
+      logger.Error(err)
+    }
+    return result
+  }
+
+  if syncSkipPreflight {
+    logger.Debugf("Skipping preflight checks.")
+  } else if err := manager.Preflight(ctx); err != nil {
+    if ctx.Err() == nil {
+      logger.Error(err)
+    }
+    return result
+  }
+
+  // State and audit log are set up before anything is changed, so failing them leaves no change unrecorded
+  runState, err := loadSyncState(instanceFile(syncStateFile, instance.Name))
+  if err != nil {
+    logger.Error(err)
+    return result
+  }
+  result.runState = runState
+  previous := runState.AppliedSettings()
+
+  since, fullSweep, err := syncActiveSince(runState)
+  if err != nil {
+    logger.Error(err)
+    return result
+  }
+  result.fullSweep = fullSweep
+
+  // Dryruns don't mutate anything to audit
+  if !env.Dryrun {
+    auditLog, err := openAuditLog(ctx, client, manager)
+    if err != nil {
+      logger.Error(err)
+      return result
+    }
+    if auditLog != nil {
+      defer auditLog.Close()
+    }
+  }
+
+  // Update instance settings
+  if !cfg.Subsystems.Enabled(config.SubsystemInstanceSettings) {
+    logger.Debugf("Skipping subsystem %s.", config.SubsystemInstanceSettings)
+  } else if syncAdmin {
+    if err := manager.UpdateInstanceSettings(ctx, env.Dryrun); err != nil {
+      logger.Errorf("failed to update instance settings: %v", err)
+      manager.SetError(true)
+    }
+  } else if cfg.InstanceSettings != nil {
+    logger.Warnf("Skipping instance_settings, as --admin is not set.")
+  }
+
+  // Update group settings
+  if cfg.Subsystems.Enabled(config.SubsystemGroupSettings) {
+    if err := manager.UpdateGroupSettings(ctx, env.Dryrun); err != nil {
+      logger.Errorf("failed to update group settings: %v", err)
+      manager.SetError(true)
+    }
+  }
+
+  // Update group push rules
+  if cfg.Subsystems.Enabled(config.SubsystemGroupPushRules) {
+    if err := manager.UpdateGroupPushRules(ctx, env.Dryrun); err != nil {
+      logger.Errorf("failed to update group push rules: %v", err)
+      manager.SetError(true)
+    }
+  }
+
+  // Create declared projects, so they are enforced below
+  if cfg.Subsystems.Enabled(config.SubsystemProjects) {
+    if err := manager.EnsureProjects(ctx, env.Dryrun); err != nil {
+      logger.Errorf("failed to create projects: %v", err)
+      manager.SetError(true)
+    }
+  }
+
+  projects, err := getSyncProjects(ctx, manager)
+  if err != nil {
+    if ctx.Err() == nil {
+      logger.Errorf("failed to get projects: %v", err)
+    }
+    return result
+  }
+
+  logger.Infof("Identified %d valid project(s).", len(projects))
+
+  summary := gl.RunSummary{Projects: len(projects)}
+
+  var pending []gitlab.Project
+  for _, project := range projects {
+    if !since.IsZero() && project.LastActivityAt != nil && !project.LastActivityAt.After(since) {
+      logger.Debugf("Skipping project %s, inactive since %s.", project.PathWithNamespace, project.LastActivityAt.Format(time.RFC3339))
+      continue
+    }
+
+    if syncResume && runState.IsCompleted(project.PathWithNamespace) {
+      logger.Infof("Skipping project %s, already enforced.", project.PathWithNamespace)
+      continue
+    }
+
+    pending = append(pending, project)
+  }
+
+  prefetchProjectSettings(ctx, manager, pending)
+
+  var scanned int
+  var succeeded []string
+  durations := make(map[string]time.Duration)
+  for index, project := range pending {
+    if ctx.Err() != nil {
+      logger.Warnf("Skipping remaining %d project(s).", len(pending)-index)
+      break
+    }
+    if syncFailFast && manager.GetError() {
+      logger.Warnf("Aborting on the first error (--fail-fast), skipping remaining %d project(s).", len(pending)-index)
+      break
+    }
+
+    // Projects lacking permission would only fail with 403 midway
+    if !manager.CheckProjectPermission(project) {
+      result.projects = append(result.projects, history.ProjectResult{Project: project.PathWithNamespace, Outcome: history.OutcomeSkipped})
+      continue
+    }
+    scanned++
+
+    logger.Infof("Processing project #%d: %s", index + 1, project.PathWithNamespace)
 
-      // Update approval settings
-      if err := manager.UpdateProjectApprovalSettings(project, env.Dryrun); err != nil {
-        logger.Errorf("failed to update approval settings of repo %v: %v", project.PathWithNamespace, err)
-        manager.SetError(true)
+    projectStarted := time.Now()
+    ok := syncProject(ctx, manager, project)
+    durations[project.PathWithNamespace] = time.Since(projectStarted)
+
+    if !ok {
+      manager.SetError(true)
+      summary.Failed++
+      result.projects = append(result.projects, history.ProjectResult{Project: project.PathWithNamespace, Outcome: history.OutcomeFailed, Duration: durations[project.PathWithNamespace]})
+      continue
+    }
+    succeeded = append(succeeded, project.PathWithNamespace)
+
+    // Dryruns don't enforce anything
+    if !env.Dryrun {
+      runState.SetApplied(project.PathWithNamespace, manager.AppliedSettings(project.PathWithNamespace))
+      if err := runState.Complete(project.PathWithNamespace, time.Now()); err != nil {
+        logger.Errorf("failed to record completion of repo %v: %v", project.PathWithNamespace, err)
       }
     }
+  }
+
+  // Destructive changes precede the change log, so they aren't overlooked
+  if err := reports.write("destructive", gl.OutputFormatText, manager.GenerateDestructiveReport); err != nil {
+    logger.Errorf("failed to create destructive changes report: %v", err)
+    manager.SetError(true)
+  }
+
+  changelog := func(w io.Writer) error {
+    return manager.GenerateChangeLogReport(w, renderer)
+  }
+  if err := reports.write("changelog", outputFormat, changelog); err != nil {
+    logger.Errorf("failed to create changelog report: %v", err)
+    manager.SetError(true)
+  }
+
+  // Failures follow the change log, so the changes that were made are seen next to the ones that failed
+  if err := reports.write("failures", gl.OutputFormatText, manager.GenerateFailureReport); err != nil {
+    logger.Errorf("failed to create failure report: %v", err)
+    manager.SetError(true)
+  }
+
+  if err := writeNotApplied(reports, manager); err != nil {
+    logger.Errorf("failed to create not applied report: %v", err)
+    manager.SetError(true)
+  }
+
+  if err := writeHTMLReport(manager, instance.Name); err != nil {
+    logger.Errorf("failed to create html report: %v", err)
+    manager.SetError(true)
+  }
+
+  if err := reports.write("branch_coverage", gl.OutputFormatText, manager.GenerateBranchCoverageReport); err != nil {
+    logger.Errorf("failed to create branch coverage report: %v", err)
+    manager.SetError(true)
+  }
+
+  if err := reports.write("security_scanning", gl.OutputFormatText, manager.GenerateScanningCoverageReport); err != nil {
+    logger.Errorf("failed to create security scanning coverage report: %v", err)
+    manager.SetError(true)
+  }
 
-    if err := manager.GenerateChangeLogReport(); err != nil {
-      logger.Errorf("failed to create changelog report: %v", err)
+  if err := reports.write("stale_branches", gl.OutputFormatText, manager.GenerateStaleBranchesReport); err != nil {
+    logger.Errorf("failed to create stale branches report: %v", err)
+    manager.SetError(true)
+  }
+
+  if err := reports.write("violations", gl.OutputFormatText, manager.GenerateViolationsReport); err != nil {
+    logger.Errorf("failed to create violations report: %v", err)
+    manager.SetError(true)
+  }
+
+  if err := reports.write("exemptions", gl.OutputFormatText, manager.GenerateExemptionsReport); err != nil {
+    logger.Errorf("failed to create exemptions report: %v", err)
+    manager.SetError(true)
+  }
+
+  if err := reports.write("unavailable", gl.OutputFormatText, manager.GenerateUnavailableReport); err != nil {
+    logger.Errorf("failed to create unavailable features report: %v", err)
+    manager.SetError(true)
+  }
+
+  if err := reports.write("no_permission", gl.OutputFormatText, manager.GenerateNoPermissionReport); err != nil {
+    logger.Errorf("failed to create no permission report: %v", err)
+    manager.SetError(true)
+  }
+
+  // Without applied settings there is nothing to reconcile with yet
+  if len(previous) == 0 {
+    logger.Infof("No applied settings recorded in %s yet, this run records the baseline.", runState.Path())
+  } else if err := writeReconciliation(reports, manager.Reconcile(previous, succeeded)); err != nil {
+    logger.Errorf("failed to create reconciliation report: %v", err)
+    manager.SetError(true)
+  }
+
+  changes := manager.ChangeLog()
+
+  // Dryruns don't change anything to roll back or record
+  if !env.Dryrun {
+    if err := writeRollback(manager, instance.Name, started); err != nil {
+      logger.Errorf("failed to record rollback: %v", err)
       manager.SetError(true)
     }
 
-    if manager.GetError() {
-      logger.Fatal("Error(s) encountered.")
+    var changed []gitlab.Project
+    for _, project := range pending {
+      if _, ok := changes[project.PathWithNamespace]; ok {
+        changed = append(changed, project)
+      }
     }
-  },
+    if err := manager.CommitRunResults(ctx, started, changed); err != nil {
+      logger.Errorf("failed to commit run results: %v", err)
+      manager.SetError(true)
+    }
+  }
+
+  for _, project := range succeeded {
+    outcome := history.OutcomeInSync
+    if _, ok := changes[project]; ok {
+      summary.Changed++
+      outcome = history.OutcomeChanged
+    } else {
+      summary.InSync++
+    }
+    result.projects = append(result.projects, history.ProjectResult{Project: project, Outcome: outcome, Duration: durations[project]})
+  }
+  summary.Skipped = summary.Projects - scanned
+  summary.APICalls = gitlabTransport.Requests()
+  summary.Elapsed = time.Since(started)
+
+  if err := writeSummary(reports, summary); err != nil {
+    logger.Errorf("failed to create summary report: %v", err)
+    manager.SetError(true)
+  }
+
+  result.summary = summary
+  result.scanned = scanned
+  result.failed = manager.GetError()
+
+  return result
+}
+
+// writeSummary writes the summary report, as JSON for the json output format and as text for all others
+func writeSummary(reports *reportOutput, summary gl.RunSummary) error {
+  format := gl.OutputFormatText
+  if outputFormat == gl.OutputFormatJSON {
+    format = gl.OutputFormatJSON
+  }
+
+  return reports.write("summary", format, func(w io.Writer) error {
+    return summary.Render(w, format)
+  })
+}
+
+// writeNotApplied writes the settings GitLab accepted but didn't apply, as JSON for the json
+// output format and as text for all others
+func writeNotApplied(reports *reportOutput, manager *gl.ProjectManager) error {
+  format := gl.OutputFormatText
+  if outputFormat == gl.OutputFormatJSON {
+    format = gl.OutputFormatJSON
+  }
+
+  return reports.write("not_applied", format, func(w io.Writer) error {
+    return manager.GenerateNotAppliedReport(w, format)
+  })
+}
+
+// writeReconciliation writes the reconciliation report, as JSON for the json output format and
+// as text for all others
+func writeReconciliation(reports *reportOutput, reconciliation gl.Reconciliation) error {
+  if len(reconciliation.OutOfBand) > 0 {
+    logger.Warnf("%d setting(s) were changed out of band since the last run.", len(reconciliation.OutOfBand))
+  }
+
+  format := gl.OutputFormatText
+  if outputFormat == gl.OutputFormatJSON {
+    format = gl.OutputFormatJSON
+  }
+
+  return reports.write("reconciliation", format, func(w io.Writer) error {
+    return reconciliation.Render(w, format)
+  })
 }
 
 func init() {
@@ -82,4 +499,191 @@ func init() {
   // Cobra supports local flags which will only run when this command
   // is called directly, e.g.:
   // syncCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+  syncCmd.Flags().BoolVar(&syncAdmin, "admin", false, "Apply instance_settings (requires a token with administrator rights)")
+  syncCmd.Flags().BoolVar(&syncResume, "resume", false, "Resume the previous run, skipping the projects it already enforced")
+  syncCmd.Flags().StringVar(&syncStateFile, "state-file", ".gitlab-settings-enforcer.state.json", "File recording the progress of runs")
+  syncCmd.Flags().StringVar(&syncSince, "since", "", "Only enforce projects active after the given RFC3339 timestamp")
+  syncCmd.Flags().BoolVar(&syncIncremental, "incremental", false, "Only enforce projects active since the start of the last successful run")
+  syncCmd.Flags().BoolVar(&syncSkipPreflight, "skip-preflight", false, "Don't verify the scopes of the token and its access on the configured groups before enforcing projects")
+  syncCmd.Flags().BoolVar(&syncPruneStaleBranches, "prune-stale-branches", false, "Delete the stale branches found by the stale-branches subsystem (see stale_branches)")
+  syncCmd.Flags().BoolVar(&syncFailFast, "fail-fast", false, "Abort the run on the first error, skipping the remaining projects")
+  syncCmd.Flags().BoolVar(&syncAllowTransfer, "allow-transfer", false, "Transfer projects located outside the namespace configured in project_placement, which are only reported otherwise")
+  syncCmd.Flags().BoolVar(&syncAllowDestructive, "allow-destructive", false, "Apply destructive changes (visibility from or to public, disabling merge requests, archiving, weakening branch protection), which are refused otherwise")
+  syncCmd.Flags().BoolVar(&syncFailOnDrift, "fail-on-drift", false, "Exit with code 2 if drifted settings were found")
+  syncCmd.Flags().StringSliceVar(&syncOnly, "only", nil, "Only run these subsystems, e.g. branches,approvals (overrides subsystems.only)")
+  syncCmd.Flags().StringSliceVar(&syncSkip, "skip", nil, "Don't run these subsystems, e.g. project-settings (overrides subsystems.skip)")
+  syncCmd.Flags().StringArrayVar(&syncProjects, "project", nil, "Only enforce this project path or glob, ignoring the project whitelist, blacklist and filters (repeatable)")
+  syncCmd.Flags().StringVar(&rollbackDir, "rollback-dir", "", "Directory to write the original settings of changed projects to, for the rollback command")
+  syncCmd.Flags().StringVar(&statusFile, "status-file", "", "File to merge the latest outcome and drifted settings of every enforced project into, for serve")
+  syncCmd.Flags().StringVar(&historyDB, "history-db", "", "SQLite database to record the run, its project outcomes and changes in, for the history command")
+  syncCmd.Flags().DurationVar(&syncFullSweepInterval, "full-sweep-interval", 0, "Enforce all projects with --incremental if the last full sweep is older than this (e.g. 168h)")
+}
+
+// syncProject enforces all settings of a single project and returns whether all succeeded. Errors
+// are recorded per project for the failure report.
+func syncProject(ctx context.Context, manager *gl.ProjectManager, project gitlab.Project) bool {
+  ok := true
+  enabled := cfg.Subsystems.Enabled
+  fail := func(action string, err error) {
+    logger.Errorf("failed to %s of repo %v: %v", action, project.PathWithNamespace, err)
+    manager.AddFailure(project.PathWithNamespace, fmt.Errorf("failed to %s: %v", action, err))
+    ok = false
+  }
+
+  // Update branches
+  if enabled(config.SubsystemBranches) {
+    if err := manager.EnsureBranchesAndProtection(ctx, project, env.Dryrun); err != nil {
+      fail("ensure branches", err)
+    }
+  }
+
+  // Update general settings
+  if enabled(config.SubsystemProjectSettings) {
+    if err := manager.UpdateProjectSettings(ctx, project, env.Dryrun); err != nil {
+      fail("update project settings", err)
+    }
+    if err := manager.UpdateProjectAccessLevels(ctx, project, env.Dryrun); err != nil {
+      fail("update project access levels", err)
+    }
+  }
+
+  // Remove fork relationship
+  if enabled(config.SubsystemForkRelationship) {
+    if err := manager.RemoveForkRelationship(ctx, project, env.Dryrun); err != nil {
+      fail("remove fork relationship", err)
+    }
+  }
+
+  // Update approval settings
+  if enabled(config.SubsystemApprovals) {
+    if err := manager.UpdateProjectApprovalSettings(ctx, project, env.Dryrun); err != nil {
+      fail("update approval settings", err)
+    }
+  }
+
+  // Update repository files
+  if enabled(config.SubsystemRepositoryFiles) {
+    if err := manager.EnsureRepositoryFiles(ctx, project, env.Dryrun); err != nil {
+      fail("ensure repository files", err)
+    }
+  }
+
+  // Update compliance framework
+  if enabled(config.SubsystemComplianceFramework) {
+    if err := manager.EnsureComplianceFramework(ctx, project, env.Dryrun); err != nil {
+      fail("ensure compliance framework", err)
+    }
+  }
+
+  // Update security scanning
+  if enabled(config.SubsystemSecurityScanning) {
+    if err := manager.EnsureSecurityScanning(ctx, project, env.Dryrun); err != nil {
+      fail("ensure security scanning", err)
+    }
+  }
+
+  // Audit branch names
+  if enabled(config.SubsystemBranchNaming) {
+    if err := manager.AuditBranchNaming(ctx, project); err != nil {
+      fail("audit branch names", err)
+    }
+  }
+
+  // Audit (and prune) stale branches
+  if enabled(config.SubsystemStaleBranches) {
+    if err := manager.AuditStaleBranches(ctx, project, syncPruneStaleBranches, env.Dryrun); err != nil {
+      fail("audit stale branches", err)
+    }
+  }
+
+  // Transfer after all others, as they use the path of the project
+  if enabled(config.SubsystemPlacement) {
+    if err := manager.EnsureProjectPlacement(ctx, project, syncAllowTransfer, env.Dryrun); err != nil {
+      fail("ensure project placement", err)
+    }
+  }
+
+  // Archive last, as archived repositories are read-only
+  if enabled(config.SubsystemArchival) {
+    if err := manager.EnsureArchivalState(ctx, project, env.Dryrun); err != nil {
+      fail("ensure archival state", err)
+    }
+  }
+
+  return ok
+}
+
+// getSyncProjects returns the projects given by --project, or the projects of the configured groups
+func getSyncProjects(ctx context.Context, manager *gl.ProjectManager) ([]gitlab.Project, error) {
+  if len(syncProjects) == 0 {
+    return manager.GetProjects(ctx)
+  }
+
+  logger.Infof("Enforcing the projects matching %s only.", strings.Join(syncProjects, ", "))
+  return manager.GetProjectsByPattern(ctx, syncProjects)
+}
+
+// applySubsystemFlags overrides the subsystems selected in the config with --only and --skip
+func applySubsystemFlags(cmd *cobra.Command) error {
+  if cmd.Flags().Changed("only") {
+    cfg.Subsystems.Only = syncOnly
+  }
+  if cmd.Flags().Changed("skip") {
+    cfg.Subsystems.Skip = syncSkip
+  }
+
+  return config.CheckSubsystems(cfg.Subsystems)
+}
+
+// loadSyncState loads the state file at path, starting a new run unless --resume is set
+func loadSyncState(path string) (*state.State, error) {
+  runState, err := state.Load(path)
+  if err != nil {
+    return nil, err
+  }
+
+  if syncResume {
+    logger.Infof("Resuming run started at %s, %d project(s) already enforced.", runState.StartedAt.Format(time.RFC3339), len(runState.Completed))
+    return runState, nil
+  }
+
+  runState.Reset(time.Now())
+  if !env.Dryrun {
+    if err := runState.Save(); err != nil {
+      return nil, err
+    }
+  }
+
+  return runState, nil
+}
+
+// syncActiveSince returns the time after which projects must have been active to be enforced,
+// and whether all projects are enforced. A zero time enforces all projects.
+func syncActiveSince(runState *state.State) (time.Time, bool, error) {
+  if syncSince != "" {
+    since, err := time.Parse(time.RFC3339, syncSince)
+    if err != nil {
+      return time.Time{}, false, fmt.Errorf("invalid --since %q: %v", syncSince, err)
+    }
+
+    logger.Infof("Enforcing projects active since %s.", since.Format(time.RFC3339))
+    return since, false, nil
+  }
+
+  if !syncIncremental {
+    return time.Time{}, true, nil
+  }
+
+  if runState.LastRun.IsZero() {
+    logger.Infof("No previous successful run recorded, enforcing all projects.")
+    return time.Time{}, true, nil
+  }
+
+  if syncFullSweepInterval > 0 && runState.StartedAt.Sub(runState.LastFullSweep) >= syncFullSweepInterval {
+    logger.Infof("Last full sweep is older than %s, enforcing all projects.", syncFullSweepInterval)
+    return time.Time{}, true, nil
+  }
+
+  logger.Infof("Enforcing projects active since the last run at %s.", runState.LastRun.Format(time.RFC3339))
+  return runState.LastRun, false, nil
 }
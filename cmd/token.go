@@ -0,0 +1,121 @@
+package cmd
+
+import (
+  "errors"
+  "fmt"
+  "io/ioutil"
+  "os"
+  "strings"
+
+  gl "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/gitlab"
+)
+
+// tokenFile is read instead of the GITLAB_TOKEN env var, "-" reads the token from stdin
+var tokenFile string
+
+// Types of the GitLab token set by GITLAB_TOKEN_TYPE
+const (
+  tokenTypePrivate = "private"
+  tokenTypeOAuth   = "oauth"
+)
+
+var (
+  errTokenMissing     = errors.New("no GitLab token given, set GITLAB_TOKEN, GITLAB_TOKEN_FILE or --token-file")
+  errTokenTypeInvalid = errors.New("GITLAB_TOKEN_TYPE must be private or oauth")
+)
+
+func init() {
+  rootCmd.PersistentFlags().StringVar(&tokenFile, "token-file", "", "Read the GitLab token from this file, or from stdin if \"-\" (overrides GITLAB_TOKEN_FILE)")
+}
+
+// resolveToken sets env.GitlabToken from --token-file or GITLAB_TOKEN_FILE, if given, and
+// env.GitlabOauthRefreshToken from GITLAB_OAUTH_REFRESH_TOKEN_FILE.
+// Surrounding whitespace (e.g. a trailing newline) is removed.
+func resolveToken() error {
+  switch env.GitlabTokenType {
+  case tokenTypePrivate, tokenTypeOAuth:
+  default:
+    return errTokenTypeInvalid
+  }
+
+  path := env.GitlabTokenFile
+  if tokenFile != "" {
+    path = tokenFile
+  }
+
+  if path != "" {
+    token, err := readToken(path)
+    if err != nil {
+      return err
+    }
+    env.GitlabToken = token
+  }
+
+  if env.GitlabOauthRefreshTokenFile != "" {
+    token, err := readToken(env.GitlabOauthRefreshTokenFile)
+    if err != nil {
+      return err
+    }
+    env.GitlabOauthRefreshToken = token
+  }
+
+  return nil
+}
+
+// checkToken returns an error if no GitLab token was given. It isn't checked by resolveToken,
+// as the instances of the config bring their own tokens.
+func checkToken() error {
+  // An OAuth access token can be obtained using the refresh token
+  if env.GitlabToken == "" && (env.GitlabTokenType != tokenTypeOAuth || env.GitlabOauthRefreshToken == "") {
+    return errTokenMissing
+  }
+
+  return nil
+}
+
+// oauthRefresh returns the refresh settings of OAuth tokens. The refresh token rotated by
+// GitLab is written back to GITLAB_OAUTH_REFRESH_TOKEN_FILE, so the next run can use it.
+func oauthRefresh() gl.OAuthRefresh {
+  refresh := gl.OAuthRefresh{
+    RefreshToken: env.GitlabOauthRefreshToken,
+    ClientID:     env.GitlabOauthClientID,
+    ClientSecret: env.GitlabOauthClientSecret,
+  }
+
+  if path := env.GitlabOauthRefreshTokenFile; path != "" {
+    refresh.OnRefresh = func(refreshToken string) error {
+      if err := ioutil.WriteFile(path, []byte(refreshToken+"\n"), 0600); err != nil {
+        return fmt.Errorf("failed to store refreshed oauth token in %q: %v", path, err)
+      }
+      return nil
+    }
+  } else if refresh.RefreshToken != "" {
+    refresh.OnRefresh = func(string) error {
+      logger.Warnf("GitLab rotated the oauth refresh token, set GITLAB_OAUTH_REFRESH_TOKEN_FILE to keep it for the next run.")
+      return nil
+    }
+  }
+
+  return refresh
+}
+
+// readToken reads the token from the file at path, or from stdin if path is "-"
+func readToken(path string) (string, error) {
+  var b []byte
+  var err error
+  if path == "-" {
+    b, err = ioutil.ReadAll(os.Stdin)
+  } else {
+    b, err = ioutil.ReadFile(path)
+  }
+  if err != nil {
+    return "", fmt.Errorf("failed to read GitLab token from %q: %v", path, err)
+  }
+
+  token := strings.TrimSpace(string(b))
+  if token == "" {
+    return "", fmt.Errorf("GitLab token file %q is empty", path)
+  }
+
+  return token, nil
+}
@@ -0,0 +1,187 @@
+package audit
+
+import (
+  "bytes"
+  "context"
+  "encoding/json"
+  "fmt"
+  "net/http"
+  "os"
+  "sync"
+  "time"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/storage"
+)
+
+// Entry records a single mutating API call
+type Entry struct {
+  Timestamp time.Time         `json:"timestamp"`
+  Actor     string            `json:"actor"`
+  Project   string            `json:"project"`
+  Endpoint  string            `json:"endpoint"`
+  Changes   map[string]Change `json:"changes,omitempty"`
+  Error     string            `json:"error,omitempty"`
+}
+
+// Change holds the value of a setting before and after the API call
+type Change struct {
+  Before interface{} `json:"before"`
+  After  interface{} `json:"after"`
+}
+
+// Sink persists audit entries
+type Sink interface {
+  Write(ctx context.Context, entry Entry) error
+  Close() error
+}
+
+// FileSink appends the entries as JSON lines to a file, which is never truncated
+type FileSink struct {
+  mu   sync.Mutex
+  file *os.File
+}
+
+// NewFileSink opens (or creates) the audit log file at the given path for appending
+func NewFileSink(path string) (*FileSink, error) {
+  f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+  if err != nil {
+    return nil, fmt.Errorf("failed to open audit log %q: %v", path, err)
+  }
+
+  return &FileSink{file: f}, nil
+}
+
+// Write implements Sink
+func (s *FileSink) Write(ctx context.Context, entry Entry) error {
+  line, err := json.Marshal(entry)
+  if err != nil {
+    return fmt.Errorf("failed to marshal audit entry: %v", err)
+  }
+
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  if _, err := s.file.Write(append(line, '\n')); err != nil {
+    return fmt.Errorf("failed to write audit log %q: %v", s.file.Name(), err)
+  }
+
+  // Entries must survive a crash of the run
+  return s.file.Sync()
+}
+
+// Close implements Sink
+func (s *FileSink) Close() error {
+  return s.file.Close()
+}
+
+// ObjectSink writes the entries as JSON lines to a remote object (see package storage). Objects
+// can't be appended to, so the object is replaced with all entries written so far on every write.
+type ObjectSink struct {
+  mu    sync.Mutex
+  path  string
+  lines []byte
+}
+
+// NewObjectSink returns a new ObjectSink writing to the remote object at the given path
+func NewObjectSink(path string) *ObjectSink {
+  return &ObjectSink{path: path}
+}
+
+// Write implements Sink
+func (s *ObjectSink) Write(ctx context.Context, entry Entry) error {
+  line, err := json.Marshal(entry)
+  if err != nil {
+    return fmt.Errorf("failed to marshal audit entry: %v", err)
+  }
+
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  s.lines = append(append(s.lines, line...), '\n')
+  if err := storage.WriteFile(s.path, s.lines, 0600); err != nil {
+    return fmt.Errorf("failed to write audit log %q: %v", s.path, err)
+  }
+
+  return nil
+}
+
+// Close implements Sink
+func (s *ObjectSink) Close() error {
+  return nil
+}
+
+// HTTPSink posts every entry as JSON to a remote endpoint
+type HTTPSink struct {
+  httpClient *http.Client
+  url        string
+}
+
+// NewHTTPSink returns a new HTTPSink posting to the given URL
+func NewHTTPSink(httpClient *http.Client, url string) *HTTPSink {
+  if httpClient == nil {
+    httpClient = http.DefaultClient
+  }
+
+  return &HTTPSink{
+    httpClient: httpClient,
+    url:        url,
+  }
+}
+
+// Write implements Sink
+func (s *HTTPSink) Write(ctx context.Context, entry Entry) error {
+  body, err := json.Marshal(entry)
+  if err != nil {
+    return fmt.Errorf("failed to marshal audit entry: %v", err)
+  }
+
+  req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+  if err != nil {
+    return fmt.Errorf("failed to create audit request: %v", err)
+  }
+  req = req.WithContext(ctx)
+  req.Header.Set("Content-Type", "application/json")
+
+  resp, err := s.httpClient.Do(req)
+  if err != nil {
+    return fmt.Errorf("failed to post audit entry: %v", err)
+  }
+  defer resp.Body.Close()
+
+  if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+    return fmt.Errorf("audit endpoint returned unexpected status code %d", resp.StatusCode)
+  }
+
+  return nil
+}
+
+// Close implements Sink
+func (s *HTTPSink) Close() error {
+  return nil
+}
+
+// MultiSink writes every entry to all of its sinks
+type MultiSink []Sink
+
+// Write implements Sink
+func (s MultiSink) Write(ctx context.Context, entry Entry) error {
+  for _, sink := range s {
+    if err := sink.Write(ctx, entry); err != nil {
+      return err
+    }
+  }
+
+  return nil
+}
+
+// Close implements Sink
+func (s MultiSink) Close() error {
+  var firstErr error
+  for _, sink := range s {
+    if err := sink.Close(); err != nil && firstErr == nil {
+      firstErr = err
+    }
+  }
+
+  return firstErr
+}
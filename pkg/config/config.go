@@ -3,34 +3,108 @@ package config
 import (
   "encoding/json"
   "fmt"
-  "io/ioutil"
+  "net/url"
   "os"
   "path/filepath"
+  "regexp"
+  "strings"
+  "time"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/internal/stringslice"
 )
 
-// Parse takes the given configFilePath and reads the containing config file into a config struct
-func Parse(configFilePath string) (*Config, error) {
+// instanceNamePattern matches the names of instances, which are used in file names
+var instanceNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// Parse takes the given configFilePath and reads the containing config file into a config struct.
+// The format (json, yaml or toml) is detected by the file extension, unless set in opts.
+// If configFilePath is a directory, all config files inside are merged, see ParseDir.
+func Parse(configFilePath string, opts Options) (*Config, error) {
   if err := checkFilePath(&configFilePath); err != nil {
     return nil, err
   }
 
-  // nolint: gosec
-  b, err := ioutil.ReadFile(configFilePath)
+  if info, err := os.Stat(configFilePath); err == nil && info.IsDir() {
+    return ParseDir(configFilePath, opts)
+  }
+
+  return ParseFrom(NewFileSource(), configFilePath, opts)
+}
+
+// ParseFrom reads the config file at the given location of the source into a config struct.
+// Included files and repository file sources are read from the same source.
+func ParseFrom(source Source, configFilePath string, opts Options) (*Config, error) {
+  if err := checkFormat(opts.Format); err != nil {
+    return nil, err
+  }
+
+  content, err := loadWithIncludes(source, configFilePath, opts, make(map[string]bool))
   if err != nil {
-    return nil, fmt.Errorf("failed to read config file %q: %v", configFilePath, err)
+    return nil, err
+  }
+
+  return decodeConfig(content, source, configFilePath)
+}
+
+// checkFormat returns an error if the given config format is unknown
+func checkFormat(format string) error {
+  switch format {
+  case "", FormatJSON, FormatYAML, FormatTOML:
+    return nil
+  default:
+    return fmt.Errorf("unknown config format %q, must be one of: %s, %s, %s", format, FormatJSON, FormatYAML, FormatTOML)
+  }
+}
+
+// decodeConfig validates the merged config content and decodes it into a config struct.
+// Referenced files are read from their resolved locations, configFilePath names the config in errors.
+func decodeConfig(content map[string]interface{}, source Source, configFilePath string) (*Config, error) {
+  b, err := json.Marshal(content)
+  if err != nil {
+    return nil, fmt.Errorf("failed to merge config file %q: %v", configFilePath, err)
+  }
+
+  if err := validateSchema(b); err != nil {
+    return nil, fmt.Errorf("invalid config file %q: %v", configFilePath, err)
   }
 
   cfg := &Config{
     ProjectBlacklist: make([]string, 0),
     ProjectWhitelist: make([]string, 0),
+    HTTP:             DefaultHTTP(),
+    RateLimit:        DefaultRateLimit(),
+    Retry:            DefaultRetry(),
   }
   if err := json.Unmarshal(b, cfg); err != nil {
     return nil, fmt.Errorf("failed to unmarshal config file %q: %v", configFilePath, err)
   }
 
+  if err := loadRepositoryFiles(cfg, source); err != nil {
+    return nil, err
+  }
+
+  if err := loadRegoPolicies(cfg, source); err != nil {
+    return nil, err
+  }
+
   return checkConfig(cfg)
 }
 
+// validateSchema validates the merged JSON config against the generated config schema
+func validateSchema(b []byte) error {
+  var content interface{}
+  if err := json.Unmarshal(b, &content); err != nil {
+    return err
+  }
+
+  violations := GenerateSchema().Validate(content)
+  if len(violations) > 0 {
+    return fmt.Errorf("schema validation failed:\n  %s", strings.Join(violations, "\n  "))
+  }
+
+  return nil
+}
+
 func checkFilePath(configFilePath *string) error {
   var err error
   *configFilePath, err = filepath.Abs(*configFilePath)
@@ -47,11 +121,75 @@ func checkFilePath(configFilePath *string) error {
   return nil
 }
 
+// CheckProjectFilters validates the project filters, which may be overridden on the command line
+func CheckProjectFilters(filters ProjectFilters) error {
+  for _, visibility := range filters.Visibility {
+    switch visibility {
+    case "private", "internal", "public":
+    default:
+      return fmt.Errorf("%v, got %q%s", errProjectFiltersVisibilityInvalid, visibility, didYouMean(visibility, visibilityValues()))
+    }
+  }
+
+  if filters.MaxInactiveDays < 0 {
+    return errProjectFiltersInactiveDaysInvalid
+  }
+
+  return nil
+}
+
+// checkProjectList validates the entries of project_list, which must not be patterns
+func checkProjectList(projects []string) error {
+  for _, project := range projects {
+    if project == "" || stringslice.IsPattern(project) {
+      return fmt.Errorf("%v, got %q", errProjectListEntryInvalid, project)
+    }
+  }
+
+  return nil
+}
+
+// checkAllGroups validates all_groups, defaulting its mode to membership
+func checkAllGroups(allGroups *AllGroups) error {
+  if allGroups == nil {
+    return nil
+  }
+
+  if allGroups.Mode == "" {
+    allGroups.Mode = AllGroupsModeMembership
+  }
+  modes := []string{AllGroupsModeAdmin, AllGroupsModeMembership}
+  if !stringslice.Contains(allGroups.Mode, modes) {
+    return fmt.Errorf("%v, got %q%s", errAllGroupsModeInvalid, allGroups.Mode, didYouMean(allGroups.Mode, modes))
+  }
+  for _, pattern := range allGroups.Exclude {
+    if err := stringslice.ValidatePattern(pattern); err != nil {
+      return fmt.Errorf("invalid all_groups.exclude pattern %q: %v", pattern, err)
+    }
+  }
+
+  return nil
+}
+
 func checkConfig(cfg *Config) (*Config, error) {
+  if cfg.GroupID < 0 {
+    return nil, errGroupIDInvalid
+  }
+
+  if err := checkProjectList(cfg.ProjectList); err != nil {
+    return nil, err
+  }
+
   if len(cfg.ProjectBlacklist) > 0 && len(cfg.ProjectWhitelist) > 0 {
     return nil, errOnlyOneOfBlacklistAndWhitelistAllowed
   }
 
+  for _, pattern := range append(append([]string{}, cfg.ProjectBlacklist...), cfg.ProjectWhitelist...) {
+    if err := stringslice.ValidatePattern(pattern); err != nil {
+      return nil, fmt.Errorf("invalid project pattern %q: %v", pattern, err)
+    }
+  }
+
   if cfg.ProjectSettings != nil {
     // Contains ProjectsSettings section
     if cfg.ProjectSettings.Name != nil {
@@ -59,5 +197,498 @@ func checkConfig(cfg *Config) (*Config, error) {
     }
   }
 
+  if err := CheckProjectFilters(cfg.ProjectFilters); err != nil {
+    return nil, err
+  }
+
+  if err := CheckSubsystems(cfg.Subsystems); err != nil {
+    return nil, err
+  }
+
+  if err := checkSettingModes(cfg.SettingModes); err != nil {
+    return nil, err
+  }
+  if err := checkAllowedValues(cfg.AllowedValues); err != nil {
+    return nil, err
+  }
+
+  for profile, policy := range cfg.Profiles {
+    if policy.ProjectSettings != nil && policy.ProjectSettings.Name != nil {
+      return nil, fmt.Errorf("profiles[%q]: %v", profile, errProjectSettingsNameMustBeEmpty)
+    }
+    if err := checkSettingModes(policy.SettingModes); err != nil {
+      return nil, fmt.Errorf("profiles[%q]: %v", profile, err)
+    }
+    if err := checkAllowedValues(policy.AllowedValues); err != nil {
+      return nil, fmt.Errorf("profiles[%q]: %v", profile, err)
+    }
+  }
+
+  if _, ok := cfg.Profiles[cfg.DefaultProfile]; cfg.DefaultProfile != "" && !ok {
+    return nil, fmt.Errorf("default_profile %q is not defined in profiles", cfg.DefaultProfile)
+  }
+
+  for pattern, profile := range cfg.ProfileAssignments {
+    if err := stringslice.ValidatePattern(pattern); err != nil {
+      return nil, fmt.Errorf("invalid profile_assignments pattern %q: %v", pattern, err)
+    }
+    if _, ok := cfg.Profiles[profile]; !ok {
+      return nil, fmt.Errorf("profile_assignments[%q]: profile %q is not defined in profiles", pattern, profile)
+    }
+  }
+
+  for subgroup, policy := range cfg.Subgroups {
+    if policy.ProjectSettings != nil && policy.ProjectSettings.Name != nil {
+      return nil, fmt.Errorf("subgroups[%q]: %v", subgroup, errProjectSettingsNameMustBeEmpty)
+    }
+    if err := checkSettingModes(policy.SettingModes); err != nil {
+      return nil, fmt.Errorf("subgroups[%q]: %v", subgroup, err)
+    }
+    if err := checkAllowedValues(policy.AllowedValues); err != nil {
+      return nil, fmt.Errorf("subgroups[%q]: %v", subgroup, err)
+    }
+  }
+
+  for pattern, override := range cfg.Overrides {
+    if err := stringslice.ValidatePattern(pattern); err != nil {
+      return nil, fmt.Errorf("invalid overrides pattern %q: %v", pattern, err)
+    }
+    if override.ProjectSettings != nil && override.ProjectSettings.Name != nil {
+      return nil, fmt.Errorf("overrides[%q]: %v", pattern, errProjectSettingsNameMustBeEmpty)
+    }
+    if err := checkSettingModes(override.SettingModes); err != nil {
+      return nil, fmt.Errorf("overrides[%q]: %v", pattern, err)
+    }
+    if err := checkAllowedValues(override.AllowedValues); err != nil {
+      return nil, fmt.Errorf("overrides[%q]: %v", pattern, err)
+    }
+  }
+
+  for i, rule := range cfg.Rules {
+    if err := checkRule(rule); err != nil {
+      return nil, fmt.Errorf("rules[%d]: %v", i, err)
+    }
+  }
+
+  for _, p := range cfg.RegoPolicies {
+    if p.Name == "" {
+      return nil, errRegoPolicyNameRequired
+    }
+    if p.Module == "" {
+      return nil, fmt.Errorf("rego policy %q: %v", p.Name, errRegoPolicyModuleRequired)
+    }
+  }
+
+  for i, e := range cfg.Exemptions {
+    if e.Project == "" {
+      return nil, fmt.Errorf("exemptions[%d]: %v", i, errExemptionProjectRequired)
+    }
+    if err := stringslice.ValidatePattern(e.Project); err != nil {
+      return nil, fmt.Errorf("exemptions[%d]: invalid project pattern %q: %v", i, e.Project, err)
+    }
+    if len(e.Settings) == 0 {
+      return nil, fmt.Errorf("exemptions[%d]: %v", i, errExemptionSettingsRequired)
+    }
+    if _, err := time.Parse(ExemptionDateFormat, e.Expires); err != nil {
+      return nil, fmt.Errorf("exemptions[%d]: %v", i, errExemptionExpiresInvalid)
+    }
+  }
+
+  if cfg.HTTP.RequestTimeoutSeconds < 0 || cfg.HTTP.DialTimeoutSeconds < 0 || cfg.HTTP.KeepAliveSeconds < 0 || cfg.HTTP.MaxIdleConns < 0 {
+    return nil, errHTTPInvalid
+  }
+  if cfg.HTTP.ProxyURL != "" {
+    if _, err := url.Parse(cfg.HTTP.ProxyURL); err != nil {
+      return nil, fmt.Errorf("invalid http.proxy_url %q: %v", cfg.HTTP.ProxyURL, err)
+    }
+  }
+  if (cfg.HTTP.ClientCertFile == "") != (cfg.HTTP.ClientKeyFile == "") {
+    return nil, errHTTPClientCertInvalid
+  }
+
+  if cfg.RateLimit.RequestsPerSecond < 0 || cfg.RateLimit.MinRemaining < 0 || cfg.RateLimit.MaxRetries < 0 || cfg.RateLimit.MaxWaitSeconds < 0 {
+    return nil, errRateLimitInvalid
+  }
+
+  if cfg.Retry.MaxAttempts < 1 || cfg.Retry.InitialBackoffMillis < 0 || cfg.Retry.MaxBackoffMillis < 0 {
+    return nil, errRetryInvalid
+  }
+
+  if cfg.RepositoryOverrides != nil {
+    // Contains RepositoryOverrides section
+    if cfg.RepositoryOverrides.Path == "" {
+      cfg.RepositoryOverrides.Path = DefaultRepositoryOverridesPath
+    }
+
+    for _, key := range cfg.RepositoryOverrides.Allowed {
+      switch key {
+      case "protected_branches", "approval_settings", "project_settings":
+        continue
+      }
+
+      parts := strings.SplitN(key, ".", 2)
+      if len(parts) != 2 || parts[1] == "" || (parts[0] != "approval_settings" && parts[0] != "project_settings") || key == "project_settings.name" {
+        return nil, fmt.Errorf("%q: %v", key, errRepositoryOverridesAllowedInvalid)
+      }
+    }
+  }
+
+  if cfg.Notifications != nil {
+    // Contains Notifications section
+    if email := cfg.Notifications.Email; email != nil && (email.Server == "" || email.Port == 0 || email.From == "" || len(email.To) == 0) {
+      return nil, errNotificationEmailInvalid
+    }
+    if email := cfg.Notifications.Email; email != nil && (email.Username == "") != (email.Password == "") {
+      return nil, errNotificationEmailAuthInvalid
+    }
+
+    if teams := cfg.Notifications.Teams; teams != nil {
+      if teams.WebhookURL == "" {
+        return nil, errNotificationTeamsWebhookRequired
+      }
+      if _, err := url.Parse(teams.WebhookURL); err != nil {
+        return nil, fmt.Errorf("invalid notifications.teams.webhook_url %q: %v", teams.WebhookURL, err)
+      }
+    }
+
+    for _, webhook := range cfg.Notifications.Webhooks {
+      if webhook.URL == "" {
+        return nil, errNotificationWebhookURLRequired
+      }
+      if _, err := url.Parse(webhook.URL); err != nil {
+        return nil, fmt.Errorf("invalid notifications.webhooks[].url %q: %v", webhook.URL, err)
+      }
+    }
+  }
+
+  if cfg.AuditLog != nil {
+    // Contains AuditLog section
+    if cfg.AuditLog.File == "" && cfg.AuditLog.URL == "" && cfg.AuditLog.Repository == nil {
+      return nil, errAuditLogSinkRequired
+    }
+    if cfg.AuditLog.Repository != nil && cfg.AuditLog.Repository.Project == "" {
+      return nil, errAuditRepositoryProjectRequired
+    }
+    if _, err := url.Parse(cfg.AuditLog.URL); cfg.AuditLog.URL != "" && err != nil {
+      return nil, fmt.Errorf("invalid audit_log.url %q: %v", cfg.AuditLog.URL, err)
+    }
+  }
+
+  names := make(map[string]bool, len(cfg.Instances))
+  for i, instance := range cfg.Instances {
+    if !instanceNamePattern.MatchString(instance.Name) || names[instance.Name] {
+      return nil, fmt.Errorf("instances[%d]: %v", i, errInstanceNameInvalid)
+    }
+    names[instance.Name] = true
+
+    if instance.Endpoint == "" {
+      return nil, fmt.Errorf("instances[%q]: %v", instance.Name, errInstanceEndpointRequired)
+    }
+    if (instance.TokenEnv == "") == (instance.TokenFile == "") {
+      return nil, fmt.Errorf("instances[%q]: %v", instance.Name, errInstanceTokenInvalid)
+    }
+    if instance.GroupName == "" && instance.GroupID == 0 && len(instance.Groups) == 0 && len(instance.Users) == 0 && len(instance.ProjectList) == 0 && instance.AllGroups == nil {
+      return nil, fmt.Errorf("instances[%q]: %v", instance.Name, errInstanceGroupsRequired)
+    }
+    if err := checkProjectList(instance.ProjectList); err != nil {
+      return nil, fmt.Errorf("instances[%q]: %v", instance.Name, err)
+    }
+    if err := checkAllGroups(instance.AllGroups); err != nil {
+      return nil, fmt.Errorf("instances[%q]: %v", instance.Name, err)
+    }
+  }
+  if len(cfg.Instances) > 0 {
+    // Replaced by the ones of each instance, see useInstance
+    rootGroups := []struct {
+      key string
+      set bool
+    }{
+      {"group_name", cfg.GroupName != ""},
+      {"group_id", cfg.GroupID != 0},
+      {"groups", len(cfg.Groups) > 0},
+      {"users", len(cfg.Users) > 0},
+      {"project_list", len(cfg.ProjectList) > 0},
+      {"all_groups", cfg.AllGroups != nil},
+    }
+    for _, root := range rootGroups {
+      if root.set {
+        return nil, fmt.Errorf("%s %v", root.key, errInstancesRootGroupsInvalid)
+      }
+    }
+  }
+
+  if cfg.GroupSettings != nil && cfg.GroupSettings.DefaultBranchProtection != nil {
+    // Contains GroupSettings section
+    if *cfg.GroupSettings.DefaultBranchProtection < 0 || *cfg.GroupSettings.DefaultBranchProtection > 4 {
+      return nil, errDefaultBranchProtectionInvalid
+    }
+  }
+
+  if cfg.RepositoryFiles != nil {
+    // Contains RepositoryFiles section
+    switch cfg.RepositoryFiles.Method {
+    case RepositoryFilesMethodCommit:
+    case RepositoryFilesMethodMergeRequest:
+      if cfg.RepositoryFiles.Branch == "" {
+        return nil, errRepositoryFilesBranchRequired
+      }
+    default:
+      return nil, fmt.Errorf("%v, got %q%s", errRepositoryFilesMethodInvalid, cfg.RepositoryFiles.Method, didYouMean(cfg.RepositoryFiles.Method, []string{RepositoryFilesMethodCommit, RepositoryFilesMethodMergeRequest}))
+    }
+
+    for _, f := range cfg.RepositoryFiles.Files {
+      if f.Path == "" {
+        return nil, errRepositoryFilePathRequired
+      }
+    }
+  }
+
+  if cfg.InitialCommit != nil {
+    for _, f := range cfg.InitialCommit.Files {
+      if f.Path == "" {
+        return nil, errInitialCommitFilePathRequired
+      }
+    }
+  }
+
+  if m := cfg.DefaultBranchMigration; m != nil && (m.From == "" || m.To == "" || m.From == m.To) {
+    return nil, errDefaultBranchMigrationInvalid
+  }
+
+  if cfg.StaleBranches != nil && cfg.StaleBranches.MaxAgeDays < 0 {
+    return nil, errStaleBranchesMaxAgeInvalid
+  }
+
+  if scanning := cfg.SecurityScanning; scanning != nil {
+    for _, scanner := range scanning.Scanners {
+      if !stringslice.Contains(scanner, Scanners) {
+        return nil, fmt.Errorf("%v, got %q%s", errSecurityScannerInvalid, scanner, didYouMean(scanner, Scanners))
+      }
+    }
+
+    if scanning.Method == "" {
+      scanning.Method = RepositoryFilesMethodCommit
+    }
+    switch scanning.Method {
+    case RepositoryFilesMethodCommit:
+    case RepositoryFilesMethodMergeRequest:
+      if scanning.Branch == "" {
+        return nil, errSecurityScanningBranchRequired
+      }
+    default:
+      return nil, fmt.Errorf("%v, got %q%s", errSecurityScanningMethodInvalid, scanning.Method, didYouMean(scanning.Method, []string{RepositoryFilesMethodCommit, RepositoryFilesMethodMergeRequest}))
+    }
+  }
+
+  if cfg.ProjectAccessLevels != nil {
+    for attribute, level := range cfg.ProjectAccessLevels.Levels() {
+      values := []string{FeatureAccessDisabled, FeatureAccessPrivate, FeatureAccessEnabled}
+      if attribute == "pages_access_level" {
+        values = append(values, FeatureAccessPublic)
+      }
+      if !stringslice.Contains(level, values) {
+        return nil, fmt.Errorf("%v, got %s %q%s", errProjectAccessLevelInvalid, attribute, level, didYouMean(level, values))
+      }
+    }
+  }
+
+  if err := checkAllGroups(cfg.AllGroups); err != nil {
+    return nil, err
+  }
+
+  if cfg.Archival != nil {
+    for _, pattern := range append(append([]string{}, cfg.Archival.Archived...), cfg.Archival.Unarchived...) {
+      if err := stringslice.ValidatePattern(pattern); err != nil {
+        return nil, fmt.Errorf("invalid archival pattern %q: %v", pattern, err)
+      }
+    }
+  }
+
+  for _, placement := range cfg.ProjectPlacement {
+    if len(placement.Projects) == 0 || placement.Namespace == "" {
+      return nil, errProjectPlacementInvalid
+    }
+    for _, pattern := range placement.Projects {
+      if err := stringslice.ValidatePattern(pattern); err != nil {
+        return nil, fmt.Errorf("invalid project_placement pattern %q: %v", pattern, err)
+      }
+    }
+  }
+
+  declared := make(map[string]bool)
+  for i := range cfg.Projects {
+    project := &cfg.Projects[i]
+    if project.Name == "" || project.Path == "" {
+      return nil, errProjectNameRequired
+    }
+    if strings.Contains(project.Path, "/") {
+      return nil, fmt.Errorf("%v, got %q", errProjectPathInvalid, project.Path)
+    }
+
+    if project.Group == "" {
+      project.Group = cfg.GroupName
+    }
+    if project.Group == "" {
+      return nil, errProjectGroupRequired
+    }
+
+    if declared[project.PathWithNamespace()] {
+      return nil, fmt.Errorf("%v, got %s", errProjectDuplicate, project.PathWithNamespace())
+    }
+    declared[project.PathWithNamespace()] = true
+  }
+
+  if cfg.BranchNaming != nil {
+    if len(cfg.BranchNaming.AllowedPatterns) == 0 {
+      return nil, errBranchNamingPatternsRequired
+    }
+    for _, pattern := range cfg.BranchNaming.AllowedPatterns {
+      if _, err := regexp.Compile(pattern); err != nil {
+        return nil, fmt.Errorf("invalid branch_naming.allowed_patterns %q: %v", pattern, err)
+      }
+    }
+  }
+
   return cfg, nil
 }
+
+// visibilityValues returns the visibility levels of projects
+func visibilityValues() []string {
+  return []string{"private", "internal", "public"}
+}
+
+// checkRule validates the condition and policy of a rule
+func checkRule(rule Rule) error {
+  for _, visibility := range rule.When.Visibility {
+    switch visibility {
+    case "private", "internal", "public":
+    default:
+      return fmt.Errorf("%v, got %q%s", errRuleVisibilityInvalid, visibility, didYouMean(visibility, visibilityValues()))
+    }
+  }
+
+  if rule.When.Path != "" {
+    if err := stringslice.ValidatePattern(rule.When.Path); err != nil {
+      return fmt.Errorf("invalid path pattern %q: %v", rule.When.Path, err)
+    }
+  }
+
+  if rule.When.MinInactiveDays < 0 || rule.When.MaxInactiveDays < 0 {
+    return errRuleInactiveDaysInvalid
+  }
+
+  if rule.ProjectSettings != nil && rule.ProjectSettings.Name != nil {
+    return errProjectSettingsNameMustBeEmpty
+  }
+  if err := checkSettingModes(rule.SettingModes); err != nil {
+    return err
+  }
+
+  return checkAllowedValues(rule.AllowedValues)
+}
+
+// checkSettingModes validates the keys (section or section.setting) and values of setting_modes
+func checkSettingModes(modes map[string]string) error {
+  for key, mode := range modes {
+    switch strings.SplitN(key, ".", 2)[0] {
+    case "approval_settings", "project_settings":
+    default:
+      return fmt.Errorf("%q: %v", key, errSettingModeSectionInvalid)
+    }
+
+    switch mode {
+    case SettingModeEnforce, SettingModeDefaultOnly:
+    default:
+      return fmt.Errorf("%q: %v, got %q%s", key, errSettingModeInvalid, mode, didYouMean(mode, []string{SettingModeEnforce, SettingModeDefaultOnly}))
+    }
+  }
+
+  return nil
+}
+
+// checkAllowedValues validates the keys (section.setting), values and modes of allowed_values
+func checkAllowedValues(allowedValues map[string]AllowedValues) error {
+  for key, allowed := range allowedValues {
+    parts := strings.SplitN(key, ".", 2)
+    if len(parts) != 2 || parts[1] == "" || (parts[0] != "approval_settings" && parts[0] != "project_settings") {
+      return fmt.Errorf("%q: %v", key, errAllowedValuesKeyInvalid)
+    }
+
+    if len(allowed.Values) == 0 {
+      return fmt.Errorf("%q: %v", key, errAllowedValuesEmpty)
+    }
+
+    switch allowed.Mode {
+    case "", AllowedValuesModeRemediate, AllowedValuesModeFlag:
+    default:
+      return fmt.Errorf("%q: %v, got %q%s", key, errAllowedValuesModeInvalid, allowed.Mode, didYouMean(allowed.Mode, []string{AllowedValuesModeRemediate, AllowedValuesModeFlag}))
+    }
+  }
+
+  return nil
+}
+
+// loadRepositoryFiles reads the content of repository files given by their resolved source
+func loadRepositoryFiles(cfg *Config, source Source) error {
+  if cfg.InitialCommit != nil {
+    if err := loadFileSources(cfg.InitialCommit.Files, source); err != nil {
+      return err
+    }
+  }
+
+  if cfg.RepositoryFiles == nil {
+    return nil
+  }
+
+  if cfg.RepositoryFiles.Method == "" {
+    cfg.RepositoryFiles.Method = RepositoryFilesMethodCommit
+  }
+
+  return loadFileSources(cfg.RepositoryFiles.Files, source)
+}
+
+// loadFileSources replaces the source of each file by its content
+func loadFileSources(files []RepositoryFile, source Source) error {
+  for i, f := range files {
+    if f.Source == "" {
+      continue
+    }
+    if f.Content != "" {
+      return errRepositoryFileContentAmbiguous
+    }
+
+    b, err := source.Read(f.Source)
+    if err != nil {
+      return fmt.Errorf("failed to read repository file source %q: %v", f.Source, err)
+    }
+
+    files[i].Content = string(b)
+  }
+
+  return nil
+}
+
+// loadRegoPolicies reads the modules of rego policies given by their resolved source
+func loadRegoPolicies(cfg *Config, source Source) error {
+  for i, p := range cfg.RegoPolicies {
+    if p.Query == "" {
+      cfg.RegoPolicies[i].Query = DefaultRegoQuery
+    }
+
+    if p.Source == "" {
+      continue
+    }
+    if p.Module != "" {
+      return fmt.Errorf("rego policy %q: %v", p.Name, errRegoPolicyModuleAmbiguous)
+    }
+
+    b, err := source.Read(p.Source)
+    if err != nil {
+      return fmt.Errorf("failed to read rego policy source %q: %v", p.Source, err)
+    }
+
+    cfg.RegoPolicies[i].Module = string(b)
+  }
+
+  return nil
+}
@@ -0,0 +1,26 @@
+package config
+
+import (
+  "testing"
+)
+
+func TestCheckProjectFilters(t *testing.T) {
+  tests := []struct {
+    filters ProjectFilters
+    want    error
+  }{
+    {ProjectFilters{Visibility: []string{"private", "internal"}, MaxInactiveDays: 30}, nil},
+    {ProjectFilters{MaxInactiveDays: -1}, errProjectFiltersInactiveDaysInvalid},
+  }
+
+  for _, test := range tests {
+    if err := CheckProjectFilters(test.filters); err != test.want {
+      t.Errorf("CheckProjectFilters(%+v) = %v, want %v", test.filters, err, test.want)
+    }
+  }
+
+  // As given by --visibility
+  if err := CheckProjectFilters(ProjectFilters{Visibility: []string{"secret"}}); err == nil {
+    t.Errorf("Expected visibility %q to be rejected", "secret")
+  }
+}
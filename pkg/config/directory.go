@@ -0,0 +1,120 @@
+package config
+
+import (
+  "fmt"
+  "io/ioutil"
+  "path/filepath"
+  "reflect"
+  "strings"
+)
+
+// configExtensions are the extensions of the files merged by ParseDir
+var configExtensions = map[string]bool{
+  ".json": true,
+  ".yaml": true,
+  ".yml":  true,
+  ".toml": true,
+}
+
+// ParseDir merges all config files (json, yaml or toml by extension) directly inside dir into a
+// config struct, in the lexical order of their names. Hidden files and subdirectories are ignored.
+// Unlike includes, files must not set the same value differently. Referenced files are resolved
+// relative to the file referencing them.
+func ParseDir(dir string, opts Options) (*Config, error) {
+  if err := checkFormat(opts.Format); err != nil {
+    return nil, err
+  }
+
+  files, err := configFiles(dir)
+  if err != nil {
+    return nil, err
+  }
+  if len(files) == 0 {
+    return nil, fmt.Errorf("no config files found in directory %q", dir)
+  }
+
+  source := NewFileSource()
+  merged := make(map[string]interface{})
+  origins := make(map[string]string)
+  for _, file := range files {
+    content, err := loadWithIncludes(source, file, opts, make(map[string]bool))
+    if err != nil {
+      return nil, err
+    }
+
+    if err := mergeDisjoint(merged, content, "", file, origins); err != nil {
+      return nil, err
+    }
+  }
+
+  return decodeConfig(merged, source, dir)
+}
+
+// configFiles returns the paths of the config files directly inside dir, sorted by name
+func configFiles(dir string) ([]string, error) {
+  infos, err := ioutil.ReadDir(dir)
+  if err != nil {
+    return nil, fmt.Errorf("failed to read config directory %q: %v", dir, err)
+  }
+
+  var files []string
+  for _, info := range infos {
+    name := info.Name()
+    if info.IsDir() || strings.HasPrefix(name, ".") || !configExtensions[strings.ToLower(filepath.Ext(name))] {
+      continue
+    }
+
+    files = append(files, filepath.Join(dir, name))
+  }
+
+  return files, nil
+}
+
+// mergeDisjoint merges src, read from file, into dst. Nested objects are merged recursively,
+// all other values must not be set by dst already, unless they are equal. origins records the
+// file setting each value by its dotted key, to name both files of a conflict.
+func mergeDisjoint(dst map[string]interface{}, src map[string]interface{}, prefix string, file string, origins map[string]string) error {
+  for key, value := range src {
+    path := key
+    if prefix != "" {
+      path = prefix + "." + key
+    }
+
+    srcMap, srcIsMap := value.(map[string]interface{})
+    dstMap, dstIsMap := dst[key].(map[string]interface{})
+
+    if srcIsMap && dstIsMap {
+      if err := mergeDisjoint(dstMap, srcMap, path, file, origins); err != nil {
+        return err
+      }
+      continue
+    }
+
+    if existing, ok := dst[key]; ok {
+      if !reflect.DeepEqual(existing, value) {
+        return fmt.Errorf("conflicting values of %s in config files %q and %q", path, originOf(origins, path), file)
+      }
+      continue
+    }
+
+    dst[key] = value
+    origins[path] = file
+  }
+
+  return nil
+}
+
+// originOf returns the file setting the value at path, or one of its parents
+func originOf(origins map[string]string, path string) string {
+  for {
+    if file, ok := origins[path]; ok {
+      return file
+    }
+
+    i := strings.LastIndex(path, ".")
+    if i < 0 {
+      return ""
+    }
+    path = path[:i]
+  }
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+  "reflect"
+  "strings"
+  "testing"
+)
+
+func TestMergeDisjoint(t *testing.T) {
+  dst := make(map[string]interface{})
+  origins := make(map[string]string)
+
+  branches := map[string]interface{}{
+    "group_name": "example",
+    "project_settings": map[string]interface{}{
+      "merge_method": "ff",
+    },
+  }
+  approvals := map[string]interface{}{
+    "group_name": "example",
+    "project_settings": map[string]interface{}{
+      "issues_enabled": true,
+    },
+  }
+  if err := mergeDisjoint(dst, branches, "", "branches.yaml", origins); err != nil {
+    t.Fatal(err)
+  }
+  if err := mergeDisjoint(dst, approvals, "", "approvals.yaml", origins); err != nil {
+    t.Fatalf("Expected equal values to merge, but got %v", err)
+  }
+
+  expected := map[string]interface{}{
+    "group_name": "example",
+    "project_settings": map[string]interface{}{
+      "merge_method":   "ff",
+      "issues_enabled": true,
+    },
+  }
+  if !reflect.DeepEqual(dst, expected) {
+    t.Errorf("Expected merged config %v, but got %v", expected, dst)
+  }
+
+  conflicting := map[string]interface{}{
+    "project_settings": map[string]interface{}{
+      "merge_method": "merge",
+    },
+  }
+  err := mergeDisjoint(dst, conflicting, "", "webhooks.yaml", origins)
+  if err == nil {
+    t.Fatal("Expected conflicting values to fail")
+  }
+  if !strings.Contains(err.Error(), "project_settings.merge_method") || !strings.Contains(err.Error(), "branches.yaml") || !strings.Contains(err.Error(), "webhooks.yaml") {
+    t.Errorf("Expected error naming the setting and both files, but got %v", err)
+  }
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+  "encoding/json"
+  "strings"
+  "time"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/internal/stringslice"
+)
+
+// ExemptionDateFormat is the format of the expiry date of exemptions
+const ExemptionDateFormat = "2006-01-02"
+
+// Exemption excludes the settings (a section like approval_settings, or a single setting like
+// project_settings.merge_method) of the matching projects from enforcement until it expires
+type Exemption struct {
+  Project  string   `json:"project"`
+  Settings []string `json:"settings"`
+  Expires  string   `json:"expires"`
+  Reason   string   `json:"reason"`
+}
+
+// Expired returns whether the exemption has expired at the given time.
+// An exemption is valid through the end of its expiry date (UTC).
+func (e Exemption) Expired(now time.Time) bool {
+  expires, err := time.Parse(ExemptionDateFormat, e.Expires)
+  if err != nil {
+    return true
+  }
+
+  return !now.UTC().Before(expires.AddDate(0, 0, 1))
+}
+
+// ExemptionsFor returns the active and the expired exemptions matching the project
+func (c *Config) ExemptionsFor(projectPath string, now time.Time) ([]Exemption, []Exemption) {
+  var active, expired []Exemption
+  for _, e := range c.Exemptions {
+    if !stringslice.Match(e.Project, projectPath) {
+      continue
+    }
+
+    if e.Expired(now) {
+      expired = append(expired, e)
+    } else {
+      active = append(active, e)
+    }
+  }
+
+  return active, expired
+}
+
+// Without returns a copy of the policy without the given sections and settings.
+// Single protected branches are removed by protected_branches.<name>.
+func (p *Policy) Without(keys []string) (*Policy, error) {
+  b, err := json.Marshal(p)
+  if err != nil {
+    return nil, err
+  }
+
+  content := make(map[string]interface{})
+  if err := json.Unmarshal(b, &content); err != nil {
+    return nil, err
+  }
+
+  var branches []string
+  for _, key := range keys {
+    parts := strings.SplitN(key, ".", 2)
+    if len(parts) == 1 {
+      delete(content, key)
+      continue
+    }
+
+    if parts[0] == "protected_branches" {
+      branches = append(branches, parts[1])
+      continue
+    }
+
+    if section, ok := content[parts[0]].(map[string]interface{}); ok {
+      delete(section, parts[1])
+      if len(section) == 0 {
+        delete(content, parts[0])
+      }
+    }
+  }
+
+  b, err = json.Marshal(content)
+  if err != nil {
+    return nil, err
+  }
+
+  result := &Policy{}
+  if err := json.Unmarshal(b, result); err != nil {
+    return nil, err
+  }
+
+  if len(branches) > 0 {
+    var kept []ProtectedBranch
+    for _, branch := range result.ProtectedBranches {
+      if !stringslice.Contains(branch.Name, branches) {
+        kept = append(kept, branch)
+      }
+    }
+    result.ProtectedBranches = kept
+  }
+
+  return result, nil
+}
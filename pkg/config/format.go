@@ -0,0 +1,66 @@
+package config
+
+import (
+  "encoding/json"
+  "fmt"
+  "path/filepath"
+  "strings"
+
+  "github.com/BurntSushi/toml"
+  "github.com/ghodss/yaml"
+)
+
+// Supported config file formats
+const (
+  FormatJSON = "json"
+  FormatYAML = "yaml"
+  FormatTOML = "toml"
+)
+
+// detectFormat returns the format explicitly requested, or the one matching the extension of the
+// config file. JSON is assumed if neither is known.
+func detectFormat(configFilePath string, format string) string {
+  if format != "" {
+    return format
+  }
+
+  // Ignore query strings of remote locations
+  configFilePath = strings.SplitN(configFilePath, "?", 2)[0]
+
+  switch strings.ToLower(filepath.Ext(configFilePath)) {
+  case ".yaml", ".yml":
+    return FormatYAML
+  case ".toml":
+    return FormatTOML
+  default:
+    return FormatJSON
+  }
+}
+
+// decode unmarshals the raw config content of the given format into a generic map
+func decode(b []byte, format string) (map[string]interface{}, error) {
+  content := make(map[string]interface{})
+
+  switch format {
+  case FormatJSON:
+    if err := json.Unmarshal(b, &content); err != nil {
+      return nil, err
+    }
+  case FormatYAML:
+    jsonData, err := yaml.YAMLToJSON(b)
+    if err != nil {
+      return nil, err
+    }
+    if err := json.Unmarshal(jsonData, &content); err != nil {
+      return nil, err
+    }
+  case FormatTOML:
+    if _, err := toml.Decode(string(b), &content); err != nil {
+      return nil, err
+    }
+  default:
+    return nil, fmt.Errorf("unknown config format %q", format)
+  }
+
+  return content, nil
+}
@@ -0,0 +1,155 @@
+package config
+
+import (
+  "fmt"
+)
+
+// includeKey is the config key listing the config fragments to include
+const includeKey = "include"
+
+// loadWithIncludes reads the given config file from the source into a generic map. All files
+// listed in its include section are loaded first (relative to the including file) and the
+// including file is deep-merged on top of them. Sources of repository files and rego policies
+// are resolved relative to the file configuring them before merging. The format of the file is
+// either the one given in opts or, if empty, detected by its extension; included files use the
+// format given for the include. Files are rendered as templates before decoding if opts provides values.
+func loadWithIncludes(source Source, configFilePath string, opts Options, visited map[string]bool) (map[string]interface{}, error) {
+  if visited[configFilePath] {
+    return nil, fmt.Errorf("config file %q is included recursively", configFilePath)
+  }
+  visited[configFilePath] = true
+  defer delete(visited, configFilePath)
+
+  b, err := source.Read(configFilePath)
+  if err != nil {
+    return nil, fmt.Errorf("failed to read config file %q: %v", configFilePath, err)
+  }
+
+  if opts.Values != nil {
+    b, err = render(configFilePath, b, opts.Values)
+    if err != nil {
+      return nil, fmt.Errorf("failed to template config file %q: %v", configFilePath, err)
+    }
+  }
+
+  fileFormat := detectFormat(configFilePath, opts.Format)
+  content, err := decode(b, fileFormat)
+  if err != nil {
+    return nil, fmt.Errorf("failed to unmarshal config file %q as %s: %v", configFilePath, fileFormat, err)
+  }
+
+  resolveSources(source, content, configFilePath)
+
+  includes, err := includedFiles(source, content, configFilePath)
+  if err != nil {
+    return nil, fmt.Errorf("invalid include section in config file %q: %v", configFilePath, err)
+  }
+  delete(content, includeKey)
+
+  merged := make(map[string]interface{})
+  for _, include := range includes {
+    fragment, err := loadWithIncludes(source, include.path, Options{Format: include.format, Values: opts.Values}, visited)
+    if err != nil {
+      return nil, err
+    }
+
+    deepMerge(merged, fragment)
+  }
+
+  deepMerge(merged, content)
+
+  return merged, nil
+}
+
+// includedFile is an entry of the include section
+type includedFile struct {
+  path   string
+  format string
+}
+
+// includedFiles returns the include section of the given config content, resolved against the
+// including file. Entries are either a path or an object with the path and the format of the file.
+func includedFiles(source Source, content map[string]interface{}, configFilePath string) ([]includedFile, error) {
+  raw, ok := content[includeKey]
+  if !ok {
+    return nil, nil
+  }
+
+  list, ok := raw.([]interface{})
+  if !ok {
+    return nil, errIncludeInvalid
+  }
+
+  var files []includedFile
+  for _, item := range list {
+    var file includedFile
+    switch entry := item.(type) {
+    case string:
+      file.path = entry
+    case map[string]interface{}:
+      file.path, _ = entry["path"].(string)
+      file.format, _ = entry["format"].(string)
+      if err := checkFormat(file.format); err != nil {
+        return nil, err
+      }
+    }
+
+    if file.path == "" {
+      return nil, errIncludeInvalid
+    }
+
+    file.path = source.Resolve(configFilePath, file.path)
+    files = append(files, file)
+  }
+
+  return files, nil
+}
+
+// resolveSources replaces the source of each repository file, initial commit file and rego
+// policy in content by its location relative to the config file
+func resolveSources(source Source, content map[string]interface{}, configFilePath string) {
+  for _, fields := range sourceEntries(content) {
+    if ref, ok := fields["source"].(string); ok && ref != "" {
+      fields["source"] = source.Resolve(configFilePath, ref)
+    }
+  }
+}
+
+// sourceEntries returns the repository files, initial commit files and rego policies in content,
+// which may read their content from a source
+func sourceEntries(content map[string]interface{}) []map[string]interface{} {
+  lists := []interface{}{content["rego_policies"]}
+  for _, key := range []string{"repository_files", "initial_commit"} {
+    if section, ok := content[key].(map[string]interface{}); ok {
+      lists = append(lists, section["files"])
+    }
+  }
+
+  var result []map[string]interface{}
+  for _, list := range lists {
+    entries, _ := list.([]interface{})
+    for _, entry := range entries {
+      if fields, ok := entry.(map[string]interface{}); ok {
+        result = append(result, fields)
+      }
+    }
+  }
+
+  return result
+}
+
+// deepMerge merges src into dst. Nested objects are merged recursively, all other values
+// (including lists) of src replace the ones of dst.
+func deepMerge(dst map[string]interface{}, src map[string]interface{}) {
+  for key, value := range src {
+    srcMap, srcIsMap := value.(map[string]interface{})
+    dstMap, dstIsMap := dst[key].(map[string]interface{})
+
+    if srcIsMap && dstIsMap {
+      deepMerge(dstMap, srcMap)
+      continue
+    }
+
+    dst[key] = value
+  }
+}
@@ -0,0 +1,175 @@
+package config
+
+import (
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "reflect"
+  "testing"
+)
+
+func TestDeepMerge(t *testing.T) {
+  dst := map[string]interface{}{
+    "group_name": "example",
+    "project_settings": map[string]interface{}{
+      "merge_method":   "merge",
+      "issues_enabled": true,
+    },
+    "project_blacklist": []interface{}{"example/a"},
+  }
+  src := map[string]interface{}{
+    "project_settings": map[string]interface{}{
+      "merge_method": "ff",
+    },
+    "project_blacklist": []interface{}{"example/b"},
+  }
+
+  deepMerge(dst, src)
+
+  expected := map[string]interface{}{
+    "group_name": "example",
+    "project_settings": map[string]interface{}{
+      "merge_method":   "ff",
+      "issues_enabled": true,
+    },
+    "project_blacklist": []interface{}{"example/b"},
+  }
+  if !reflect.DeepEqual(dst, expected) {
+    t.Errorf("Expected merged config %v, but got %v", expected, dst)
+  }
+}
+
+func TestLoadWithIncludes(t *testing.T) {
+  dir, err := ioutil.TempDir("", "config")
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer os.RemoveAll(dir)
+
+  files := map[string]string{
+    "common.json": `{"create_default_branch": true, "group_name": "common"}`,
+    "main.json":   `{"include": ["common.json"], "group_name": "example"}`,
+    "loop.json":   `{"include": ["loop.json"]}`,
+  }
+  for name, content := range files {
+    if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+      t.Fatal(err)
+    }
+  }
+
+  content, err := loadWithIncludes(NewFileSource(), filepath.Join(dir, "main.json"), Options{}, make(map[string]bool))
+  if err != nil {
+    t.Fatalf("Expected no error, but got %v", err)
+  }
+
+  expected := map[string]interface{}{
+    "create_default_branch": true,
+    "group_name":            "example",
+  }
+  if !reflect.DeepEqual(content, expected) {
+    t.Errorf("Expected loaded config %v, but got %v", expected, content)
+  }
+
+  if _, err := loadWithIncludes(NewFileSource(), filepath.Join(dir, "loop.json"), Options{}, make(map[string]bool)); err == nil {
+    t.Errorf("Expected an error for a recursive include, but got none")
+  }
+}
+
+func TestLoadWithIncludesResolvesSources(t *testing.T) {
+  dir, err := ioutil.TempDir("", "config")
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer os.RemoveAll(dir)
+
+  if err := os.Mkdir(filepath.Join(dir, "fragments"), 0700); err != nil {
+    t.Fatal(err)
+  }
+
+  files := map[string]string{
+    "main.json":            `{"include": ["fragments/files.json"], "rego_policies": [{"name": "main", "source": "main.rego"}]}`,
+    "fragments/files.json": `{"repository_files": {"files": [{"path": "CODEOWNERS", "source": "CODEOWNERS"}]}}`,
+  }
+  for name, content := range files {
+    if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+      t.Fatal(err)
+    }
+  }
+
+  content, err := loadWithIncludes(NewFileSource(), filepath.Join(dir, "main.json"), Options{}, make(map[string]bool))
+  if err != nil {
+    t.Fatalf("Expected no error, but got %v", err)
+  }
+
+  expected := map[string]interface{}{
+    "repository_files": map[string]interface{}{
+      "files": []interface{}{
+        map[string]interface{}{"path": "CODEOWNERS", "source": filepath.Join(dir, "fragments", "CODEOWNERS")},
+      },
+    },
+    "rego_policies": []interface{}{
+      map[string]interface{}{"name": "main", "source": filepath.Join(dir, "main.rego")},
+    },
+  }
+  if !reflect.DeepEqual(content, expected) {
+    t.Errorf("Expected loaded config %v, but got %v", expected, content)
+  }
+}
+
+func TestLoadWithIncludesOfMixedFormats(t *testing.T) {
+  dir, err := ioutil.TempDir("", "config")
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer os.RemoveAll(dir)
+
+  files := map[string]string{
+    "main.yaml":     "include:\n  - settings.json\n  - branches.toml\n  - path: defaults.txt\n    format: yaml\ngroup_name: example\n",
+    "settings.json": `{"project_settings": {"merge_method": "ff"}}`,
+    "branches.toml": "create_default_branch = true\n",
+    "defaults.txt":  "project_blacklist:\n  - example/legacy\n",
+  }
+  for name, content := range files {
+    if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+      t.Fatal(err)
+    }
+  }
+
+  expected := map[string]interface{}{
+    "group_name":            "example",
+    "project_settings":      map[string]interface{}{"merge_method": "ff"},
+    "create_default_branch": true,
+    "project_blacklist":     []interface{}{"example/legacy"},
+  }
+
+  // The format forced for the config file doesn't apply to its includes
+  for _, format := range []string{"", FormatYAML} {
+    content, err := loadWithIncludes(NewFileSource(), filepath.Join(dir, "main.yaml"), Options{Format: format}, make(map[string]bool))
+    if err != nil {
+      t.Fatalf("Expected no error for format %q, but got %v", format, err)
+    }
+
+    if !reflect.DeepEqual(content, expected) {
+      t.Errorf("Expected loaded config %v for format %q, but got %v", expected, format, content)
+    }
+  }
+}
+
+func TestIncludedFilesInvalid(t *testing.T) {
+  tests := []struct {
+    name    string
+    include interface{}
+  }{
+    {"not a list", "common.json"},
+    {"no path", []interface{}{map[string]interface{}{"format": "json"}}},
+    {"unknown format", []interface{}{map[string]interface{}{"path": "common.conf", "format": "ini"}}},
+    {"number", []interface{}{42}},
+  }
+
+  for _, test := range tests {
+    content := map[string]interface{}{includeKey: test.include}
+    if _, err := includedFiles(NewFileSource(), content, "main.json"); err == nil {
+      t.Errorf("Expected an error for %s, but got none", test.name)
+    }
+  }
+}
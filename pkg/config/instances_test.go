@@ -0,0 +1,86 @@
+package config
+
+import (
+  "testing"
+)
+
+func TestCheckConfigInstanceAllGroups(t *testing.T) {
+  cfg := &Config{
+    HTTP:      DefaultHTTP(),
+    RateLimit: DefaultRateLimit(),
+    Retry:     DefaultRetry(),
+    Instances: []Instance{
+      {Name: "internal", Endpoint: "https://gitlab.example.com", TokenEnv: "TOKEN", AllGroups: &AllGroups{}},
+    },
+  }
+
+  if _, err := checkConfig(cfg); err != nil {
+    t.Fatalf("checkConfig() failed: %v", err)
+  }
+  if mode := cfg.Instances[0].AllGroups.Mode; mode != AllGroupsModeMembership {
+    t.Errorf("expected all_groups.mode to default to %q, got %q", AllGroupsModeMembership, mode)
+  }
+
+  cfg.AllGroups = &AllGroups{}
+  if _, err := checkConfig(cfg); err == nil || err.Error() != "all_groups "+errInstancesRootGroupsInvalid.Error() {
+    t.Errorf("expected an error for all_groups at the root, got %v", err)
+  }
+}
+
+func TestCheckConfigInstanceProjectList(t *testing.T) {
+  cfg := &Config{
+    HTTP:      DefaultHTTP(),
+    RateLimit: DefaultRateLimit(),
+    Retry:     DefaultRetry(),
+    Instances: []Instance{
+      {Name: "internal", Endpoint: "https://gitlab.example.com", TokenEnv: "TOKEN", ProjectList: []string{"example/api", "42"}},
+    },
+  }
+
+  if _, err := checkConfig(cfg); err != nil {
+    t.Fatalf("checkConfig() failed: %v", err)
+  }
+
+  cfg.Instances[0].ProjectList = []string{"example/*"}
+  if _, err := checkConfig(cfg); err == nil {
+    t.Errorf("expected an error for a pattern in project_list of an instance")
+  }
+
+  cfg.Instances[0].ProjectList = nil
+  cfg.Instances[0].GroupName = "example"
+  cfg.ProjectList = []string{"example/api"}
+  if _, err := checkConfig(cfg); err == nil || err.Error() != "project_list "+errInstancesRootGroupsInvalid.Error() {
+    t.Errorf("expected an error for project_list at the root, got %v", err)
+  }
+}
+
+func TestCheckConfigInstanceRootGroups(t *testing.T) {
+  tests := []struct {
+    key string
+    set func(cfg *Config)
+  }{
+    {"group_name", func(cfg *Config) { cfg.GroupName = "example" }},
+    {"group_id", func(cfg *Config) { cfg.GroupID = 4711 }},
+    {"groups", func(cfg *Config) { cfg.Groups = []string{"example"} }},
+    {"users", func(cfg *Config) { cfg.Users = []string{"jdoe"} }},
+  }
+
+  for _, test := range tests {
+    cfg := &Config{
+      HTTP:      DefaultHTTP(),
+      RateLimit: DefaultRateLimit(),
+      Retry:     DefaultRetry(),
+      Instances: []Instance{
+        {Name: "internal", Endpoint: "https://gitlab.example.com", TokenEnv: "TOKEN", GroupName: "platform"},
+      },
+    }
+    if _, err := checkConfig(cfg); err != nil {
+      t.Fatalf("checkConfig() failed: %v", err)
+    }
+
+    test.set(cfg)
+    if _, err := checkConfig(cfg); err == nil || err.Error() != test.key+" "+errInstancesRootGroupsInvalid.Error() {
+      t.Errorf("expected an error for %s at the root, got %v", test.key, err)
+    }
+  }
+}
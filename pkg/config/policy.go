@@ -0,0 +1,231 @@
+package config
+
+import (
+  "encoding/json"
+  "fmt"
+  "sort"
+  "strings"
+
+  "github.com/xanzy/go-gitlab"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/internal/stringslice"
+)
+
+// Policy holds the settings enforced on a single project
+type Policy struct {
+  ProtectedBranches []ProtectedBranch                          `json:"protected_branches,omitempty"`
+  ApprovalSettings  *gitlab.ChangeApprovalConfigurationOptions `json:"approval_settings,omitempty"`
+  ProjectSettings   *gitlab.EditProjectOptions                 `json:"project_settings,omitempty"`
+  SettingModes      map[string]string                          `json:"setting_modes,omitempty"`
+  AllowedValues     map[string]AllowedValues                   `json:"allowed_values,omitempty"`
+}
+
+// ModeFor returns the mode of the given setting of a section (e.g. project_settings).
+// A mode set for the setting takes precedence over the mode of its section, enforce is the default.
+func (p *Policy) ModeFor(section string, setting string) string {
+  if mode, ok := p.SettingModes[section+"."+setting]; ok {
+    return mode
+  }
+  if mode, ok := p.SettingModes[section]; ok {
+    return mode
+  }
+  return SettingModeEnforce
+}
+
+// PolicyFor returns the effective policy of the given project, which is the root level
+// policy of the config with the assigned profile, the policies of all parent subgroups
+// (outermost first) and all matching overrides applied on top of it
+func (c *Config) PolicyFor(projectPath string) (*Policy, error) {
+  policy := &Policy{
+    ProtectedBranches: c.ProtectedBranches,
+    ApprovalSettings:  c.ApprovalSettings,
+    ProjectSettings:   c.ProjectSettings,
+    SettingModes:      c.SettingModes,
+    AllowedValues:     c.AllowedValues,
+  }
+
+  if profile := c.ProfileFor(projectPath); profile != "" {
+    profilePolicy := c.Profiles[profile]
+
+    merged, err := mergePolicies(policy, &profilePolicy)
+    if err != nil {
+      return nil, fmt.Errorf("failed to apply profile %q to project %s: %v", profile, projectPath, err)
+    }
+
+    policy = merged
+  }
+
+  for _, subgroup := range c.parentSubgroups(projectPath) {
+    subgroupPolicy := c.Subgroups[subgroup]
+
+    merged, err := mergePolicies(policy, &subgroupPolicy)
+    if err != nil {
+      return nil, fmt.Errorf("failed to apply subgroup %q to project %s: %v", subgroup, projectPath, err)
+    }
+
+    policy = merged
+  }
+
+  for _, pattern := range matchingPatterns(overrideKeys(c.Overrides), projectPath) {
+    override := c.Overrides[pattern]
+
+    merged, err := mergePolicies(policy, &override)
+    if err != nil {
+      return nil, fmt.Errorf("failed to apply override %q to project %s: %v", pattern, projectPath, err)
+    }
+
+    policy = merged
+  }
+
+  return policy, nil
+}
+
+// parentSubgroups returns the configured subgroups containing the project, outermost first
+func (c *Config) parentSubgroups(projectPath string) []string {
+  var subgroups []string
+  for subgroup := range c.Subgroups {
+    if strings.HasPrefix(projectPath, strings.TrimSuffix(subgroup, "/")+"/") {
+      subgroups = append(subgroups, subgroup)
+    }
+  }
+
+  sort.Slice(subgroups, func(i, j int) bool {
+    return strings.Count(strings.TrimSuffix(subgroups[i], "/"), "/") < strings.Count(strings.TrimSuffix(subgroups[j], "/"), "/")
+  })
+
+  return subgroups
+}
+
+// ProfileFor returns the name of the profile assigned to the project by the most specific
+// matching profile_assignments pattern, or the default_profile if none matches
+func (c *Config) ProfileFor(projectPath string) string {
+  var keys []string
+  for pattern := range c.ProfileAssignments {
+    keys = append(keys, pattern)
+  }
+
+  patterns := matchingPatterns(keys, projectPath)
+  if len(patterns) == 0 {
+    return c.DefaultProfile
+  }
+
+  return c.ProfileAssignments[patterns[len(patterns)-1]]
+}
+
+func overrideKeys(overrides map[string]Policy) []string {
+  var keys []string
+  for pattern := range overrides {
+    keys = append(keys, pattern)
+  }
+  return keys
+}
+
+// matchingPatterns returns the patterns matching the project path, least specific first
+func matchingPatterns(keys []string, projectPath string) []string {
+  var patterns []string
+  exact := false
+  for _, pattern := range keys {
+    if pattern == projectPath {
+      exact = true
+      continue
+    }
+    if stringslice.Match(pattern, projectPath) {
+      patterns = append(patterns, pattern)
+    }
+  }
+
+  sort.Slice(patterns, func(i, j int) bool {
+    if len(patterns[i]) != len(patterns[j]) {
+      return len(patterns[i]) < len(patterns[j])
+    }
+    return patterns[i] < patterns[j]
+  })
+
+  // An exact match always wins
+  if exact {
+    patterns = append(patterns, projectPath)
+  }
+
+  return patterns
+}
+
+// Merge returns a new policy with all values set in override applied on top of the policy
+func (p *Policy) Merge(override *Policy) (*Policy, error) {
+  return mergePolicies(p, override)
+}
+
+// mergePolicies returns a new policy with all values set in override applied on top of base.
+// Protected branches are merged by name.
+func mergePolicies(base *Policy, override *Policy) (*Policy, error) {
+  merged := &Policy{}
+
+  if err := mergeJSON(&merged.ApprovalSettings, base.ApprovalSettings, override.ApprovalSettings); err != nil {
+    return nil, err
+  }
+  if err := mergeJSON(&merged.ProjectSettings, base.ProjectSettings, override.ProjectSettings); err != nil {
+    return nil, err
+  }
+
+  merged.ProtectedBranches = mergeProtectedBranches(base.ProtectedBranches, override.ProtectedBranches)
+
+  if len(base.SettingModes) > 0 || len(override.SettingModes) > 0 {
+    merged.SettingModes = make(map[string]string)
+    for key, mode := range base.SettingModes {
+      merged.SettingModes[key] = mode
+    }
+    for key, mode := range override.SettingModes {
+      merged.SettingModes[key] = mode
+    }
+  }
+
+  if len(base.AllowedValues) > 0 || len(override.AllowedValues) > 0 {
+    merged.AllowedValues = make(map[string]AllowedValues)
+    for key, allowed := range base.AllowedValues {
+      merged.AllowedValues[key] = allowed
+    }
+    for key, allowed := range override.AllowedValues {
+      merged.AllowedValues[key] = allowed
+    }
+  }
+
+  return merged, nil
+}
+
+// mergeJSON unmarshals the JSON representation of every non-nil source into dst, in order
+func mergeJSON(dst interface{}, sources ...interface{}) error {
+  for _, source := range sources {
+    b, err := json.Marshal(source)
+    if err != nil {
+      return err
+    }
+    if string(b) == "null" {
+      continue
+    }
+    if err := json.Unmarshal(b, dst); err != nil {
+      return err
+    }
+  }
+
+  return nil
+}
+
+// mergeProtectedBranches replaces branches of base with the equally named ones of override and appends new ones
+func mergeProtectedBranches(base []ProtectedBranch, override []ProtectedBranch) []ProtectedBranch {
+  merged := append([]ProtectedBranch{}, base...)
+
+  for _, o := range override {
+    replaced := false
+    for i, b := range merged {
+      if b.Name == o.Name {
+        merged[i] = o
+        replaced = true
+      }
+    }
+
+    if !replaced {
+      merged = append(merged, o)
+    }
+  }
+
+  return merged
+}
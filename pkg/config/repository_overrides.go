@@ -0,0 +1,79 @@
+package config
+
+import (
+  "encoding/json"
+  "fmt"
+  "sort"
+)
+
+// DefaultRepositoryOverridesPath is the override file read from projects if no path is configured
+const DefaultRepositoryOverridesPath = ".gitlab-settings.yml"
+
+// ApplyRepositoryOverride merges the whitelisted settings of a project's override file content
+// on top of the given policy. The settings which are not allowed are left out and returned.
+func (c *Config) ApplyRepositoryOverride(policy *Policy, content []byte) (*Policy, []string, error) {
+  if c.RepositoryOverrides == nil {
+    return policy, nil, nil
+  }
+
+  decoded, err := decode(content, detectFormat(c.RepositoryOverrides.Path, ""))
+  if err != nil {
+    return nil, nil, fmt.Errorf("failed to decode %s: %v", c.RepositoryOverrides.Path, err)
+  }
+
+  allowed := make(map[string]bool)
+  for _, key := range c.RepositoryOverrides.Allowed {
+    allowed[key] = true
+  }
+
+  filtered := make(map[string]interface{})
+  var rejected []string
+
+  for section, value := range decoded {
+    if allowed[section] {
+      filtered[section] = value
+      continue
+    }
+
+    settings, ok := value.(map[string]interface{})
+    if !ok {
+      rejected = append(rejected, section)
+      continue
+    }
+
+    kept := make(map[string]interface{})
+    for setting, v := range settings {
+      if allowed[section+"."+setting] {
+        kept[setting] = v
+      } else {
+        rejected = append(rejected, section+"."+setting)
+      }
+    }
+    if len(kept) > 0 {
+      filtered[section] = kept
+    }
+  }
+  sort.Strings(rejected)
+
+  b, err := json.Marshal(filtered)
+  if err != nil {
+    return nil, nil, err
+  }
+
+  override := &Policy{}
+  if err := json.Unmarshal(b, override); err != nil {
+    return nil, nil, fmt.Errorf("invalid %s: %v", c.RepositoryOverrides.Path, err)
+  }
+
+  // The name is never allowed to be changed, see project_settings.name
+  if override.ProjectSettings != nil {
+    override.ProjectSettings.Name = nil
+  }
+
+  merged, err := mergePolicies(policy, override)
+  if err != nil {
+    return nil, nil, err
+  }
+
+  return merged, rejected, nil
+}
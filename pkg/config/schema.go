@@ -0,0 +1,307 @@
+package config
+
+import (
+  "fmt"
+  "reflect"
+  "sort"
+  "strings"
+  "time"
+
+  "github.com/xanzy/go-gitlab"
+)
+
+const schemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// Schema is the subset of JSON Schema used to describe the config
+type Schema struct {
+  Schema               string             `json:"$schema,omitempty"`
+  Title                string             `json:"title,omitempty"`
+  Type                 string             `json:"type,omitempty"`
+  Format               string             `json:"format,omitempty"`
+  Enum                 []interface{}      `json:"enum,omitempty"`
+  Properties           map[string]*Schema `json:"properties,omitempty"`
+  AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+  Items                *Schema            `json:"items,omitempty"`
+  OneOf                []*Schema          `json:"oneOf,omitempty"`
+}
+
+// schemaEnums lists the allowed values of config specific and GitLab enum types, so invalid
+// values fail when loading the config instead of per project at the API
+var schemaEnums = map[reflect.Type][]interface{}{
+  reflect.TypeOf(AccessLevel("")):             {AccessLevelDeveloper, AccessLevelMaintainer, AccessLevelNoOne},
+  reflect.TypeOf(gitlab.VisibilityValue("")):  {string(gitlab.PrivateVisibility), string(gitlab.InternalVisibility), string(gitlab.PublicVisibility)},
+  reflect.TypeOf(gitlab.MergeMethodValue("")): {string(gitlab.NoFastForwardMerge), string(gitlab.RebaseMerge), string(gitlab.FastForwardMerge)},
+}
+
+// GenerateSchema returns the JSON Schema describing the complete config structure
+func GenerateSchema() *Schema {
+  schema := schemaFor(reflect.TypeOf(Config{}))
+  schema.Schema = schemaDraft
+  schema.Title = "gitlab-settings-enforcer config"
+
+  // include is resolved before the config is unmarshalled
+  schema.Properties[includeKey] = &Schema{Type: "array", Items: &Schema{OneOf: []*Schema{
+    {Type: "string"},
+    {
+      Type: "object",
+      Properties: map[string]*Schema{
+        "path":   {Type: "string"},
+        "format": {Type: "string", Enum: []interface{}{FormatJSON, FormatYAML, FormatTOML}},
+      },
+      AdditionalProperties: false,
+    },
+  }}}
+
+  return schema
+}
+
+// schemaFor returns the schema of the given type
+func schemaFor(t reflect.Type) *Schema {
+  for t.Kind() == reflect.Ptr {
+    t = t.Elem()
+  }
+
+  if enum, ok := schemaEnums[t]; ok {
+    return &Schema{Type: "string", Enum: enum}
+  }
+
+  if t == reflect.TypeOf(time.Time{}) {
+    return &Schema{Type: "string", Format: "date-time"}
+  }
+
+  switch t.Kind() {
+  case reflect.Bool:
+    return &Schema{Type: "boolean"}
+  case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+    reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+    return &Schema{Type: "integer"}
+  case reflect.Float32, reflect.Float64:
+    return &Schema{Type: "number"}
+  case reflect.String:
+    return &Schema{Type: "string"}
+  case reflect.Slice, reflect.Array:
+    return &Schema{Type: "array", Items: schemaFor(t.Elem())}
+  case reflect.Map:
+    return &Schema{Type: "object", AdditionalProperties: schemaFor(t.Elem())}
+  case reflect.Struct:
+    schema := &Schema{Type: "object", Properties: make(map[string]*Schema), AdditionalProperties: false}
+    addStructProperties(schema, t)
+    return schema
+  default:
+    // interface{} and other dynamic values accept anything
+    return &Schema{}
+  }
+}
+
+// addStructProperties adds the JSON properties of all exported struct fields to the schema
+func addStructProperties(schema *Schema, t reflect.Type) {
+  for i := 0; i < t.NumField(); i++ {
+    field := t.Field(i)
+    if field.PkgPath != "" {
+      continue
+    }
+
+    name := field.Name
+    if tag, ok := field.Tag.Lookup("json"); ok {
+      tagName := strings.Split(tag, ",")[0]
+      if tagName == "-" {
+        continue
+      }
+      if tagName != "" {
+        name = tagName
+      }
+    }
+
+    if field.Anonymous && field.Type.Kind() == reflect.Struct {
+      addStructProperties(schema, field.Type)
+      continue
+    }
+
+    schema.Properties[name] = schemaFor(field.Type)
+  }
+}
+
+// Validate checks the generic (JSON decoded) value against the schema and returns one
+// error message per violation, prefixed with the path of the offending value
+func (s *Schema) Validate(value interface{}) []string {
+  var violations []string
+  s.validate(value, "", &violations)
+  sort.Strings(violations)
+  return violations
+}
+
+func (s *Schema) validate(value interface{}, path string, violations *[]string) {
+  if value == nil {
+    // null resets a value to its default
+    return
+  }
+
+  fail := func(format string, args ...interface{}) {
+    location := path
+    if location == "" {
+      location = "(root)"
+    }
+    *violations = append(*violations, fmt.Sprintf("%s: %s", location, fmt.Sprintf(format, args...)))
+  }
+
+  if len(s.OneOf) > 0 {
+    for _, alternative := range s.OneOf {
+      if len(alternative.Validate(value)) == 0 {
+        return
+      }
+    }
+    fail("unexpected value %v", value)
+    return
+  }
+
+  switch s.Type {
+  case "boolean":
+    if _, ok := value.(bool); !ok {
+      fail("expected a boolean, got %v", value)
+    }
+  case "integer":
+    if n, ok := value.(float64); !ok || n != float64(int64(n)) {
+      fail("expected an integer, got %v", value)
+    }
+  case "number":
+    if _, ok := value.(float64); !ok {
+      fail("expected a number, got %v", value)
+    }
+  case "string":
+    str, ok := value.(string)
+    if !ok {
+      fail("expected a string, got %v", value)
+      return
+    }
+    if len(s.Enum) > 0 && !enumContains(s.Enum, str) {
+      fail("invalid value %q, must be one of: %s%s", str, enumList(s.Enum), didYouMean(str, enumValues(s.Enum)))
+    }
+  case "array":
+    list, ok := value.([]interface{})
+    if !ok {
+      fail("expected a list, got %v", value)
+      return
+    }
+    for i, item := range list {
+      s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i), violations)
+    }
+  case "object":
+    object, ok := value.(map[string]interface{})
+    if !ok {
+      fail("expected an object, got %v", value)
+      return
+    }
+    for key, item := range object {
+      itemPath := key
+      if path != "" {
+        itemPath = path + "." + key
+      }
+
+      if property := s.property(key); property != nil {
+        property.validate(item, itemPath, violations)
+        continue
+      }
+
+      switch additional := s.AdditionalProperties.(type) {
+      case *Schema:
+        additional.validate(item, fmt.Sprintf("%s[%q]", path, key), violations)
+      case bool:
+        if !additional {
+          var names []string
+          for name := range s.Properties {
+            names = append(names, name)
+          }
+          *violations = append(*violations, fmt.Sprintf("%s: unknown setting%s", itemPath, didYouMean(key, names)))
+        }
+      }
+    }
+  }
+}
+
+// property returns the schema of the named property, matching case-insensitively like encoding/json
+func (s *Schema) property(name string) *Schema {
+  if property, ok := s.Properties[name]; ok {
+    return property
+  }
+
+  for key, property := range s.Properties {
+    if strings.EqualFold(key, name) {
+      return property
+    }
+  }
+
+  return nil
+}
+
+func enumContains(enum []interface{}, value string) bool {
+  for _, e := range enum {
+    if e == value {
+      return true
+    }
+  }
+  return false
+}
+
+func enumList(enum []interface{}) string {
+  return strings.Join(enumValues(enum), ", ")
+}
+
+func enumValues(enum []interface{}) []string {
+  var values []string
+  for _, e := range enum {
+    values = append(values, fmt.Sprintf("%v", e))
+  }
+  return values
+}
+
+// didYouMean returns a hint naming the candidate closest to the misspelled value, "" if none is
+// close enough. Candidates differing only in case, or in at most a third of the characters, are
+// suggested.
+func didYouMean(value string, candidates []string) string {
+  sort.Strings(candidates)
+
+  best, bestDistance := "", len(value)/3+1
+  for _, candidate := range candidates {
+    if strings.EqualFold(candidate, value) {
+      return fmt.Sprintf(" (did you mean %q?)", candidate)
+    }
+    if distance := editDistance(strings.ToLower(value), strings.ToLower(candidate)); distance < bestDistance {
+      best, bestDistance = candidate, distance
+    }
+  }
+
+  if best == "" {
+    return ""
+  }
+  return fmt.Sprintf(" (did you mean %q?)", best)
+}
+
+// editDistance returns the Levenshtein distance of a and b
+func editDistance(a string, b string) int {
+  previous := make([]int, len(b)+1)
+  for j := range previous {
+    previous[j] = j
+  }
+
+  for i := 1; i <= len(a); i++ {
+    current := make([]int, len(b)+1)
+    current[0] = i
+    for j := 1; j <= len(b); j++ {
+      cost := 1
+      if a[i-1] == b[j-1] {
+        cost = 0
+      }
+      current[j] = minInt(previous[j]+1, minInt(current[j-1]+1, previous[j-1]+cost))
+    }
+    previous = current
+  }
+
+  return previous[len(b)]
+}
+
+func minInt(a int, b int) int {
+  if a < b {
+    return a
+  }
+  return b
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+  "strings"
+  "testing"
+)
+
+func TestDidYouMean(t *testing.T) {
+  candidates := []string{AccessLevelDeveloper, AccessLevelMaintainer, AccessLevelNoOne}
+
+  tests := map[string]string{
+    "maintaner":  ` (did you mean "maintainer"?)`,
+    "Maintainer": ` (did you mean "maintainer"?)`,
+    "none":       ` (did you mean "noone"?)`,
+    "owner":      "",
+  }
+  for value, expected := range tests {
+    if hint := didYouMean(value, candidates); hint != expected {
+      t.Errorf("didYouMean(%q) = %q, expected %q", value, hint, expected)
+    }
+  }
+}
+
+func TestSchemaValidateEnums(t *testing.T) {
+  content := map[string]interface{}{
+    "project_settings": map[string]interface{}{
+      "visibility":   "pubic",
+      "merge_method": "ff",
+      "merge_mehtod": "ff",
+    },
+    "protected_branches": []interface{}{
+      map[string]interface{}{"name": "main", "push_access_level": "maintaner", "merge_access_level": "developer"},
+    },
+  }
+
+  violations := strings.Join(GenerateSchema().Validate(content), "\n")
+  for _, expected := range []string{
+    `project_settings.visibility: invalid value "pubic", must be one of: private, internal, public (did you mean "public"?)`,
+    `project_settings.merge_mehtod: unknown setting (did you mean "merge_method"?)`,
+    `protected_branches[0].push_access_level: invalid value "maintaner", must be one of: developer, maintainer, noone (did you mean "maintainer"?)`,
+  } {
+    if !strings.Contains(violations, expected) {
+      t.Errorf("expected violation %q, got:\n%s", expected, violations)
+    }
+  }
+  if strings.Contains(violations, "merge_method:") {
+    t.Errorf("expected valid merge_method, got:\n%s", violations)
+  }
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+  "fmt"
+  "io/ioutil"
+  "net/http"
+  "net/url"
+  "path/filepath"
+  "time"
+)
+
+// Source reads config files (and the files referenced by them) from a location
+type Source interface {
+  // Read returns the content of the file at the given location
+  Read(location string) ([]byte, error)
+  // Resolve returns the location of ref, relative to the file at base
+  Resolve(base string, ref string) string
+}
+
+// fileSource reads config files from the local file system
+type fileSource struct{}
+
+// NewFileSource returns a Source reading from the local file system
+func NewFileSource() Source {
+  return fileSource{}
+}
+
+func (fileSource) Read(location string) ([]byte, error) {
+  if err := checkFilePath(&location); err != nil {
+    return nil, err
+  }
+
+  // nolint: gosec
+  return ioutil.ReadFile(location)
+}
+
+func (fileSource) Resolve(base string, ref string) string {
+  if filepath.IsAbs(ref) {
+    return ref
+  }
+
+  return filepath.Join(filepath.Dir(base), ref)
+}
+
+// CheckNoLocalFiles returns an error if the given JSON config reads local files: includes, sources
+// of repository files, initial commit files and rego policies, or token files of instances.
+// Configs written by less trusted users (like the spec of a Kubernetes policy) are checked
+// before parsing, so they can't commit or send secrets of the enforcer's file system.
+func CheckNoLocalFiles(b []byte) error {
+  content, err := decode(b, FormatJSON)
+  if err != nil {
+    return err
+  }
+
+  if _, ok := content[includeKey]; ok {
+    return errLocalIncludeForbidden
+  }
+
+  for _, fields := range sourceEntries(content) {
+    if ref, ok := fields["source"].(string); ok && ref != "" {
+      return fmt.Errorf("%v, got %q", errLocalSourceForbidden, ref)
+    }
+  }
+
+  instances, _ := content["instances"].([]interface{})
+  for _, instance := range instances {
+    fields, _ := instance.(map[string]interface{})
+    if ref, ok := fields["token_file"].(string); ok && ref != "" {
+      return fmt.Errorf("%v, got %q", errLocalTokenFileForbidden, ref)
+    }
+  }
+
+  return nil
+}
+
+// urlSource reads config files via HTTP(S)
+type urlSource struct {
+  httpClient *http.Client
+}
+
+// NewURLSource returns a Source downloading from HTTP(S) URLs with the given client
+func NewURLSource(httpClient *http.Client) Source {
+  if httpClient == nil {
+    httpClient = &http.Client{Timeout: 30 * time.Second}
+  }
+
+  return urlSource{httpClient: httpClient}
+}
+
+func (s urlSource) Read(location string) ([]byte, error) {
+  resp, err := s.httpClient.Get(location)
+  if err != nil {
+    return nil, err
+  }
+  defer resp.Body.Close()
+
+  if resp.StatusCode != http.StatusOK {
+    return nil, fmt.Errorf("unexpected response status code %d", resp.StatusCode)
+  }
+
+  return ioutil.ReadAll(resp.Body)
+}
+
+func (urlSource) Resolve(base string, ref string) string {
+  baseURL, err := url.Parse(base)
+  if err != nil {
+    return ref
+  }
+
+  refURL, err := url.Parse(ref)
+  if err != nil {
+    return ref
+  }
+
+  return baseURL.ResolveReference(refURL).String()
+}
@@ -0,0 +1,28 @@
+package config
+
+import (
+  "testing"
+)
+
+func TestCheckNoLocalFiles(t *testing.T) {
+  tests := []struct {
+    name    string
+    spec    string
+    wantErr bool
+  }{
+    {"settings only", `{"group_name": "example", "repository_files": {"files": [{"path": "a.md", "content": "a"}]}}`, false},
+    {"include", `{"group_name": "example", "include": ["/etc/passwd"]}`, true},
+    {"repository file source", `{"group_name": "example", "repository_files": {"files": [{"path": "token", "source": "/var/run/secrets/kubernetes.io/serviceaccount/token"}]}}`, true},
+    {"initial commit source", `{"group_name": "example", "initial_commit": {"files": [{"path": "env", "source": "/proc/self/environ"}]}}`, true},
+    {"rego policy source", `{"group_name": "example", "rego_policies": [{"name": "a", "source": "../policy.rego"}]}`, true},
+    {"instance token file", `{"instances": [{"name": "a", "endpoint": "https://gitlab.example.com", "token_file": "/proc/self/environ", "group_name": "example"}]}`, true},
+    {"invalid json", `{`, true},
+  }
+
+  for _, test := range tests {
+    err := CheckNoLocalFiles([]byte(test.spec))
+    if (err != nil) != test.wantErr {
+      t.Errorf("%s: expected error %v, but got %v", test.name, test.wantErr, err)
+    }
+  }
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+  "fmt"
+  "strings"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/internal/stringslice"
+)
+
+// Enforcement subsystems which can be selected by subsystems.only and subsystems.skip
+const (
+  SubsystemInstanceSettings    = "instance-settings"
+  SubsystemGroupSettings       = "group-settings"
+  SubsystemGroupPushRules      = "group-push-rules"
+  SubsystemProjects            = "projects"
+  SubsystemBranches            = "branches"
+  SubsystemProjectSettings     = "project-settings"
+  SubsystemForkRelationship    = "fork-relationship"
+  SubsystemApprovals           = "approvals"
+  SubsystemRepositoryFiles     = "repository-files"
+  SubsystemComplianceFramework = "compliance-framework"
+  SubsystemSecurityScanning    = "security-scanning"
+  SubsystemBranchNaming        = "branch-naming"
+  SubsystemStaleBranches       = "stale-branches"
+  SubsystemPlacement           = "placement"
+  SubsystemArchival            = "archival"
+)
+
+// AllSubsystems lists all enforcement subsystems in the order they are run
+var AllSubsystems = []string{
+  SubsystemInstanceSettings,
+  SubsystemGroupSettings,
+  SubsystemGroupPushRules,
+  SubsystemProjects,
+  SubsystemBranches,
+  SubsystemProjectSettings,
+  SubsystemForkRelationship,
+  SubsystemApprovals,
+  SubsystemRepositoryFiles,
+  SubsystemComplianceFramework,
+  SubsystemSecurityScanning,
+  SubsystemBranchNaming,
+  SubsystemStaleBranches,
+  SubsystemPlacement,
+  SubsystemArchival,
+}
+
+// Subsystems selects the enforcement subsystems run by sync.
+// If Only is set, only those subsystems are run; subsystems in Skip are never run.
+type Subsystems struct {
+  Only []string `json:"only"`
+  Skip []string `json:"skip"`
+}
+
+// Enabled returns whether the given subsystem is run
+func (s Subsystems) Enabled(subsystem string) bool {
+  if len(s.Only) > 0 && !stringslice.Contains(subsystem, s.Only) {
+    return false
+  }
+
+  return !stringslice.Contains(subsystem, s.Skip)
+}
+
+// CheckSubsystems validates that only known subsystems are selected
+func CheckSubsystems(s Subsystems) error {
+  for _, subsystem := range append(append([]string{}, s.Only...), s.Skip...) {
+    if !stringslice.Contains(subsystem, AllSubsystems) {
+      return fmt.Errorf("unknown subsystem %q, must be one of: %s", subsystem, strings.Join(AllSubsystems, ", "))
+    }
+  }
+
+  return nil
+}
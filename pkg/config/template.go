@@ -0,0 +1,64 @@
+package config
+
+import (
+  "bytes"
+  "fmt"
+  "strings"
+  "text/template"
+
+  "github.com/Masterminds/sprig"
+)
+
+// render executes the raw config content as a text/template (including the sprig functions),
+// providing the given values as .Values
+func render(name string, b []byte, values map[string]interface{}) ([]byte, error) {
+  tmpl, err := template.New(name).Option("missingkey=error").Funcs(sprig.TxtFuncMap()).Parse(string(b))
+  if err != nil {
+    return nil, fmt.Errorf("failed to parse template: %v", err)
+  }
+
+  var rendered bytes.Buffer
+  if err := tmpl.Execute(&rendered, map[string]interface{}{"Values": values}); err != nil {
+    return nil, fmt.Errorf("failed to render template: %v", err)
+  }
+
+  return rendered.Bytes(), nil
+}
+
+// ParseValues reads a values file (json, yaml or toml) used to render config templates
+func ParseValues(source Source, valuesFilePath string) (map[string]interface{}, error) {
+  b, err := source.Read(valuesFilePath)
+  if err != nil {
+    return nil, fmt.Errorf("failed to read values file %q: %v", valuesFilePath, err)
+  }
+
+  values, err := decode(b, detectFormat(valuesFilePath, ""))
+  if err != nil {
+    return nil, fmt.Errorf("failed to unmarshal values file %q: %v", valuesFilePath, err)
+  }
+
+  return values, nil
+}
+
+// SetValue sets a single key=value assignment on values. Dots in the key address nested values.
+func SetValue(values map[string]interface{}, assignment string) error {
+  parts := strings.SplitN(assignment, "=", 2)
+  if len(parts) != 2 || parts[0] == "" {
+    return fmt.Errorf("invalid value %q, expected key=value", assignment)
+  }
+
+  keys := strings.Split(parts[0], ".")
+  current := values
+  for _, key := range keys[:len(keys)-1] {
+    next, ok := current[key].(map[string]interface{})
+    if !ok {
+      next = make(map[string]interface{})
+      current[key] = next
+    }
+    current = next
+  }
+
+  current[keys[len(keys)-1]] = parts[1]
+
+  return nil
+}
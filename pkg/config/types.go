@@ -2,6 +2,7 @@ package config
 
 import (
   "errors"
+  "strconv"
 
   "github.com/xanzy/go-gitlab"
 )
@@ -10,33 +11,204 @@ import (
 const (
   AccessLevelDeveloper  = "developer"
   AccessLevelMaintainer = "maintainer"
+  AccessLevelNoOne      = "noone"
+)
+
+// Methods used to deliver repository files
+const (
+  RepositoryFilesMethodCommit       = "commit"
+  RepositoryFilesMethodMergeRequest = "merge_request"
+)
+
+// Security scanners enabled by security_scanning
+const (
+  ScannerSAST               = "sast"
+  ScannerSecretDetection    = "secret_detection"
+  ScannerDependencyScanning = "dependency_scanning"
+)
+
+// Scanners lists all security scanners known to security_scanning
+var Scanners = []string{ScannerSAST, ScannerSecretDetection, ScannerDependencyScanning}
+
+// Access levels of project features, pages can be public as well
+const (
+  FeatureAccessDisabled = "disabled"
+  FeatureAccessPrivate  = "private"
+  FeatureAccessEnabled  = "enabled"
+  FeatureAccessPublic   = "public"
+)
+
+// DefaultRegoQuery is evaluated against rego policies if no query is configured
+const DefaultRegoQuery = "data.gitlab_settings"
+
+// Modes controlling how a setting is applied
+const (
+  SettingModeEnforce     = "enforce"
+  SettingModeDefaultOnly = "default-only"
+)
+
+// Modes discovering the groups enforced by all_groups
+const (
+  AllGroupsModeAdmin      = "admin"
+  AllGroupsModeMembership = "membership"
+)
+
+// Modes controlling how values outside of the allowed values are handled
+const (
+  AllowedValuesModeRemediate = "remediate"
+  AllowedValuesModeFlag      = "flag"
 )
 
 var (
   errFileDoesNotExist                      = errors.New("given config file does not exist")
   errOnlyOneOfBlacklistAndWhitelistAllowed = errors.New("only one is allowed: project_blacklist / project_whitelist")
   errProjectSettingsNameMustBeEmpty        = errors.New("project_settings.name must be empty")
+  errProjectFiltersVisibilityInvalid       = errors.New("project_filters.visibility must only contain: private, internal, public")
+  errProjectFiltersInactiveDaysInvalid     = errors.New("project_filters.max_inactive_days must not be negative")
+  errDefaultBranchProtectionInvalid        = errors.New("group_settings.default_branch_protection must be between 0 and 4")
+  errRepositoryFilesMethodInvalid          = errors.New("repository_files.method must be one of: commit, merge_request")
+  errRepositoryFilesBranchRequired         = errors.New("repository_files.branch is required when method is merge_request")
+  errRepositoryFilePathRequired            = errors.New("repository_files.files[].path must be set")
+  errRepositoryFileContentAmbiguous        = errors.New("only one is allowed: repository_files.files[].content / repository_files.files[].source")
+  errInitialCommitFilePathRequired         = errors.New("initial_commit.files[].path must be set")
+  errDefaultBranchMigrationInvalid         = errors.New("default_branch_migration.from and default_branch_migration.to must be set and differ")
+  errStaleBranchesMaxAgeInvalid            = errors.New("stale_branches.max_age_days must not be negative")
+  errBranchNamingPatternsRequired          = errors.New("branch_naming.allowed_patterns must not be empty")
+  errSecurityScannerInvalid                = errors.New("security_scanning.scanners must only contain: sast, secret_detection, dependency_scanning")
+  errSecurityScanningMethodInvalid         = errors.New("security_scanning.method must be one of: commit, merge_request")
+  errSecurityScanningBranchRequired        = errors.New("security_scanning.branch is required when method is merge_request")
+  errProjectAccessLevelInvalid             = errors.New("project_access_levels values must be one of: disabled, private, enabled (pages: public as well)")
+  errProjectNameRequired                   = errors.New("projects[].name and projects[].path must be set")
+  errProjectPathInvalid                    = errors.New("projects[].path must not contain '/', use projects[].group for subgroups")
+  errProjectGroupRequired                  = errors.New("projects[].group must be set when group_name is not")
+  errProjectDuplicate                      = errors.New("projects[] must not declare a project twice")
+  errProjectPlacementInvalid               = errors.New("project_placement[] requires projects and namespace")
+  errAllGroupsModeInvalid                  = errors.New("all_groups.mode must be one of: admin, membership")
+  errGroupIDInvalid                        = errors.New("group_id must not be negative")
+  errProjectListEntryInvalid               = errors.New("project_list entries must be project paths or IDs, not patterns")
+  errSettingModeInvalid                    = errors.New("setting_modes values must be one of: enforce, default-only")
+  errSettingModeSectionInvalid             = errors.New("setting_modes keys must start with one of: approval_settings, project_settings")
+  errAllowedValuesKeyInvalid               = errors.New("allowed_values keys must be approval_settings.<setting> or project_settings.<setting>")
+  errAllowedValuesEmpty                    = errors.New("allowed_values values must not be empty")
+  errAllowedValuesModeInvalid              = errors.New("allowed_values mode must be one of: remediate, flag")
+  errRuleVisibilityInvalid                 = errors.New("rules[].when.visibility must only contain: private, internal, public")
+  errRuleInactiveDaysInvalid               = errors.New("rules[].when inactive days must not be negative")
+  errRegoPolicyNameRequired                = errors.New("rego_policies[].name must be set")
+  errRegoPolicyModuleRequired              = errors.New("rego_policies[].module or rego_policies[].source must be set")
+  errRegoPolicyModuleAmbiguous             = errors.New("only one is allowed: rego_policies[].module / rego_policies[].source")
+  errExemptionProjectRequired              = errors.New("exemptions[].project must be set")
+  errExemptionSettingsRequired             = errors.New("exemptions[].settings must not be empty")
+  errExemptionExpiresInvalid               = errors.New("exemptions[].expires must be a date formatted as YYYY-MM-DD")
+  errRateLimitInvalid                      = errors.New("rate_limit values must not be negative")
+  errHTTPInvalid                           = errors.New("http timeouts and max_idle_conns must not be negative")
+  errHTTPClientCertInvalid                 = errors.New("http.client_cert_file and http.client_key_file must be set together")
+  errRetryInvalid                          = errors.New("retry.max_attempts must be at least 1 and backoffs must not be negative")
+  errNotificationEmailInvalid              = errors.New("notifications.email requires server, port, from and to")
+  errNotificationEmailAuthInvalid          = errors.New("notifications.email requires username and password together")
+  errNotificationTeamsWebhookRequired      = errors.New("notifications.teams.webhook_url must be set")
+  errNotificationWebhookURLRequired        = errors.New("notifications.webhooks[].url must be set")
+  errAuditLogSinkRequired                  = errors.New("audit_log requires file, url or repository")
+  errAuditRepositoryProjectRequired        = errors.New("audit_log.repository.project must be set")
+  errInstanceNameInvalid                   = errors.New("instances[].name must be unique and only contain letters, digits, '-', '_' and '.'")
+  errInstanceEndpointRequired              = errors.New("instances[].endpoint must be set")
+  errInstanceTokenInvalid                  = errors.New("instances[] requires exactly one of token_env and token_file")
+  errInstanceGroupsRequired                = errors.New("instances[] requires group_name, group_id, groups, users, project_list or all_groups")
+  errInstancesRootGroupsInvalid            = errors.New("must be set per instance when instances are configured")
+  errRepositoryOverridesAllowedInvalid     = errors.New("repository_overrides.allowed must only contain protected_branches, approval_settings[.<setting>] or project_settings[.<setting>]")
+  errLocalIncludeForbidden                 = errors.New("include must not be used, local files can't be read")
+  errLocalSourceForbidden                  = errors.New("repository_files, initial_commit and rego_policies must not use source, local files can't be read")
+  errLocalTokenFileForbidden               = errors.New("instances[].token_file must not be used, local files can't be read")
+  errIncludeInvalid                        = errors.New("expected a list of file paths or objects with a path and a format")
 )
 
+// Options controls how config files are read
+type Options struct {
+  // Format forces the format (json, yaml or toml) of the config file, detected by extension if empty.
+  // Included files are detected by their own extension unless their include sets the format.
+  Format string
+  // Values enables rendering config files as templates and is provided to them as .Values
+  Values map[string]interface{}
+}
+
 // Config stores the root group name and some additional configuration values
 // settings documented at https://godoc.org/github.com/xanzy/go-gitlab#CreateProjectOptions
 type Config struct {
-  GroupName           string                                            `json:"group_name"`
-  CreateDefaultBranch bool                                              `json:"create_default_branch"`
-  Error               bool
-  ProjectBlacklist    []string                                          `json:"project_blacklist"`
-  ProjectWhitelist    []string                                          `json:"project_whitelist"`
-  ProtectedBranches   []ProtectedBranch                                 `json:"protected_branches"`
+  GroupName              string                                         `json:"group_name"`
+  GroupID                int                                            `json:"group_id"`
+  Groups                 []string                                       `json:"groups"`
+  Users                  []string                                       `json:"users"`
+  ProjectList            []string                                       `json:"project_list"`
+  AllGroups              *AllGroups                                     `json:"all_groups"`
+  CreateDefaultBranch    bool                                           `json:"create_default_branch"`
+  RemoveForkRelationship bool                                           `json:"remove_fork_relationship"`
+  DefaultBranchSource    string                                         `json:"default_branch_source"`
+  ProjectBlacklist       []string                                       `json:"project_blacklist"`
+  ProjectWhitelist       []string                                       `json:"project_whitelist"`
+  ProjectTopicsFilter    *TopicsFilter                                  `json:"project_topics_filter"`
+  ProjectFilters         ProjectFilters                                 `json:"project_filters"`
+  Subsystems             Subsystems                                     `json:"subsystems"`
+  ProtectedBranches      []ProtectedBranch                              `json:"protected_branches"`
+  ComplianceFramework    string                                         `json:"compliance_framework"`
+  RepositoryFiles        *RepositoryFiles                               `json:"repository_files"`
+  InitialCommit          *InitialCommit                                 `json:"initial_commit"`
+  DefaultBranchMigration *DefaultBranchMigration                        `json:"default_branch_migration"`
+  StaleBranches          *StaleBranches                                 `json:"stale_branches"`
+  BranchNaming           *BranchNaming                                  `json:"branch_naming"`
+  SecurityScanning       *SecurityScanning                              `json:"security_scanning"`
+  ProjectAccessLevels    *ProjectAccessLevels                           `json:"project_access_levels"`
+  Projects               []ProjectDeclaration                           `json:"projects"`
+  Archival               *Archival                                      `json:"archival"`
+  ProjectPlacement       []ProjectPlacement                             `json:"project_placement"`
+  RepositoryOverrides    *RepositoryOverrides                           `json:"repository_overrides"`
 
   ApprovalSettings    *gitlab.ChangeApprovalConfigurationOptions        `json:"approval_settings"`
   ProjectSettings     *gitlab.EditProjectOptions                        `json:"project_settings"`
+  InstanceSettings    *InstanceSettings                                 `json:"instance_settings"`
+  GroupPushRules      *PushRules                                        `json:"group_push_rules"`
+  GroupSettings       *GroupSettings                                    `json:"group_settings"`
+  SettingModes        map[string]string                                 `json:"setting_modes"`
+  AllowedValues       map[string]AllowedValues                          `json:"allowed_values"`
   Compliance          *ComplianceSettings                               `json:"compliance"`
+  Profiles            map[string]Policy                                 `json:"profiles"`
+  ProfileAssignments  map[string]string                                 `json:"profile_assignments"`
+  DefaultProfile      string                                            `json:"default_profile"`
+  Subgroups           map[string]Policy                                 `json:"subgroups"`
+  Overrides           map[string]Policy                                 `json:"overrides"`
+  Rules               []Rule                                            `json:"rules"`
+  RegoPolicies        []RegoPolicy                                      `json:"rego_policies"`
+  Exemptions          []Exemption                                       `json:"exemptions"`
+  HTTP                HTTP                                              `json:"http"`
+  RateLimit           RateLimit                                         `json:"rate_limit"`
+  Retry               Retry                                             `json:"retry"`
+  Notifications       *Notifications                                    `json:"notifications"`
+  AuditLog            *AuditLog                                         `json:"audit_log"`
+  Instances           []Instance                                        `json:"instances"`
+}
+
+// GroupNames returns the paths of all configured groups, group_name first. Without group_name,
+// the group of group_id is named by its ID.
+func (c *Config) GroupNames() []string {
+  var names []string
+  if c.GroupName != "" {
+    names = append(names, c.GroupName)
+  } else if c.GroupID != 0 {
+    names = append(names, strconv.Itoa(c.GroupID))
+  }
+
+  for _, name := range c.Groups {
+    if name != c.GroupName {
+      names = append(names, name)
+    }
+  }
+
+  return names
 }
 
 // ComplianceSettings defines what is displayed and mandatory settings.
 type ComplianceSettings struct {
-  Email     EmailConfig                       `json:"email"`
-  Mandatory map[string]map[string]interface{} `json:"mandatory"`
+  Email         EmailConfig                       `json:"email"`
+  Mandatory     map[string]map[string]interface{} `json:"mandatory"`
+  RequiredFiles []string                          `json:"required_files"`
 }
 
 // EmailConfig
@@ -45,6 +217,339 @@ type EmailConfig struct {
   Port      int
   Server    string
   To        []string
+  Username  string `json:"username"`
+  Password  string `json:"password"`
+  StartTLS  bool   `json:"starttls"`
+}
+
+// Notifications configures the backends a digest of every run is sent to
+type Notifications struct {
+  Email    *EmailConfig          `json:"email"`
+  Teams    *TeamsNotification    `json:"teams"`
+  Webhooks []WebhookNotification `json:"webhooks"`
+}
+
+// TeamsNotification posts the run digest to a Microsoft Teams incoming webhook
+type TeamsNotification struct {
+  WebhookURL string `json:"webhook_url"`
+}
+
+// WebhookNotification posts the run results as JSON to an arbitrary HTTP endpoint.
+// If Secret is set, the body is signed with HMAC-SHA256.
+type WebhookNotification struct {
+  URL     string            `json:"url"`
+  Secret  string            `json:"secret"`
+  Headers map[string]string `json:"headers"`
+}
+
+// Instance is a GitLab instance enforced by sync. The token is read from the env var TokenEnv
+// or the file TokenFile, so it doesn't have to be stored in the config.
+type Instance struct {
+  Name        string     `json:"name"`
+  Endpoint    string     `json:"endpoint"`
+  TokenEnv    string     `json:"token_env"`
+  TokenFile   string     `json:"token_file"`
+  GroupName   string     `json:"group_name"`
+  GroupID     int        `json:"group_id"`
+  Groups      []string   `json:"groups"`
+  Users       []string   `json:"users"`
+  ProjectList []string   `json:"project_list"`
+  AllGroups   *AllGroups `json:"all_groups"`
+}
+
+// AuditLog configures where every mutating API call is recorded
+type AuditLog struct {
+  File       string           `json:"file"`
+  URL        string           `json:"url"`
+  Repository *AuditRepository `json:"repository"`
+}
+
+// AuditRepository is a GitLab repository the change log and the settings of the changed projects
+// are committed to after every run
+type AuditRepository struct {
+  Project     string `json:"project"`
+  Branch      string `json:"branch"`
+  Path        string `json:"path"`
+  AuthorName  string `json:"author_name"`
+  AuthorEmail string `json:"author_email"`
+}
+
+// ProjectFilters skips projects by their state
+type ProjectFilters struct {
+  SkipArchived    bool     `json:"skip_archived"`
+  Visibility      []string `json:"visibility"`
+  MaxInactiveDays int      `json:"max_inactive_days"`
+}
+
+// Rule applies its policy to all projects matching its condition
+type Rule struct {
+  Name string        `json:"name"`
+  When RuleCondition `json:"when"`
+  Policy
+}
+
+// RuleCondition matches projects by their attributes, all set fields must match
+type RuleCondition struct {
+  Visibility      []string `json:"visibility"`
+  Topics          []string `json:"topics"`
+  Path            string   `json:"path"`
+  Languages       []string `json:"languages"`
+  MinInactiveDays int      `json:"min_inactive_days"`
+  MaxInactiveDays int      `json:"max_inactive_days"`
+}
+
+// RegoPolicy is a Rego module evaluated against the current settings of every project.
+// The query result may contain violations (a list of messages) and desired settings (a Policy).
+type RegoPolicy struct {
+  Name   string `json:"name"`
+  Module string `json:"module"`
+  Source string `json:"source"`
+  Query  string `json:"query"`
+}
+
+// HTTP holds the settings of the HTTP transport used to talk to GitLab
+type HTTP struct {
+  RequestTimeoutSeconds int    `json:"request_timeout_seconds"`
+  DialTimeoutSeconds    int    `json:"dial_timeout_seconds"`
+  KeepAliveSeconds      int    `json:"keep_alive_seconds"`
+  ProxyURL              string `json:"proxy_url"`
+  MaxIdleConns          int    `json:"max_idle_conns"`
+  CACertFile            string `json:"ca_cert_file"`
+  ClientCertFile        string `json:"client_cert_file"`
+  ClientKeyFile         string `json:"client_key_file"`
+  InsecureSkipVerify    bool   `json:"insecure_skip_verify"`
+}
+
+// DefaultHTTP returns the HTTP settings used if none are configured
+func DefaultHTTP() HTTP {
+  return HTTP{
+    RequestTimeoutSeconds: 60,
+    DialTimeoutSeconds:    10,
+    KeepAliveSeconds:      30,
+    MaxIdleConns:          100,
+  }
+}
+
+// RateLimit paces the requests sent to GitLab and controls how throttled (429) requests are retried
+type RateLimit struct {
+  RequestsPerSecond float64 `json:"requests_per_second"`
+  MinRemaining      int     `json:"min_remaining"`
+  MaxRetries        int     `json:"max_retries"`
+  MaxWaitSeconds    int     `json:"max_wait_seconds"`
+}
+
+// DefaultRateLimit returns the rate limit settings used if none are configured
+func DefaultRateLimit() RateLimit {
+  return RateLimit{
+    MaxRetries:     5,
+    MaxWaitSeconds: 60,
+  }
+}
+
+// Retry controls how requests failing with a transient error (network timeouts and the
+// retryable status codes) are retried, with a backoff doubling on each attempt
+type Retry struct {
+  MaxAttempts          int   `json:"max_attempts"`
+  InitialBackoffMillis int   `json:"initial_backoff_ms"`
+  MaxBackoffMillis     int   `json:"max_backoff_ms"`
+  RetryableStatusCodes []int `json:"retryable_status_codes"`
+}
+
+// DefaultRetry returns the retry settings used if none are configured
+func DefaultRetry() Retry {
+  return Retry{
+    MaxAttempts:          3,
+    InitialBackoffMillis: 500,
+    MaxBackoffMillis:     10000,
+    RetryableStatusCodes: []int{500, 502, 503, 504},
+  }
+}
+
+// RepositoryOverrides lets projects override a whitelisted subset of their settings
+// with a file in their default branch
+type RepositoryOverrides struct {
+  Path    string   `json:"path"`
+  Allowed []string `json:"allowed"`
+}
+
+// AllowedValues defines the set of acceptable values of a setting
+type AllowedValues struct {
+  Values []interface{} `json:"values"`
+  Mode   string        `json:"mode"`
+}
+
+// TopicsFilter selects projects by their topics
+type TopicsFilter struct {
+  Include []string `json:"include"`
+  Exclude []string `json:"exclude"`
+}
+
+// RepositoryFiles defines files which must exist on the default branch of every project
+type RepositoryFiles struct {
+  Method        string           `json:"method"`
+  Branch        string           `json:"branch"`
+  CommitMessage string           `json:"commit_message"`
+  AuthorName    string           `json:"author_name"`
+  AuthorEmail   string           `json:"author_email"`
+  Files         []RepositoryFile `json:"files"`
+}
+
+// RepositoryFile defines a single file and its content, either inline or read from a local source file
+type RepositoryFile struct {
+  Path      string `json:"path"`
+  Content   string `json:"content"`
+  Source    string `json:"source"`
+  Overwrite bool   `json:"overwrite"`
+}
+
+// InitialCommit defines the commit initializing empty repositories, so their default branch can be
+// created and protected
+type InitialCommit struct {
+  CommitMessage string           `json:"commit_message"`
+  AuthorName    string           `json:"author_name"`
+  AuthorEmail   string           `json:"author_email"`
+  Files         []RepositoryFile `json:"files"`
+}
+
+// DefaultBranchMigration defines the move of the default branch of projects (e.g. from master to
+// main) performed by migrate-default-branch
+type DefaultBranchMigration struct {
+  From            string `json:"from"`
+  To              string `json:"to"`
+  DeleteOldBranch bool   `json:"delete_old_branch"`
+}
+
+// StaleBranches defines when branches count as stale, reported (and pruned with
+// --prune-stale-branches) by the stale-branches subsystem. Protected branches and the default
+// branch never do.
+type StaleBranches struct {
+  MaxAgeDays int      `json:"max_age_days"`
+  Exclude    []string `json:"exclude"`
+}
+
+// BranchNaming defines the allowed names of branches, audited by the branch-naming subsystem.
+// The default branch is always allowed.
+type BranchNaming struct {
+  AllowedPatterns []string `json:"allowed_patterns"`
+}
+
+// SecurityScanning defines the security scanners whose CI templates every project must include in
+// its .gitlab-ci.yml, reported by the security-scanning subsystem and added if Enforce is set
+type SecurityScanning struct {
+  Scanners      []string `json:"scanners"`
+  Enforce       bool     `json:"enforce"`
+  Method        string   `json:"method"`
+  Branch        string   `json:"branch"`
+  CommitMessage string   `json:"commit_message"`
+}
+
+// AllGroups enforces all top-level groups on the instance (admin) or all the token can maintain
+// (membership) besides the configured ones, except the groups matching Exclude
+type AllGroups struct {
+  Mode    string   `json:"mode"`
+  Exclude []string `json:"exclude"`
+}
+
+// ProjectDeclaration defines a project which must exist, created by the projects subsystem if absent
+type ProjectDeclaration struct {
+  Name     string `json:"name"`
+  Path     string `json:"path"`
+  Group    string `json:"group"`
+  Template string `json:"template"`
+}
+
+// PathWithNamespace returns the full path of the declared project
+func (p ProjectDeclaration) PathWithNamespace() string {
+  return p.Group + "/" + p.Path
+}
+
+// Archival defines the projects which must be archived and the ones which must not be, by
+// glob or regular expression patterns. Archived takes precedence over unarchived.
+type Archival struct {
+  Archived   []string `json:"archived"`
+  Unarchived []string `json:"unarchived"`
+}
+
+// ProjectPlacement defines the namespace the projects matching any of the glob or regular
+// expression patterns belong in
+type ProjectPlacement struct {
+  Projects  []string `json:"projects"`
+  Namespace string   `json:"namespace"`
+}
+
+// ProjectAccessLevels defines who may access the features of every project. Older servers only
+// know whether a feature is enabled, private and enabled levels translate to enabled there.
+// settings documented at https://docs.gitlab.com/ee/api/projects.html#edit-project
+type ProjectAccessLevels struct {
+  Builds                *string `json:"builds_access_level,omitempty"`
+  MergeRequests         *string `json:"merge_requests_access_level,omitempty"`
+  Issues                *string `json:"issues_access_level,omitempty"`
+  Repository            *string `json:"repository_access_level,omitempty"`
+  Wiki                  *string `json:"wiki_access_level,omitempty"`
+  Snippets              *string `json:"snippets_access_level,omitempty"`
+  Pages                 *string `json:"pages_access_level,omitempty"`
+  Forking               *string `json:"forking_access_level,omitempty"`
+  ContainerRegistry     *string `json:"container_registry_access_level,omitempty"`
+  Analytics             *string `json:"analytics_access_level,omitempty"`
+  SecurityAndCompliance *string `json:"security_and_compliance_access_level,omitempty"`
+}
+
+// Levels returns the configured access levels keyed by their API attribute
+func (l *ProjectAccessLevels) Levels() map[string]string {
+  levels := make(map[string]string)
+  for attribute, level := range map[string]*string{
+    "builds_access_level":                  l.Builds,
+    "merge_requests_access_level":          l.MergeRequests,
+    "issues_access_level":                  l.Issues,
+    "repository_access_level":              l.Repository,
+    "wiki_access_level":                    l.Wiki,
+    "snippets_access_level":                l.Snippets,
+    "pages_access_level":                   l.Pages,
+    "forking_access_level":                 l.Forking,
+    "container_registry_access_level":      l.ContainerRegistry,
+    "analytics_access_level":               l.Analytics,
+    "security_and_compliance_access_level": l.SecurityAndCompliance,
+  } {
+    if level != nil {
+      levels[attribute] = *level
+    }
+  }
+
+  return levels
+}
+
+// GroupSettings defines the defaults of a group which apply to newly created projects
+type GroupSettings struct {
+  DefaultBranchProtection *int    `json:"default_branch_protection,omitempty"`
+  DefaultBranchName       *string `json:"default_branch_name,omitempty"`
+}
+
+// InstanceSettings defines the application settings of the instance. Besides the settings known
+// to go-gitlab it supports the terms of service and the allowlist of local outbound requests.
+// settings documented at https://docs.gitlab.com/ee/api/settings.html#change-application-settings
+type InstanceSettings struct {
+  gitlab.UpdateSettingsOptions
+  EnforceTerms                              *bool    `json:"enforce_terms,omitempty"`
+  Terms                                     *string  `json:"terms,omitempty"`
+  AllowLocalRequestsFromWebHooksAndServices *bool    `json:"allow_local_requests_from_web_hooks_and_services,omitempty"`
+  AllowLocalRequestsFromSystemHooks         *bool    `json:"allow_local_requests_from_system_hooks,omitempty"`
+  OutboundLocalRequestsWhitelist            []string `json:"outbound_local_requests_whitelist,omitempty"`
+}
+
+// PushRules defines the push rules of a group
+// settings documented at https://docs.gitlab.com/ee/api/groups.html#push-rules
+type PushRules struct {
+  CommitMessageRegex         *string `json:"commit_message_regex,omitempty"`
+  CommitMessageNegativeRegex *string `json:"commit_message_negative_regex,omitempty"`
+  BranchNameRegex            *string `json:"branch_name_regex,omitempty"`
+  AuthorEmailRegex           *string `json:"author_email_regex,omitempty"`
+  FileNameRegex              *string `json:"file_name_regex,omitempty"`
+  DenyDeleteTag              *bool   `json:"deny_delete_tag,omitempty"`
+  MemberCheck                *bool   `json:"member_check,omitempty"`
+  PreventSecrets             *bool   `json:"prevent_secrets,omitempty"`
+  CommitCommitterCheck       *bool   `json:"commit_committer_check,omitempty"`
+  RejectUnsignedCommits      *bool   `json:"reject_unsigned_commits,omitempty"`
+  MaxFileSize                *int    `json:"max_file_size,omitempty"`
 }
 
 // ProtectedBranch defines who can act on a protected branch
@@ -68,3 +573,16 @@ func (a AccessLevel) Value() *gitlab.AccessLevelValue {
       return gitlab.AccessLevel(gitlab.NoPermissions)
   }
 }
+
+// AccessLevelOf returns the access level of the gitlab numeric value. Levels above maintainer
+// (owner, admin) can't be configured and are returned as maintainer.
+func AccessLevelOf(value gitlab.AccessLevelValue) AccessLevel {
+  switch {
+    case value >= gitlab.MaintainerPermissions:
+      return AccessLevelMaintainer
+    case value >= gitlab.DeveloperPermissions:
+      return AccessLevelDeveloper
+    default:
+      return AccessLevelNoOne
+  }
+}
@@ -0,0 +1,116 @@
+package gitlab
+
+import (
+  "context"
+  "encoding/json"
+  "fmt"
+  "net/http"
+
+  "github.com/xanzy/go-gitlab"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/config"
+)
+
+// legacyFeatureFlags are the boolean attributes older servers offer instead of the access levels
+var legacyFeatureFlags = map[string]string{
+  "builds_access_level":             "jobs_enabled",
+  "merge_requests_access_level":     "merge_requests_enabled",
+  "issues_access_level":             "issues_enabled",
+  "wiki_access_level":               "wiki_enabled",
+  "snippets_access_level":           "snippets_enabled",
+  "container_registry_access_level": "container_registry_enabled",
+}
+
+// requestAttributes wraps the attributes of a request body, as go-gitlab fails to encode
+// options which are no struct
+type requestAttributes struct {
+  attributes map[string]interface{}
+}
+
+// MarshalJSON sends the wrapped attributes as JSON body
+func (r requestAttributes) MarshalJSON() ([]byte, error) {
+  return json.Marshal(r.attributes)
+}
+
+// UpdateProjectAccessLevels applies the project_access_levels section to the project. Servers
+// not returning an access level get the equivalent legacy flag (e.g. wiki_enabled) instead,
+// levels without one are skipped with a warning.
+// https://docs.gitlab.com/ee/api/projects.html#edit-project
+func (m *ProjectManager) UpdateProjectAccessLevels(ctx context.Context, project gitlab.Project, dryrun bool) error {
+  // Exit if nothing to configure
+  if m.config.ProjectAccessLevels == nil {
+    m.logger.Debugf("No project_access_levels section provided in config")
+    return nil
+  }
+
+  m.logger.Debugf("Updating access levels of project %s ...", project.PathWithNamespace)
+
+  path := fmt.Sprintf("projects/%d", project.ID)
+
+  req, err := m.apiClient.NewRequest(http.MethodGet, path, nil, []gitlab.OptionFunc{gitlab.WithContext(ctx)})
+  if err != nil {
+    return fmt.Errorf("failed to create request for access levels of project %s: %v", project.PathWithNamespace, err)
+  }
+
+  current := make(map[string]interface{})
+  if _, err := m.apiClient.Do(req, &current); err != nil {
+    return fmt.Errorf("failed to get access levels of project %s: %v", project.PathWithNamespace, err)
+  }
+
+  drift := make(map[string]settingDrift)
+  options := make(map[string]interface{})
+  for attribute, level := range m.config.ProjectAccessLevels.Levels() {
+    var setting string
+    var to interface{}
+    if _, ok := current[attribute]; ok {
+      setting, to = attribute, level
+    } else if flag, ok := legacyFeatureFlags[attribute]; ok {
+      setting, to = flag, level != config.FeatureAccessDisabled
+    } else {
+      m.logger.Warnf("Skipping project_access_levels.%s of project %s: not supported by GitLab", attribute, project.PathWithNamespace)
+      continue
+    }
+
+    if current[setting] != to {
+      drift[setting] = settingDrift{From: current[setting], To: to}
+      options[setting] = to
+    }
+  }
+
+  if len(drift) == 0 {
+    m.logger.Debugf("No action required.")
+    return nil
+  }
+
+  if err := m.guardDestructive(project.PathWithNamespace, "project_access_levels", drift, dryrun); err != nil {
+    return err
+  }
+
+  m.logger.Debugf("---[ HTTP Payload for UpdateProjectAccessLevels ]---\n")
+  m.logger.Debugf("%+v\n", options)
+
+  if dryrun {
+    m.logSkippedCall(project, "EditProject", options)
+    m.addDrift(project.PathWithNamespace, "project_access_levels", drift)
+    return nil
+  }
+
+  req, err = m.apiClient.NewRequest(http.MethodPut, path, requestAttributes{options}, []gitlab.OptionFunc{gitlab.WithContext(ctx)})
+  if err != nil {
+    return fmt.Errorf("failed to create request for access levels of project %s: %v", project.PathWithNamespace, err)
+  }
+  _, err = m.apiClient.Do(req, nil)
+  m.audit(project.PathWithNamespace, http.MethodPut+" "+path, drift, err)
+  if err != nil {
+    return fmt.Errorf("failed to update access levels of project %s: %v", project.PathWithNamespace, err)
+  }
+
+  // The prefetched settings are outdated now
+  m.forgetPrefetchedProjectSettings(project)
+
+  m.addDrift(project.PathWithNamespace, "project_access_levels", drift)
+
+  m.logger.Debugf("Updating access levels of project %s done.", project.PathWithNamespace)
+
+  return nil
+}
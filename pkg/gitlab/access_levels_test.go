@@ -0,0 +1,57 @@
+package gitlab
+
+import (
+  "context"
+  "encoding/json"
+  "io/ioutil"
+  "net/http"
+  "reflect"
+  "testing"
+
+  "github.com/xanzy/go-gitlab"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/config"
+)
+
+func TestUpdateProjectAccessLevels(t *testing.T) {
+  var payload map[string]interface{}
+
+  mux := http.NewServeMux()
+  mux.HandleFunc("/api/v4/projects/1", func(w http.ResponseWriter, r *http.Request) {
+    switch r.Method {
+    case http.MethodGet:
+      // An older server returning the legacy flag of the wiki
+      w.Write([]byte(`{"id": 1, "issues_access_level": "enabled", "wiki_enabled": true}`))
+    case http.MethodPut:
+      if r.URL.RawQuery != "" {
+        t.Errorf("Expected no query parameters, but got %q", r.URL.RawQuery)
+      }
+      body, _ := ioutil.ReadAll(r.Body)
+      if err := json.Unmarshal(body, &payload); err != nil {
+        t.Errorf("Failed to unmarshal payload %s: %v", body, err)
+      }
+      w.Write([]byte(`{"id": 1}`))
+    }
+  })
+
+  manager, closeServer := newTestProjectManager(t, mux, &config.Config{
+    ProjectAccessLevels: &config.ProjectAccessLevels{
+      Issues: gitlab.String(config.FeatureAccessPrivate),
+      Wiki:   gitlab.String(config.FeatureAccessDisabled),
+    },
+  })
+  defer closeServer()
+
+  project := gitlab.Project{ID: 1, PathWithNamespace: "example/api"}
+  if err := manager.UpdateProjectAccessLevels(context.Background(), project, false); err != nil {
+    t.Fatalf("Failed to update access levels: %v", err)
+  }
+
+  expected := map[string]interface{}{
+    "issues_access_level": "private",
+    "wiki_enabled":        false,
+  }
+  if !reflect.DeepEqual(payload, expected) {
+    t.Errorf("Expected payload %v, but got %v", expected, payload)
+  }
+}
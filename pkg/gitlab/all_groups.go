@@ -0,0 +1,104 @@
+package gitlab
+
+import (
+  "context"
+  "fmt"
+  "net/http"
+  "strings"
+
+  "github.com/xanzy/go-gitlab"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/config"
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/internal/stringslice"
+)
+
+// listAllGroupsOptions holds the filters of the Groups API go-gitlab doesn't offer yet
+type listAllGroupsOptions struct {
+  gitlab.ListOptions
+  AllAvailable   *bool `url:"all_available,omitempty" json:"all_available,omitempty"`
+  MinAccessLevel *int  `url:"min_access_level,omitempty" json:"min_access_level,omitempty"`
+  TopLevelOnly   *bool `url:"top_level_only,omitempty" json:"top_level_only,omitempty"`
+}
+
+// DiscoverGroups adds the top-level groups found by all_groups to the configured groups, so they
+// are enforced with the policy of the config like the others; subgroups configures per group
+// overrides. Groups are only discovered once.
+// https://docs.gitlab.com/ee/api/groups.html#list-groups
+func (m *ProjectManager) DiscoverGroups(ctx context.Context) error {
+  settings := m.config.AllGroups
+  if settings == nil {
+    return nil
+  }
+
+  m.mu.Lock()
+  discovered := m.groupsDiscovered
+  m.mu.Unlock()
+  if discovered {
+    return nil
+  }
+
+  m.logger.Debugf("Discovering groups (%s) ...", settings.Mode)
+
+  opt := &listAllGroupsOptions{
+    ListOptions: gitlab.ListOptions{
+      Page:    1,
+      PerPage: 100,
+    },
+    TopLevelOnly: gitlab.Bool(true),
+  }
+  if settings.Mode == config.AllGroupsModeAdmin {
+    opt.AllAvailable = gitlab.Bool(true)
+  } else {
+    opt.MinAccessLevel = gitlab.Int(int(gitlab.MaintainerPermissions))
+  }
+
+  var found []string
+  for {
+    req, err := m.apiClient.NewRequest(http.MethodGet, "groups", opt, []gitlab.OptionFunc{gitlab.WithContext(ctx)})
+    if err != nil {
+      return fmt.Errorf("failed to create request for groups: %v", err)
+    }
+
+    var groups []gitlab.Group
+    resp, err := m.apiClient.Do(req, &groups)
+    if err != nil {
+      return fmt.Errorf("failed to discover groups: %v", err)
+    }
+
+    for _, group := range groups {
+      // Older servers ignore top_level_only
+      if strings.Contains(group.FullPath, "/") {
+        continue
+      }
+      if stringslice.MatchAny(group.FullPath, settings.Exclude) {
+        m.logger.Debugf("Skipping group %s, excluded by all_groups.exclude.", group.FullPath)
+        continue
+      }
+
+      found = append(found, group.FullPath)
+    }
+
+    // Exit the loop when we've seen all pages.
+    if resp.NextPage == 0 {
+      break
+    }
+
+    // Update the page number to get the next page.
+    opt.Page = resp.NextPage
+  }
+
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  configured := m.config.GroupNames()
+  for _, group := range found {
+    if !stringslice.Contains(group, configured) {
+      m.config.Groups = append(m.config.Groups, group)
+    }
+  }
+  m.groupsDiscovered = true
+
+  m.logger.Infof("Discovered %d group(s).", len(found))
+
+  return nil
+}
@@ -0,0 +1,63 @@
+package gitlab
+
+import (
+  "reflect"
+  "strings"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/config"
+)
+
+// applyAllowedValues adjusts the desired settings of the given section to its allowed_values:
+// settings whose current value is allowed are left untouched, others are set to the desired
+// value (or the first allowed value, if it isn't allowed itself) in remediate mode and are
+// reported in flag mode
+func (m *ProjectManager) applyAllowedValues(projectPath string, section string, policy *config.Policy, desiredMap map[string]interface{}, currentMap map[string]interface{}) {
+  for key, allowed := range policy.AllowedValues {
+    if !strings.HasPrefix(key, section+".") {
+      continue
+    }
+    setting := strings.TrimPrefix(key, section+".")
+
+    current := currentMap[setting]
+    if isAllowedValue(current, allowed.Values) {
+      m.logger.Debugf("Keeping allowed value %v of setting %s", current, key)
+      delete(desiredMap, setting)
+      continue
+    }
+
+    if allowed.Mode == config.AllowedValuesModeFlag {
+      m.logger.Warnf("Setting %s of project %s has value %v, allowed are: %v", key, projectPath, current, allowed.Values)
+      delete(desiredMap, setting)
+
+      m.mu.Lock()
+      if _, ok := m.AllowedValuesViolations[projectPath]; !ok {
+        m.AllowedValuesViolations[projectPath] = make(map[string]interface{})
+      }
+      m.AllowedValuesViolations[projectPath][key] = current
+      m.mu.Unlock()
+
+      m.addViolation(projectPath, "%s: value %v is not one of the allowed values %v", key, current, allowed.Values)
+      continue
+    }
+
+    if desired, ok := desiredMap[setting]; !ok || !isAllowedValue(desired, allowed.Values) {
+      desiredMap[setting] = allowed.Values[0]
+    }
+  }
+}
+
+// isAllowedValue reports whether the value is one of the allowed values. Both are compared in
+// their JSON representation like drifted settings, so 2 matches 2.0 but not "2".
+func isAllowedValue(value interface{}, allowed []interface{}) bool {
+  normalized, err := toJSONValue(value)
+  if err != nil {
+    return false
+  }
+
+  for _, a := range allowed {
+    if allowedValue, err := toJSONValue(a); err == nil && reflect.DeepEqual(normalized, allowedValue) {
+      return true
+    }
+  }
+  return false
+}
@@ -0,0 +1,29 @@
+package gitlab
+
+import (
+  "testing"
+)
+
+func TestIsAllowedValue(t *testing.T) {
+  tests := []struct {
+    value   interface{}
+    allowed []interface{}
+    want    bool
+  }{
+    {"private", []interface{}{"private", "internal"}, true},
+    {"public", []interface{}{"private", "internal"}, false},
+    {float64(2), []interface{}{2}, true},
+    {2, []interface{}{float64(2)}, true},
+    {float64(2), []interface{}{"2"}, false},
+    {"true", []interface{}{true}, false},
+    {true, []interface{}{true}, true},
+    {nil, []interface{}{"<nil>"}, false},
+    {[]interface{}{"a"}, []interface{}{[]string{"a"}}, true},
+  }
+
+  for _, test := range tests {
+    if got := isAllowedValue(test.value, test.allowed); got != test.want {
+      t.Errorf("isAllowedValue(%#v, %#v) = %t, want %t", test.value, test.allowed, got, test.want)
+    }
+  }
+}
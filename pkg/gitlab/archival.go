@@ -0,0 +1,79 @@
+package gitlab
+
+import (
+  "context"
+  "fmt"
+  "net/http"
+
+  "github.com/xanzy/go-gitlab"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/internal/stringslice"
+)
+
+// EnsureArchivalState archives the project if it matches archival.archived and unarchives it if
+// it matches archival.unarchived. Archiving is a destructive change and refused unless allowed.
+// https://docs.gitlab.com/ee/api/projects.html#archive-a-project
+func (m *ProjectManager) EnsureArchivalState(ctx context.Context, project gitlab.Project, dryrun bool) error {
+  archival := m.config.Archival
+
+  // Exit if nothing to configure
+  if archival == nil {
+    m.logger.Debugf("No archival section provided in config")
+    return nil
+  }
+
+  var archived bool
+  switch {
+  case stringslice.MatchAny(project.PathWithNamespace, archival.Archived):
+    archived = true
+  case stringslice.MatchAny(project.PathWithNamespace, archival.Unarchived):
+    archived = false
+  default:
+    m.logger.Debugf("Archival state of project %s isn't configured.", project.PathWithNamespace)
+    return nil
+  }
+
+  if project.Archived == archived {
+    m.logger.Debugf("No action required.")
+    return nil
+  }
+
+  drift := map[string]settingDrift{
+    "archived": {From: project.Archived, To: archived},
+  }
+  if err := m.guardDestructive(project.PathWithNamespace, "project_settings", drift, dryrun); err != nil {
+    return err
+  }
+
+  action := "unarchive"
+  if archived {
+    action = "archive"
+  }
+
+  if dryrun {
+    m.logSkippedCall(project, action, map[string]bool{"archived": archived})
+    m.addDrift(project.PathWithNamespace, "project_settings", drift)
+    return nil
+  }
+
+  path := fmt.Sprintf("projects/%d/%s", project.ID, action)
+
+  req, err := m.apiClient.NewRequest(http.MethodPost, path, nil, []gitlab.OptionFunc{gitlab.WithContext(ctx)})
+  if err != nil {
+    return fmt.Errorf("failed to create request to %s project %s: %v", action, project.PathWithNamespace, err)
+  }
+  _, err = m.apiClient.Do(req, nil)
+  m.audit(project.PathWithNamespace, http.MethodPost+" "+path, drift, err)
+  if err != nil {
+    return fmt.Errorf("failed to %s project %s: %v", action, project.PathWithNamespace, err)
+  }
+
+  // The prefetched settings are outdated now
+  m.forgetPrefetchedProjectSettings(project)
+
+  m.addDrift(project.PathWithNamespace, "project_settings", drift)
+
+  m.logger.Infof("Project %s %sd.", project.PathWithNamespace, action)
+
+  return nil
+}
@@ -0,0 +1,55 @@
+package gitlab
+
+import (
+  "context"
+  "time"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/audit"
+)
+
+// SetAuditLog makes the manager record every mutating API call in sink, attributed to actor
+// (the identity of the token in use)
+func (m *ProjectManager) SetAuditLog(sink audit.Sink, actor string) {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  m.auditSink = sink
+  m.auditActor = actor
+}
+
+// audit records a mutating API call on project (a project or group path) together with the
+// settings it was meant to change and the error it failed with, if any.
+// Failing to record the call is logged and fails the run, but doesn't stop it.
+func (m *ProjectManager) audit(project string, endpoint string, drift map[string]settingDrift, callErr error) {
+  m.mu.Lock()
+  sink, actor := m.auditSink, m.auditActor
+  m.mu.Unlock()
+
+  if sink == nil {
+    return
+  }
+
+  entry := audit.Entry{
+    Timestamp: time.Now().UTC(),
+    Actor:     actor,
+    Project:   project,
+    Endpoint:  endpoint,
+  }
+
+  if len(drift) > 0 {
+    entry.Changes = make(map[string]audit.Change)
+    for setting, values := range drift {
+      entry.Changes[setting] = audit.Change{Before: values.From, After: values.To}
+    }
+  }
+
+  if callErr != nil {
+    entry.Error = callErr.Error()
+  }
+
+  // The entry is written even if the run has been interrupted meanwhile
+  if err := sink.Write(context.Background(), entry); err != nil {
+    m.logger.Errorf("failed to record %s on %s in audit log: %v", endpoint, project, err)
+    m.SetError(true)
+  }
+}
@@ -0,0 +1,101 @@
+package gitlab
+
+import (
+  "context"
+  "encoding/json"
+  "fmt"
+  "path"
+  "time"
+
+  "github.com/xanzy/go-gitlab"
+)
+
+// auditRepositoryTimeFormat formats the time of a run in the paths of the audit repository
+const auditRepositoryTimeFormat = "20060102T150405Z"
+
+// CommitRunResults commits the change log of the run and the current settings of the given
+// changed projects to the repository of audit_log.repository, if configured and anything changed.
+// The change log of each run is added as runs/<time>/changelog.json, the settings of each project
+// are kept up to date in projects/<path>.json.
+func (m *ProjectManager) CommitRunResults(ctx context.Context, started time.Time, changed []gitlab.Project) error {
+  if m.config.AuditLog == nil || m.config.AuditLog.Repository == nil {
+    return nil
+  }
+  settings := m.config.AuditLog.Repository
+
+  changelog := m.ChangeLog()
+  if len(changelog) == 0 {
+    m.logger.Debugf("Nothing changed, skipping the audit repository commit.")
+    return nil
+  }
+
+  repository, _, err := m.projectsClient.GetProject(settings.Project, &gitlab.GetProjectOptions{}, gitlab.WithContext(ctx))
+  if err != nil {
+    return fmt.Errorf("failed to get audit repository %s: %v", settings.Project, err)
+  }
+
+  branch := settings.Branch
+  if branch == "" {
+    branch = repository.DefaultBranch
+  }
+  if branch == "" {
+    return fmt.Errorf("audit repository %s has no default branch, set audit_log.repository.branch", settings.Project)
+  }
+
+  body, err := json.MarshalIndent(changelog, "", "  ")
+  if err != nil {
+    return fmt.Errorf("failed to marshal change log: %v", err)
+  }
+
+  run := started.UTC().Format(auditRepositoryTimeFormat)
+  actions := []*gitlab.CommitAction{{
+    Action:   gitlab.FileCreate,
+    FilePath: path.Join(settings.Path, "runs", run, "changelog.json"),
+    Content:  string(body) + "\n",
+  }}
+
+  for _, project := range changed {
+    snapshot, err := m.SnapshotProject(ctx, project)
+    if err != nil {
+      return err
+    }
+
+    body, err := json.MarshalIndent(snapshot, "", "  ")
+    if err != nil {
+      return fmt.Errorf("failed to marshal settings of project %s: %v", project.PathWithNamespace, err)
+    }
+
+    filePath := path.Join(settings.Path, "projects", project.PathWithNamespace+".json")
+    _, exists, err := m.getRepositoryFileContent(ctx, *repository, filePath, branch)
+    if err != nil {
+      return err
+    }
+
+    action := gitlab.FileCreate
+    if exists {
+      action = gitlab.FileUpdate
+    }
+    actions = append(actions, &gitlab.CommitAction{Action: action, FilePath: filePath, Content: string(body) + "\n"})
+  }
+
+  opt := &gitlab.CreateCommitOptions{
+    Branch:        gitlab.String(branch),
+    CommitMessage: gitlab.String("Record enforcement run " + run),
+    Actions:       actions,
+  }
+  if settings.AuthorName != "" {
+    opt.AuthorName = gitlab.String(settings.AuthorName)
+  }
+  if settings.AuthorEmail != "" {
+    opt.AuthorEmail = gitlab.String(settings.AuthorEmail)
+  }
+
+  commit, _, err := m.commitsClient.CreateCommit(repository.ID, opt, gitlab.WithContext(ctx))
+  if err != nil {
+    return fmt.Errorf("failed to commit run results to audit repository %s: %v", settings.Project, err)
+  }
+
+  m.logger.Infof("Committed the results of the run to audit repository %s (%s).", settings.Project, commit.ShortID)
+
+  return nil
+}
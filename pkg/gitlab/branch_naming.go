@@ -0,0 +1,55 @@
+package gitlab
+
+import (
+  "context"
+  "regexp"
+
+  "github.com/xanzy/go-gitlab"
+)
+
+// AuditBranchNaming records a policy violation for every branch of the project matching none of
+// branch_naming.allowed_patterns. The default branch is always allowed.
+func (m *ProjectManager) AuditBranchNaming(ctx context.Context, project gitlab.Project) error {
+  if m.config.BranchNaming == nil {
+    m.logger.Debugf("No branch_naming provided in config")
+    return nil
+  }
+
+  m.logger.Debugf("Auditing branch names of project %s ...", project.PathWithNamespace)
+
+  // The patterns were validated when loading the config
+  var patterns []*regexp.Regexp
+  for _, pattern := range m.config.BranchNaming.AllowedPatterns {
+    if expr, err := regexp.Compile(pattern); err == nil {
+      patterns = append(patterns, expr)
+    }
+  }
+
+  names, err := m.listBranchNames(ctx, project)
+  if err != nil {
+    return err
+  }
+
+  for _, name := range names {
+    if name == project.DefaultBranch || matchesAny(patterns, name) {
+      continue
+    }
+
+    m.addViolation(project.PathWithNamespace, "branch_naming: branch %q matches none of the allowed patterns", name)
+  }
+
+  m.logger.Debugf("Auditing branch names of project %s done.", project.PathWithNamespace)
+
+  return nil
+}
+
+// matchesAny returns whether the name matches any of the patterns
+func matchesAny(patterns []*regexp.Regexp, name string) bool {
+  for _, pattern := range patterns {
+    if pattern.MatchString(name) {
+      return true
+    }
+  }
+
+  return false
+}
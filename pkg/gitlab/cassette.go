@@ -0,0 +1,179 @@
+package gitlab
+
+import (
+  "bytes"
+  "crypto/sha256"
+  "encoding/base64"
+  "encoding/json"
+  "fmt"
+  "io/ioutil"
+  "net/http"
+  "os"
+  "path/filepath"
+  "sync"
+  "unicode/utf8"
+)
+
+// Modes of a CassetteTransport
+const (
+  CassetteRecord = "record"
+  CassetteReplay = "replay"
+)
+
+// cassetteInteraction is a recorded response, stored as a file of the cassette directory
+type cassetteInteraction struct {
+  Method     string      `json:"method"`
+  URL        string      `json:"url"`
+  StatusCode int         `json:"status_code"`
+  Header     http.Header `json:"header"`
+  Body       string      `json:"body,omitempty"`
+  BodyBase64 string      `json:"body_base64,omitempty"`
+}
+
+// CassetteTransport is an http.RoundTripper recording the responses of GitLab to a directory of
+// cassette files, or replaying them without talking to GitLab. Requests are identified by their
+// method, path, query and body; repeated requests are recorded and replayed in order, the last
+// recorded response answering any further repetition.
+// It is safe for concurrent use.
+type CassetteTransport struct {
+  base http.RoundTripper
+  mode string
+  dir  string
+
+  mu    sync.Mutex
+  calls map[string]int
+}
+
+// NewCassetteTransport returns a CassetteTransport recording the responses of base to dir, or
+// replaying them from dir, as given by mode
+func NewCassetteTransport(base http.RoundTripper, mode string, dir string) (*CassetteTransport, error) {
+  switch mode {
+  case CassetteRecord:
+    if err := os.MkdirAll(dir, 0755); err != nil {
+      return nil, fmt.Errorf("failed to create cassette directory %s: %v", dir, err)
+    }
+  case CassetteReplay:
+    if _, err := os.Stat(dir); err != nil {
+      return nil, fmt.Errorf("failed to open cassette directory %s: %v", dir, err)
+    }
+  default:
+    return nil, fmt.Errorf("unknown cassette mode %q", mode)
+  }
+
+  return &CassetteTransport{
+    base:  base,
+    mode:  mode,
+    dir:   dir,
+    calls: make(map[string]int),
+  }, nil
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *CassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+  var body []byte
+  if req.Body != nil {
+    b, err := ioutil.ReadAll(req.Body)
+    req.Body.Close()
+    if err != nil {
+      return nil, err
+    }
+    body = b
+    req.Body = ioutil.NopCloser(bytes.NewReader(body))
+  }
+
+  key := fmt.Sprintf("%x", sha256.Sum256([]byte(req.Method+" "+req.URL.RequestURI()+"\n"+string(body))))[:16]
+
+  t.mu.Lock()
+  call := t.calls[key]
+  t.calls[key]++
+  t.mu.Unlock()
+
+  if t.mode == CassetteReplay {
+    return t.replay(req, key, call)
+  }
+
+  return t.record(req, key, call)
+}
+
+// record sends the request and stores the response as the given call of the request
+func (t *CassetteTransport) record(req *http.Request, key string, call int) (*http.Response, error) {
+  resp, err := t.base.RoundTrip(req)
+  if err != nil {
+    return nil, err
+  }
+
+  body, err := ioutil.ReadAll(resp.Body)
+  resp.Body.Close()
+  if err != nil {
+    return nil, err
+  }
+  resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+  interaction := cassetteInteraction{
+    Method:     req.Method,
+    URL:        req.URL.RequestURI(),
+    StatusCode: resp.StatusCode,
+    Header:     resp.Header,
+  }
+  if utf8.Valid(body) {
+    interaction.Body = string(body)
+  } else {
+    interaction.BodyBase64 = base64.StdEncoding.EncodeToString(body)
+  }
+
+  b, err := json.MarshalIndent(interaction, "", "  ")
+  if err != nil {
+    return nil, err
+  }
+  if err := ioutil.WriteFile(t.file(key, call), b, 0600); err != nil {
+    return nil, fmt.Errorf("failed to record %s %s: %v", req.Method, req.URL.Path, err)
+  }
+
+  return resp, nil
+}
+
+// replay answers the request with the recorded response of the given call, or the last one
+// recorded before
+func (t *CassetteTransport) replay(req *http.Request, key string, call int) (*http.Response, error) {
+  var b []byte
+  var err error
+  for ; call >= 0; call-- {
+    if b, err = ioutil.ReadFile(t.file(key, call)); err == nil || !os.IsNotExist(err) {
+      break
+    }
+  }
+  if os.IsNotExist(err) {
+    return nil, fmt.Errorf("no recorded response for %s %s in %s", req.Method, req.URL.RequestURI(), t.dir)
+  } else if err != nil {
+    return nil, err
+  }
+
+  var interaction cassetteInteraction
+  if err := json.Unmarshal(b, &interaction); err != nil {
+    return nil, fmt.Errorf("failed to read recorded response for %s %s: %v", req.Method, req.URL.Path, err)
+  }
+
+  body := []byte(interaction.Body)
+  if interaction.BodyBase64 != "" {
+    if body, err = base64.StdEncoding.DecodeString(interaction.BodyBase64); err != nil {
+      return nil, fmt.Errorf("failed to read recorded response for %s %s: %v", req.Method, req.URL.Path, err)
+    }
+  }
+
+  return &http.Response{
+    Status:        fmt.Sprintf("%d %s", interaction.StatusCode, http.StatusText(interaction.StatusCode)),
+    StatusCode:    interaction.StatusCode,
+    Proto:         "HTTP/1.1",
+    ProtoMajor:    1,
+    ProtoMinor:    1,
+    Header:        interaction.Header,
+    Body:          ioutil.NopCloser(bytes.NewReader(body)),
+    ContentLength: int64(len(body)),
+    Request:       req,
+  }, nil
+}
+
+// file returns the path of the cassette file holding the given call of a request
+func (t *CassetteTransport) file(key string, call int) string {
+  return filepath.Join(t.dir, fmt.Sprintf("%s-%d.json", key, call))
+}
@@ -0,0 +1,208 @@
+package gitlab
+
+import (
+  "encoding/json"
+  "fmt"
+  "io"
+  "path"
+  "reflect"
+  "sort"
+  "strings"
+
+  "github.com/xanzy/go-gitlab"
+)
+
+// compareIgnoredSettings are the editable project settings which identify a project rather than
+// configure it, so they differ between compared projects by design
+var compareIgnoredSettings = map[string]bool{
+  "name": true,
+  "path": true,
+}
+
+// SettingDifference is a setting differing between two projects of the same relative path.
+// A project missing on one side is reported as setting "exists".
+type SettingDifference struct {
+  Project string      `json:"project"`
+  Setting string      `json:"setting"`
+  Source  interface{} `json:"source"`
+  Target  interface{} `json:"target"`
+}
+
+// CompareSnapshots compares the settings of the projects of both snapshots, matching them by
+// their path relative to the given prefixes (e.g. the paths of the compared groups). Only settings
+// which can be changed through the API are compared.
+func CompareSnapshots(source *Snapshot, sourcePrefix string, target *Snapshot, targetPrefix string) ([]SettingDifference, error) {
+  sourceProjects := relativeProjects(source, sourcePrefix)
+  targetProjects := relativeProjects(target, targetPrefix)
+
+  projects := make(map[string]bool)
+  for project := range sourceProjects {
+    projects[project] = true
+  }
+  for project := range targetProjects {
+    projects[project] = true
+  }
+
+  var differences []SettingDifference
+  for project := range projects {
+    sourceSnapshot, inSource := sourceProjects[project]
+    targetSnapshot, inTarget := targetProjects[project]
+    if !inSource || !inTarget {
+      differences = append(differences, SettingDifference{Project: project, Setting: "exists", Source: inSource, Target: inTarget})
+      continue
+    }
+
+    sourceSettings, err := comparableSettings(sourceSnapshot)
+    if err != nil {
+      return nil, fmt.Errorf("invalid settings of project %s: %v", project, err)
+    }
+    targetSettings, err := comparableSettings(targetSnapshot)
+    if err != nil {
+      return nil, fmt.Errorf("invalid settings of project %s: %v", project, err)
+    }
+
+    for setting := range mergeKeys(sourceSettings, targetSettings) {
+      if !reflect.DeepEqual(sourceSettings[setting], targetSettings[setting]) {
+        differences = append(differences, SettingDifference{Project: project, Setting: setting, Source: sourceSettings[setting], Target: targetSettings[setting]})
+      }
+    }
+  }
+
+  sort.Slice(differences, func(i, j int) bool {
+    if differences[i].Project != differences[j].Project {
+      return differences[i].Project < differences[j].Project
+    }
+    return differences[i].Setting < differences[j].Setting
+  })
+
+  return differences, nil
+}
+
+// CommonNamespace returns the longest namespace shared by all projects of the snapshot
+func (s *Snapshot) CommonNamespace() string {
+  var common []string
+  first := true
+  for project := range s.Projects {
+    namespace := strings.Split(path.Dir(project), "/")
+    if first {
+      common, first = namespace, false
+      continue
+    }
+
+    i := 0
+    for i < len(common) && i < len(namespace) && common[i] == namespace[i] {
+      i++
+    }
+    common = common[:i]
+  }
+
+  return strings.Join(common, "/")
+}
+
+// relativeProjects returns the projects of the snapshot keyed by their path relative to prefix
+func relativeProjects(s *Snapshot, prefix string) map[string]ProjectSnapshot {
+  projects := make(map[string]ProjectSnapshot, len(s.Projects))
+  for project, snapshot := range s.Projects {
+    projects[strings.TrimPrefix(project, strings.TrimSuffix(prefix, "/")+"/")] = snapshot
+  }
+
+  return projects
+}
+
+// comparableSettings flattens the editable settings of the snapshot into a map keyed by their
+// section, e.g. "project_settings.merge_method" or "protected_branches.main.push_access_level"
+func comparableSettings(snapshot ProjectSnapshot) (map[string]interface{}, error) {
+  settings := make(map[string]interface{})
+
+  projectSettings := &gitlab.EditProjectOptions{}
+  if err := fromJSONMap(snapshot.ProjectSettings, projectSettings); err != nil {
+    return nil, err
+  }
+  values, err := toJSONMap(projectSettings)
+  if err != nil {
+    return nil, err
+  }
+  for setting, value := range values {
+    if !compareIgnoredSettings[setting] {
+      settings["project_settings."+setting] = value
+    }
+  }
+
+  approvalSettings := &gitlab.ChangeApprovalConfigurationOptions{}
+  if err := fromJSONMap(snapshot.ApprovalSettings, approvalSettings); err != nil {
+    return nil, err
+  }
+  if values, err = toJSONMap(approvalSettings); err != nil {
+    return nil, err
+  }
+  for setting, value := range values {
+    settings["approval_settings."+setting] = value
+  }
+
+  for _, b := range snapshot.ProtectedBranches {
+    settings["protected_branches."+b.Name+".push_access_level"] = accessLevelValue(firstAccessLevel(b.PushAccessLevels))
+    settings["protected_branches."+b.Name+".merge_access_level"] = accessLevelValue(firstAccessLevel(b.MergeAccessLevels))
+  }
+
+  return settings, nil
+}
+
+// accessLevelValue dereferences the access level, nil if unset
+func accessLevelValue(level *gitlab.AccessLevelValue) interface{} {
+  if level == nil {
+    return nil
+  }
+
+  return int(*level)
+}
+
+// mergeKeys returns the union of the keys of both maps
+func mergeKeys(a map[string]interface{}, b map[string]interface{}) map[string]bool {
+  keys := make(map[string]bool, len(a))
+  for key := range a {
+    keys[key] = true
+  }
+  for key := range b {
+    keys[key] = true
+  }
+
+  return keys
+}
+
+// RenderDifferences writes the differences as JSON for the json output format, as text for all others
+func RenderDifferences(w io.Writer, format string, source string, target string, differences []SettingDifference) error {
+  if format == OutputFormatJSON {
+    if differences == nil {
+      differences = []SettingDifference{}
+    }
+
+    body, err := json.MarshalIndent(map[string]interface{}{
+      "source":      source,
+      "target":      target,
+      "differences": differences,
+    }, "", "  ")
+    if err != nil {
+      return fmt.Errorf("failed to marshal differences: %v", err)
+    }
+
+    _, err = fmt.Fprintf(w, "%s\n", body)
+    return err
+  }
+
+  fmt.Fprintf(w, "\nDIFFERENCES (%s -> %s)\n", source, target)
+  if len(differences) == 0 {
+    _, err := fmt.Fprintf(w, "  None, all settings match.\n\n")
+    return err
+  }
+
+  project := ""
+  for _, d := range differences {
+    if d.Project != project {
+      project = d.Project
+      fmt.Fprintf(w, "  %s\n", project)
+    }
+    fmt.Fprintf(w, "    %s: %s -> %s\n", d.Setting, FormatValue(d.Source), FormatValue(d.Target))
+  }
+  _, err := fmt.Fprintf(w, "\n")
+  return err
+}
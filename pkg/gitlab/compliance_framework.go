@@ -0,0 +1,160 @@
+package gitlab
+
+import (
+  "context"
+  "errors"
+  "fmt"
+  "strings"
+
+  "github.com/xanzy/go-gitlab"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/internal/stringslice"
+)
+
+const (
+  queryProjectComplianceFrameworks = `query($path: ID!) {
+  project(fullPath: $path) {
+    complianceFrameworks { nodes { id name } }
+  }
+}`
+
+  queryNamespaceComplianceFrameworks = `query($path: ID!) {
+  namespace(fullPath: $path) {
+    complianceFrameworks { nodes { id name } }
+  }
+}`
+
+  mutationSetComplianceFramework = `mutation($project: ProjectID!, $framework: ComplianceManagementFrameworkID) {
+  projectSetComplianceFramework(input: {projectId: $project, complianceFrameworkId: $framework}) {
+    errors
+  }
+}`
+)
+
+type complianceFrameworkNodes struct {
+  ComplianceFrameworks struct {
+    Nodes []struct {
+      ID   string `json:"id"`
+      Name string `json:"name"`
+    } `json:"nodes"`
+  } `json:"complianceFrameworks"`
+}
+
+// EnsureComplianceFramework assigns the compliance framework configured in
+// compliance_framework to the project, if not already assigned.
+func (m *ProjectManager) EnsureComplianceFramework(ctx context.Context, project gitlab.Project, dryrun bool) error {
+  framework := m.config.ComplianceFramework
+
+  // Exit if nothing to configure
+  if framework == "" {
+    m.logger.Debugf("No compliance_framework provided in config")
+    return nil
+  }
+
+  m.logger.Debugf("Ensuring compliance framework %s on project %s ...", framework, project.PathWithNamespace)
+
+  current, err := m.getProjectComplianceFrameworks(ctx, project)
+  if err != nil {
+    return err
+  }
+
+  if stringslice.Contains(framework, current) {
+    m.logger.Debugf("No action required.")
+    return nil
+  }
+
+  frameworkID, err := m.getComplianceFrameworkID(ctx, project, framework)
+  if err != nil {
+    return err
+  }
+
+  if dryrun {
+    m.logger.Infof("DRYRUN: Skipped executing API call [projectSetComplianceFramework]")
+    m.addChange(project.PathWithNamespace, "compliance_framework", "name", strings.Join(current, ","), framework)
+    return nil
+  }
+
+  var result struct {
+    ProjectSetComplianceFramework struct {
+      Errors []string `json:"errors"`
+    } `json:"projectSetComplianceFramework"`
+  }
+
+  variables := map[string]interface{}{
+    "project":   fmt.Sprintf("gid://gitlab/Project/%d", project.ID),
+    "framework": frameworkID,
+  }
+  err = m.graphqlClient.Query(ctx, mutationSetComplianceFramework, variables, &result)
+  if err == nil && len(result.ProjectSetComplianceFramework.Errors) > 0 {
+    err = errors.New(strings.Join(result.ProjectSetComplianceFramework.Errors, "; "))
+  }
+  m.audit(project.PathWithNamespace, "POST graphql projectSetComplianceFramework", map[string]settingDrift{
+    "name": {From: strings.Join(current, ","), To: framework},
+  }, err)
+  if err != nil {
+    return fmt.Errorf("failed to set compliance framework %s on project %s: %v", framework, project.PathWithNamespace, err)
+  }
+
+  m.addChange(project.PathWithNamespace, "compliance_framework", "name", strings.Join(current, ","), framework)
+
+  m.logger.Debugf("Ensuring compliance framework %s on project %s done.", framework, project.PathWithNamespace)
+
+  return nil
+}
+
+// getProjectComplianceFrameworks returns the names of the compliance frameworks assigned to the project
+func (m *ProjectManager) getProjectComplianceFrameworks(ctx context.Context, project gitlab.Project) ([]string, error) {
+  var result struct {
+    Project *complianceFrameworkNodes `json:"project"`
+  }
+
+  variables := map[string]interface{}{"path": project.PathWithNamespace}
+  if err := m.graphqlClient.Query(ctx, queryProjectComplianceFrameworks, variables, &result); err != nil {
+    return nil, fmt.Errorf("failed to get compliance frameworks of project %s: %v", project.PathWithNamespace, err)
+  }
+  if result.Project == nil {
+    return nil, fmt.Errorf("failed to get compliance frameworks of project %s: project not found", project.PathWithNamespace)
+  }
+
+  names := make([]string, 0)
+  for _, node := range result.Project.ComplianceFrameworks.Nodes {
+    names = append(names, node.Name)
+  }
+
+  return names, nil
+}
+
+// getComplianceFrameworkID resolves the ID of the named framework, which is defined on the project's top-level group
+func (m *ProjectManager) getComplianceFrameworkID(ctx context.Context, project gitlab.Project, framework string) (string, error) {
+  namespace := strings.Split(project.PathWithNamespace, "/")[0]
+
+  m.mu.Lock()
+  id, ok := m.complianceFrameworkIDs[namespace]
+  m.mu.Unlock()
+  if ok {
+    return id, nil
+  }
+
+  var result struct {
+    Namespace *complianceFrameworkNodes `json:"namespace"`
+  }
+
+  variables := map[string]interface{}{"path": namespace}
+  if err := m.graphqlClient.Query(ctx, queryNamespaceComplianceFrameworks, variables, &result); err != nil {
+    return "", fmt.Errorf("failed to get compliance frameworks of group %s: %v", namespace, err)
+  }
+  if result.Namespace == nil {
+    return "", fmt.Errorf("failed to get compliance frameworks of group %s: group not found", namespace)
+  }
+
+  for _, node := range result.Namespace.ComplianceFrameworks.Nodes {
+    if node.Name == framework {
+      m.mu.Lock()
+      m.complianceFrameworkIDs[namespace] = node.ID
+      m.mu.Unlock()
+      return node.ID, nil
+    }
+  }
+
+  return "", fmt.Errorf("compliance framework %s is not defined on group %s", framework, namespace)
+}
@@ -0,0 +1,44 @@
+package gitlab
+
+import (
+  "fmt"
+  "path"
+  "strings"
+
+  "github.com/xanzy/go-gitlab"
+)
+
+// RepositoryConfigSource reads config files from a ref of a GitLab repository
+type RepositoryConfigSource struct {
+  repositoryFilesClient repositoryFilesClient
+  project               string
+  ref                   string
+}
+
+// NewRepositoryConfigSource returns a RepositoryConfigSource for the given project path (or ID) and ref
+func NewRepositoryConfigSource(repositoryFilesClient repositoryFilesClient, project string, ref string) *RepositoryConfigSource {
+  return &RepositoryConfigSource{
+    repositoryFilesClient: repositoryFilesClient,
+    project:               project,
+    ref:                   ref,
+  }
+}
+
+// Read returns the raw content of the file at the given path of the repository
+func (s *RepositoryConfigSource) Read(location string) ([]byte, error) {
+  content, _, err := s.repositoryFilesClient.GetRawFile(s.project, location, &gitlab.GetRawFileOptions{Ref: gitlab.String(s.ref)})
+  if err != nil {
+    return nil, fmt.Errorf("failed to get file %s of project %s at %s: %v", location, s.project, s.ref, err)
+  }
+
+  return content, nil
+}
+
+// Resolve returns the repository path of ref, relative to the file at base
+func (s *RepositoryConfigSource) Resolve(base string, ref string) string {
+  if strings.HasPrefix(ref, "/") {
+    return strings.TrimPrefix(path.Clean(ref), "/")
+  }
+
+  return path.Join(path.Dir(base), ref)
+}
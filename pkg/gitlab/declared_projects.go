@@ -0,0 +1,89 @@
+package gitlab
+
+import (
+  "context"
+  "fmt"
+  "net/http"
+
+  "github.com/xanzy/go-gitlab"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/config"
+)
+
+// createProjectOptions holds the attributes of a created project, including the template which
+// go-gitlab doesn't offer yet
+type createProjectOptions struct {
+  Name         string `json:"name"`
+  Path         string `json:"path"`
+  NamespaceID  int    `json:"namespace_id"`
+  TemplateName string `json:"template_name,omitempty"`
+}
+
+// EnsureProjects creates the projects declared in the projects section which don't exist yet.
+// They are enforced like all others afterwards, as they are listed with the projects of their group.
+// https://docs.gitlab.com/ee/api/projects.html#create-project
+func (m *ProjectManager) EnsureProjects(ctx context.Context, dryrun bool) error {
+  // Exit if nothing to configure
+  if len(m.config.Projects) == 0 {
+    m.logger.Debugf("No projects section provided in config")
+    return nil
+  }
+
+  for _, declared := range m.config.Projects {
+    if err := m.ensureProject(ctx, declared, dryrun); err != nil {
+      return err
+    }
+  }
+
+  return nil
+}
+
+// ensureProject creates a single declared project, unless it exists already
+func (m *ProjectManager) ensureProject(ctx context.Context, declared config.ProjectDeclaration, dryrun bool) error {
+  path := declared.PathWithNamespace()
+
+  _, resp, err := m.projectsClient.GetProject(path, &gitlab.GetProjectOptions{}, gitlab.WithContext(ctx))
+  if err == nil {
+    m.logger.Debugf("Project %s already exists.", path)
+    return nil
+  }
+  if resp == nil || resp.StatusCode != http.StatusNotFound {
+    return fmt.Errorf("failed to check for project %s: %v", path, err)
+  }
+
+  groupID, err := m.getGroupID(ctx, declared.Group)
+  if err != nil {
+    return err
+  }
+
+  opt := &createProjectOptions{
+    Name:         declared.Name,
+    Path:         declared.Path,
+    NamespaceID:  groupID,
+    TemplateName: declared.Template,
+  }
+
+  m.logger.Infof("Creating project %s ...", path)
+
+  if dryrun {
+    m.logSkippedCall(gitlab.Project{PathWithNamespace: path}, "CreateProject", opt)
+    m.addChange(path, "project", "exists", false, true)
+    return nil
+  }
+
+  req, err := m.apiClient.NewRequest(http.MethodPost, "projects", opt, []gitlab.OptionFunc{gitlab.WithContext(ctx)})
+  if err != nil {
+    return fmt.Errorf("failed to create request for project %s: %v", path, err)
+  }
+  _, err = m.apiClient.Do(req, nil)
+  m.audit(path, http.MethodPost+" projects", map[string]settingDrift{
+    "exists": {From: false, To: true},
+  }, err)
+  if err != nil {
+    return fmt.Errorf("failed to create project %s: %v", path, err)
+  }
+
+  m.addChange(path, "project", "exists", false, true)
+
+  return nil
+}
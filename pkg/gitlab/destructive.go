@@ -0,0 +1,170 @@
+package gitlab
+
+import (
+  "fmt"
+  "io"
+  "math"
+  "sort"
+  "strings"
+)
+
+// destructiveSettings detect high-risk transitions of single settings, returning why the change
+// is destructive, or "" if it isn't
+var destructiveSettings = map[string]map[string]func(from interface{}, to interface{}) string{
+  "project_settings": {
+    "visibility": func(from interface{}, to interface{}) string {
+      switch {
+      case from == to:
+        return ""
+      case to == "public":
+        return "exposes the project publicly"
+      case from == "public":
+        return "hides the public project"
+      }
+      return ""
+    },
+    "merge_requests_enabled": func(from interface{}, to interface{}) string {
+      if from == true && to == false {
+        return "disables merge requests"
+      }
+      return ""
+    },
+    "archived": func(from interface{}, to interface{}) string {
+      if from != true && to == true {
+        return "archives the project"
+      }
+      return ""
+    },
+  },
+  "project_access_levels": {
+    "merge_requests_access_level": func(from interface{}, to interface{}) string {
+      if from != "disabled" && to == "disabled" {
+        return "disables merge requests"
+      }
+      return ""
+    },
+    "merge_requests_enabled": func(from interface{}, to interface{}) string {
+      if from == true && to == false {
+        return "disables merge requests"
+      }
+      return ""
+    },
+  },
+}
+
+// destructiveChange is a high-risk change found for a project
+type destructiveChange struct {
+  Setting string
+  Change  string
+  Reason  string
+  Refused bool
+}
+
+// SetAllowDestructive allows applying destructive changes, which are refused otherwise
+func (m *ProjectManager) SetAllowDestructive(allow bool) {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  m.allowDestructive = allow
+}
+
+// guardDestructive records the destructive changes among the drifted settings of the subsection
+// and, unless destructive changes are allowed, refuses to apply them. Dryruns only record them.
+func (m *ProjectManager) guardDestructive(project string, subsection string, drift map[string]settingDrift, dryrun bool) error {
+  var found []destructiveChange
+  for setting, values := range drift {
+    if reason := destructiveReason(subsection, setting, values); reason != "" {
+      found = append(found, destructiveChange{
+        Setting: subsection + "." + setting,
+        Change:  fmt.Sprintf("%s => %s", quoteValue(values.From), quoteValue(values.To)),
+        Reason:  reason,
+      })
+    }
+  }
+  if len(found) == 0 {
+    return nil
+  }
+  sort.Slice(found, func(i, j int) bool {
+    return found[i].Setting < found[j].Setting
+  })
+
+  m.mu.Lock()
+  refused := !m.allowDestructive && !dryrun
+  var settings []string
+  for i := range found {
+    found[i].Refused = refused
+    settings = append(settings, found[i].Setting)
+    m.destructive[project] = append(m.destructive[project], found[i])
+  }
+  m.mu.Unlock()
+
+  if !refused {
+    m.logger.Warnf("Destructive change(s) of project %s: %s", project, strings.Join(settings, ", "))
+    return nil
+  }
+
+  return fmt.Errorf("refusing destructive change(s) of %s without --allow-destructive: %s", subsection, strings.Join(settings, ", "))
+}
+
+// destructiveReason returns why the change of the setting is destructive, "" if it isn't.
+// Protected branches are weakened by allowing lower roles to push or merge.
+func destructiveReason(subsection string, setting string, values settingDrift) string {
+  if subsection == "protected_branches" {
+    from, wasSet := values.From.(float64)
+    to, _ := values.To.(float64)
+    if wasSet && accessLevelRank(to) < accessLevelRank(from) {
+      return "weakens the protection of branch " + setting[:strings.LastIndex(setting, ".")]
+    }
+    return ""
+  }
+
+  if detect, ok := destructiveSettings[subsection][setting]; ok {
+    return detect(values.From, values.To)
+  }
+  return ""
+}
+
+// accessLevelRank orders access levels of protected branches by their strictness, where no
+// access (0) is the strictest
+func accessLevelRank(level float64) float64 {
+  if level == 0 {
+    return math.MaxFloat64
+  }
+
+  return level
+}
+
+// GenerateDestructiveReport writes the destructive changes found per project to w, marking the
+// refused ones. Nothing is written if none were found.
+func (m *ProjectManager) GenerateDestructiveReport(w io.Writer) error {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  if len(m.destructive) == 0 {
+    return nil
+  }
+
+  var project_names []string
+  for project_name := range m.destructive {
+    project_names = append(project_names, project_name)
+  }
+  sort.Strings(project_names)
+
+  fmt.Fprintf(w, "\n!!! DESTRUCTIVE CHANGES !!!\n")
+
+  for _, name := range project_names {
+    fmt.Fprintf(w, "  %s\n", name)
+
+    for _, change := range m.destructive[name] {
+      fmt.Fprintf(w, "    %s: %s (%s)", change.Setting, change.Change, change.Reason)
+      if change.Refused {
+        fmt.Fprintf(w, " REFUSED, requires --allow-destructive")
+      }
+      fmt.Fprintf(w, "\n")
+    }
+
+    fmt.Fprintf(w, "\n")
+  }
+
+  return nil
+}
@@ -0,0 +1,275 @@
+package gitlab
+
+import (
+  "context"
+  "encoding/json"
+  "fmt"
+  "io"
+  "net/http"
+  "strconv"
+  "strings"
+
+  "github.com/xanzy/go-gitlab"
+)
+
+// Statuses of doctor checks
+const (
+  DoctorOK      = "ok"
+  DoctorWarning = "warning"
+  DoctorFailed  = "failed"
+)
+
+// DoctorCheck is the outcome of a single doctor check
+type DoctorCheck struct {
+  Name    string `json:"name"`
+  Status  string `json:"status"`
+  Message string `json:"message"`
+}
+
+// doctorFeature is a config section requiring a minimum GitLab version or edition
+type doctorFeature struct {
+  section    string
+  configured func(m *ProjectManager) bool
+  major      int
+  minor      int
+  premium    bool
+}
+
+// doctorFeatures are the config sections depending on the GitLab version or edition
+var doctorFeatures = []doctorFeature{
+  {"approval_settings", func(m *ProjectManager) bool { return m.config.ApprovalSettings != nil }, 10, 6, true},
+  {"group_push_rules", func(m *ProjectManager) bool { return m.config.GroupPushRules != nil }, 13, 4, true},
+  {"compliance_framework", func(m *ProjectManager) bool { return m.config.ComplianceFramework != "" }, 14, 2, true},
+}
+
+type doctorVersion struct {
+  Version  string `json:"version"`
+  Revision string `json:"revision"`
+}
+
+type doctorUser struct {
+  ID       int    `json:"id"`
+  Username string `json:"username"`
+  IsAdmin  bool   `json:"is_admin"`
+}
+
+type doctorToken struct {
+  Name      string   `json:"name"`
+  Scopes    []string `json:"scopes"`
+  ExpiresAt string   `json:"expires_at"`
+}
+
+type doctorMember struct {
+  AccessLevel gitlab.AccessLevelValue `json:"access_level"`
+}
+
+// Doctor verifies that GitLab is reachable with the token, and that the token and GitLab itself
+// suffice to enforce the config. Later checks are skipped once GitLab can't be talked to.
+func (m *ProjectManager) Doctor(ctx context.Context) []DoctorCheck {
+  var checks []DoctorCheck
+  add := func(name string, status string, format string, args ...interface{}) {
+    checks = append(checks, DoctorCheck{Name: name, Status: status, Message: fmt.Sprintf(format, args...)})
+  }
+
+  var version doctorVersion
+  if resp, err := m.doctorGet(ctx, "version", &version); err != nil {
+    if resp == nil || (resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden) {
+      add("endpoint", DoctorFailed, "GitLab is not reachable: %v", err)
+      return checks
+    }
+    add("endpoint", DoctorOK, "GitLab is reachable")
+    add("token", DoctorFailed, "GitLab rejected the token: %v", err)
+    return checks
+  }
+  add("endpoint", DoctorOK, "GitLab %s is reachable", version.Version)
+
+  var user doctorUser
+  if _, err := m.doctorGet(ctx, "user", &user); err != nil {
+    add("token", DoctorFailed, "failed to get the user of the token: %v", err)
+    return checks
+  }
+  if user.IsAdmin {
+    add("token", DoctorOK, "the token belongs to user %s (administrator)", user.Username)
+  } else {
+    add("token", DoctorOK, "the token belongs to user %s", user.Username)
+  }
+
+  checks = append(checks, m.doctorScopes(ctx))
+
+  if m.config.InstanceSettings != nil && !user.IsAdmin {
+    add("instance_settings", DoctorWarning, "instance_settings are configured, but user %s is no administrator", user.Username)
+  }
+
+  m.ResolveGroupID()
+  for _, group := range m.config.GroupNames() {
+    checks = append(checks, m.doctorGroupAccess(ctx, group, user))
+  }
+
+  checks = append(checks, m.doctorVersion(version)...)
+
+  return checks
+}
+
+// doctorScopes checks that the token has the api scope, which is required to change settings
+func (m *ProjectManager) doctorScopes(ctx context.Context) DoctorCheck {
+  check := DoctorCheck{Name: "scopes"}
+
+  // Only personal, project and group access tokens can be inspected
+  var token doctorToken
+  if _, err := m.doctorGet(ctx, "personal_access_tokens/self", &token); err != nil {
+    check.Status = DoctorWarning
+    check.Message = fmt.Sprintf("the scopes of the token can't be determined (GitLab 15.5 or newer and an access token are required): %v", err)
+    return check
+  }
+
+  expiry := ""
+  if token.ExpiresAt != "" {
+    expiry = ", expires at " + token.ExpiresAt
+  }
+
+  for _, scope := range token.Scopes {
+    if scope == "api" {
+      check.Status = DoctorOK
+      check.Message = fmt.Sprintf("token %s has scopes %s%s", token.Name, strings.Join(token.Scopes, ", "), expiry)
+      return check
+    }
+  }
+
+  check.Status = DoctorFailed
+  check.Message = fmt.Sprintf("token %s has scopes %s%s, but the api scope is required to change settings", token.Name, strings.Join(token.Scopes, ", "), expiry)
+  return check
+}
+
+// doctorGroupAccess checks that the user may change the settings of the group and its projects
+func (m *ProjectManager) doctorGroupAccess(ctx context.Context, group string, user doctorUser) DoctorCheck {
+  check := DoctorCheck{Name: "group " + group}
+
+  groupID, err := m.getGroupID(ctx, group)
+  if err != nil {
+    check.Status = DoctorFailed
+    check.Message = err.Error()
+    return check
+  }
+
+  if user.IsAdmin {
+    check.Status = DoctorOK
+    check.Message = "administrators may change all settings"
+    return check
+  }
+
+  var member doctorMember
+  resp, err := m.doctorGet(ctx, fmt.Sprintf("groups/%d/members/all/%d", groupID, user.ID), &member)
+  if err != nil && (resp == nil || resp.StatusCode != http.StatusNotFound) {
+    check.Status = DoctorFailed
+    check.Message = fmt.Sprintf("failed to get the access level of user %s: %v", user.Username, err)
+    return check
+  }
+
+  required, requiredName := gitlab.MaintainerPermissions, "maintainer"
+  if m.config.GroupSettings != nil {
+    required, requiredName = gitlab.OwnerPermissions, "owner"
+  }
+
+  switch {
+    case err != nil:
+      check.Status = DoctorFailed
+      check.Message = fmt.Sprintf("user %s is no member, %s access is required", user.Username, requiredName)
+    case member.AccessLevel < required:
+      check.Status = DoctorFailed
+      check.Message = fmt.Sprintf("user %s has access level %d, %s access is required", user.Username, member.AccessLevel, requiredName)
+    default:
+      check.Status = DoctorOK
+      check.Message = fmt.Sprintf("user %s has access level %d", user.Username, member.AccessLevel)
+  }
+
+  return check
+}
+
+// doctorVersion checks the version and edition of GitLab against the configured features
+func (m *ProjectManager) doctorVersion(version doctorVersion) []DoctorCheck {
+  major, minor, ok := parseGitlabVersion(version.Version)
+  premium := strings.HasSuffix(version.Version, "-ee")
+
+  var checks []DoctorCheck
+  for _, feature := range doctorFeatures {
+    if !feature.configured(m) {
+      continue
+    }
+
+    check := DoctorCheck{Name: feature.section, Status: DoctorOK, Message: "supported by GitLab " + version.Version}
+    switch {
+      case !ok:
+        check.Status = DoctorWarning
+        check.Message = fmt.Sprintf("unknown GitLab version %q, %d.%d is required", version.Version, feature.major, feature.minor)
+      case major < feature.major || (major == feature.major && minor < feature.minor):
+        check.Status = DoctorFailed
+        check.Message = fmt.Sprintf("GitLab %s is too old, %d.%d is required", version.Version, feature.major, feature.minor)
+      case feature.premium && !premium:
+        check.Status = DoctorFailed
+        check.Message = fmt.Sprintf("GitLab %s is no Enterprise Edition, a Premium license is required", version.Version)
+    }
+    checks = append(checks, check)
+  }
+
+  return checks
+}
+
+// doctorGet performs a raw GET request of the REST API
+func (m *ProjectManager) doctorGet(ctx context.Context, path string, v interface{}) (*gitlab.Response, error) {
+  req, err := m.apiClient.NewRequest(http.MethodGet, path, nil, []gitlab.OptionFunc{gitlab.WithContext(ctx)})
+  if err != nil {
+    return nil, err
+  }
+
+  return m.apiClient.Do(req, v)
+}
+
+// parseGitlabVersion returns the major and minor version of a GitLab version like 16.5.1-ee
+func parseGitlabVersion(version string) (int, int, bool) {
+  parts := strings.SplitN(version, ".", 3)
+  if len(parts) < 2 {
+    return 0, 0, false
+  }
+
+  major, err := strconv.Atoi(parts[0])
+  if err != nil {
+    return 0, 0, false
+  }
+  minor, err := strconv.Atoi(parts[1])
+  if err != nil {
+    return 0, 0, false
+  }
+
+  return major, minor, true
+}
+
+// DoctorChecksFailed returns whether any of the checks failed
+func DoctorChecksFailed(checks []DoctorCheck) bool {
+  for _, check := range checks {
+    if check.Status == DoctorFailed {
+      return true
+    }
+  }
+
+  return false
+}
+
+// RenderDoctorChecks writes the checks as JSON for the json output format, as text for all others
+func RenderDoctorChecks(w io.Writer, format string, checks []DoctorCheck) error {
+  if format == OutputFormatJSON {
+    body, err := json.MarshalIndent(checks, "", "  ")
+    if err != nil {
+      return fmt.Errorf("failed to marshal doctor checks: %v", err)
+    }
+
+    _, err = fmt.Fprintf(w, "%s\n", body)
+    return err
+  }
+
+  fmt.Fprintf(w, "\nDOCTOR\n")
+  for _, check := range checks {
+    fmt.Fprintf(w, "  [%s] %s: %s\n", strings.ToUpper(check.Status), check.Name, check.Message)
+  }
+  _, err := fmt.Fprintf(w, "\n")
+  return err
+}
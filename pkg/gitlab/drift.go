@@ -0,0 +1,86 @@
+package gitlab
+
+import (
+  "encoding/json"
+  "fmt"
+  "reflect"
+)
+
+// settingDrift holds the current and desired value of a drifted setting
+type settingDrift struct {
+  From interface{}
+  To   interface{}
+}
+
+// computeDrift compares the settings set in desired (usually an API options struct,
+// whose unset fields are omitted when marshalled) with the matching fields of current
+// (the API resource) and returns the drifted settings keyed by their JSON name.
+func computeDrift(desired interface{}, current interface{}) (map[string]settingDrift, error) {
+  desiredMap, err := toJSONMap(desired)
+  if err != nil {
+    return nil, fmt.Errorf("failed to convert desired settings: %v", err)
+  }
+
+  currentMap, err := toJSONMap(current)
+  if err != nil {
+    return nil, fmt.Errorf("failed to convert current settings: %v", err)
+  }
+
+  drift := make(map[string]settingDrift)
+  for key, to := range desiredMap {
+    from := currentMap[key]
+    if !reflect.DeepEqual(from, to) {
+      drift[key] = settingDrift{From: from, To: to}
+    }
+  }
+
+  return drift, nil
+}
+
+// readableSettings returns the settings of desired which are returned by the read model current.
+// Some settings are write-only (e.g. import_url or packages_enabled of a project), GitLab never
+// returns them, so they would drift on every run.
+func readableSettings(desired interface{}, current interface{}) (map[string]interface{}, error) {
+  desiredMap, err := toJSONMap(desired)
+  if err != nil {
+    return nil, fmt.Errorf("failed to convert desired settings: %v", err)
+  }
+
+  currentMap, err := toJSONMap(current)
+  if err != nil {
+    return nil, fmt.Errorf("failed to convert current settings: %v", err)
+  }
+
+  for key := range desiredMap {
+    if _, ok := currentMap[key]; !ok {
+      delete(desiredMap, key)
+    }
+  }
+
+  return desiredMap, nil
+}
+
+// toJSONMap converts a struct into a map of its JSON representation
+func toJSONMap(v interface{}) (map[string]interface{}, error) {
+  jsonData, err := json.Marshal(v)
+  if err != nil {
+    return nil, err
+  }
+
+  result := make(map[string]interface{})
+  if err := json.Unmarshal(jsonData, &result); err != nil {
+    return nil, err
+  }
+
+  return result, nil
+}
+
+// fromJSONMap converts a map of JSON values into the struct v points to
+func fromJSONMap(values map[string]interface{}, v interface{}) error {
+  b, err := json.Marshal(values)
+  if err != nil {
+    return err
+  }
+
+  return json.Unmarshal(b, v)
+}
@@ -0,0 +1,36 @@
+package gitlab
+
+import (
+  "reflect"
+  "testing"
+
+  "github.com/xanzy/go-gitlab"
+)
+
+func TestReadableSettings(t *testing.T) {
+  options := &gitlab.EditProjectOptions{
+    MergeMethod:                              gitlab.MergeMethod(gitlab.FastForwardMerge),
+    ImportURL:                                gitlab.String("https://example.com/api.git"),
+    PackagesEnabled:                          gitlab.Bool(true),
+    ExternalAuthorizationClassificationLabel: gitlab.String("confidential"),
+  }
+  current := &gitlab.Project{MergeMethod: gitlab.NoFastForwardMerge}
+
+  readable, err := readableSettings(options, current)
+  if err != nil {
+    t.Fatalf("Expected no error, but got %v", err)
+  }
+
+  expected := map[string]interface{}{"merge_method": "ff"}
+  if !reflect.DeepEqual(readable, expected) {
+    t.Errorf("Expected readable settings %v, but got %v", expected, readable)
+  }
+
+  drift, err := computeDrift(readable, current)
+  if err != nil {
+    t.Fatalf("Expected no error, but got %v", err)
+  }
+  if len(drift) != 1 || drift["merge_method"].From != "merge" {
+    t.Errorf("Expected only merge_method to drift, but got %v", drift)
+  }
+}
@@ -0,0 +1,76 @@
+package gitlab
+
+import (
+  "fmt"
+  "io"
+  "sort"
+  "strings"
+  "time"
+
+  "github.com/xanzy/go-gitlab"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/config"
+)
+
+// applyExemptions removes the settings of all active exemptions of the project from policy
+// and records the expired ones
+func (m *ProjectManager) applyExemptions(project gitlab.Project, policy *config.Policy) (*config.Policy, error) {
+  active, expired := m.config.ExemptionsFor(project.PathWithNamespace, time.Now())
+
+  if len(expired) > 0 {
+    m.mu.Lock()
+    m.ExpiredExemptions[project.PathWithNamespace] = expired
+    m.mu.Unlock()
+  }
+
+  if len(active) == 0 {
+    return policy, nil
+  }
+
+  var settings []string
+  for _, e := range active {
+    m.logger.Debugf("Project %s is exempted from %s until %s", project.PathWithNamespace, strings.Join(e.Settings, ", "), e.Expires)
+    settings = append(settings, e.Settings...)
+  }
+
+  exempted, err := policy.Without(settings)
+  if err != nil {
+    return nil, fmt.Errorf("failed to apply exemptions to project %s: %v", project.PathWithNamespace, err)
+  }
+
+  return exempted, nil
+}
+
+// GenerateExemptionsReport writes the expired exemptions, whose settings are enforced again, to w
+func (m *ProjectManager) GenerateExemptionsReport(w io.Writer) error {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  if len(m.ExpiredExemptions) == 0 {
+    return nil
+  }
+
+  var project_names []string
+  for project_name := range m.ExpiredExemptions {
+    project_names = append(project_names, project_name)
+  }
+  sort.Strings(project_names)
+
+  fmt.Fprintf(w, "\nEXPIRED EXEMPTIONS\n")
+
+  for _, name := range project_names {
+    fmt.Fprintf(w, "  %s\n", name)
+
+    for _, e := range m.ExpiredExemptions[name] {
+      fmt.Fprintf(w, "    %s: expired %s", strings.Join(e.Settings, ", "), e.Expires)
+      if e.Reason != "" {
+        fmt.Fprintf(w, " (%s)", e.Reason)
+      }
+      fmt.Fprintf(w, "\n")
+    }
+
+    fmt.Fprintf(w, "\n")
+  }
+
+  return nil
+}
@@ -0,0 +1,42 @@
+package gitlab
+
+import (
+  "fmt"
+  "io"
+  "sort"
+)
+
+// AddFailure records an error raised while enforcing the project, for the failure report
+func (m *ProjectManager) AddFailure(project string, err error) {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  m.failures[project] = append(m.failures[project], err.Error())
+}
+
+// GenerateFailureReport writes the errors raised while enforcing projects, grouped by project, to
+// w. Nothing is written if no project failed.
+func (m *ProjectManager) GenerateFailureReport(w io.Writer) error {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  if len(m.failures) == 0 {
+    return nil
+  }
+
+  var projects []string
+  for project := range m.failures {
+    projects = append(projects, project)
+  }
+  sort.Strings(projects)
+
+  fmt.Fprintf(w, "\nFAILURES (%d project(s))\n", len(projects))
+  for _, project := range projects {
+    fmt.Fprintf(w, "  %s\n", project)
+    for _, failure := range m.failures[project] {
+      fmt.Fprintf(w, "    %s\n", failure)
+    }
+  }
+
+  return nil
+}
@@ -0,0 +1,54 @@
+package gitlab
+
+import (
+  "context"
+  "fmt"
+  "net/http"
+
+  "github.com/xanzy/go-gitlab"
+)
+
+// RemoveForkRelationship removes the relationship of forked projects to the project they were
+// forked from, if remove_fork_relationship is set. Merge requests into the upstream project aren't
+// possible afterwards.
+// https://docs.gitlab.com/ee/api/projects.html#delete-an-existing-forked-from-relationship
+func (m *ProjectManager) RemoveForkRelationship(ctx context.Context, project gitlab.Project, dryrun bool) error {
+  // Exit if nothing to configure
+  if !m.config.RemoveForkRelationship {
+    m.logger.Debugf("remove_fork_relationship not set in config")
+    return nil
+  }
+
+  if project.ForkedFromProject == nil {
+    m.logger.Debugf("No action required.")
+    return nil
+  }
+
+  upstream := project.ForkedFromProject.PathWithNamespace
+
+  if dryrun {
+    m.logSkippedCall(project, "DeleteProjectForkRelation", nil)
+    m.addChange(project.PathWithNamespace, "project", "forked_from", upstream, nil)
+    return nil
+  }
+
+  path := fmt.Sprintf("projects/%d/fork", project.ID)
+
+  req, err := m.apiClient.NewRequest(http.MethodDelete, path, nil, []gitlab.OptionFunc{gitlab.WithContext(ctx)})
+  if err != nil {
+    return fmt.Errorf("failed to create request to remove the fork relationship of project %s: %v", project.PathWithNamespace, err)
+  }
+  _, err = m.apiClient.Do(req, nil)
+  m.audit(project.PathWithNamespace, http.MethodDelete+" "+path, map[string]settingDrift{
+    "forked_from": {From: upstream},
+  }, err)
+  if err != nil {
+    return fmt.Errorf("failed to remove the fork relationship of project %s: %v", project.PathWithNamespace, err)
+  }
+
+  m.addChange(project.PathWithNamespace, "project", "forked_from", upstream, nil)
+
+  m.logger.Infof("Removed the fork relationship of project %s to %s.", project.PathWithNamespace, upstream)
+
+  return nil
+}
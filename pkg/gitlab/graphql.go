@@ -0,0 +1,95 @@
+package gitlab
+
+import (
+  "bytes"
+  "context"
+  "encoding/json"
+  "fmt"
+  "net/http"
+  "net/url"
+  "strings"
+)
+
+// GraphQLClient executes queries against the GitLab GraphQL API
+type GraphQLClient struct {
+  httpClient *http.Client
+  endpoint   string
+  token      string
+}
+
+type graphqlRequest struct {
+  Query     string                 `json:"query"`
+  Variables map[string]interface{} `json:"variables,omitempty"`
+}
+
+type graphqlResponse struct {
+  Data   json.RawMessage `json:"data"`
+  Errors []struct {
+    Message string `json:"message"`
+  } `json:"errors"`
+}
+
+// NewGraphQLClient returns a GraphQLClient talking to the GraphQL endpoint of the
+// GitLab instance serving the given REST API base URL (e.g. https://gitlab.com/api/v4/)
+func NewGraphQLClient(httpClient *http.Client, baseURL *url.URL, token string) *GraphQLClient {
+  if httpClient == nil {
+    httpClient = http.DefaultClient
+  }
+
+  endpoint := baseURL.ResolveReference(&url.URL{Path: "../graphql"})
+
+  return &GraphQLClient{
+    httpClient: httpClient,
+    endpoint:   endpoint.String(),
+    token:      token,
+  }
+}
+
+// Query executes the given query (or mutation) and unmarshals the data section of the response into v
+func (c *GraphQLClient) Query(ctx context.Context, query string, variables map[string]interface{}, v interface{}) error {
+  body, err := json.Marshal(graphqlRequest{Query: query, Variables: variables})
+  if err != nil {
+    return fmt.Errorf("failed to marshal graphql request: %v", err)
+  }
+
+  req, err := http.NewRequest(http.MethodPost, c.endpoint, bytes.NewReader(body))
+  if err != nil {
+    return fmt.Errorf("failed to create graphql request: %v", err)
+  }
+  req = req.WithContext(ctx)
+  req.Header.Set("Content-Type", "application/json")
+  req.Header.Set("Authorization", "Bearer "+c.token)
+
+  resp, err := c.httpClient.Do(req)
+  if err != nil {
+    return fmt.Errorf("failed to execute graphql request: %v", err)
+  }
+  defer resp.Body.Close()
+
+  if resp.StatusCode != http.StatusOK {
+    return fmt.Errorf("graphql request returned unexpected status code %d", resp.StatusCode)
+  }
+
+  var result graphqlResponse
+  if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+    return fmt.Errorf("failed to decode graphql response: %v", err)
+  }
+
+  if len(result.Errors) > 0 {
+    var messages []string
+    for _, e := range result.Errors {
+      messages = append(messages, e.Message)
+    }
+    return fmt.Errorf("graphql request failed: %s", strings.Join(messages, "; "))
+  }
+
+  if v == nil {
+    return nil
+  }
+
+  if err := json.Unmarshal(result.Data, v); err != nil {
+    return fmt.Errorf("failed to unmarshal graphql data: %v", err)
+  }
+
+  return nil
+}
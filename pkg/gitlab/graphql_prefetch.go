@@ -0,0 +1,171 @@
+package gitlab
+
+import (
+  "context"
+  "encoding/json"
+  "fmt"
+  "strconv"
+  "strings"
+
+  "github.com/iancoleman/strcase"
+  "github.com/xanzy/go-gitlab"
+)
+
+// graphqlBatchSize is the number of projects fetched per GraphQL query (the maximum page size)
+const graphqlBatchSize = 100
+
+// graphqlProjectFields lists the project settings fetched in bulk, by their GraphQL name.
+// Their snake_case form matches the name used by the REST API, unless renamed by graphqlRESTNames.
+var graphqlProjectFields = []string{
+  "archived",
+  "autocloseReferencedIssues",
+  "containerRegistryEnabled",
+  "description",
+  "issuesEnabled",
+  "jobsEnabled",
+  "lfsEnabled",
+  "mergeRequestsEnabled",
+  "onlyAllowMergeIfAllDiscussionsAreResolved",
+  "onlyAllowMergeIfPipelineSucceeds",
+  "printingMergeRequestLinkEnabled",
+  "publicJobs",
+  "removeSourceBranchAfterMerge",
+  "requestAccessEnabled",
+  "sharedRunnersEnabled",
+  "snippetsEnabled",
+  "visibility",
+  "wikiEnabled",
+}
+
+// graphqlRESTNames maps the GraphQL names of project settings named differently by the REST API
+var graphqlRESTNames = map[string]string{
+  "publicJobs": "public_builds",
+}
+
+const graphqlProjectsQuery = `query($ids: [ID!], $first: Int) {
+  projects(ids: $ids, first: $first) {
+    nodes {
+      id
+      %s
+    }
+  }
+}`
+
+// PrefetchProjectSettings fetches the project settings of all given projects in bulk using
+// the GraphQL API. GetProjectSettings returns the prefetched settings instead of calling the
+// REST API, until the settings of a project are updated.
+func (m *ProjectManager) PrefetchProjectSettings(ctx context.Context, projects []gitlab.Project) error {
+  m.logger.Debugf("Prefetching project settings of %d project(s) ...", len(projects))
+
+  byID := make(map[int]gitlab.Project)
+  for _, p := range projects {
+    byID[p.ID] = p
+  }
+
+  query := fmt.Sprintf(graphqlProjectsQuery, strings.Join(graphqlProjectFields, "\n      "))
+
+  for start := 0; start < len(projects); start += graphqlBatchSize {
+    end := start + graphqlBatchSize
+    if end > len(projects) {
+      end = len(projects)
+    }
+
+    var ids []string
+    for _, p := range projects[start:end] {
+      ids = append(ids, fmt.Sprintf("gid://gitlab/Project/%d", p.ID))
+    }
+
+    var result struct {
+      Projects struct {
+        Nodes []map[string]interface{} `json:"nodes"`
+      } `json:"projects"`
+    }
+
+    variables := map[string]interface{}{"ids": ids, "first": graphqlBatchSize}
+    if err := m.graphqlClient.Query(ctx, query, variables, &result); err != nil {
+      return fmt.Errorf("failed to prefetch project settings: %v", err)
+    }
+
+    for _, node := range result.Projects.Nodes {
+      id, err := graphqlProjectID(node["id"])
+      if err != nil {
+        return err
+      }
+
+      listed, ok := byID[id]
+      if !ok {
+        continue
+      }
+
+      settings, err := overlayGraphQLSettings(listed, node)
+      if err != nil {
+        return fmt.Errorf("failed to convert prefetched settings of project %s: %v", listed.PathWithNamespace, err)
+      }
+
+      m.mu.Lock()
+      m.prefetchedSettings[id] = settings
+      m.mu.Unlock()
+    }
+  }
+
+  m.logger.Debugf("Prefetching project settings done.")
+
+  return nil
+}
+
+// prefetchedProjectSettings returns the prefetched settings of the project, if any
+func (m *ProjectManager) prefetchedProjectSettings(project gitlab.Project) (*gitlab.Project, bool) {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  settings, ok := m.prefetchedSettings[project.ID]
+  return settings, ok
+}
+
+// forgetPrefetchedProjectSettings drops the prefetched settings of a project once they are outdated
+func (m *ProjectManager) forgetPrefetchedProjectSettings(project gitlab.Project) {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  delete(m.prefetchedSettings, project.ID)
+}
+
+// graphqlProjectID extracts the numeric ID of a GraphQL global project ID (gid://gitlab/Project/<id>)
+func graphqlProjectID(v interface{}) (int, error) {
+  gid, _ := v.(string)
+
+  id, err := strconv.Atoi(gid[strings.LastIndex(gid, "/")+1:])
+  if err != nil {
+    return 0, fmt.Errorf("invalid graphql project id %q", gid)
+  }
+
+  return id, nil
+}
+
+// overlayGraphQLSettings returns a copy of the listed project with the settings of the GraphQL node
+// applied. Settings not available in GraphQL keep the value returned by the project listing.
+func overlayGraphQLSettings(listed gitlab.Project, node map[string]interface{}) (*gitlab.Project, error) {
+  settings := make(map[string]interface{})
+  for key, value := range node {
+    if key == "id" {
+      continue
+    }
+    if name, ok := graphqlRESTNames[key]; ok {
+      settings[name] = value
+      continue
+    }
+    settings[strcase.ToSnake(key)] = value
+  }
+
+  b, err := json.Marshal(settings)
+  if err != nil {
+    return nil, err
+  }
+
+  project := listed
+  if err := json.Unmarshal(b, &project); err != nil {
+    return nil, err
+  }
+
+  return &project, nil
+}
@@ -0,0 +1,35 @@
+package gitlab
+
+import (
+  "testing"
+
+  "github.com/xanzy/go-gitlab"
+)
+
+func TestOverlayGraphQLSettings(t *testing.T) {
+  listed := gitlab.Project{ID: 1, PathWithNamespace: "example/project", PublicBuilds: false, IssuesEnabled: false}
+  node := map[string]interface{}{
+    "id":            "gid://gitlab/Project/1",
+    "publicJobs":    true,
+    "issuesEnabled": true,
+    "visibility":    "private",
+  }
+
+  project, err := overlayGraphQLSettings(listed, node)
+  if err != nil {
+    t.Fatalf("Expected no error, but got %v", err)
+  }
+
+  if !project.PublicBuilds {
+    t.Errorf("Expected publicJobs to set public_builds, but it didn't")
+  }
+  if !project.IssuesEnabled {
+    t.Errorf("Expected issuesEnabled to set issues_enabled, but it didn't")
+  }
+  if project.Visibility != gitlab.PrivateVisibility {
+    t.Errorf("Expected visibility %q, but got %q", gitlab.PrivateVisibility, project.Visibility)
+  }
+  if project.PathWithNamespace != listed.PathWithNamespace {
+    t.Errorf("Expected the listed path %q to be kept, but got %q", listed.PathWithNamespace, project.PathWithNamespace)
+  }
+}
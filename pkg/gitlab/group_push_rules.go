@@ -0,0 +1,95 @@
+package gitlab
+
+import (
+  "context"
+  "fmt"
+  "net/http"
+
+  "github.com/xanzy/go-gitlab"
+)
+
+// UpdateGroupPushRules applies the group_push_rules section to the configured groups,
+// so they cascade to all projects created within it.
+// https://docs.gitlab.com/ee/api/groups.html#push-rules
+func (m *ProjectManager) UpdateGroupPushRules(ctx context.Context, dryrun bool) error {
+  // Exit if nothing to configure
+  if m.config.GroupPushRules == nil {
+    m.logger.Debugf("No group_push_rules section provided in config")
+    return nil
+  }
+
+  for _, group := range m.config.GroupNames() {
+    if err := m.updateGroupPushRules(ctx, group, dryrun); err != nil {
+      return err
+    }
+  }
+
+  return nil
+}
+
+// updateGroupPushRules applies the group_push_rules section to a single group
+func (m *ProjectManager) updateGroupPushRules(ctx context.Context, group string, dryrun bool) error {
+  m.logger.Debugf("Updating push rules of group %s ...", group)
+
+  groupID, err := m.getGroupID(ctx, group)
+  if err != nil {
+    return err
+  }
+
+  path := fmt.Sprintf("groups/%d/push_rule", groupID)
+
+  // Get current push rules, GitLab answers 404 if none are defined yet
+  current := make(map[string]interface{})
+  method := http.MethodPut
+
+  req, err := m.apiClient.NewRequest(http.MethodGet, path, nil, []gitlab.OptionFunc{gitlab.WithContext(ctx)})
+  if err != nil {
+    return fmt.Errorf("failed to create request for push rules of group %s: %v", group, err)
+  }
+  if resp, err := m.apiClient.Do(req, &current); err != nil {
+    if resp == nil || resp.StatusCode != http.StatusNotFound {
+      return fmt.Errorf("failed to get push rules of group %s: %v", group, err)
+    }
+    method = http.MethodPost
+  }
+
+  drift, err := computeDrift(m.config.GroupPushRules, current)
+  if err != nil {
+    return err
+  }
+
+  if len(drift) == 0 {
+    m.logger.Debugf("No action required.")
+    return nil
+  }
+
+  m.logger.Debugf("---[ HTTP Payload for UpdateGroupPushRules ]---\n")
+  m.logger.Debugf("%+v\n", m.config.GroupPushRules)
+
+  if dryrun {
+    m.logger.Infof("DRYRUN: Skipped executing API call [%s %s]", method, path)
+    m.addDrift(group, "group_push_rules", drift)
+    return nil
+  }
+
+  req, err = m.apiClient.NewRequest(method, path, m.config.GroupPushRules, []gitlab.OptionFunc{gitlab.WithContext(ctx)})
+  if err != nil {
+    return fmt.Errorf("failed to create request for push rules of group %s: %v", group, err)
+  }
+  resp, err := m.apiClient.Do(req, nil)
+  m.audit(group, method+" "+path, drift, err)
+  if err != nil && m.skipUnavailable(unavailableFeature("group_push_rules", group, resp)) {
+    return nil
+  }
+  if err != nil {
+    return fmt.Errorf("failed to update push rules of group %s: %v", group, err)
+  }
+
+  for setting, values := range drift {
+    m.addChange(group, "group_push_rules", setting, values.From, values.To)
+  }
+
+  m.logger.Debugf("Updating push rules of group %s done.", group)
+
+  return nil
+}
@@ -0,0 +1,112 @@
+package gitlab
+
+import (
+  "context"
+  "fmt"
+  "net/http"
+
+  "github.com/xanzy/go-gitlab"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/config"
+)
+
+// groupDefaults holds the default related attributes of the Groups API, read from the
+// response and sent as the payload of updates
+type groupDefaults struct {
+  DefaultBranchProtection *int    `json:"default_branch_protection,omitempty"`
+  DefaultBranch           *string `json:"default_branch,omitempty"`
+}
+
+// newGroupDefaults converts the group_settings section into the attributes of the Groups API,
+// which names the default branch default_branch
+func newGroupDefaults(settings *config.GroupSettings) groupDefaults {
+  return groupDefaults{
+    DefaultBranchProtection: settings.DefaultBranchProtection,
+    DefaultBranch:           settings.DefaultBranchName,
+  }
+}
+
+// UpdateGroupSettings applies the group_settings section to the configured groups,
+// so newly created projects inherit compliant defaults.
+// https://docs.gitlab.com/ee/api/groups.html#update-group
+func (m *ProjectManager) UpdateGroupSettings(ctx context.Context, dryrun bool) error {
+  // Exit if nothing to configure
+  if m.config.GroupSettings == nil {
+    m.logger.Debugf("No group_settings section provided in config")
+    return nil
+  }
+
+  for _, group := range m.config.GroupNames() {
+    if err := m.updateGroupSettings(ctx, group, dryrun); err != nil {
+      return err
+    }
+  }
+
+  return nil
+}
+
+// updateGroupSettings applies the group_settings section to a single group
+func (m *ProjectManager) updateGroupSettings(ctx context.Context, group string, dryrun bool) error {
+  m.logger.Debugf("Updating settings of group %s ...", group)
+
+  groupID, err := m.getGroupID(ctx, group)
+  if err != nil {
+    return err
+  }
+
+  path := fmt.Sprintf("groups/%d", groupID)
+
+  req, err := m.apiClient.NewRequest(http.MethodGet, path, nil, []gitlab.OptionFunc{gitlab.WithContext(ctx)})
+  if err != nil {
+    return fmt.Errorf("failed to create request for settings of group %s: %v", group, err)
+  }
+
+  var defaults groupDefaults
+  if _, err := m.apiClient.Do(req, &defaults); err != nil {
+    return fmt.Errorf("failed to get settings of group %s: %v", group, err)
+  }
+
+  current := config.GroupSettings{
+    DefaultBranchProtection: defaults.DefaultBranchProtection,
+    DefaultBranchName:       defaults.DefaultBranch,
+  }
+
+  drift, err := computeDrift(m.config.GroupSettings, current)
+  if err != nil {
+    return err
+  }
+
+  if len(drift) == 0 {
+    m.logger.Debugf("No action required.")
+    return nil
+  }
+
+  payload := newGroupDefaults(m.config.GroupSettings)
+
+  m.logger.Debugf("---[ HTTP Payload for UpdateGroupSettings ]---\n")
+  m.logger.Debugf("%+v\n", payload)
+
+  if dryrun {
+    m.logger.Infof("DRYRUN: Skipped executing API call [UpdateGroup]")
+    m.addDrift(group, "group_settings", drift)
+    return nil
+  }
+
+  req, err = m.apiClient.NewRequest(http.MethodPut, path, payload, []gitlab.OptionFunc{gitlab.WithContext(ctx)})
+  if err != nil {
+    return fmt.Errorf("failed to create request for settings of group %s: %v", group, err)
+  }
+  _, err = m.apiClient.Do(req, nil)
+  m.audit(group, http.MethodPut+" "+path, drift, err)
+  if err != nil {
+    return fmt.Errorf("failed to update settings of group %s: %v", group, err)
+  }
+
+  for setting, values := range drift {
+    m.addChange(group, "group_settings", setting, values.From, values.To)
+  }
+
+  m.logger.Debugf("Updating settings of group %s done.", group)
+
+  return nil
+}
@@ -0,0 +1,93 @@
+package gitlab
+
+import (
+  "context"
+  "encoding/json"
+  "io/ioutil"
+  "net/http"
+  "reflect"
+  "testing"
+
+  "github.com/xanzy/go-gitlab"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/config"
+)
+
+func TestNewGroupDefaultsRoundTrip(t *testing.T) {
+  settings := &config.GroupSettings{
+    DefaultBranchProtection: gitlab.Int(2),
+    DefaultBranchName:       gitlab.String("main"),
+  }
+
+  body, err := json.Marshal(newGroupDefaults(settings))
+  if err != nil {
+    t.Fatalf("Failed to marshal payload: %v", err)
+  }
+
+  var attributes map[string]interface{}
+  if err := json.Unmarshal(body, &attributes); err != nil {
+    t.Fatalf("Failed to unmarshal payload: %v", err)
+  }
+  expected := map[string]interface{}{
+    "default_branch_protection": float64(2),
+    "default_branch":            "main",
+  }
+  if !reflect.DeepEqual(attributes, expected) {
+    t.Errorf("Expected payload %v, but got %v", expected, attributes)
+  }
+
+  var defaults groupDefaults
+  if err := json.Unmarshal(body, &defaults); err != nil {
+    t.Fatalf("Failed to unmarshal payload: %v", err)
+  }
+  if !reflect.DeepEqual(defaults, newGroupDefaults(settings)) {
+    t.Errorf("Expected payload to read back as %+v, but got %+v", newGroupDefaults(settings), defaults)
+  }
+}
+
+func TestUpdateGroupSettingsPayload(t *testing.T) {
+  var payload map[string]interface{}
+
+  mux := http.NewServeMux()
+  mux.HandleFunc("/api/v4/groups/example", func(w http.ResponseWriter, r *http.Request) {
+    w.Write([]byte(`{"id": 1, "full_path": "example"}`))
+  })
+  mux.HandleFunc("/api/v4/groups/1", func(w http.ResponseWriter, r *http.Request) {
+    switch r.Method {
+    case http.MethodGet:
+      w.Write([]byte(`{"id": 1, "default_branch_protection": 2, "default_branch": "master"}`))
+    case http.MethodPut:
+      body, _ := ioutil.ReadAll(r.Body)
+      if err := json.Unmarshal(body, &payload); err != nil {
+        t.Errorf("Failed to unmarshal payload %s: %v", body, err)
+      }
+      w.Write([]byte(`{"id": 1}`))
+    }
+  })
+
+  manager, closeServer := newTestProjectManager(t, mux, &config.Config{
+    GroupName: "example",
+    GroupSettings: &config.GroupSettings{
+      DefaultBranchProtection: gitlab.Int(2),
+      DefaultBranchName:       gitlab.String("main"),
+    },
+  })
+  defer closeServer()
+
+  if err := manager.UpdateGroupSettings(context.Background(), false); err != nil {
+    t.Fatalf("Failed to update group settings: %v", err)
+  }
+
+  expected := map[string]interface{}{
+    "default_branch_protection": float64(2),
+    "default_branch":            "main",
+  }
+  if !reflect.DeepEqual(payload, expected) {
+    t.Errorf("Expected payload %v, but got %v", expected, payload)
+  }
+
+  changes := manager.changes["example"]["group_settings"]
+  if len(changes) != 1 || changes["default_branch_name"] == nil {
+    t.Errorf("Expected only default_branch_name to be changed, but got %v", changes)
+  }
+}
@@ -0,0 +1,129 @@
+package gitlab
+
+import (
+  "fmt"
+  "html/template"
+  "io"
+  "time"
+)
+
+// htmlReportTemplate renders a standalone page without external resources, so it can be
+// shared as a single file. Clicking a table header sorts the table by that column.
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>GitLab Settings Enforcer Report{{if .Planned}} (planned){{end}}</title>
+<style>
+  body { font-family: sans-serif; margin: 2em; color: #333; }
+  table { border-collapse: collapse; margin-bottom: 2em; }
+  th, td { border: 1px solid #ccc; padding: 0.3em 0.8em; text-align: left; }
+  th { background: #f0f0f0; cursor: pointer; }
+  td.value { font-family: monospace; }
+  .summary td:first-child { font-weight: bold; }
+</style>
+</head>
+<body>
+<h1>GitLab Settings Enforcer Report{{if .Planned}} (planned){{end}}</h1>{{if .Planned}}
+<p>Dryrun: these changes have not been applied.</p>{{end}}
+<table class="summary">
+  <tr><td>Generated</td><td>{{.Generated}}</td></tr>
+  <tr><td>Changed projects</td><td>{{len .Projects}}</td></tr>
+  <tr><td>Changed settings</td><td>{{.Settings}}</td></tr>
+</table>
+{{if .Projects}}
+<h2>Projects</h2>
+<table class="sortable">
+  <thead><tr><th>Project</th><th>Changed settings</th></tr></thead>
+  <tbody>
+  {{range .Projects}}<tr><td><a href="#{{.Name}}">{{.Name}}</a></td><td>{{len .Changes}}</td></tr>
+  {{end}}</tbody>
+</table>
+{{range .Projects}}
+<h2 id="{{.Name}}">{{.Name}}</h2>
+<table class="sortable">
+  <thead><tr><th>Section</th><th>Setting</th><th>From</th><th>To</th></tr></thead>
+  <tbody>
+  {{range .Changes}}<tr><td>{{.Subsection}}</td><td>{{.Setting}}</td><td class="value">{{.From}}</td><td class="value">{{.To}}</td></tr>
+  {{end}}</tbody>
+</table>
+{{end}}
+{{else}}
+<p>No changes discovered.</p>
+{{end}}
+<script>
+document.querySelectorAll("table.sortable").forEach(function(table) {
+  table.querySelectorAll("th").forEach(function(th, column) {
+    var ascending = true;
+    th.addEventListener("click", function() {
+      var tbody = table.tBodies[0];
+      var rows = Array.prototype.slice.call(tbody.rows);
+      rows.sort(function(a, b) {
+        var x = a.cells[column].textContent, y = b.cells[column].textContent;
+        var cmp = isNaN(x) || isNaN(y) ? x.localeCompare(y) : x - y;
+        return ascending ? cmp : -cmp;
+      });
+      ascending = !ascending;
+      rows.forEach(function(row) { tbody.appendChild(row); });
+    });
+  });
+});
+</script>
+</body>
+</html>
+`))
+
+type htmlReport struct {
+  Planned   bool
+  Generated string
+  Settings  int
+  Projects  []htmlReportProject
+}
+
+type htmlReportProject struct {
+  Name    string
+  Changes []htmlReportChange
+}
+
+type htmlReportChange struct {
+  Subsection string
+  Setting    string
+  From       string
+  To         string
+}
+
+// htmlRenderer renders the change log as standalone HTML page, with a summary, a section per
+// project and sortable tables
+type htmlRenderer struct {
+  planned bool
+}
+
+// Render implements ChangeLogRenderer
+func (r htmlRenderer) Render(w io.Writer, changelog ChangeLog) error {
+  report := htmlReport{Planned: r.planned, Generated: time.Now().Format(time.RFC1123)}
+
+  for _, name := range changelog.projectNames() {
+    project := htmlReportProject{Name: name}
+
+    for _, subsection := range changelog.subsections(name) {
+      for _, setting := range changelog.settings(name, subsection) {
+        change := changelog[name][subsection][setting]
+        project.Changes = append(project.Changes, htmlReportChange{
+          Subsection: subsection,
+          Setting:    setting,
+          From:       FormatValue(change["From"]),
+          To:         FormatValue(change["To"]),
+        })
+      }
+    }
+
+    report.Settings += len(project.Changes)
+    report.Projects = append(report.Projects, project)
+  }
+
+  if err := htmlReportTemplate.Execute(w, report); err != nil {
+    return fmt.Errorf("failed to render html report: %v", err)
+  }
+
+  return nil
+}
@@ -0,0 +1,60 @@
+package gitlab
+
+import (
+  "context"
+  "fmt"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/config"
+  "github.com/xanzy/go-gitlab"
+)
+
+const defaultInitialCommitMessage = "Initial commit"
+
+// initializeRepository creates the initial commit configured in initial_commit on the given
+// branch of an empty repository, which creates the branch and makes it the default branch.
+// Without configured files a README.md carrying the project name is committed.
+func (m *ProjectManager) initializeRepository(ctx context.Context, project gitlab.Project, branch string, dryrun bool) error {
+  settings := m.config.InitialCommit
+
+  files := settings.Files
+  if len(files) == 0 {
+    files = []config.RepositoryFile{{Path: "README.md", Content: "# " + project.Name + "\n"}}
+  }
+
+  var actions []*gitlab.CommitAction
+  for _, f := range files {
+    actions = append(actions, &gitlab.CommitAction{Action: gitlab.FileCreate, FilePath: f.Path, Content: f.Content})
+  }
+
+  opt := &gitlab.CreateCommitOptions{
+    Branch:        gitlab.String(branch),
+    CommitMessage: gitlab.String(defaultInitialCommitMessage),
+    Actions:       actions,
+  }
+  if settings.CommitMessage != "" {
+    opt.CommitMessage = gitlab.String(settings.CommitMessage)
+  }
+  if settings.AuthorName != "" {
+    opt.AuthorName = gitlab.String(settings.AuthorName)
+  }
+  if settings.AuthorEmail != "" {
+    opt.AuthorEmail = gitlab.String(settings.AuthorEmail)
+  }
+
+  m.logger.Infof("Initializing empty repository of project %s on branch %s ...", project.PathWithNamespace, branch)
+
+  if dryrun {
+    m.logSkippedCall(project, "CreateCommit", opt)
+  } else {
+    _, _, err := m.commitsClient.CreateCommit(project.ID, opt, gitlab.WithContext(ctx))
+    m.audit(project.PathWithNamespace, fmt.Sprintf("POST projects/%d/repository/commits", project.ID), repositoryFilesDrift(opt), err)
+    if err != nil {
+      return fmt.Errorf("failed to initialize empty repository of project %s: %v", project.PathWithNamespace, err)
+    }
+  }
+
+  // Dryruns record the planned initialization
+  m.addChange(project.PathWithNamespace, "repository", "initial_commit", nil, branch)
+
+  return nil
+}
@@ -0,0 +1,93 @@
+package gitlab
+
+import (
+  "context"
+  "fmt"
+  "net/http"
+
+  "github.com/xanzy/go-gitlab"
+)
+
+// instanceChangeLogKey is used in place of a project path for instance level changes
+const instanceChangeLogKey = "(instance)"
+
+// UpdateInstanceSettings applies the instance_settings section via the Application Settings API.
+// This requires a token with administrator rights.
+// https://docs.gitlab.com/ee/api/settings.html
+func (m *ProjectManager) UpdateInstanceSettings(ctx context.Context, dryrun bool) error {
+  // Exit if nothing to configure
+  if m.config.InstanceSettings == nil {
+    m.logger.Debugf("No instance_settings section provided in config")
+    return nil
+  }
+
+  m.logger.Debugf("Updating instance settings ...")
+
+  // go-gitlab neither reads nor writes the terms of service and the outbound request settings,
+  // so the settings are requested directly
+  req, err := m.apiClient.NewRequest(http.MethodGet, "application/settings", nil, []gitlab.OptionFunc{gitlab.WithContext(ctx)})
+  if err != nil {
+    return fmt.Errorf("failed to create request for instance settings: %v", err)
+  }
+
+  current := make(map[string]interface{})
+  resp, err := m.apiClient.Do(req, &current)
+  if err != nil {
+    if resp != nil && resp.StatusCode == http.StatusForbidden {
+      return fmt.Errorf("failed to get current instance settings: token has no administrator rights")
+    }
+    return fmt.Errorf("failed to get current instance settings: %v", err)
+  }
+
+  drift, err := computeDrift(m.config.InstanceSettings, current)
+  if err != nil {
+    return err
+  }
+
+  if len(drift) == 0 {
+    m.logger.Debugf("No action required.")
+    return nil
+  }
+
+  m.logger.Debugf("---[ HTTP Payload for UpdateInstanceSettings ]---\n")
+  m.logger.Debugf("%+v\n", m.config.InstanceSettings)
+
+  if dryrun {
+    m.logger.Infof("DRYRUN: Skipped executing API call [UpdateSettings]")
+    m.addDrift(instanceChangeLogKey, "instance_settings", drift)
+    return nil
+  }
+
+  req, err = m.apiClient.NewRequest(http.MethodPut, "application/settings", m.config.InstanceSettings, []gitlab.OptionFunc{gitlab.WithContext(ctx)})
+  if err != nil {
+    return fmt.Errorf("failed to create request for instance settings: %v", err)
+  }
+
+  updated := make(map[string]interface{})
+  response, err := m.apiClient.Do(req, &updated)
+  m.audit(instanceChangeLogKey, http.MethodPut+" application/settings", drift, err)
+
+  m.logger.Debugf("---[ HTTP Response for UpdateInstanceSettings ]---\n")
+  m.logger.Debugf("%v\n", response)
+
+  if err != nil {
+    return fmt.Errorf("failed to update instance settings: %v", err)
+  }
+
+  // Record the values actually returned by GitLab
+  applied, err := computeDrift(m.config.InstanceSettings, updated)
+  if err != nil {
+    return err
+  }
+  for setting, values := range drift {
+    to := values.To
+    if notApplied, ok := applied[setting]; ok {
+      to = notApplied.From
+    }
+    m.addChange(instanceChangeLogKey, "instance_settings", setting, values.From, to)
+  }
+
+  m.logger.Debugf("Updating instance settings done.")
+
+  return nil
+}
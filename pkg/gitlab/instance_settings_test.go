@@ -0,0 +1,61 @@
+package gitlab
+
+import (
+  "context"
+  "encoding/json"
+  "io/ioutil"
+  "net/http"
+  "reflect"
+  "testing"
+
+  "github.com/xanzy/go-gitlab"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/config"
+)
+
+func TestUpdateInstanceSettingsTermsAndOutboundRequests(t *testing.T) {
+  var payload map[string]interface{}
+
+  mux := http.NewServeMux()
+  mux.HandleFunc("/api/v4/application/settings", func(w http.ResponseWriter, r *http.Request) {
+    switch r.Method {
+    case http.MethodGet:
+      w.Write([]byte(`{"signup_enabled": false, "enforce_terms": false, "terms": "", "outbound_local_requests_whitelist": ["gitlab.example.com"]}`))
+    case http.MethodPut:
+      body, _ := ioutil.ReadAll(r.Body)
+      if err := json.Unmarshal(body, &payload); err != nil {
+        t.Errorf("Failed to unmarshal payload %s: %v", body, err)
+      }
+      w.Write([]byte(`{"signup_enabled": false, "enforce_terms": true, "terms": "Be nice.", "outbound_local_requests_whitelist": ["gitlab.example.com"]}`))
+    }
+  })
+
+  settings := &config.InstanceSettings{
+    EnforceTerms:                   gitlab.Bool(true),
+    Terms:                          gitlab.String("Be nice."),
+    OutboundLocalRequestsWhitelist: []string{"gitlab.example.com"},
+  }
+  settings.SignupEnabled = gitlab.Bool(false)
+
+  manager, closeServer := newTestProjectManager(t, mux, &config.Config{InstanceSettings: settings})
+  defer closeServer()
+
+  if err := manager.UpdateInstanceSettings(context.Background(), false); err != nil {
+    t.Fatalf("Failed to update instance settings: %v", err)
+  }
+
+  expected := map[string]interface{}{
+    "signup_enabled":                    false,
+    "enforce_terms":                     true,
+    "terms":                             "Be nice.",
+    "outbound_local_requests_whitelist": []interface{}{"gitlab.example.com"},
+  }
+  if !reflect.DeepEqual(payload, expected) {
+    t.Errorf("Expected payload %v, but got %v", expected, payload)
+  }
+
+  changes := manager.changes[instanceChangeLogKey]["instance_settings"]
+  if len(changes) != 2 || changes["enforce_terms"] == nil || changes["terms"] == nil {
+    t.Errorf("Expected only enforce_terms and terms to be changed, but got %v", changes)
+  }
+}
@@ -0,0 +1,49 @@
+package gitlab
+
+import (
+  "encoding/json"
+  "fmt"
+  "io"
+  "text/tabwriter"
+
+  "github.com/xanzy/go-gitlab"
+)
+
+// listedProject is a project as listed by RenderProjects
+type listedProject struct {
+  ID         int    `json:"id"`
+  Path       string `json:"path"`
+  Visibility string `json:"visibility"`
+  Archived   bool   `json:"archived"`
+}
+
+// RenderProjects writes the ID, path, visibility and archive state of the projects as JSON for
+// the json output format, as text table for all others
+func RenderProjects(w io.Writer, format string, projects []gitlab.Project) error {
+  listed := make([]listedProject, 0, len(projects))
+  for _, p := range projects {
+    listed = append(listed, listedProject{ID: p.ID, Path: p.PathWithNamespace, Visibility: string(p.Visibility), Archived: p.Archived})
+  }
+
+  if format == OutputFormatJSON {
+    body, err := json.MarshalIndent(listed, "", "  ")
+    if err != nil {
+      return fmt.Errorf("failed to marshal projects: %v", err)
+    }
+
+    _, err = fmt.Fprintf(w, "%s\n", body)
+    return err
+  }
+
+  tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+  fmt.Fprintf(tw, "ID\tPATH\tVISIBILITY\tARCHIVED\n")
+  for _, p := range listed {
+    fmt.Fprintf(tw, "%d\t%s\t%s\t%t\n", p.ID, p.Path, p.Visibility, p.Archived)
+  }
+  if err := tw.Flush(); err != nil {
+    return err
+  }
+
+  _, err := fmt.Fprintf(w, "\n%d project(s) matched.\n", len(listed))
+  return err
+}
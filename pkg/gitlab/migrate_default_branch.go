@@ -0,0 +1,206 @@
+package gitlab
+
+import (
+  "context"
+  "fmt"
+  "net/http"
+
+  "github.com/xanzy/go-gitlab"
+)
+
+// MigrateDefaultBranch moves the default branch of the project as configured in
+// default_branch_migration: the new branch is created from the old one, takes over its
+// protection and becomes the default branch, open merge requests into the old branch are
+// retargeted and, if configured, the old branch is deleted. Projects whose default branch isn't
+// the old branch are left alone, so migrations can be repeated until all projects succeeded.
+func (m *ProjectManager) MigrateDefaultBranch(ctx context.Context, project gitlab.Project, dryrun bool) error {
+  migration := m.config.DefaultBranchMigration
+  from, to := migration.From, migration.To
+
+  if project.DefaultBranch != from {
+    m.logger.Infof("Skipping project %s, its default branch is %s.", project.PathWithNamespace, project.DefaultBranch)
+    return nil
+  }
+
+  m.logger.Debugf("Migrating default branch of project %s from %s to %s ...", project.PathWithNamespace, from, to)
+
+  if err := m.createMigrationBranch(ctx, project, from, to, dryrun); err != nil {
+    return err
+  }
+
+  // The new branch is protected before it becomes the default branch
+  if err := m.moveBranchProtection(ctx, project, from, to, dryrun); err != nil {
+    return err
+  }
+
+  opt := &gitlab.EditProjectOptions{DefaultBranch: gitlab.String(to)}
+  if dryrun {
+    m.logSkippedCall(project, "EditProject", opt)
+  } else {
+    _, _, err := m.projectsClient.EditProject(project.ID, opt, gitlab.WithContext(ctx))
+    m.audit(project.PathWithNamespace, fmt.Sprintf("PUT projects/%d", project.ID), map[string]settingDrift{
+      "default_branch": {From: from, To: to},
+    }, err)
+    if err != nil {
+      return fmt.Errorf("failed to change default branch of project %s to %s: %v", project.PathWithNamespace, to, err)
+    }
+  }
+  m.addChange(project.PathWithNamespace, "project_settings", "default_branch", from, to)
+
+  if err := m.retargetMergeRequests(ctx, project, from, to, dryrun); err != nil {
+    return err
+  }
+
+  if !migration.DeleteOldBranch {
+    return nil
+  }
+
+  if dryrun {
+    m.logger.Infof("DRYRUN: Skipped executing API call [DeleteBranch] on project %s.", project.PathWithNamespace)
+  } else {
+    _, err := m.branchesClient.DeleteBranch(project.ID, from, gitlab.WithContext(ctx))
+    m.audit(project.PathWithNamespace, fmt.Sprintf("DELETE projects/%d/repository/branches", project.ID), map[string]settingDrift{
+      "branch": {From: from},
+    }, err)
+    if err != nil {
+      return fmt.Errorf("failed to delete branch %s of project %s: %v", from, project.PathWithNamespace, err)
+    }
+  }
+  m.addChange(project.PathWithNamespace, "branches", from+".exists", true, false)
+
+  m.logger.Debugf("Migrating default branch of project %s done.", project.PathWithNamespace)
+
+  return nil
+}
+
+// createMigrationBranch creates the branch to from the branch from, unless it exists already
+func (m *ProjectManager) createMigrationBranch(ctx context.Context, project gitlab.Project, from string, to string, dryrun bool) error {
+  _, resp, err := m.branchesClient.GetBranch(project.ID, to, gitlab.WithContext(ctx))
+  if err == nil {
+    m.logger.Debugf("Branch %s of project %s already exists.", to, project.PathWithNamespace)
+    return nil
+  }
+  if resp == nil || resp.StatusCode != http.StatusNotFound {
+    return fmt.Errorf("failed to check for branch %s of project %s: %v", to, project.PathWithNamespace, err)
+  }
+
+  opt := &gitlab.CreateBranchOptions{Branch: gitlab.String(to), Ref: gitlab.String(from)}
+  if dryrun {
+    m.logSkippedCall(project, "CreateBranch", opt)
+  } else {
+    _, _, err := m.branchesClient.CreateBranch(project.ID, opt, gitlab.WithContext(ctx))
+    m.audit(project.PathWithNamespace, fmt.Sprintf("POST projects/%d/repository/branches", project.ID), map[string]settingDrift{
+      "branch": {To: to},
+    }, err)
+    if err != nil {
+      return fmt.Errorf("failed to create branch %s of project %s: %v", to, project.PathWithNamespace, err)
+    }
+  }
+  m.addChange(project.PathWithNamespace, "branches", to+".exists", false, true)
+
+  return nil
+}
+
+// moveBranchProtection protects the branch to with the access levels of the branch from, unless
+// it's protected already, and unprotects the branch from. Wildcard protections are left alone.
+func (m *ProjectManager) moveBranchProtection(ctx context.Context, project gitlab.Project, from string, to string, dryrun bool) error {
+  current, err := m.listProtectedBranches(ctx, project.ID)
+  if err != nil {
+    return fmt.Errorf("failed to list protected branches of project %s: %v", project.PathWithNamespace, err)
+  }
+
+  var old *gitlab.ProtectedBranch
+  var protected bool
+  for _, b := range current {
+    switch b.Name {
+    case from:
+      old = b
+    case to:
+      protected = true
+    }
+  }
+  if old == nil {
+    m.logger.Debugf("Branch %s of project %s isn't protected, no protection to move.", from, project.PathWithNamespace)
+    return nil
+  }
+
+  if !protected {
+    opt := &gitlab.ProtectRepositoryBranchesOptions{
+      Name:             gitlab.String(to),
+      PushAccessLevel:  firstAccessLevel(old.PushAccessLevels),
+      MergeAccessLevel: firstAccessLevel(old.MergeAccessLevels),
+    }
+    drift := branchProtectionDrift(to, opt, nil)
+
+    if dryrun {
+      m.logSkippedCall(project, "ProtectRepositoryBranches", opt)
+    } else {
+      _, _, err := m.protectedBranchesClient.ProtectRepositoryBranches(project.ID, opt, gitlab.WithContext(ctx))
+      m.audit(project.PathWithNamespace, fmt.Sprintf("POST projects/%d/protected_branches", project.ID), drift, err)
+      if err != nil {
+        return fmt.Errorf("failed to protect branch %s of project %s: %v", to, project.PathWithNamespace, err)
+      }
+    }
+    m.addDrift(project.PathWithNamespace, "protected_branches", drift)
+  }
+
+  if dryrun {
+    m.logger.Infof("DRYRUN: Skipped executing API call [UnprotectRepositoryBranches] on project %s.", project.PathWithNamespace)
+  } else {
+    _, err := m.protectedBranchesClient.UnprotectRepositoryBranches(project.ID, from, gitlab.WithContext(ctx))
+    m.audit(project.PathWithNamespace, fmt.Sprintf("DELETE projects/%d/protected_branches", project.ID), map[string]settingDrift{
+      from + ".protected": {From: true, To: false},
+    }, err)
+    if err != nil {
+      return fmt.Errorf("failed to unprotect branch %s of project %s: %v", from, project.PathWithNamespace, err)
+    }
+  }
+  m.addChange(project.PathWithNamespace, "protected_branches", from+".protected", true, false)
+
+  return nil
+}
+
+// retargetMergeRequests changes the target branch of all open merge requests into the branch
+// from to the branch to
+func (m *ProjectManager) retargetMergeRequests(ctx context.Context, project gitlab.Project, from string, to string, dryrun bool) error {
+  var mergeRequests []*gitlab.MergeRequest
+
+  opt := &gitlab.ListProjectMergeRequestsOptions{
+    ListOptions:  gitlab.ListOptions{PerPage: 100, Page: 1},
+    State:        gitlab.String("opened"),
+    TargetBranch: gitlab.String(from),
+  }
+  for {
+    page, resp, err := m.mergeRequestsClient.ListProjectMergeRequests(project.ID, opt, gitlab.WithContext(ctx))
+    if err != nil {
+      return fmt.Errorf("failed to list merge requests of project %s: %v", project.PathWithNamespace, err)
+    }
+    mergeRequests = append(mergeRequests, page...)
+
+    if resp.NextPage == 0 {
+      break
+    }
+    opt.Page = resp.NextPage
+  }
+
+  for _, mr := range mergeRequests {
+    setting := fmt.Sprintf("!%d.target_branch", mr.IID)
+
+    if dryrun {
+      m.logger.Infof("DRYRUN: Skipped executing API call [UpdateMergeRequest] of !%d on project %s.", mr.IID, project.PathWithNamespace)
+    } else {
+      _, _, err := m.mergeRequestsClient.UpdateMergeRequest(project.ID, mr.IID, &gitlab.UpdateMergeRequestOptions{
+        TargetBranch: gitlab.String(to),
+      }, gitlab.WithContext(ctx))
+      m.audit(project.PathWithNamespace, fmt.Sprintf("PUT projects/%d/merge_requests/%d", project.ID, mr.IID), map[string]settingDrift{
+        setting: {From: from, To: to},
+      }, err)
+      if err != nil {
+        return fmt.Errorf("failed to retarget merge request !%d of project %s: %v", mr.IID, project.PathWithNamespace, err)
+      }
+    }
+    m.addChange(project.PathWithNamespace, "merge_requests", setting, from, to)
+  }
+
+  return nil
+}
@@ -0,0 +1,169 @@
+package gitlab
+
+import (
+  "encoding/json"
+  "fmt"
+  "io"
+  "io/ioutil"
+  "net/http"
+  "net/url"
+  "strings"
+  "sync"
+  "time"
+)
+
+// oauthRefreshMargin refreshes access tokens this long before they expire
+const oauthRefreshMargin = 30 * time.Second
+
+// OAuthRefresh holds what is needed to refresh an expired OAuth2 access token
+type OAuthRefresh struct {
+  RefreshToken string
+  ClientID     string
+  ClientSecret string
+  // OnRefresh is called with the new refresh token after each refresh, as GitLab revokes
+  // the used one. It may be nil.
+  OnRefresh func(refreshToken string) error
+}
+
+// OAuthTransport is an http.RoundTripper authenticating requests with an OAuth2 access token.
+// If a refresh token is set, the access token is refreshed before it expires and whenever
+// GitLab rejects it.
+type OAuthTransport struct {
+  base        http.RoundTripper
+  tokenURL    string
+  refreshable bool
+
+  mu          sync.Mutex
+  refresh     OAuthRefresh
+  accessToken string
+  expiry      time.Time
+}
+
+type oauthTokenResponse struct {
+  AccessToken  string `json:"access_token"`
+  RefreshToken string `json:"refresh_token"`
+  ExpiresIn    int    `json:"expires_in"`
+}
+
+// NewOAuthTransport returns an OAuthTransport sending requests through base, refreshing tokens
+// at the OAuth token endpoint of the GitLab instance serving the given REST API base URL
+func NewOAuthTransport(base http.RoundTripper, baseURL *url.URL, accessToken string, refresh OAuthRefresh) *OAuthTransport {
+  if base == nil {
+    base = http.DefaultTransport
+  }
+
+  tokenURL := baseURL.ResolveReference(&url.URL{Path: "../../oauth/token"})
+
+  return &OAuthTransport{
+    base:        base,
+    tokenURL:    tokenURL.String(),
+    refreshable: refresh.RefreshToken != "",
+    refresh:     refresh,
+    accessToken: accessToken,
+  }
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *OAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+  token, err := t.token(req, false)
+  if err != nil {
+    return nil, err
+  }
+
+  resp, err := t.base.RoundTrip(t.authorize(req, token))
+  if err != nil || resp.StatusCode != http.StatusUnauthorized || !t.refreshable {
+    return resp, err
+  }
+
+  // The body of the first attempt has been consumed
+  if req.Body != nil && req.GetBody == nil {
+    return resp, nil
+  }
+
+  io.Copy(ioutil.Discard, resp.Body)
+  resp.Body.Close()
+
+  token, err = t.token(req, true)
+  if err != nil {
+    return nil, err
+  }
+
+  retry := t.authorize(req, token)
+  if req.GetBody != nil {
+    if retry.Body, err = req.GetBody(); err != nil {
+      return nil, err
+    }
+  }
+
+  return t.base.RoundTrip(retry)
+}
+
+// authorize returns a copy of req carrying the access token
+func (t *OAuthTransport) authorize(req *http.Request, token string) *http.Request {
+  authorized := req.WithContext(req.Context())
+  authorized.Header = make(http.Header, len(req.Header))
+  for k, v := range req.Header {
+    authorized.Header[k] = v
+  }
+  authorized.Header.Set("Authorization", "Bearer "+token)
+
+  return authorized
+}
+
+// token returns the current access token, refreshing it first if there is none yet, it expires
+// soon or force is set
+func (t *OAuthTransport) token(req *http.Request, force bool) (string, error) {
+  t.mu.Lock()
+  defer t.mu.Unlock()
+
+  expiring := t.accessToken == "" || (!t.expiry.IsZero() && time.Now().Add(oauthRefreshMargin).After(t.expiry))
+  if !t.refreshable || (!force && !expiring) {
+    return t.accessToken, nil
+  }
+
+  form := url.Values{
+    "grant_type":    {"refresh_token"},
+    "refresh_token": {t.refresh.RefreshToken},
+    "client_id":     {t.refresh.ClientID},
+    "client_secret": {t.refresh.ClientSecret},
+  }
+
+  refreshReq, err := http.NewRequest(http.MethodPost, t.tokenURL, strings.NewReader(form.Encode()))
+  if err != nil {
+    return "", fmt.Errorf("failed to create oauth token request: %v", err)
+  }
+  refreshReq = refreshReq.WithContext(req.Context())
+  refreshReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+  resp, err := t.base.RoundTrip(refreshReq)
+  if err != nil {
+    return "", fmt.Errorf("failed to refresh oauth token: %v", err)
+  }
+  defer resp.Body.Close()
+
+  if resp.StatusCode != http.StatusOK {
+    return "", fmt.Errorf("failed to refresh oauth token: unexpected status code %d", resp.StatusCode)
+  }
+
+  var result oauthTokenResponse
+  if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+    return "", fmt.Errorf("failed to decode oauth token response: %v", err)
+  }
+
+  t.accessToken = result.AccessToken
+  t.expiry = time.Time{}
+  if result.ExpiresIn > 0 {
+    t.expiry = time.Now().Add(time.Duration(result.ExpiresIn) * time.Second)
+  }
+
+  if result.RefreshToken != "" && result.RefreshToken != t.refresh.RefreshToken {
+    t.refresh.RefreshToken = result.RefreshToken
+    if t.refresh.OnRefresh != nil {
+      if err := t.refresh.OnRefresh(result.RefreshToken); err != nil {
+        return "", err
+      }
+    }
+  }
+
+  return t.accessToken, nil
+}
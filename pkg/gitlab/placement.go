@@ -0,0 +1,79 @@
+package gitlab
+
+import (
+  "context"
+  "fmt"
+  "net/http"
+  "strings"
+
+  "github.com/xanzy/go-gitlab"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/internal/stringslice"
+)
+
+// transferProjectOptions holds the target of a project transfer
+type transferProjectOptions struct {
+  Namespace string `json:"namespace"`
+}
+
+// EnsureProjectPlacement transfers the project into the namespace of the first project_placement
+// entry it matches, if it is located elsewhere. Without allowTransfer the misplacement is only
+// reported as policy violation, as transfers change the path of the project.
+// https://docs.gitlab.com/ee/api/projects.html#transfer-a-project-to-a-new-namespace
+func (m *ProjectManager) EnsureProjectPlacement(ctx context.Context, project gitlab.Project, allowTransfer bool, dryrun bool) error {
+  // Exit if nothing to configure
+  if len(m.config.ProjectPlacement) == 0 {
+    m.logger.Debugf("No project_placement section provided in config")
+    return nil
+  }
+
+  var namespace string
+  for _, placement := range m.config.ProjectPlacement {
+    if stringslice.MatchAny(project.PathWithNamespace, placement.Projects) {
+      namespace = placement.Namespace
+      break
+    }
+  }
+  if namespace == "" {
+    m.logger.Debugf("Placement of project %s isn't configured.", project.PathWithNamespace)
+    return nil
+  }
+
+  current := project.PathWithNamespace[:strings.LastIndex(project.PathWithNamespace, "/")]
+  if current == namespace {
+    m.logger.Debugf("No action required.")
+    return nil
+  }
+
+  if !allowTransfer {
+    m.addViolation(project.PathWithNamespace, "project_placement: project belongs in %s, transfer it with --allow-transfer", namespace)
+    return nil
+  }
+
+  opt := &transferProjectOptions{Namespace: namespace}
+  if dryrun {
+    m.logSkippedCall(project, "TransferProject", opt)
+    m.addChange(project.PathWithNamespace, "project", "namespace", current, namespace)
+    return nil
+  }
+
+  path := fmt.Sprintf("projects/%d/transfer", project.ID)
+
+  req, err := m.apiClient.NewRequest(http.MethodPut, path, opt, []gitlab.OptionFunc{gitlab.WithContext(ctx)})
+  if err != nil {
+    return fmt.Errorf("failed to create request to transfer project %s: %v", project.PathWithNamespace, err)
+  }
+  _, err = m.apiClient.Do(req, nil)
+  m.audit(project.PathWithNamespace, http.MethodPut+" "+path, map[string]settingDrift{
+    "namespace": {From: current, To: namespace},
+  }, err)
+  if err != nil {
+    return fmt.Errorf("failed to transfer project %s to %s: %v", project.PathWithNamespace, namespace, err)
+  }
+
+  m.addChange(project.PathWithNamespace, "project", "namespace", current, namespace)
+
+  m.logger.Infof("Project %s transferred to %s.", project.PathWithNamespace, namespace)
+
+  return nil
+}
@@ -0,0 +1,116 @@
+package gitlab
+
+import (
+  "context"
+  "fmt"
+  "io"
+  "sort"
+  "strings"
+
+  "github.com/xanzy/go-gitlab"
+)
+
+// accessLevelNames names the access levels in messages
+var accessLevelNames = map[gitlab.AccessLevelValue]string{
+  gitlab.NoPermissions:         "no",
+  gitlab.GuestPermissions:      "guest",
+  gitlab.ReporterPermissions:   "reporter",
+  gitlab.DeveloperPermissions:  "developer",
+  gitlab.MaintainerPermissions: "maintainer",
+  gitlab.OwnerPermissions:      "owner",
+}
+
+// Preflight verifies, before any project is enforced, that the token has the api scope and
+// sufficient access on all configured groups, as checked by Doctor. It records the user of the
+// token for CheckProjectPermission.
+func (m *ProjectManager) Preflight(ctx context.Context) error {
+  var user doctorUser
+  if _, err := m.doctorGet(ctx, "user", &user); err != nil {
+    return fmt.Errorf("failed to get the user of the token: %v", err)
+  }
+
+  checks := []DoctorCheck{m.doctorScopes(ctx)}
+  for _, group := range m.config.GroupNames() {
+    checks = append(checks, m.doctorGroupAccess(ctx, group, user))
+  }
+
+  var failures []string
+  for _, check := range checks {
+    switch check.Status {
+    case DoctorFailed:
+      failures = append(failures, check.Name+": "+check.Message)
+    case DoctorWarning:
+      m.logger.Warnf("Preflight %s: %s", check.Name, check.Message)
+    }
+  }
+  if len(failures) > 0 {
+    return fmt.Errorf("preflight failed: %s", strings.Join(failures, "; "))
+  }
+
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  m.tokenUser = &user
+
+  return nil
+}
+
+// CheckProjectPermission returns false and records the project as skipped if the user of the
+// token has less than maintainer access on it. The access is taken from the permissions listed
+// with the project; projects without them, all projects of administrators and all projects
+// before Preflight ran are assumed to be accessible.
+func (m *ProjectManager) CheckProjectPermission(project gitlab.Project) bool {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  if m.tokenUser == nil || m.tokenUser.IsAdmin || project.Permissions == nil {
+    return true
+  }
+
+  var level gitlab.AccessLevelValue
+  known := false
+  if access := project.Permissions.ProjectAccess; access != nil {
+    level, known = access.AccessLevel, true
+  }
+  if access := project.Permissions.GroupAccess; access != nil && access.AccessLevel > level {
+    level, known = access.AccessLevel, true
+  }
+  if !known || level >= gitlab.MaintainerPermissions {
+    return true
+  }
+
+  name, ok := accessLevelNames[level]
+  if !ok {
+    name = fmt.Sprintf("level %d", level)
+  }
+  reason := fmt.Sprintf("%s access, maintainer required", name)
+
+  m.logger.Warnf("Skipping project %s: no permission (%s).", project.PathWithNamespace, reason)
+  m.noPermission[project.PathWithNamespace] = reason
+
+  return false
+}
+
+// GenerateNoPermissionReport writes the projects skipped for lack of permission to w. Nothing is
+// written if all projects were accessible.
+func (m *ProjectManager) GenerateNoPermissionReport(w io.Writer) error {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  if len(m.noPermission) == 0 {
+    return nil
+  }
+
+  var projects []string
+  for project := range m.noPermission {
+    projects = append(projects, project)
+  }
+  sort.Strings(projects)
+
+  fmt.Fprintf(w, "\nNO PERMISSION\n")
+  for _, project := range projects {
+    fmt.Fprintf(w, "  %s: skipped: no permission (%s)\n", project, m.noPermission[project])
+  }
+
+  return nil
+}
@@ -0,0 +1,62 @@
+package gitlab
+
+import (
+  "time"
+
+  "github.com/xanzy/go-gitlab"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/internal/stringslice"
+)
+
+// isProjectSelected returns whether the project passes all project filters set in the config
+func (m *ProjectManager) isProjectSelected(p gitlab.Project) bool {
+  if len(m.config.ProjectWhitelist) > 0 && !stringslice.MatchAny(p.PathWithNamespace, m.config.ProjectWhitelist) {
+    m.logger.Debugf("Skipping repo %s as it's not whitelisted", p.PathWithNamespace)
+    return false
+  }
+  if stringslice.MatchAny(p.PathWithNamespace, m.config.ProjectBlacklist) {
+    m.logger.Debugf("Skipping repo %s as it's blacklisted", p.PathWithNamespace)
+    return false
+  }
+
+  filters := m.config.ProjectFilters
+  if filters.SkipArchived && p.Archived {
+    m.logger.Debugf("Skipping repo %s as it's archived", p.PathWithNamespace)
+    return false
+  }
+  if len(filters.Visibility) > 0 && !stringslice.Contains(string(p.Visibility), filters.Visibility) {
+    m.logger.Debugf("Skipping repo %s as its visibility is %s", p.PathWithNamespace, p.Visibility)
+    return false
+  }
+  if filters.MaxInactiveDays > 0 && p.LastActivityAt != nil {
+    inactiveSince := time.Now().AddDate(0, 0, -filters.MaxInactiveDays)
+    if p.LastActivityAt.Before(inactiveSince) {
+      m.logger.Debugf("Skipping repo %s as it has been inactive since %s", p.PathWithNamespace, p.LastActivityAt.Format(time.RFC3339))
+      return false
+    }
+  }
+
+  if filter := m.config.ProjectTopicsFilter; filter != nil {
+    if len(filter.Include) > 0 && !containsAny(p.TagList, filter.Include) {
+      m.logger.Debugf("Skipping repo %s as it carries none of the included topics", p.PathWithNamespace)
+      return false
+    }
+    if containsAny(p.TagList, filter.Exclude) {
+      m.logger.Debugf("Skipping repo %s as it carries an excluded topic", p.PathWithNamespace)
+      return false
+    }
+  }
+
+  return true
+}
+
+// containsAny returns whether any of elems is contained in slice
+func containsAny(slice []string, elems []string) bool {
+  for _, e := range elems {
+    if stringslice.Contains(e, slice) {
+      return true
+    }
+  }
+
+  return false
+}
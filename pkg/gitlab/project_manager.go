@@ -1,38 +1,78 @@
 package gitlab
 
 import (
+  "bytes"
+  "context"
   "encoding/json"
+  "errors"
   "fmt"
+  "io"
   "net/http"
   "net/url"
   "net/smtp"
   "reflect"
-  "regexp"
   "sort"
   "strconv"
   "strings"
+  "sync"
 
   "github.com/iancoleman/strcase"
   "github.com/r3labs/diff"
   "github.com/sirupsen/logrus"
   "github.com/xanzy/go-gitlab"
 
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/audit"
   "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/config"
-  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/internal/stringslice"
 )
 
-// ProjectManager fetches a list of repositories from GitLab
+// ProjectManager fetches a list of repositories from GitLab.
+// Its methods are safe for concurrent use; the exported result maps must not be accessed
+// directly while projects are being processed.
 type ProjectManager struct {
-  logger                   *logrus.Entry
-  groupsClient             groupsClient
-  projectsClient           projectsClient
-  protectedBranchesClient  protectedBranchesClient
-  branchesClient           branchesClient
-  config                   *config.Config
-  ApprovalSettingsOriginal map[string]*gitlab.ProjectApprovals
-  ApprovalSettingsUpdated  map[string]*gitlab.ProjectApprovals
-  ProjectSettingsOriginal  map[string]*gitlab.Project
-  ProjectSettingsUpdated   map[string]*gitlab.Project
+  mu                        sync.Mutex
+  failed                    bool
+  allowDestructive          bool
+  groupsDiscovered          bool
+  auditSink                 audit.Sink
+  auditActor                string
+  logger                    *logrus.Entry
+  groupsClient              groupsClient
+  projectsClient            projectsClient
+  protectedBranchesClient   protectedBranchesClient
+  branchesClient            branchesClient
+  repositoryFilesClient     repositoryFilesClient
+  commitsClient             commitsClient
+  mergeRequestsClient       mergeRequestsClient
+  apiClient                 apiClient
+  graphqlClient             graphqlClient
+  config                    *config.Config
+  changes                   ChangeLog
+  notApplied                ChangeLog
+  destructive               map[string][]destructiveChange
+  unavailable               map[string][]string
+  noPermission              map[string]string
+  failures                  map[string][]string
+  tokenUser                 *doctorUser
+  complianceFrameworkIDs    map[string]string
+  policies                  map[string]*config.Policy
+  groupIDs                  map[string]int
+  groupIDPath               string
+  prefetchedSettings        map[int]*gitlab.Project
+  fetchedApprovalSettings   map[int]*gitlab.ProjectApprovals
+  enforced                  map[string]map[string]EnforcedSetting
+  ApprovalSettingsOriginal  map[string]*gitlab.ProjectApprovals
+  ApprovalSettingsUpdated   map[string]*gitlab.ProjectApprovals
+  ProjectSettingsOriginal   map[string]*gitlab.Project
+  ProjectSettingsUpdated    map[string]*gitlab.Project
+  ProtectedBranchesOriginal map[string][]*gitlab.ProtectedBranch
+  ProtectedBranchesUpdated  map[string][]*gitlab.ProtectedBranch
+  RequiredFilesAudit        map[string]map[string]bool
+  BranchCoverage            map[string]map[string][]string
+  StaleBranches             map[string][]StaleBranch
+  ScanningCoverage          map[string]map[string]bool
+  AllowedValuesViolations   map[string]map[string]interface{}
+  Violations                map[string][]string
+  ExpiredExemptions         map[string][]config.Exemption
 }
 
 // NewProjectManager returns a new ProjectManager instance
@@ -42,19 +82,50 @@ func NewProjectManager(
   projectsClient projectsClient,
   protectedBranchesClient protectedBranchesClient,
   branchesClient branchesClient,
-  config *config.Config,
+  repositoryFilesClient repositoryFilesClient,
+  commitsClient commitsClient,
+  mergeRequestsClient mergeRequestsClient,
+  apiClient apiClient,
+  graphqlClient graphqlClient,
+  cfg *config.Config,
 ) *ProjectManager {
   return &ProjectManager{
-    logger:                   logger,
-    groupsClient:             groupsClient,
-    projectsClient:           projectsClient,
-    protectedBranchesClient:  protectedBranchesClient,
-    branchesClient:           branchesClient,
-    config:                   config,
-    ApprovalSettingsOriginal: make(map[string]*gitlab.ProjectApprovals),
-    ApprovalSettingsUpdated:  make(map[string]*gitlab.ProjectApprovals),
-    ProjectSettingsOriginal:  make(map[string]*gitlab.Project),
-    ProjectSettingsUpdated:   make(map[string]*gitlab.Project),
+    logger:                    logger,
+    groupsClient:              groupsClient,
+    projectsClient:            projectsClient,
+    protectedBranchesClient:   protectedBranchesClient,
+    branchesClient:            branchesClient,
+    repositoryFilesClient:     repositoryFilesClient,
+    commitsClient:             commitsClient,
+    mergeRequestsClient:       mergeRequestsClient,
+    apiClient:                 apiClient,
+    graphqlClient:             graphqlClient,
+    config:                    cfg,
+    changes:                   make(ChangeLog),
+    notApplied:                make(ChangeLog),
+    destructive:               make(map[string][]destructiveChange),
+    unavailable:               make(map[string][]string),
+    noPermission:              make(map[string]string),
+    failures:                  make(map[string][]string),
+    complianceFrameworkIDs:    make(map[string]string),
+    policies:                  make(map[string]*config.Policy),
+    groupIDs:                  make(map[string]int),
+    prefetchedSettings:        make(map[int]*gitlab.Project),
+    fetchedApprovalSettings:   make(map[int]*gitlab.ProjectApprovals),
+    enforced:                  make(map[string]map[string]EnforcedSetting),
+    ApprovalSettingsOriginal:  make(map[string]*gitlab.ProjectApprovals),
+    ApprovalSettingsUpdated:   make(map[string]*gitlab.ProjectApprovals),
+    ProjectSettingsOriginal:   make(map[string]*gitlab.Project),
+    ProjectSettingsUpdated:    make(map[string]*gitlab.Project),
+    ProtectedBranchesOriginal: make(map[string][]*gitlab.ProtectedBranch),
+    ProtectedBranchesUpdated:  make(map[string][]*gitlab.ProtectedBranch),
+    RequiredFilesAudit:        make(map[string]map[string]bool),
+    BranchCoverage:            make(map[string]map[string][]string),
+    StaleBranches:             make(map[string][]StaleBranch),
+    ScanningCoverage:          make(map[string]map[string]bool),
+    AllowedValuesViolations:   make(map[string]map[string]interface{}),
+    Violations:                make(map[string][]string),
+    ExpiredExemptions:         make(map[string][]config.Exemption),
   }
 }
 
@@ -78,33 +149,106 @@ func (m *ProjectManager) ComplianceReady() bool {
 // EnsureBranchesAndProtection ensures that
 //  1) the default branch exists
 //  2) all of the protected branches are configured correctly
-func (m *ProjectManager) EnsureBranchesAndProtection(project gitlab.Project, dryrun bool) error {
-  if err := m.ensureDefaultBranch(project, dryrun); err != nil {
+func (m *ProjectManager) EnsureBranchesAndProtection(ctx context.Context, project gitlab.Project, dryrun bool) error {
+  policy, err := m.policyFor(ctx, project)
+  if err != nil {
     return err
   }
 
-  for _, b := range m.config.ProtectedBranches {
-    if dryrun {
-      m.logger.Infof("DRYRUN: Skipped executing API call [UnprotectRepositoryBranches] on %v branch.", b.Name)
-      m.logger.Infof("DRYRUN: Skipped executing API call [ProtectRepositoryBranches] on %v branch.", b.Name)
-      continue
-    }
+  if err := m.ensureDefaultBranch(ctx, project, policy, dryrun); err != nil {
+    return err
+  }
 
-    // Remove protections (if present)
-    if resp, err := m.protectedBranchesClient.UnprotectRepositoryBranches(project.ID, b.Name); err != nil && resp.StatusCode != http.StatusNotFound {
-      return fmt.Errorf("failed to unprotect branch %v before protection: %v", b.Name, err)
-    }
+  var patterns []string
+  for _, b := range policy.ProtectedBranches {
+    patterns = append(patterns, b.Name)
+  }
+  if err := m.recordBranchCoverage(ctx, project, patterns); err != nil {
+    return err
+  }
 
+  current, err := m.listProtectedBranches(ctx, project.ID)
+  if err != nil {
+    return fmt.Errorf("failed to list protected branches: %v", err)
+  }
+
+  // Record current protections states
+  m.mu.Lock()
+  m.ProtectedBranchesOriginal[project.PathWithNamespace] = current
+  m.mu.Unlock()
+
+  protected := make(map[string]*gitlab.ProtectedBranch)
+  for _, b := range current {
+    protected[b.Name] = b
+  }
+
+  applied := make(map[string]*gitlab.ProtectRepositoryBranchesOptions)
+  drifts := make(map[string]map[string]settingDrift)
+  for _, b := range policy.ProtectedBranches {
     opt := &gitlab.ProtectRepositoryBranchesOptions{
       Name:             gitlab.String(b.Name),
       PushAccessLevel:  b.PushAccessLevel.Value(),
       MergeAccessLevel: b.MergeAccessLevel.Value(),
     }
 
-    // (Re)add protections
-    if _, _, err := m.protectedBranchesClient.ProtectRepositoryBranches(project.ID, opt); err != nil {
-      return fmt.Errorf("failed to protect branch %s: %v", b.Name, err)
+    existing, isProtected := protected[b.Name]
+    drift := branchProtectionDrift(b.Name, opt, existing)
+    if isProtected && len(drift) == 0 {
+      m.logger.Debugf("Protection of branch %s of project %s is up to date.", b.Name, project.PathWithNamespace)
+      continue
+    }
+    if err := m.guardDestructive(project.PathWithNamespace, "protected_branches", drift, dryrun); err != nil {
+      return err
+    }
+
+    if dryrun {
+      m.logSkippedCall(project, "ProtectRepositoryBranches", opt)
+      m.addDrift(project.PathWithNamespace, "protected_branches", drift)
+      continue
+    }
+
+    if isProtected {
+      if err := m.updateBranchProtection(ctx, project, opt, drift); err != nil {
+        return err
+      }
+    } else {
+      _, _, err = m.protectedBranchesClient.ProtectRepositoryBranches(project.ID, opt, gitlab.WithContext(ctx))
+      m.audit(project.PathWithNamespace, fmt.Sprintf("POST projects/%d/protected_branches", project.ID), drift, err)
+      if err != nil {
+        return fmt.Errorf("failed to protect branch %s: %v", b.Name, err)
+      }
+    }
+    applied[b.Name], drifts[b.Name] = opt, drift
+  }
+
+  if len(applied) == 0 {
+    return nil
+  }
+
+  // Verify the protections took effect, only those are changes
+  current, err = m.listProtectedBranches(ctx, project.ID)
+  if err != nil {
+    return fmt.Errorf("failed to list protected branches after protection: %v", err)
+  }
+
+  m.mu.Lock()
+  m.ProtectedBranchesUpdated[project.PathWithNamespace] = current
+  m.mu.Unlock()
+
+  protected = make(map[string]*gitlab.ProtectedBranch)
+  for _, b := range current {
+    protected[b.Name] = b
+  }
+
+  for name, opt := range applied {
+    remaining := branchProtectionDrift(name, opt, protected[name])
+    for setting := range drifts[name] {
+      if _, ok := remaining[setting]; ok {
+        m.addNotApplied(project.PathWithNamespace, "protected_branches", setting, remaining[setting])
+        delete(drifts[name], setting)
+      }
     }
+    m.addDrift(project.PathWithNamespace, "protected_branches", drifts[name])
   }
 
   return nil
@@ -112,133 +256,91 @@ func (m *ProjectManager) EnsureBranchesAndProtection(project gitlab.Project, dry
 
 // GetError returns the Error status
 func (m *ProjectManager) GetError() (bool) {
-  return m.config.Error
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  return m.failed
 }
 
-// GenerateChangeLogReport to console the altered project settings
-func (m *ProjectManager) GenerateChangeLogReport() error {
-  m.logger.Debugf("Generate Change Log Report")
+// ChangeLog returns the altered project settings
+func (m *ProjectManager) ChangeLog() ChangeLog {
+  m.mu.Lock()
+  defer m.mu.Unlock()
 
-  if err := m.debugPrintAllSettings(); err != nil {
-    panic(err)
-  }
-
-  // Get differences
-  approvalDifflog, err := diff.Diff(m.ApprovalSettingsOriginal, m.ApprovalSettingsUpdated)
+  // Changes which can't be determined are missing, everything else is kept
+  changelog, err := m.changeLog()
   if err != nil {
-    panic(err)
-  }
-  projectDifflog, err := diff.Diff(m.ProjectSettingsOriginal, m.ProjectSettingsUpdated)
-  if err != nil {
-    panic(err)
+    m.logger.Errorf("failed to determine all changes: %v", err)
   }
 
-  m.logger.Debugf("---[ Approval Diff Log ]---")
-  m.logger.Debugf("%+v\n", approvalDifflog)
-  m.logger.Debugf("---[ Project Diff Log ]---")
-  m.logger.Debugf("%+v\n", projectDifflog)
+  return changelog
+}
 
-  changelog := make(map[string]map[string]map[string]map[string]interface{})
+// GenerateChangeLogReport writes the altered project settings to w using the given renderer.
+// If the changes can't be determined or rendered, a raw dump of the recorded settings is written
+// instead and the error is returned.
+func (m *ProjectManager) GenerateChangeLogReport(w io.Writer, renderer ChangeLogRenderer) error {
+  m.logger.Debugf("Generate Change Log Report")
 
-  // Process Approvals
-  m.logger.Debugf("Process Approval Diff Log")
-  for _, v := range approvalDifflog {
-    // If REPO doesn't exist in map, make it.
-    if _, ok := changelog[v.Path[0]]; ! ok {
-      changelog[v.Path[0]] = make(map[string]map[string]map[string]interface{})
-    }
-    if _, ok := changelog[v.Path[0]]["approval_settings"]; ! ok {
-      changelog[v.Path[0]]["approval_settings"] = make(map[string]map[string]interface{})
-    }
+  m.mu.Lock()
+  defer m.mu.Unlock()
 
-    setting_name := strcase.ToSnake(v.Path[len(v.Path)-1])
-    changelog[v.Path[0]]["approval_settings"][setting_name] = make(map[string]interface{})
-    changelog[v.Path[0]]["approval_settings"][setting_name]["From"] = v.From
-    changelog[v.Path[0]]["approval_settings"][setting_name]["To"] = v.To
-  }
+  m.debugPrintAllSettings()
 
-  // Process Projects
-  m.logger.Debugf("Process Project Diff Log")
-  for _, v := range projectDifflog {
-    // If REPO doesn't exist in map, make it.
-    if _, ok := changelog[v.Path[0]]; ! ok {
-      changelog[v.Path[0]] = make(map[string]map[string]map[string]interface{})
+  changelog, err := m.changeLog()
+  if err == nil {
+    // Rendered completely before anything is written, so a failure doesn't leave half a report
+    var body bytes.Buffer
+    if r, ok := renderer.(enforcedRenderer); ok {
+      err = r.RenderEnforced(&body, changelog, m.enforcedSettings())
+    } else {
+      err = renderer.Render(&body, changelog)
     }
-    if _, ok := changelog[v.Path[0]]["project_settings"]; ! ok {
-      changelog[v.Path[0]]["project_settings"] = make(map[string]map[string]interface{})
+    if err == nil {
+      _, err = w.Write(body.Bytes())
+      return err
     }
-
-    setting_name := strcase.ToSnake(v.Path[len(v.Path)-1])
-    changelog[v.Path[0]]["project_settings"][setting_name] = make(map[string]interface{})
-    changelog[v.Path[0]]["project_settings"][setting_name]["From"] = v.From
-    changelog[v.Path[0]]["project_settings"][setting_name]["To"] = v.To
   }
 
-  // Output Raw JSON
-  body, err := json.MarshalIndent(changelog, "", "  ")
-  if err != nil {
-    panic(err)
+  m.logger.Warnf("Writing a raw dump of the change log instead: %v", err)
+  if dumpErr := m.dumpChangeLog(w, changelog); dumpErr != nil {
+    return fmt.Errorf("failed to render change log: %v, and failed to dump it: %v", err, dumpErr)
   }
-  m.logger.Debugf("---[ Change Log (JSON) ]---")
-  m.logger.Debugf("%s\n", string(body))
 
-  if len(changelog) != 0 {
-    // Get longest length of setting name
-    var longest_setting_name int
-    var project_names []string
-    for project_name, subsections := range changelog {
-      // Add to list of project names to allow sorting
-      project_names = append(project_names, project_name)
-
-      for _, data := range subsections {
-        for setting := range data {
-          if len(setting) > longest_setting_name {
-            longest_setting_name = len(setting)
-          }
-        }
-      }
-    }
-    sort.Strings(project_names)
-
-    // Output Formated Report
-    fmt.Printf("\nCHANGE LOG\n")
-
-    for _, name := range project_names {
-      fmt.Printf("  %s\n", name)
-
-      var subsections []string
-      for subsection := range changelog[name] {
-        subsections = append(subsections, subsection)
-      }
-      sort.Strings(subsections)
+  return fmt.Errorf("failed to render change log, wrote a raw dump instead: %v", err)
+}
 
-      for _, subsection := range subsections {
-        var settings []string
-        for setting := range changelog[name][subsection] {
-          settings = append(settings, setting)
-        }
-        sort.Strings(settings)
+// dumpChangeLog writes the changes determined so far and the recorded settings as JSON, falling
+// back to Go syntax if they can't be marshaled
+func (m *ProjectManager) dumpChangeLog(w io.Writer, changelog ChangeLog) error {
+  dump := map[string]interface{}{
+    "changes":                     changelog,
+    "approval_settings_original":  m.ApprovalSettingsOriginal,
+    "approval_settings_updated":   m.ApprovalSettingsUpdated,
+    "project_settings_original":   m.ProjectSettingsOriginal,
+    "project_settings_updated":    m.ProjectSettingsUpdated,
+    "protected_branches_original": m.ProtectedBranchesOriginal,
+    "protected_branches_updated":  m.ProtectedBranchesUpdated,
+  }
 
-        for _, setting := range settings {
-          fmt.Printf("    %-*s", longest_setting_name+2, setting+":")
-          fmt.Printf("\"%v\" => \"%v\"\n", changelog[name][subsection][setting]["From"], changelog[name][subsection][setting]["To"])
-        }
-      }
+  fmt.Fprintf(w, "\nCHANGE LOG (raw dump)\n")
 
-      fmt.Printf("\n")
-    }
-  } else {
-    fmt.Printf("\nNo changes discovered.\n")
+  body, err := json.MarshalIndent(dump, "", "  ")
+  if err != nil {
+    _, err = fmt.Fprintf(w, "%+v\n", dump)
+    return err
   }
 
-  return nil
+  _, err = fmt.Fprintf(w, "%s\n", body)
+  return err
 }
 
 // GenerateComplianceEmail emails the compliance state of mandatory settings
 func (m *ProjectManager) GenerateComplianceEmail() error {
-  if err := m.debugPrintAllSettings(); err != nil {
-    panic(err)
-  }
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  m.debugPrintAllSettings()
 
   m.logger.Debugf("---[ Compliance Settings ]---")
   m.logger.Debugf("%v\n", m.config.Compliance)
@@ -274,6 +376,15 @@ func (m *ProjectManager) GenerateComplianceEmail() error {
     sort.Strings(settings[subsection])
   }
 
+  // Create sorted list of required files
+  required_files := append([]string{}, m.config.Compliance.RequiredFiles...)
+  for _, path := range required_files {
+    if len(path) > longest_setting_name {
+      longest_setting_name = len(path)
+    }
+  }
+  sort.Strings(required_files)
+
   // Print Title
   email_body := fmt.Sprintf("\r\n<h2>Compliance Report</h2>\r\n")
   email_body += fmt.Sprintf("<table>\r\n")
@@ -326,27 +437,43 @@ func (m *ProjectManager) GenerateComplianceEmail() error {
       }
     }
 
+    // Required files
+    if len(m.config.Compliance.RequiredFiles) > 0 {
+      email_body += fmt.Sprintf(" <tr>\r\n")
+      email_body += fmt.Sprintf("  <td style=\"text-indent:40px\"><b>required_files</b></td>\r\n")
+      email_body += fmt.Sprintf("  <td style=\"text-indent:40px\"><b>%s</b></td>\r\n", m.requiredFilesResult(name))
+      email_body += fmt.Sprintf(" </tr>\r\n")
+
+      for _, path := range required_files {
+        email_body += fmt.Sprintf(" <tr>\r\n")
+        email_body += fmt.Sprintf("  <td style=\"text-indent:60px\">%-*s</td>", longest_setting_name+2, path+":")
+        email_body += fmt.Sprintf("  <td style=\"text-indent:40px\">%s</td>\r\n", m.requiredFileState(name, path))
+        email_body += fmt.Sprintf(" </tr>\r\n")
+      }
+    }
+
     email_body += fmt.Sprintf("</table>\r\n")
   }
 
   if err := m.SendEmail(m.config.Compliance.Email.To, m.config.Compliance.Email.From, "Compliance Report", email_body); err != nil {
-    m.logger.Fatal(err)
+    return fmt.Errorf("failed to send compliance email: %v", err)
   }
 
   return nil
 }
 
-// GenerateComplianceReport prints to console the compliance state of mandatory settings
-func (m *ProjectManager) GenerateComplianceReport() error {
-  if err := m.debugPrintAllSettings(); err != nil {
-    panic(err)
-  }
+// GenerateComplianceReport writes the compliance state of mandatory settings to w
+func (m *ProjectManager) GenerateComplianceReport(w io.Writer) error {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  m.debugPrintAllSettings()
 
   m.logger.Debugf("---[ Compliance Settings ]---")
   m.logger.Debugf("%v\n", m.config.Compliance)
 
   // Print Title
-  fmt.Printf("\nCOMPLIANCE REPORT\n")
+  fmt.Fprintf(w, "\nCOMPLIANCE REPORT\n")
 
   // Create sorted list of projects
   var project_names []string
@@ -379,17 +506,26 @@ func (m *ProjectManager) GenerateComplianceReport() error {
     sort.Strings(settings[subsection])
   }
 
+  // Create sorted list of required files
+  required_files := append([]string{}, m.config.Compliance.RequiredFiles...)
+  for _, path := range required_files {
+    if len(path) > longest_setting_name {
+      longest_setting_name = len(path)
+    }
+  }
+  sort.Strings(required_files)
+
   // Loop through projects
   for _, name := range project_names {
-    fmt.Printf("  %s\n", name)
+    fmt.Fprintf(w, "  %s\n", name)
 
     // Loop through subsections
     for _, subsection := range subsections {
-      fmt.Printf("    %s:\n", subsection)
+      fmt.Fprintf(w, "    %s:\n", subsection)
 
       // Loop through settings
       for _, setting := range settings[subsection] {
-        fmt.Printf("      %-*s", longest_setting_name+2, setting+":")
+        fmt.Fprintf(w, "      %-*s", longest_setting_name+2, setting+":")
 
         var setting_value interface{}
         switch subsection {
@@ -411,28 +547,40 @@ func (m *ProjectManager) GenerateComplianceReport() error {
           }
         }
 
-        fmt.Printf("%v", setting_value)
+        fmt.Fprintf(w, "%v", setting_value)
 
         if setting_value != m.config.Compliance.Mandatory[subsection][setting] {
-          fmt.Printf(" (%v)", m.config.Compliance.Mandatory[subsection][setting])
+          fmt.Fprintf(w, " (%v)", m.config.Compliance.Mandatory[subsection][setting])
         }
 
-        fmt.Printf("\n")
+        fmt.Fprintf(w, "\n")
+      }
+    }
+
+    // Required files
+    if len(m.config.Compliance.RequiredFiles) > 0 {
+      fmt.Fprintf(w, "    required_files: %s\n", m.requiredFilesResult(name))
+
+      for _, path := range required_files {
+        fmt.Fprintf(w, "      %-*s%s\n", longest_setting_name+2, path+":", m.requiredFileState(name, path))
       }
     }
 
-    fmt.Printf("\n")
+    fmt.Fprintf(w, "\n")
   }
 
   return nil
 }
 
 // GetProjectMergeRequestSettings identifies the current state of a GitLab projece
-func (m *ProjectManager) GetProjectApprovalSettings(project gitlab.Project) (*gitlab.ProjectApprovals, error) {
+func (m *ProjectManager) GetProjectApprovalSettings(ctx context.Context, project gitlab.Project) (*gitlab.ProjectApprovals, error) {
   m.logger.Debugf("Get merge request approval settings of project %s ...", project.PathWithNamespace)
 
-  returned_approval, response, err := m.projectsClient.GetApprovalConfiguration(project.ID)
+  returned_approval, response, err := m.projectsClient.GetApprovalConfiguration(project.ID, gitlab.WithContext(ctx))
   if err != nil {
+    if unavailable := unavailableFeature("approval_settings", project.PathWithNamespace, response); unavailable != nil {
+      return nil, unavailable
+    }
     return nil, fmt.Errorf("failed to get current approval settings of project %s: %v", project.PathWithNamespace, err)
   }
 
@@ -444,65 +592,56 @@ func (m *ProjectManager) GetProjectApprovalSettings(project gitlab.Project) (*gi
   return returned_approval, nil
 }
 
-// GetProjects fetches a list of accessible repos within the groups set in config file
-func (m *ProjectManager) GetProjects() ([]gitlab.Project, error) {
-  var repos []gitlab.Project
+// GetProjects fetches a list of accessible repos within the groups and user namespaces set in config
+// file, together with the projects listed in project_list
+func (m *ProjectManager) GetProjects(ctx context.Context) ([]gitlab.Project, error) {
+  return m.getNamespaceProjects(ctx, m.isProjectSelected)
+}
 
-  m.logger.Debugf("Fetching projects under %s path ...", m.config.GroupName)
+// getNamespaceProjects fetches the selected projects of the configured groups and user namespaces
+// and of project_list
+func (m *ProjectManager) getNamespaceProjects(ctx context.Context, selected func(gitlab.Project) bool) ([]gitlab.Project, error) {
+  var repos []gitlab.Project
+  seen := make(map[int]bool)
 
-  // Identify Group/Subgroup's ID
-  var groupID int
+  add := func(projects []gitlab.Project) {
+    for _, p := range projects {
+      // Groups may be nested within each other
+      if seen[p.ID] {
+        continue
+      }
+      seen[p.ID] = true
 
-  m.logger.Debugf("Identifying %s's GroupID", m.config.GroupName)
-  if strings.ContainsAny(m.config.GroupName, "/") {
-    // Nested Path
-    group_ID, err := m.GetSubgroupID(m.config.GroupName, 1, 0)
-    if err != nil {
-      return []gitlab.Project{}, fmt.Errorf("failed to fetch GitLab group info for %q: %v", m.config.GroupName, err)
+      repos = append(repos, p)
     }
-    groupID = group_ID
-  } else {
-    // BugFix: Without this pre-processing, go-gitlab library stalls.
-    var group_name string = strings.Replace(url.PathEscape(m.config.GroupName), ".", "%2E", -1)
-    group, _, err := m.groupsClient.GetGroup(group_name)
-    if err != nil {
-      return []gitlab.Project{}, fmt.Errorf("failed to fetch GitLab group info for %q: %v", group_name, err)
-    }
-    groupID = group.ID
   }
 
-  m.logger.Debugf("GroupID is %d", groupID)
+  m.ResolveGroupID()
+  if err := m.DiscoverGroups(ctx); err != nil {
+    return []gitlab.Project{}, err
+  }
 
-  // Get Project objects
-  for {
-    projects, resp, err := m.groupsClient.ListGroupProjects(groupID, listGroupProjectOps, addIncludeSubgroups)
+  for _, groupName := range m.config.GroupNames() {
+    projects, err := m.getGroupProjects(ctx, groupName, selected)
     if err != nil {
-      return []gitlab.Project{}, fmt.Errorf("failed to fetch GitLab projects for %s [%d]: %v", m.config.GroupName, groupID, err)
-    }
-
-    for _, p := range projects {
-      if len(m.config.ProjectWhitelist) > 0 && !stringslice.Contains(p.PathWithNamespace, m.config.ProjectWhitelist) {
-        m.logger.Debugf("Skipping repo %s as it's not whitelisted", p.PathWithNamespace)
-        continue
-      }
-      if stringslice.Contains(p.PathWithNamespace, m.config.ProjectBlacklist) {
-        m.logger.Debugf("Skipping repo %s as it's blacklisted", p.PathWithNamespace)
-        continue
-      }
-
-      repos = append(repos, *p)
+      return []gitlab.Project{}, err
     }
+    add(projects)
+  }
 
-    // Exit the loop when we've seen all pages.
-    if listGroupProjectOps.Page >= resp.TotalPages || resp.TotalPages == 1 {
-      break
+  for _, username := range m.config.Users {
+    projects, err := m.getUserProjects(ctx, username, selected)
+    if err != nil {
+      return []gitlab.Project{}, err
     }
-
-    // Update the page number to get the next page.
-    listGroupProjectOps.Page = resp.NextPage
+    add(projects)
   }
 
-  m.logger.Debugf("Fetching projects under path done. Retrieved %d.", len(repos))
+  projects, err := m.getListedProjects(ctx, selected)
+  if err != nil {
+    return []gitlab.Project{}, err
+  }
+  add(projects)
 
   return repos, nil
 }
@@ -510,10 +649,15 @@ func (m *ProjectManager) GetProjects() ([]gitlab.Project, error) {
 // GetProjectSettings gets the settings in GitLab for the provided project, using
 // the Project API
 // https://docs.gitlab.com/ee/api/projects.html
-func (m *ProjectManager) GetProjectSettings(project gitlab.Project) (*gitlab.Project, error) {
+func (m *ProjectManager) GetProjectSettings(ctx context.Context, project gitlab.Project) (*gitlab.Project, error) {
   m.logger.Debugf("Get project settings of project %s ...", project.PathWithNamespace)
 
-  returned_project, response, err := m.projectsClient.GetProject(project.ID, &gitlab.GetProjectOptions{})
+  if settings, ok := m.prefetchedProjectSettings(project); ok {
+    m.logger.Debugf("Using prefetched project settings of project %s", project.PathWithNamespace)
+    return settings, nil
+  }
+
+  returned_project, response, err := m.projectsClient.GetProject(project.ID, &gitlab.GetProjectOptions{}, gitlab.WithContext(ctx))
   if err != nil {
     return nil, fmt.Errorf("failed to get current project settings of project %s: %v", project.PathWithNamespace, err)
   }
@@ -527,41 +671,64 @@ func (m *ProjectManager) GetProjectSettings(project gitlab.Project) (*gitlab.Pro
 }
 
 // GetSubgroupID walks the provided path, returning the Group ID of the last desired subgroup.
-func (m *ProjectManager) GetSubgroupID(path string, indent int, group_ID int) (int, error) {
-  var subgroup_ID int
-
-  subpath := strings.Split(path, "/")[indent]
-  path_count := len(strings.Split(path, "/"))-1
+// Resolved paths are cached, so groups sharing parents are only walked once.
+func (m *ProjectManager) GetSubgroupID(ctx context.Context, path string, indent int, group_ID int) (int, error) {
+  parts := strings.Split(path, "/")
+  subpath := parts[indent]
+  path_count := len(parts)-1
   m.logger.Debugf("Walking %s, looking for %s[%d/%d].", path, subpath, indent, path_count)
 
-  var group_info string
-  if group_ID == 0 {
-    // Use base of path to get first group ID
-    group_info = strings.Split(path, "/")[0]
-  } else {
-    // Use parent ID provided.
-    group_info = strconv.Itoa(group_ID)
-  }
+  subgroup_path := strings.Join(parts[:indent+1], "/")
+  subgroup_ID, ok := m.cachedGroupID(subgroup_path)
+  if !ok {
+    var group_info string
+    if group_ID == 0 {
+      // Use base of path to get first group ID
+      group_info = parts[0]
+    } else {
+      // Use parent ID provided.
+      group_info = strconv.Itoa(group_ID)
+    }
 
-  m.logger.Debugf("Getting Subgroup(s) of %v.", group_info)
-  subgroups, _, err := m.groupsClient.ListSubgroups(group_info, listSubgroupOps)
-  if err != nil {
-    return 0, fmt.Errorf("failed to fetch GitLab subgroups for %s [%s]: %v", path, subpath, err)
-  }
+    m.logger.Debugf("Getting Subgroup(s) of %v.", group_info)
+    opt := &gitlab.ListSubgroupsOptions{
+      ListOptions: gitlab.ListOptions{
+        Page:    1,
+        PerPage: 100,
+      },
+    }
+    parent_path := strings.Join(parts[:indent], "/")
+    for {
+      subgroups, resp, err := m.groupsClient.ListSubgroups(group_info, opt, gitlab.WithContext(ctx))
+      if err != nil {
+        return 0, fmt.Errorf("failed to fetch GitLab subgroups for %s [%s]: %v", path, subpath, err)
+      }
+
+      // Cache all siblings, they are likely to be looked up as well
+      m.logger.Debugf("---[ Subgroup(s) Found: %d ]---\n", len(subgroups))
+      for _, g := range subgroups {
+        m.logger.Debugf(">>> %s <<<: %+v\n", g.Name, g)
+        m.cacheGroupID(parent_path+"/"+g.Path, g.ID)
+      }
+
+      // Exit the loop when we've seen all pages.
+      if resp.NextPage == 0 {
+        break
+      }
 
-  // Get desired subgroup_ID
-  m.logger.Debugf("---[ Subgroup(s) Found: %d ]---\n", len(subgroups))
-  for _, g := range subgroups {
-    m.logger.Debugf(">>> %s <<<: %+v\n", g.Name, g)
-    matched, _ := regexp.MatchString("^"+subpath+"$", g.Path)
-    if matched {
-      subgroup_ID = g.ID
+      // Update the page number to get the next page.
+      opt.Page = resp.NextPage
+    }
+
+    subgroup_ID, ok = m.cachedGroupID(subgroup_path)
+    if !ok {
+      return 0, fmt.Errorf("subgroup %s of %s not found", subpath, path)
     }
   }
 
   if indent != path_count {
     m.logger.Debugf("Found Group ID %d, going deeper.", subgroup_ID)
-    subgroup_ID, _ = m.GetSubgroupID(path, indent+1, subgroup_ID)
+    return m.GetSubgroupID(ctx, path, indent+1, subgroup_ID)
   }
 
   m.logger.Debugf("Coming back up from %s.", subpath)
@@ -570,32 +737,36 @@ func (m *ProjectManager) GetSubgroupID(path string, indent int, group_ID int) (i
 
 // SetError returns the Error status
 func (m *ProjectManager) SetError(state bool) (bool) {
-  m.config.Error = state
-  return m.config.Error
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  m.failed = state
+  return m.failed
 }
 
-// SendEmail
+// SendEmail sends the HTML body to the recipients through the configured SMTP server
 func (m *ProjectManager) SendEmail(to []string, from string, subject string, body string) error {
   // Connect to remote SMTP server
   smtp_server, err := smtp.Dial(m.config.Compliance.Email.Server+":"+strconv.Itoa(m.config.Compliance.Email.Port))
   if err != nil {
-    m.logger.Fatal(err)
+    return fmt.Errorf("failed to connect to SMTP server: %v", err)
   }
+  defer smtp_server.Close()
 
   // Set the sender
   if err := smtp_server.Mail(from); err != nil {
-    m.logger.Fatal(err)
+    return fmt.Errorf("failed to set sender %s: %v", from, err)
   }
 
   // Set the recipient
   if err := smtp_server.Rcpt(strings.Join(to, ",")); err != nil {
-    m.logger.Fatal(err)
+    return fmt.Errorf("failed to set recipients %s: %v", strings.Join(to, ","), err)
   }
 
   // Send the email body
   smtp_writer, err := smtp_server.Data()
   if err != nil {
-    m.logger.Fatal(err)
+    return fmt.Errorf("failed to start email body: %v", err)
   }
 
   message := fmt.Sprintf("Content-Type: text/html; charset=UTF-8\r\n")
@@ -613,67 +784,95 @@ func (m *ProjectManager) SendEmail(to []string, from string, subject string, bod
 
   _, err = smtp_writer.Write([]byte(message))
   if err != nil {
-    m.logger.Fatal(err)
+    return fmt.Errorf("failed to write email body: %v", err)
   }
 
   err = smtp_writer.Close()
   if err != nil {
-    m.logger.Fatal(err)
+    return fmt.Errorf("failed to send email body: %v", err)
   }
 
   // Send the QUIT command and close the connection.
   err = smtp_server.Quit()
   if err != nil {
-    m.logger.Fatal(err)
+    return fmt.Errorf("failed to close SMTP connection: %v", err)
   }
 
   return nil
 }
 
 // UpdateProjectMergeRequestSettings updates the project settings on gitlab
-func (m *ProjectManager) UpdateProjectApprovalSettings(project gitlab.Project, dryrun bool) error {
+func (m *ProjectManager) UpdateProjectApprovalSettings(ctx context.Context, project gitlab.Project, dryrun bool) error {
   m.logger.Debugf("Updating merge request approval settings of project %s [%d]...", project.PathWithNamespace, project.ID)
 
+  policy, err := m.policyFor(ctx, project)
+  if err != nil {
+    return err
+  }
+
   // Exit if nothing to configure
-  if m.config.ApprovalSettings == nil {
+  if policy.ApprovalSettings == nil {
     m.logger.Debugf("No approval_settings section provided in config")
     return nil
   }
 
-  // Get current settings states
-  approvalSettings, err := m.GetProjectApprovalSettings(project)
+  // Get current settings states, lower tiers don't offer approval settings
+  approvalSettings, err := m.currentApprovalSettings(ctx, project)
+  if m.skipUnavailable(err) {
+    return nil
+  }
   if err != nil {
     return fmt.Errorf("failed to get current project settings of project %s: %v", project.PathWithNamespace, err)
   }
 
   // Record current settings states
+  m.mu.Lock()
   m.ApprovalSettingsOriginal[project.PathWithNamespace] = approvalSettings
+  m.mu.Unlock()
+
+  // Leave out default-only settings which already have a value
+  options := &gitlab.ChangeApprovalConfigurationOptions{}
+  if err := m.applySettingModes(project.PathWithNamespace, "approval_settings", policy, policy.ApprovalSettings, approvalSettings, options); err != nil {
+    return err
+  }
 
   m.logger.Debugf("---[ HTTP Payload for UpdateProjectApprovalSettings ]---\n")
-  m.logger.Debugf("%+v\n", m.config.ApprovalSettings)
+  m.logger.Debugf("%+v\n", options)
 
-  settingsToChange, err := m.convertChangeApprovalConfigurationOptionsToProjectApprovals(*m.config.ApprovalSettings)
+  drift, err := computeDrift(options, approvalSettings)
   if err != nil {
     return err
   }
+  if err := m.recordEnforced(project.PathWithNamespace, "approval_settings", options, approvalSettings); err != nil {
+    return err
+  }
 
-  if ! m.willChangeApprovalSettings(approvalSettings, &settingsToChange) {
-    m.logger.Debugf("No action required.")
+  if len(drift) == 0 {
+    m.logger.Infof("Approval settings of project %s are in sync.", project.PathWithNamespace)
 
     // Record current settings states
+    m.mu.Lock()
     m.ApprovalSettingsUpdated[project.PathWithNamespace] = approvalSettings
+    m.mu.Unlock()
 
     return nil
   }
 
-  var returned_mr *gitlab.ProjectApprovals
-  var response *gitlab.Response
+  // Dryruns record the planned changes instead of diffing the settings afterwards
   if dryrun {
-    m.logger.Infof("DRYRUN: Skipped executing API call [ChangeApprovalConfiguration]")
-  } else {
-    returned_mr, response, err = m.projectsClient.ChangeApprovalConfiguration(project.ID, m.config.ApprovalSettings)
+    m.logSkippedCall(project, "ChangeApprovalConfiguration", options)
+    m.addDrift(project.PathWithNamespace, "approval_settings", drift)
+
+    m.mu.Lock()
+    m.ApprovalSettingsUpdated[project.PathWithNamespace] = approvalSettings
+    m.mu.Unlock()
+
+    return nil
   }
 
+  returned_mr, response, err := m.projectsClient.ChangeApprovalConfiguration(project.ID, options, gitlab.WithContext(ctx))
+  m.audit(project.PathWithNamespace, fmt.Sprintf("POST projects/%d/approvals", project.ID), drift, err)
+
   m.logger.Debugf("---[ HTTP Response for UpdateProjectApprovalSettings ]---\n")
   m.logger.Debugf("%v\n", response)
   m.logger.Debugf("---[ Returned MR for UpdateProjectApprovalSettings ]---\n")
@@ -684,13 +883,18 @@ func (m *ProjectManager) UpdateProjectApprovalSettings(project gitlab.Project, d
   }
 
   // Get new settings states
-  approvalSettings, err = m.GetProjectApprovalSettings(project)
+  approvalSettings, err = m.GetProjectApprovalSettings(ctx, project)
   if err != nil {
     return fmt.Errorf("failed to get current project settings of project %s: %v", project.PathWithNamespace, err)
   }
+  if err := m.verifyApplied(project.PathWithNamespace, "approval_settings", options, approvalSettings); err != nil {
+    return err
+  }
 
   // Record current settings states
+  m.mu.Lock()
   m.ApprovalSettingsUpdated[project.PathWithNamespace] = approvalSettings
+  m.mu.Unlock()
 
   m.logger.Debugf("Updating merge request approval settings of project %s done.", project.PathWithNamespace)
 
@@ -700,49 +904,84 @@ func (m *ProjectManager) UpdateProjectApprovalSettings(project gitlab.Project, d
 // UpdateProjectSettings updates the settings in GitLab for the provided project,
 // using the Project API
 // https://docs.gitlab.com/ee/api/projects.html
-func (m *ProjectManager) UpdateProjectSettings(project gitlab.Project, dryrun bool) error {
+func (m *ProjectManager) UpdateProjectSettings(ctx context.Context, project gitlab.Project, dryrun bool) error {
   m.logger.Debugf("Updating project settings of project %s ...", project.PathWithNamespace)
 
+  policy, err := m.policyFor(ctx, project)
+  if err != nil {
+    return err
+  }
+
   // Exit if nothing to configure.
-  if m.config.ProjectSettings == nil {
+  if policy.ProjectSettings == nil {
     m.logger.Debugf("No project_settings section provided in config")
     return nil
   }
 
   // Get current settings states
-  projectSettings, err := m.GetProjectSettings(project)
+  projectSettings, err := m.GetProjectSettings(ctx, project)
   if err != nil {
     return fmt.Errorf("failed to get current project settings of project %s: %v", project.PathWithNamespace, err)
   }
 
   // Record current settings states
+  m.mu.Lock()
   m.ProjectSettingsOriginal[project.PathWithNamespace] = projectSettings
+  m.mu.Unlock()
+
+  // Leave out default-only settings which already have a value
+  options := &gitlab.EditProjectOptions{}
+  if err := m.applySettingModes(project.PathWithNamespace, "project_settings", policy, policy.ProjectSettings, projectSettings, options); err != nil {
+    return err
+  }
 
   m.logger.Debugf("---[ HTTP Payload for UpdateProjectSettings ]---\n")
-  m.logger.Debugf("%+v\n", m.config.ProjectSettings)
+  m.logger.Debugf("%+v\n", options)
 
-  settingsToChange, err := m.convertEditProjectOptionsToProject(*m.config.ProjectSettings)
+  // Write-only settings are sent along with the others but can't be compared
+  readable, err := readableSettings(options, projectSettings)
   if err != nil {
     return err
   }
 
-  if ! m.willChangeProjectSettings(projectSettings, &settingsToChange) {
-    m.logger.Debugf("No action required.")
+  drift, err := computeDrift(readable, projectSettings)
+  if err != nil {
+    return err
+  }
+  if err := m.recordEnforced(project.PathWithNamespace, "project_settings", readable, projectSettings); err != nil {
+    return err
+  }
+
+  if len(drift) == 0 {
+    m.logger.Infof("Project settings of project %s are in sync.", project.PathWithNamespace)
 
     // Record current settings states
+    m.mu.Lock()
     m.ProjectSettingsUpdated[project.PathWithNamespace] = projectSettings
+    m.mu.Unlock()
 
     return nil
   }
 
-  var returned_project *gitlab.Project
-  var response *gitlab.Response
+  if err := m.guardDestructive(project.PathWithNamespace, "project_settings", drift, dryrun); err != nil {
+    return err
+  }
+
+  // Dryruns record the planned changes instead of diffing the settings afterwards
   if dryrun {
-    m.logger.Infof("DRYRUN: Skipped executing API call [EditProject]")
-  } else {
-    returned_project, response, err = m.projectsClient.EditProject(project.ID, m.config.ProjectSettings)
+    m.logSkippedCall(project, "EditProject", options)
+    m.addDrift(project.PathWithNamespace, "project_settings", drift)
+
+    m.mu.Lock()
+    m.ProjectSettingsUpdated[project.PathWithNamespace] = projectSettings
+    m.mu.Unlock()
+
+    return nil
   }
 
+  returned_project, response, err := m.projectsClient.EditProject(project.ID, options, gitlab.WithContext(ctx))
+  m.audit(project.PathWithNamespace, fmt.Sprintf("PUT projects/%d", project.ID), drift, err)
+
   m.logger.Debugf("---[ HTTP Response for UpdateProjectSettings ]---\n")
   m.logger.Debugf("%v\n", response)
   m.logger.Debugf("---[ Returned Project for UpdateProjectSettings ]---\n")
@@ -752,14 +991,22 @@ func (m *ProjectManager) UpdateProjectSettings(project gitlab.Project, dryrun bo
     return fmt.Errorf("failed to update project settings of project %s: %v", project.PathWithNamespace, err)
   }
 
+  // The prefetched settings are outdated now
+  m.forgetPrefetchedProjectSettings(project)
+
   // Get new settings states
-  projectSettings, err = m.GetProjectSettings(project)
+  projectSettings, err = m.GetProjectSettings(ctx, project)
   if err != nil {
     return fmt.Errorf("failed to get current project settings of project %s: %v", project.PathWithNamespace, err)
   }
+  if err := m.verifyApplied(project.PathWithNamespace, "project_settings", readable, projectSettings); err != nil {
+    return err
+  }
 
   // Record current settings states
+  m.mu.Lock()
   m.ProjectSettingsUpdated[project.PathWithNamespace] = projectSettings
+  m.mu.Unlock()
 
   m.logger.Debugf("Updating project settings of project %s done.", project.PathWithNamespace)
 
@@ -770,40 +1017,88 @@ func (m *ProjectManager) UpdateProjectSettings(project gitlab.Project, dryrun bo
  * Internal Functions *
  **********************/
 
-// convertChangeApprovalConfigurationOptionsToProjectApprovals
-func (m *ProjectManager) convertChangeApprovalConfigurationOptionsToProjectApprovals(current gitlab.ChangeApprovalConfigurationOptions) (gitlab.ProjectApprovals, error) {
-  jsonData, err := json.Marshal(current)
+// changeLog collects the altered project settings from the recorded settings states and
+// the changes recorded by other subsystems. If the settings states can't be compared, the
+// changes of the others are returned together with the error.
+func (m *ProjectManager) changeLog() (ChangeLog, error) {
+  var errs []string
+
+  // Get differences
+  approvalDifflog, err := diff.Diff(m.ApprovalSettingsOriginal, m.ApprovalSettingsUpdated)
   if err != nil {
-    return gitlab.ProjectApprovals{}, fmt.Errorf("failed to convert ChangeApprovalConfigurationOptions to json: %v", err)
+    errs = append(errs, fmt.Sprintf("failed to compare approval settings: %v", err))
   }
-
-  var returnValue gitlab.ProjectApprovals
-  err = json.Unmarshal(jsonData, &returnValue)
+  projectDifflog, err := diff.Diff(m.ProjectSettingsOriginal, m.ProjectSettingsUpdated)
   if err != nil {
-    return gitlab.ProjectApprovals{}, fmt.Errorf("failed to convert json to ProjectApproval struct: %v", err)
+    errs = append(errs, fmt.Sprintf("failed to compare project settings: %v", err))
   }
 
-  return returnValue, nil
+  m.logger.Debugf("---[ Approval Diff Log ]---")
+  m.logger.Debugf("%+v\n", approvalDifflog)
+  m.logger.Debugf("---[ Project Diff Log ]---")
+  m.logger.Debugf("%+v\n", projectDifflog)
+
+  changelog := make(ChangeLog)
+
+  // Process Approvals
+  m.logger.Debugf("Process Approval Diff Log")
+  for _, v := range approvalDifflog {
+    if setting_name, ok := settingPath(v); ok {
+      changelog.Add(v.Path[0], "approval_settings", setting_name, v.From, v.To)
+    }
+  }
+
+  // Process Projects
+  m.logger.Debugf("Process Project Diff Log")
+  for _, v := range projectDifflog {
+    if setting_name, ok := settingPath(v); ok {
+      changelog.Add(v.Path[0], "project_settings", setting_name, v.From, v.To)
+    }
+  }
+
+  // Process changes recorded by other subsystems
+  m.logger.Debugf("Process Subsystem Change Log")
+  for project_name, subsections := range m.changes {
+    for subsection, settings := range subsections {
+      for setting, values := range settings {
+        changelog.Add(project_name, subsection, setting, values["From"], values["To"])
+      }
+    }
+  }
+
+  // Output Raw JSON
+  if body, err := json.MarshalIndent(changelog, "", "  "); err != nil {
+    m.logger.Debugf("Error printing Change Log: %v", err)
+  } else {
+    m.logger.Debugf("---[ Change Log (JSON) ]---")
+    m.logger.Debugf("%s\n", string(body))
+  }
+
+  if len(errs) > 0 {
+    return changelog, errors.New(strings.Join(errs, "; "))
+  }
+
+  return changelog, nil
 }
 
-// convertEditProjectOptionsToProject
-func (m *ProjectManager) convertEditProjectOptionsToProject(current gitlab.EditProjectOptions) (gitlab.Project, error) {
-  jsonData, err := json.Marshal(current)
-  if err != nil {
-    return gitlab.Project{}, fmt.Errorf("failed to convert ChangeApprovalConfigurationOptions to json: %v", err)
+// settingPath returns the dotted path of the setting changed by a change of the settings maps,
+// e.g. "namespace.name" or "links.self". Changes of whole projects (a project recorded in only
+// one of the maps) don't denote a changed setting.
+func settingPath(change diff.Change) (string, bool) {
+  if len(change.Path) < 2 {
+    return "", false
   }
 
-  var returnValue gitlab.Project
-  err = json.Unmarshal(jsonData, &returnValue)
-  if err != nil {
-    return gitlab.Project{}, fmt.Errorf("failed to convert json to Project struct: %v", err)
+  var elems []string
+  for _, elem := range change.Path[1:] {
+    elems = append(elems, strcase.ToSnake(elem))
   }
 
-  return returnValue, nil
+  return strings.Join(elems, "."), true
 }
 
 // debugPrintAllSettings prints to console all capture settings
-func (m *ProjectManager) debugPrintAllSettings() error {
+func (m *ProjectManager) debugPrintAllSettings() {
   m.logger.Debugf("---[ ORIGINAL APPROVAL SETTINGS ]---")
   if err := m.debugPrintApprovalSettings(m.ApprovalSettingsOriginal); err != nil {
     m.logger.Debugf("Error printing Original Approval Settings")
@@ -820,8 +1115,6 @@ func (m *ProjectManager) debugPrintAllSettings() error {
   if err := m.debugPrintProjectSettings(m.ProjectSettingsUpdated); err != nil {
     m.logger.Debugf("Error printing Updated Project Settings")
   }
-
-  return nil
 }
 
 // debugPrintProjectSettings prints to console a SettingsMap
@@ -848,21 +1141,51 @@ func (m *ProjectManager) debugPrintProjectSettings(settings map[string]*gitlab.P
   return nil
 }
 
-func (m *ProjectManager) ensureDefaultBranch(project gitlab.Project, dryrun bool) error {
+func (m *ProjectManager) ensureDefaultBranch(ctx context.Context, project gitlab.Project, policy *config.Policy, dryrun bool) error {
+  // Empty repositories have no default branch, and nothing to create one from
+  if project.DefaultBranch == "" {
+    branch := "master"
+    if m.config.GroupSettings != nil && m.config.GroupSettings.DefaultBranchName != nil {
+      branch = *m.config.GroupSettings.DefaultBranchName
+    }
+    if policy.ProjectSettings != nil && policy.ProjectSettings.DefaultBranch != nil {
+      branch = *policy.ProjectSettings.DefaultBranch
+    }
+
+    if m.config.InitialCommit != nil {
+      return m.initializeRepository(ctx, project, branch, dryrun)
+    }
+    if m.config.CreateDefaultBranch {
+      m.logger.Warnf("Skipping creation of default branch %s of project %s: the repository is empty, set initial_commit to initialize it.", branch, project.PathWithNamespace)
+    }
+    return nil
+  }
+
   if !m.config.CreateDefaultBranch ||
-    m.config.ProjectSettings.DefaultBranch == nil ||
-    *m.config.ProjectSettings.DefaultBranch == "master" {
+    policy.ProjectSettings == nil ||
+    policy.ProjectSettings.DefaultBranch == nil ||
+    *policy.ProjectSettings.DefaultBranch == project.DefaultBranch {
+    return nil
+  }
+
+  // The branch is created from the current default branch, unless configured otherwise
+  ref := project.DefaultBranch
+  if m.config.DefaultBranchSource != "" {
+    ref = m.config.DefaultBranchSource
+  }
+  if ref == "" {
+    m.logger.Debugf("Skipping creation of default branch %s of project %s: there is no branch to create it from.", *policy.ProjectSettings.DefaultBranch, project.PathWithNamespace)
     return nil
   }
 
   opt := &gitlab.CreateBranchOptions{
-    Branch: m.config.ProjectSettings.DefaultBranch,
-    Ref:    gitlab.String("master"),
+    Branch: policy.ProjectSettings.DefaultBranch,
+    Ref:    gitlab.String(ref),
   }
 
   m.logger.Debugf("Ensuring default branch %s existence ... ", *opt.Branch)
 
-  _, resp, err := m.branchesClient.GetBranch(project.ID, *opt.Branch)
+  _, resp, err := m.branchesClient.GetBranch(project.ID, *opt.Branch, gitlab.WithContext(ctx))
   if err == nil {
     m.logger.Debugf("Ensuring default branch %s existence ... already exists!", *opt.Branch)
     return nil
@@ -873,44 +1196,160 @@ func (m *ProjectManager) ensureDefaultBranch(project gitlab.Project, dryrun bool
   }
 
   if dryrun {
-    m.logger.Infof("DRYRUN: Skipped executing API call [CreateBranch]")
+    m.logSkippedCall(project, "CreateBranch", opt)
   } else {
-    if _, _, err := m.branchesClient.CreateBranch(project.ID, opt); err != nil {
-      return fmt.Errorf("failed to create default branch %s: %v", *opt.Branch, err)
+    _, _, err := m.branchesClient.CreateBranch(project.ID, opt, gitlab.WithContext(ctx))
+    m.audit(project.PathWithNamespace, fmt.Sprintf("POST projects/%d/repository/branches", project.ID), map[string]settingDrift{
+      "branch": {To: *opt.Branch},
+    }, err)
+    if err != nil {
+      return fmt.Errorf("failed to create default branch %s from %s: %v", *opt.Branch, ref, err)
     }
   }
 
   return nil
 }
 
-// willChangeApprovalSettings takes two ProjectSettings, and confirms if the 2nd one changes the 1st
-func (m *ProjectManager) willChangeApprovalSettings(current *gitlab.ProjectApprovals, changes *gitlab.ProjectApprovals) bool {
-  changelog, _ := diff.Diff(current, changes)
+// logSkippedCall logs the API call skipped by a dryrun with its fully resolved payload as JSON,
+// so it can be verified exactly what would be sent
+func (m *ProjectManager) logSkippedCall(project gitlab.Project, call string, payload interface{}) {
+  b, err := json.Marshal(payload)
+  if err != nil {
+    m.logger.Infof("DRYRUN: Skipped executing API call [%s] on project %s.", call, project.PathWithNamespace)
+    m.logger.Warnf("failed to marshal payload of %s: %v", call, err)
+    return
+  }
 
-  changeExpected := false
-  fmt.Printf("%v\n", changelog)
+  m.logger.Infof("DRYRUN: Skipped executing API call [%s] on project %s with payload %s", call, project.PathWithNamespace, b)
+}
+
+// getGroupID resolves the ID of the given group (or nested subgroup) path
+func (m *ProjectManager) getGroupID(ctx context.Context, groupName string) (int, error) {
+  m.logger.Debugf("Identifying %s's GroupID", groupName)
+  if group_ID, ok := m.cachedGroupID(groupName); ok {
+    m.logger.Debugf("GroupID is %d", group_ID)
+    return group_ID, nil
+  }
 
-  for _, change := range changelog {
-    if change.Type == "update" {
-      changeExpected = true
+  if strings.ContainsAny(groupName, "/") {
+    // Nested Path
+    group_ID, err := m.GetSubgroupID(ctx, groupName, 1, 0)
+    if err != nil {
+      return 0, fmt.Errorf("failed to fetch GitLab group info for %q: %v", groupName, err)
     }
+    m.logger.Debugf("GroupID is %d", group_ID)
+    return group_ID, nil
+  }
+
+  // BugFix: Without this pre-processing, go-gitlab library stalls.
+  var group_name string = strings.Replace(url.PathEscape(groupName), ".", "%2E", -1)
+  group, _, err := m.groupsClient.GetGroup(group_name, gitlab.WithContext(ctx))
+  if err != nil {
+    return 0, fmt.Errorf("failed to fetch GitLab group info for %q: %v", group_name, err)
   }
+  m.cacheGroupID(groupName, group.ID)
 
-  return changeExpected
+  m.logger.Debugf("GroupID is %d", group.ID)
+  return group.ID, nil
 }
 
-// willChangeProjectSettings takes two ProjectSettings, and confirms if the 2nd one changes the 1st
-func (m *ProjectManager) willChangeProjectSettings(current *gitlab.Project, changes *gitlab.Project) bool {
-  changelog, _ := diff.Diff(current, changes)
+// ResolveGroupID makes the group configured by group_id known by its path, or by its ID without
+// group_name. The group is never looked up, neither by ID nor by walking its path.
+func (m *ProjectManager) ResolveGroupID() {
+  if m.config.GroupID == 0 {
+    return
+  }
 
-  changeExpected := false
-  fmt.Printf("%v\n", changelog)
+  name := m.config.GroupName
+  if name == "" {
+    name = strconv.Itoa(m.config.GroupID)
+  }
 
-  for _, change := range changelog {
-    if change.Type == "update" {
-      changeExpected = true
+  m.cacheGroupID(name, m.config.GroupID)
+  m.logger.Debugf("GroupID of %s is %d", name, m.config.GroupID)
+}
+
+// ResolveGroupPath looks up the current full path of the group configured by group_id without
+// group_name. Enforcing doesn't need it, but matching projects by their path (like the ones of
+// system hooks) does.
+func (m *ProjectManager) ResolveGroupPath(ctx context.Context) error {
+  if m.config.GroupID == 0 || m.config.GroupName != "" {
+    return nil
+  }
+
+  group, _, err := m.groupsClient.GetGroup(m.config.GroupID, gitlab.WithContext(ctx))
+  if err != nil {
+    return fmt.Errorf("failed to fetch GitLab group %d: %v", m.config.GroupID, err)
+  }
+
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  m.groupIDPath = group.FullPath
+  return nil
+}
+
+// cachedGroupID returns the previously resolved ID of the given group path.
+// GitLab paths are case-insensitive, so are the cache keys.
+func (m *ProjectManager) cachedGroupID(path string) (int, bool) {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  id, ok := m.groupIDs[strings.ToLower(path)]
+  return id, ok
+}
+
+// cacheGroupID records the resolved ID of the given group path
+func (m *ProjectManager) cacheGroupID(path string, id int) {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  m.groupIDs[strings.ToLower(path)] = id
+}
+
+// getGroupProjects fetches a list of accessible repos within the given group, for which selected returns true
+func (m *ProjectManager) getGroupProjects(ctx context.Context, groupName string, selected func(gitlab.Project) bool) ([]gitlab.Project, error) {
+  var repos []gitlab.Project
+
+  m.logger.Debugf("Fetching projects under %s path ...", groupName)
+
+  // Identify Group/Subgroup's ID
+  groupID, err := m.getGroupID(ctx, groupName)
+  if err != nil {
+    return []gitlab.Project{}, err
+  }
+
+  // Get Project objects
+  opt := &gitlab.ListGroupProjectsOptions{
+    ListOptions: gitlab.ListOptions{
+      Page:    1,
+      PerPage: 100,
+    },
+  }
+  for {
+    projects, resp, err := m.groupsClient.ListGroupProjects(groupID, opt, addIncludeSubgroups, gitlab.WithContext(ctx))
+    if err != nil {
+      return []gitlab.Project{}, fmt.Errorf("failed to fetch GitLab projects for %s [%d]: %v", groupName, groupID, err)
+    }
+
+    for _, p := range projects {
+      if ! selected(*p) {
+        continue
+      }
+
+      repos = append(repos, *p)
     }
+
+    // Exit the loop when we've seen all pages.
+    if opt.Page >= resp.TotalPages || resp.TotalPages == 1 {
+      break
+    }
+
+    // Update the page number to get the next page.
+    opt.Page = resp.NextPage
   }
 
-  return changeExpected
+  m.logger.Debugf("Fetching projects under path done. Retrieved %d.", len(repos))
+
+  return repos, nil
 }
@@ -0,0 +1,144 @@
+package gitlab
+
+import (
+  "context"
+  "io/ioutil"
+  "net/http"
+  "net/http/httptest"
+  "testing"
+
+  "github.com/sirupsen/logrus"
+  "github.com/xanzy/go-gitlab"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/config"
+)
+
+// newTestProjectManager returns a ProjectManager talking to a test server which serves the
+// API requests with handler. The returned function shuts the server down.
+func newTestProjectManager(t *testing.T, handler http.Handler, cfg *config.Config) (*ProjectManager, func()) {
+  server := httptest.NewServer(handler)
+
+  client := gitlab.NewClient(server.Client(), "token")
+  if err := client.SetBaseURL(server.URL + "/api/v4"); err != nil {
+    server.Close()
+    t.Fatalf("Failed to set base URL: %v", err)
+  }
+
+  logger := logrus.New()
+  logger.Out = ioutil.Discard
+
+  manager := NewProjectManager(
+    logrus.NewEntry(logger),
+    client.Groups,
+    client.Projects,
+    client.ProtectedBranches,
+    client.Branches,
+    client.RepositoryFiles,
+    client.Commits,
+    client.MergeRequests,
+    client,
+    nil,
+    cfg,
+  )
+
+  return manager, server.Close
+}
+
+func TestGetProjectsByGroupID(t *testing.T) {
+  mux := http.NewServeMux()
+  mux.HandleFunc("/api/v4/groups/4711/projects", func(w http.ResponseWriter, r *http.Request) {
+    w.Write([]byte(`[{"id": 1, "path_with_namespace": "example/api"}]`))
+  })
+  mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+    t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+    http.NotFound(w, r)
+  })
+
+  cfg := &config.Config{GroupID: 4711}
+  manager, shutdown := newTestProjectManager(t, mux, cfg)
+  defer shutdown()
+
+  projects, err := manager.GetProjects(context.Background())
+  if err != nil {
+    t.Fatalf("GetProjects() failed: %v", err)
+  }
+  if len(projects) != 1 || projects[0].PathWithNamespace != "example/api" {
+    t.Errorf("Expected project example/api, but got %+v", projects)
+  }
+  if cfg.GroupName != "" {
+    t.Errorf("Expected group_name to stay unset, but got %q", cfg.GroupName)
+  }
+}
+
+func TestIsProjectMatchedByGroupID(t *testing.T) {
+  mux := http.NewServeMux()
+  mux.HandleFunc("/api/v4/groups/4711", func(w http.ResponseWriter, r *http.Request) {
+    w.Write([]byte(`{"id": 4711, "full_path": "example/platform"}`))
+  })
+
+  manager, shutdown := newTestProjectManager(t, mux, &config.Config{GroupID: 4711})
+  defer shutdown()
+
+  if err := manager.ResolveGroupPath(context.Background()); err != nil {
+    t.Fatalf("ResolveGroupPath() failed: %v", err)
+  }
+
+  tests := []struct {
+    path string
+    want bool
+  }{
+    {"example/platform/api", true},
+    {"example/platform/team/web", true},
+    {"example/other", false},
+    {"4711/api", false},
+  }
+  for _, test := range tests {
+    if got := manager.IsProjectMatched(gitlab.Project{PathWithNamespace: test.path}); got != test.want {
+      t.Errorf("IsProjectMatched(%q) = %t, want %t", test.path, got, test.want)
+    }
+  }
+}
+
+func TestEnsureDefaultBranchOfEmptyRepository(t *testing.T) {
+  mux := http.NewServeMux()
+  mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+    t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+    http.NotFound(w, r)
+  })
+
+  manager, shutdown := newTestProjectManager(t, mux, &config.Config{CreateDefaultBranch: true})
+  defer shutdown()
+
+  policy := &config.Policy{ProjectSettings: &gitlab.EditProjectOptions{DefaultBranch: gitlab.String("main")}}
+  project := gitlab.Project{ID: 1, PathWithNamespace: "example/empty"}
+  if err := manager.ensureDefaultBranch(context.Background(), project, policy, false); err != nil {
+    t.Errorf("ensureDefaultBranch() failed: %v", err)
+  }
+}
+
+func TestUpdateProjectSettingsIgnoresWriteOnlySettings(t *testing.T) {
+  mux := http.NewServeMux()
+  mux.HandleFunc("/api/v4/projects/1", func(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodGet {
+      t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+    }
+    w.Write([]byte(`{"id": 1, "path_with_namespace": "example/api", "merge_method": "ff"}`))
+  })
+
+  manager, shutdown := newTestProjectManager(t, mux, &config.Config{
+    ProjectSettings: &gitlab.EditProjectOptions{
+      MergeMethod:     gitlab.MergeMethod(gitlab.FastForwardMerge),
+      PackagesEnabled: gitlab.Bool(true),
+    },
+  })
+  defer shutdown()
+
+  project := gitlab.Project{ID: 1, PathWithNamespace: "example/api"}
+  if err := manager.UpdateProjectSettings(context.Background(), project, false); err != nil {
+    t.Fatalf("UpdateProjectSettings() failed: %v", err)
+  }
+
+  if len(manager.NotApplied()) != 0 {
+    t.Errorf("Expected no settings which weren't applied, but got %v", manager.NotApplied())
+  }
+}
@@ -0,0 +1,328 @@
+package gitlab
+
+import (
+  "bytes"
+  "context"
+  "encoding/json"
+  "fmt"
+  "io"
+  "io/ioutil"
+  "net/http"
+  "net/url"
+  "regexp"
+  "sort"
+  "strings"
+
+  "github.com/xanzy/go-gitlab"
+)
+
+// isWildcardBranch returns whether the protected branch name is a wildcard pattern (e.g. release/*)
+func isWildcardBranch(name string) bool {
+  return strings.Contains(name, "*")
+}
+
+// wildcardBranchMatch matches a branch name against a GitLab protected branch wildcard,
+// where * matches any sequence of characters (including /)
+func wildcardBranchMatch(pattern string, name string) bool {
+  expr := "^" + strings.Replace(regexp.QuoteMeta(pattern), `\*`, ".*", -1) + "$"
+  matched, _ := regexp.MatchString(expr, name)
+  return matched
+}
+
+// listBranchNames returns the names of all branches of the project
+func (m *ProjectManager) listBranchNames(ctx context.Context, project gitlab.Project) ([]string, error) {
+  branches, err := m.listBranches(ctx, project)
+  if err != nil {
+    return nil, err
+  }
+
+  var names []string
+  for _, b := range branches {
+    names = append(names, b.Name)
+  }
+
+  return names, nil
+}
+
+// listBranches returns all branches of the project
+func (m *ProjectManager) listBranches(ctx context.Context, project gitlab.Project) ([]*gitlab.Branch, error) {
+  var branches []*gitlab.Branch
+
+  opt := &gitlab.ListBranchesOptions{}
+  opt.PerPage = 100
+  opt.Page = 1
+  for {
+    page, resp, err := m.branchesClient.ListBranches(project.ID, opt, gitlab.WithContext(ctx))
+    if err != nil {
+      return nil, fmt.Errorf("failed to list branches of project %s: %v", project.PathWithNamespace, err)
+    }
+    branches = append(branches, page...)
+
+    if resp.NextPage == 0 {
+      break
+    }
+    opt.Page = resp.NextPage
+  }
+
+  return branches, nil
+}
+
+// recordBranchCoverage records which existing branches the wildcard protected branches of the project cover
+func (m *ProjectManager) recordBranchCoverage(ctx context.Context, project gitlab.Project, patterns []string) error {
+  var wildcards []string
+  for _, pattern := range patterns {
+    if isWildcardBranch(pattern) {
+      wildcards = append(wildcards, pattern)
+    }
+  }
+
+  if len(wildcards) == 0 {
+    return nil
+  }
+
+  names, err := m.listBranchNames(ctx, project)
+  if err != nil {
+    return err
+  }
+
+  coverage := make(map[string][]string)
+  for _, pattern := range wildcards {
+    coverage[pattern] = make([]string, 0)
+    for _, name := range names {
+      if wildcardBranchMatch(pattern, name) {
+        coverage[pattern] = append(coverage[pattern], name)
+      }
+    }
+    sort.Strings(coverage[pattern])
+
+    m.logger.Debugf("Protected branch pattern %s of project %s covers: %s", pattern, project.PathWithNamespace, strings.Join(coverage[pattern], ", "))
+  }
+
+  m.mu.Lock()
+  m.BranchCoverage[project.PathWithNamespace] = coverage
+  m.mu.Unlock()
+
+  return nil
+}
+
+// GenerateBranchCoverageReport writes which branches each wildcard protected branch covers to w
+func (m *ProjectManager) GenerateBranchCoverageReport(w io.Writer) error {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  if len(m.BranchCoverage) == 0 {
+    return nil
+  }
+
+  var project_names []string
+  for project_name := range m.BranchCoverage {
+    project_names = append(project_names, project_name)
+  }
+  sort.Strings(project_names)
+
+  fmt.Fprintf(w, "\nPROTECTED BRANCH COVERAGE\n")
+
+  for _, name := range project_names {
+    fmt.Fprintf(w, "  %s\n", name)
+
+    var patterns []string
+    for pattern := range m.BranchCoverage[name] {
+      patterns = append(patterns, pattern)
+    }
+    sort.Strings(patterns)
+
+    for _, pattern := range patterns {
+      branches := m.BranchCoverage[name][pattern]
+      if len(branches) == 0 {
+        fmt.Fprintf(w, "    %s: (no branches)\n", pattern)
+        continue
+      }
+      fmt.Fprintf(w, "    %s: %s\n", pattern, strings.Join(branches, ", "))
+    }
+
+    fmt.Fprintf(w, "\n")
+  }
+
+  return nil
+}
+
+// branchProtectionDrift compares the access levels set in opt with those of the existing
+// protection of the branch (nil if unprotected), keyed by "<branch>.<setting>". Unprotected
+// branches also drift in "<branch>.protected".
+func branchProtectionDrift(name string, opt *gitlab.ProtectRepositoryBranchesOptions, existing *gitlab.ProtectedBranch) map[string]settingDrift {
+  var push, merge *gitlab.AccessLevelValue
+  if existing != nil {
+    push, merge = firstAccessLevel(existing.PushAccessLevels), firstAccessLevel(existing.MergeAccessLevels)
+  }
+
+  drift := make(map[string]settingDrift)
+  if existing == nil {
+    drift[name+".protected"] = settingDrift{From: false, To: true}
+  }
+  if opt.PushAccessLevel != nil && (push == nil || *push != *opt.PushAccessLevel) {
+    drift[name+".push_access_level"] = settingDrift{From: accessLevelDriftValue(push), To: accessLevelDriftValue(opt.PushAccessLevel)}
+  }
+  if opt.MergeAccessLevel != nil && (merge == nil || *merge != *opt.MergeAccessLevel) {
+    drift[name+".merge_access_level"] = settingDrift{From: accessLevelDriftValue(merge), To: accessLevelDriftValue(opt.MergeAccessLevel)}
+  }
+
+  return drift
+}
+
+// accessLevelDriftValue returns the access level as recorded by computeDrift, nil if unset
+func accessLevelDriftValue(level *gitlab.AccessLevelValue) interface{} {
+  if level == nil {
+    return nil
+  }
+
+  return float64(*level)
+}
+
+// protectedBranchAccess is an access entry of a protected branch, as read and written by the
+// protected branches API. Removing an entry requires its ID.
+type protectedBranchAccess struct {
+  ID          int                      `json:"id,omitempty"`
+  AccessLevel *gitlab.AccessLevelValue `json:"access_level,omitempty"`
+  UserID      *int                     `json:"user_id,omitempty"`
+  GroupID     *int                     `json:"group_id,omitempty"`
+  DeployKeyID *int                     `json:"deploy_key_id,omitempty"`
+  Destroy     bool                     `json:"_destroy,omitempty"`
+}
+
+// protectedBranchAccessLevels holds the access entries of a protected branch
+type protectedBranchAccessLevels struct {
+  PushAccessLevels  []protectedBranchAccess `json:"push_access_levels,omitempty"`
+  MergeAccessLevels []protectedBranchAccess `json:"merge_access_levels,omitempty"`
+}
+
+// protectedBranchUpdate is the payload of PATCH projects/:id/protected_branches/:name
+type protectedBranchUpdate struct {
+  AllowedToPush  []protectedBranchAccess `json:"allowed_to_push,omitempty"`
+  AllowedToMerge []protectedBranchAccess `json:"allowed_to_merge,omitempty"`
+}
+
+// updateBranchProtection changes the access levels of a protected branch in place, using
+// PATCH projects/:id/protected_branches/:name (GitLab 15.6). GitLab versions without it, and
+// tiers ignoring the access levels it is given, leave the branch unchanged, in which case the
+// branch is unprotected and protected again.
+func (m *ProjectManager) updateBranchProtection(ctx context.Context, project gitlab.Project, opt *gitlab.ProtectRepositoryBranchesOptions, drift map[string]settingDrift) error {
+  name := *opt.Name
+  path := fmt.Sprintf("projects/%d/protected_branches/%s", project.ID, url.PathEscape(name))
+
+  updated, err := m.patchBranchProtection(ctx, project, path, opt, drift)
+  if err != nil {
+    return fmt.Errorf("failed to update protection of branch %s: %v", name, err)
+  }
+  if updated {
+    return nil
+  }
+
+  m.logger.Debugf("Updating branch %s of project %s in place isn't supported, protecting it again.", name, project.PathWithNamespace)
+
+  _, err = m.protectedBranchesClient.UnprotectRepositoryBranches(project.ID, name, gitlab.WithContext(ctx))
+  m.audit(project.PathWithNamespace, "DELETE "+path, nil, err)
+  if err != nil {
+    return fmt.Errorf("failed to unprotect branch %v before protection: %v", name, err)
+  }
+
+  _, _, err = m.protectedBranchesClient.ProtectRepositoryBranches(project.ID, opt, gitlab.WithContext(ctx))
+  m.audit(project.PathWithNamespace, fmt.Sprintf("POST projects/%d/protected_branches", project.ID), drift, err)
+  if err != nil {
+    return fmt.Errorf("failed to protect branch %s: %v", name, err)
+  }
+
+  return nil
+}
+
+// patchBranchProtection replaces the role based access entries of the branch whose level drifted
+// and returns whether the update took effect. Entries of users, groups and deploy keys are kept.
+func (m *ProjectManager) patchBranchProtection(ctx context.Context, project gitlab.Project, path string, opt *gitlab.ProtectRepositoryBranchesOptions, drift map[string]settingDrift) (bool, error) {
+  req, err := m.apiClient.NewRequest(http.MethodGet, path, nil, []gitlab.OptionFunc{gitlab.WithContext(ctx)})
+  if err != nil {
+    return false, err
+  }
+  var current protectedBranchAccessLevels
+  if _, err := m.apiClient.Do(req, &current); err != nil {
+    return false, err
+  }
+
+  var update protectedBranchUpdate
+  if _, ok := drift[*opt.Name+".push_access_level"]; ok && opt.PushAccessLevel != nil {
+    update.AllowedToPush = replaceRoleAccess(current.PushAccessLevels, *opt.PushAccessLevel)
+  }
+  if _, ok := drift[*opt.Name+".merge_access_level"]; ok && opt.MergeAccessLevel != nil {
+    update.AllowedToMerge = replaceRoleAccess(current.MergeAccessLevels, *opt.MergeAccessLevel)
+  }
+
+  // Nothing to update in place, e.g. levels left to the defaults of GitLab
+  if update.AllowedToPush == nil && update.AllowedToMerge == nil {
+    return false, nil
+  }
+
+  body, err := json.Marshal(update)
+  if err != nil {
+    return false, err
+  }
+
+  // go-gitlab only sends a JSON body for POST and PUT requests
+  req, err = m.apiClient.NewRequest(http.MethodPatch, path, nil, []gitlab.OptionFunc{gitlab.WithContext(ctx)})
+  if err != nil {
+    return false, err
+  }
+  req.Body = ioutil.NopCloser(bytes.NewReader(body))
+  req.ContentLength = int64(len(body))
+  req.Header.Set("Content-Type", "application/json")
+
+  var result protectedBranchAccessLevels
+  resp, err := m.apiClient.Do(req, &result)
+  if err != nil && resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed) {
+    return false, nil
+  }
+  m.audit(project.PathWithNamespace, "PATCH "+path, drift, err)
+  if err != nil {
+    return false, err
+  }
+
+  if update.AllowedToPush != nil && !hasRoleAccess(result.PushAccessLevels, *opt.PushAccessLevel) {
+    return false, nil
+  }
+  if update.AllowedToMerge != nil && !hasRoleAccess(result.MergeAccessLevels, *opt.MergeAccessLevel) {
+    return false, nil
+  }
+
+  return true, nil
+}
+
+// replaceRoleAccess returns the entries removing all role based access entries and adding the
+// given level
+func replaceRoleAccess(entries []protectedBranchAccess, level gitlab.AccessLevelValue) []protectedBranchAccess {
+  var changes []protectedBranchAccess
+  for _, entry := range entries {
+    if isRoleAccess(entry) {
+      changes = append(changes, protectedBranchAccess{ID: entry.ID, Destroy: true})
+    }
+  }
+
+  return append(changes, protectedBranchAccess{AccessLevel: gitlab.AccessLevel(level)})
+}
+
+// hasRoleAccess returns whether the only role based access entry grants the given level
+func hasRoleAccess(entries []protectedBranchAccess, level gitlab.AccessLevelValue) bool {
+  var found bool
+  for _, entry := range entries {
+    if !isRoleAccess(entry) {
+      continue
+    }
+    if *entry.AccessLevel != level {
+      return false
+    }
+    found = true
+  }
+
+  return found
+}
+
+// isRoleAccess returns whether the access entry grants a role rather than a user, group or deploy key
+func isRoleAccess(entry protectedBranchAccess) bool {
+  return entry.AccessLevel != nil && entry.UserID == nil && entry.GroupID == nil && entry.DeployKeyID == nil
+}
@@ -0,0 +1,149 @@
+package gitlab
+
+import (
+  "encoding/json"
+  "fmt"
+  "io"
+  "reflect"
+  "sort"
+)
+
+// EnforcedSetting holds the desired value of an enforced setting and the value found before
+type EnforcedSetting struct {
+  Desired interface{}
+  Found   interface{}
+}
+
+// OutOfBandChange is a setting changed outside of the enforcer since the last run applied it
+type OutOfBandChange struct {
+  Project string      `json:"project"`
+  Setting string      `json:"setting"`
+  Applied interface{} `json:"applied"`
+  Found   interface{} `json:"found"`
+}
+
+// Reconciliation compares a run with the settings applied by the previous runs
+type Reconciliation struct {
+  NewProjects []string          `json:"new_projects"`
+  OutOfBand   []OutOfBandChange `json:"out_of_band_changes"`
+}
+
+// recordEnforced records the desired settings of a subsystem and their values found on the
+// project, keyed by "<subsection>.<setting>"
+func (m *ProjectManager) recordEnforced(project string, subsection string, desired interface{}, current interface{}) error {
+  desiredMap, err := toJSONMap(desired)
+  if err != nil {
+    return fmt.Errorf("failed to convert desired settings: %v", err)
+  }
+
+  currentMap, err := toJSONMap(current)
+  if err != nil {
+    return fmt.Errorf("failed to convert current settings: %v", err)
+  }
+
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  if _, ok := m.enforced[project]; !ok {
+    m.enforced[project] = make(map[string]EnforcedSetting)
+  }
+  for setting, value := range desiredMap {
+    m.enforced[project][subsection+"."+setting] = EnforcedSetting{Desired: value, Found: currentMap[setting]}
+  }
+
+  return nil
+}
+
+// enforcedSettings returns the sorted "<subsection>.<setting>" names enforced per project.
+// Callers must hold m.mu.
+func (m *ProjectManager) enforcedSettings() map[string][]string {
+  enforced := make(map[string][]string, len(m.enforced))
+  for project, settings := range m.enforced {
+    for setting := range settings {
+      enforced[project] = append(enforced[project], setting)
+    }
+    sort.Strings(enforced[project])
+  }
+
+  return enforced
+}
+
+// AppliedSettings returns the desired values of the settings enforced on the project, which it
+// has after a successful run
+func (m *ProjectManager) AppliedSettings(project string) map[string]interface{} {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  applied := make(map[string]interface{}, len(m.enforced[project]))
+  for setting, values := range m.enforced[project] {
+    applied[setting] = values.Desired
+  }
+
+  return applied
+}
+
+// Reconcile compares the settings found on the given projects with the settings applied to them
+// by previous runs. Projects without applied settings are new, settings found with another value
+// than applied were changed out of band.
+func (m *ProjectManager) Reconcile(previous map[string]map[string]interface{}, projects []string) Reconciliation {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  var r Reconciliation
+  for _, project := range projects {
+    applied, known := previous[project]
+    if !known {
+      r.NewProjects = append(r.NewProjects, project)
+      continue
+    }
+
+    for setting, values := range m.enforced[project] {
+      value, ok := applied[setting]
+      if ok && !reflect.DeepEqual(value, values.Found) {
+        r.OutOfBand = append(r.OutOfBand, OutOfBandChange{Project: project, Setting: setting, Applied: value, Found: values.Found})
+      }
+    }
+  }
+
+  sort.Strings(r.NewProjects)
+  sort.Slice(r.OutOfBand, func(i, j int) bool {
+    if r.OutOfBand[i].Project != r.OutOfBand[j].Project {
+      return r.OutOfBand[i].Project < r.OutOfBand[j].Project
+    }
+    return r.OutOfBand[i].Setting < r.OutOfBand[j].Setting
+  })
+
+  return r
+}
+
+// Render writes the reconciliation as JSON for the json output format, as text for all others
+func (r Reconciliation) Render(w io.Writer, format string) error {
+  if format == OutputFormatJSON {
+    if r.NewProjects == nil {
+      r.NewProjects = []string{}
+    }
+    if r.OutOfBand == nil {
+      r.OutOfBand = []OutOfBandChange{}
+    }
+
+    body, err := json.MarshalIndent(r, "", "  ")
+    if err != nil {
+      return fmt.Errorf("failed to marshal reconciliation: %v", err)
+    }
+
+    _, err = fmt.Fprintf(w, "%s\n", body)
+    return err
+  }
+
+  fmt.Fprintf(w, "\nRECONCILIATION\n")
+  fmt.Fprintf(w, "  New projects: %d\n", len(r.NewProjects))
+  for _, project := range r.NewProjects {
+    fmt.Fprintf(w, "    %s\n", project)
+  }
+  fmt.Fprintf(w, "  Changed out of band: %d\n", len(r.OutOfBand))
+  for _, c := range r.OutOfBand {
+    fmt.Fprintf(w, "    %s %s: applied %s, found %s\n", c.Project, c.Setting, FormatValue(c.Applied), FormatValue(c.Found))
+  }
+  _, err := fmt.Fprintf(w, "\n")
+  return err
+}
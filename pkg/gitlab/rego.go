@@ -0,0 +1,144 @@
+package gitlab
+
+import (
+  "context"
+  "encoding/json"
+  "fmt"
+
+  "github.com/open-policy-agent/opa/rego"
+  "github.com/xanzy/go-gitlab"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/config"
+)
+
+// regoResult is the expected result document of a rego policy query
+type regoResult struct {
+  Violations []string      `json:"violations"`
+  Desired    config.Policy `json:"desired"`
+}
+
+// applyRegoPolicies evaluates all rego policies against the current settings of the project,
+// records their violations and merges their desired settings on top of policy
+func (m *ProjectManager) applyRegoPolicies(ctx context.Context, project gitlab.Project, policy *config.Policy) (*config.Policy, error) {
+  if len(m.config.RegoPolicies) == 0 {
+    return policy, nil
+  }
+
+  input, err := m.regoInput(ctx, project, policy)
+  if err != nil {
+    return nil, err
+  }
+
+  for _, p := range m.config.RegoPolicies {
+    result, err := evalRegoPolicy(ctx, p, input)
+    if err != nil {
+      return nil, fmt.Errorf("failed to evaluate rego policy %q for project %s: %v", p.Name, project.PathWithNamespace, err)
+    }
+
+    for _, violation := range result.Violations {
+      m.addViolation(project.PathWithNamespace, "%s: %s", p.Name, violation)
+    }
+
+    if result.Desired.ProjectSettings != nil {
+      // The name is never allowed to be changed, see project_settings.name
+      result.Desired.ProjectSettings.Name = nil
+    }
+
+    policy, err = policy.Merge(&result.Desired)
+    if err != nil {
+      return nil, fmt.Errorf("failed to apply rego policy %q to project %s: %v", p.Name, project.PathWithNamespace, err)
+    }
+  }
+
+  return policy, nil
+}
+
+// regoInput returns the input document of rego policies: the project with its current settings,
+// its current approval settings and the policy computed from the config. The approval settings
+// are kept for UpdateProjectApprovalSettings, so they are only fetched once.
+func (m *ProjectManager) regoInput(ctx context.Context, project gitlab.Project, policy *config.Policy) (map[string]interface{}, error) {
+  // Approval settings are not available on every GitLab edition
+  approvalSettings, err := m.GetProjectApprovalSettings(ctx, project)
+  if err != nil {
+    m.logger.Debugf("Providing no approval settings to rego policies: %v", err)
+  } else {
+    m.mu.Lock()
+    m.fetchedApprovalSettings[project.ID] = approvalSettings
+    m.mu.Unlock()
+  }
+
+  input := make(map[string]interface{})
+  for key, value := range map[string]interface{}{
+    "project":           project,
+    "project_settings":  project,
+    "approval_settings": approvalSettings,
+    "policy":            policy,
+  } {
+    converted, err := toJSONValue(value)
+    if err != nil {
+      return nil, fmt.Errorf("failed to convert rego input %s: %v", key, err)
+    }
+    input[key] = converted
+  }
+
+  return input, nil
+}
+
+// currentApprovalSettings returns the approval settings of the project fetched for rego policies,
+// or fetches them if there are none. Fetched settings are used once, so later calls see updates.
+func (m *ProjectManager) currentApprovalSettings(ctx context.Context, project gitlab.Project) (*gitlab.ProjectApprovals, error) {
+  m.mu.Lock()
+  approvalSettings, ok := m.fetchedApprovalSettings[project.ID]
+  delete(m.fetchedApprovalSettings, project.ID)
+  m.mu.Unlock()
+
+  if ok {
+    return approvalSettings, nil
+  }
+
+  return m.GetProjectApprovalSettings(ctx, project)
+}
+
+// evalRegoPolicy evaluates the query of the rego policy and decodes its first result
+func evalRegoPolicy(ctx context.Context, p config.RegoPolicy, input map[string]interface{}) (*regoResult, error) {
+  r := rego.New(
+    rego.Query(p.Query),
+    rego.Module(p.Name+".rego", p.Module),
+    rego.Input(input),
+  )
+
+  rs, err := r.Eval(ctx)
+  if err != nil {
+    return nil, err
+  }
+
+  result := &regoResult{}
+  if len(rs) == 0 || len(rs[0].Expressions) == 0 {
+    return result, nil
+  }
+
+  b, err := json.Marshal(rs[0].Expressions[0].Value)
+  if err != nil {
+    return nil, err
+  }
+  if err := json.Unmarshal(b, result); err != nil {
+    return nil, fmt.Errorf("unexpected query result: %v", err)
+  }
+
+  return result, nil
+}
+
+// toJSONValue converts a value into its generic JSON representation
+func toJSONValue(v interface{}) (interface{}, error) {
+  b, err := json.Marshal(v)
+  if err != nil {
+    return nil, err
+  }
+
+  var result interface{}
+  if err := json.Unmarshal(b, &result); err != nil {
+    return nil, err
+  }
+
+  return result, nil
+}
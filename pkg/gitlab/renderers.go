@@ -0,0 +1,354 @@
+package gitlab
+
+import (
+  "encoding/csv"
+  "encoding/json"
+  "encoding/xml"
+  "fmt"
+  "io"
+  "sort"
+  "strings"
+)
+
+// ChangeLogRenderer writes a change log in a specific output format
+type ChangeLogRenderer interface {
+  Render(w io.Writer, changelog ChangeLog) error
+}
+
+// enforcedRenderer is a ChangeLogRenderer which also reports the enforced settings without
+// changes, given as "<subsection>.<setting>" per project
+type enforcedRenderer interface {
+  RenderEnforced(w io.Writer, changelog ChangeLog, enforced map[string][]string) error
+}
+
+// Output formats supported by NewChangeLogRenderer
+const (
+  OutputFormatText     = "text"
+  OutputFormatJSON     = "json"
+  OutputFormatJUnit    = "junit"
+  OutputFormatCSV      = "csv"
+  OutputFormatMarkdown = "markdown"
+  OutputFormatHTML     = "html"
+)
+
+// ANSI escape sequences coloring the values of the text change log
+const (
+  ansiRed   = "\x1b[31m"
+  ansiGreen = "\x1b[32m"
+  ansiReset = "\x1b[0m"
+)
+
+// RenderOptions adjust the output of the change log renderers
+type RenderOptions struct {
+  // Planned labels the change log as the changes a dryrun would apply
+  Planned bool
+  // Color highlights the previous and new values of the text change log with ANSI colors
+  Color bool
+}
+
+// NewChangeLogRenderer returns the renderer of the given output format
+func NewChangeLogRenderer(format string, opts RenderOptions) (ChangeLogRenderer, error) {
+  switch format {
+  case OutputFormatText, "":
+    return textRenderer{planned: opts.Planned, color: opts.Color}, nil
+  case OutputFormatJSON:
+    return jsonRenderer{}, nil
+  case OutputFormatJUnit:
+    return junitRenderer{planned: opts.Planned}, nil
+  case OutputFormatCSV:
+    return csvRenderer{planned: opts.Planned}, nil
+  case OutputFormatMarkdown:
+    return markdownRenderer{planned: opts.Planned}, nil
+  case OutputFormatHTML:
+    return htmlRenderer{planned: opts.Planned}, nil
+  default:
+    return nil, fmt.Errorf("unsupported output format %q", format)
+  }
+}
+
+// textRenderer renders the change log as a human readable report
+type textRenderer struct {
+  planned bool
+  color   bool
+}
+
+// Render implements ChangeLogRenderer
+func (r textRenderer) Render(w io.Writer, changelog ChangeLog) error {
+  if len(changelog) == 0 {
+    _, err := fmt.Fprintf(w, "\nNo changes discovered.\n")
+    return err
+  }
+
+  // Get longest length of setting path
+  var longest_setting_name int
+  for _, subsections := range changelog {
+    for subsection, data := range subsections {
+      for setting := range data {
+        if len(subsection+"."+setting) > longest_setting_name {
+          longest_setting_name = len(subsection + "." + setting)
+        }
+      }
+    }
+  }
+
+  // Output Formated Report
+  if r.planned {
+    fmt.Fprintf(w, "\nPLANNED CHANGES (DRYRUN)\n")
+  } else {
+    fmt.Fprintf(w, "\nCHANGE LOG\n")
+  }
+
+  for _, name := range changelog.projectNames() {
+    fmt.Fprintf(w, "  %s\n", name)
+
+    for _, subsection := range changelog.subsections(name) {
+      for _, setting := range changelog.settings(name, subsection) {
+        change := changelog[name][subsection][setting]
+        from, to := quoteValue(change["From"]), quoteValue(change["To"])
+        if r.color {
+          from, to = ansiRed+from+ansiReset, ansiGreen+to+ansiReset
+        }
+
+        fmt.Fprintf(w, "    %-*s", longest_setting_name+2, subsection+"."+setting+":")
+        fmt.Fprintf(w, "%s => %s\n", from, to)
+      }
+    }
+
+    if _, err := fmt.Fprintf(w, "\n"); err != nil {
+      return err
+    }
+  }
+
+  return nil
+}
+
+// jsonRenderer renders the change log as JSON, keyed by project, subsection and setting
+type jsonRenderer struct{}
+
+// Render implements ChangeLogRenderer
+func (jsonRenderer) Render(w io.Writer, changelog ChangeLog) error {
+  body, err := json.MarshalIndent(changelog, "", "  ")
+  if err != nil {
+    return fmt.Errorf("failed to marshal change log: %v", err)
+  }
+
+  _, err = fmt.Fprintf(w, "%s\n", body)
+  return err
+}
+
+// junitRenderer renders the change log as JUnit XML test report, with a test suite per project
+// and a test case per enforced setting. Drifted settings fail in a dryrun, remediated and
+// compliant settings pass.
+type junitRenderer struct {
+  planned bool
+}
+
+type junitTestSuites struct {
+  XMLName  xml.Name         `xml:"testsuites"`
+  Name     string           `xml:"name,attr"`
+  Tests    int              `xml:"tests,attr"`
+  Failures int              `xml:"failures,attr"`
+  Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+  Name     string          `xml:"name,attr"`
+  Tests    int             `xml:"tests,attr"`
+  Failures int             `xml:"failures,attr"`
+  Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+  Name      string        `xml:"name,attr"`
+  ClassName string        `xml:"classname,attr"`
+  Failure   *junitFailure `xml:"failure,omitempty"`
+  SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+  Message string `xml:"message,attr"`
+  Text    string `xml:",chardata"`
+}
+
+// Render implements ChangeLogRenderer
+func (r junitRenderer) Render(w io.Writer, changelog ChangeLog) error {
+  return r.RenderEnforced(w, changelog, nil)
+}
+
+// RenderEnforced implements enforcedRenderer
+func (r junitRenderer) RenderEnforced(w io.Writer, changelog ChangeLog, enforced map[string][]string) error {
+  report := junitTestSuites{Name: "gitlab-settings-enforcer"}
+  if r.planned {
+    report.Name += " (planned)"
+  }
+
+  names := changelog.projectNames()
+  for name := range enforced {
+    if _, ok := changelog[name]; !ok {
+      names = append(names, name)
+    }
+  }
+  sort.Strings(names)
+
+  for _, name := range names {
+    suite := junitTestSuite{Name: name}
+    changed := make(map[string]bool)
+
+    for _, subsection := range changelog.subsections(name) {
+      for _, setting := range changelog.settings(name, subsection) {
+        change := changelog[name][subsection][setting]
+        values := quoteValue(change["From"]) + " => " + quoteValue(change["To"])
+        testCase := junitTestCase{Name: subsection + "." + setting, ClassName: name}
+
+        // Applied changes are remediated, only the planned ones are left drifted
+        if r.planned {
+          testCase.Failure = &junitFailure{Message: fmt.Sprintf("%s.%s drifted", subsection, setting), Text: values}
+          suite.Failures++
+        } else {
+          testCase.SystemOut = "remediated: " + values
+        }
+
+        changed[testCase.Name] = true
+        suite.Cases = append(suite.Cases, testCase)
+      }
+    }
+
+    for _, setting := range enforced[name] {
+      if !changed[setting] {
+        suite.Cases = append(suite.Cases, junitTestCase{Name: setting, ClassName: name})
+      }
+    }
+    sort.SliceStable(suite.Cases, func(i, j int) bool { return suite.Cases[i].Name < suite.Cases[j].Name })
+
+    suite.Tests = len(suite.Cases)
+    report.Tests += suite.Tests
+    report.Failures += suite.Failures
+    report.Suites = append(report.Suites, suite)
+  }
+
+  body, err := xml.MarshalIndent(report, "", "  ")
+  if err != nil {
+    return fmt.Errorf("failed to marshal junit report: %v", err)
+  }
+
+  _, err = fmt.Fprintf(w, "%s%s\n", xml.Header, body)
+  return err
+}
+
+// csvRenderer renders the change log as CSV, one row per changed setting
+type csvRenderer struct {
+  planned bool
+}
+
+// Render implements ChangeLogRenderer
+func (r csvRenderer) Render(w io.Writer, changelog ChangeLog) error {
+  writer := csv.NewWriter(w)
+
+  action := "update"
+  if r.planned {
+    action = "planned"
+  }
+
+  if err := writer.Write([]string{"project", "setting", "current", "desired", "action"}); err != nil {
+    return err
+  }
+
+  for _, name := range changelog.projectNames() {
+    for _, subsection := range changelog.subsections(name) {
+      for _, setting := range changelog.settings(name, subsection) {
+        change := changelog[name][subsection][setting]
+        record := []string{
+          name,
+          subsection + "." + setting,
+          FormatValue(change["From"]),
+          FormatValue(change["To"]),
+          action,
+        }
+
+        if err := writer.Write(record); err != nil {
+          return err
+        }
+      }
+    }
+  }
+
+  writer.Flush()
+  return writer.Error()
+}
+
+// markdownRenderer renders the change log as Markdown, with a table of changes per project
+type markdownRenderer struct {
+  planned bool
+}
+
+// Render implements ChangeLogRenderer
+func (r markdownRenderer) Render(w io.Writer, changelog ChangeLog) error {
+  if len(changelog) == 0 {
+    _, err := fmt.Fprintf(w, "No changes discovered.\n")
+    return err
+  }
+
+  if r.planned {
+    fmt.Fprintf(w, "# Planned Changes (Dryrun)\n")
+  } else {
+    fmt.Fprintf(w, "# Change Log\n")
+  }
+
+  for _, name := range changelog.projectNames() {
+    fmt.Fprintf(w, "\n## %s\n\n", name)
+    fmt.Fprintf(w, "| Setting | From | To |\n")
+    fmt.Fprintf(w, "|---------|------|----|\n")
+
+    for _, subsection := range changelog.subsections(name) {
+      for _, setting := range changelog.settings(name, subsection) {
+        change := changelog[name][subsection][setting]
+        fmt.Fprintf(w, "| `%s.%s` | %s | %s |\n", subsection, setting, markdownCell(change["From"]), markdownCell(change["To"]))
+      }
+    }
+  }
+
+  _, err := fmt.Fprintf(w, "\n")
+  return err
+}
+
+// markdownCell formats a value as table cell, escaping characters which would break the table
+func markdownCell(v interface{}) string {
+  value := FormatValue(v)
+  value = strings.Replace(value, "|", "\\|", -1)
+  value = strings.Replace(value, "\n", " ", -1)
+
+  return "`" + value + "`"
+}
+
+// projectNames returns the sorted names of all changed projects
+func (c ChangeLog) projectNames() []string {
+  var project_names []string
+  for project_name := range c {
+    project_names = append(project_names, project_name)
+  }
+  sort.Strings(project_names)
+
+  return project_names
+}
+
+// subsections returns the sorted names of the changed subsections of a project
+func (c ChangeLog) subsections(project string) []string {
+  var subsections []string
+  for subsection := range c[project] {
+    subsections = append(subsections, subsection)
+  }
+  sort.Strings(subsections)
+
+  return subsections
+}
+
+// settings returns the sorted names of the changed settings of a project's subsection
+func (c ChangeLog) settings(project string, subsection string) []string {
+  var settings []string
+  for setting := range c[project][subsection] {
+    settings = append(settings, setting)
+  }
+  sort.Strings(settings)
+
+  return settings
+}
@@ -0,0 +1,62 @@
+package gitlab
+
+import (
+  "bytes"
+  "encoding/xml"
+  "strings"
+  "testing"
+)
+
+func renderJUnit(t *testing.T, planned bool) junitTestSuites {
+  changelog := ChangeLog{}
+  changelog.Add("example/api", "project_settings", "visibility", "public", "private")
+
+  enforced := map[string][]string{
+    "example/api": {"project_settings.issues_enabled", "project_settings.visibility"},
+    "example/web": {"project_settings.visibility"},
+  }
+
+  var body bytes.Buffer
+  if err := (junitRenderer{planned: planned}).RenderEnforced(&body, changelog, enforced); err != nil {
+    t.Fatalf("RenderEnforced() failed: %v", err)
+  }
+
+  var report junitTestSuites
+  if err := xml.Unmarshal(body.Bytes(), &report); err != nil {
+    t.Fatalf("failed to parse junit report: %v\n%s", err, body.String())
+  }
+
+  return report
+}
+
+func TestJUnitRendererPlanned(t *testing.T) {
+  report := renderJUnit(t, true)
+
+  if report.Tests != 3 || report.Failures != 1 {
+    t.Fatalf("got %d tests and %d failures, want 3 and 1", report.Tests, report.Failures)
+  }
+  if len(report.Suites) != 2 || report.Suites[0].Name != "example/api" || report.Suites[1].Name != "example/web" {
+    t.Fatalf("unexpected suites %+v", report.Suites)
+  }
+
+  cases := report.Suites[0].Cases
+  if cases[0].Name != "project_settings.issues_enabled" || cases[0].Failure != nil {
+    t.Errorf("compliant setting should pass, got %+v", cases[0])
+  }
+  if cases[1].Name != "project_settings.visibility" || cases[1].Failure == nil {
+    t.Errorf("drifted setting should fail, got %+v", cases[1])
+  }
+}
+
+func TestJUnitRendererRemediated(t *testing.T) {
+  report := renderJUnit(t, false)
+
+  if report.Tests != 3 || report.Failures != 0 {
+    t.Fatalf("got %d tests and %d failures, want 3 and 0", report.Tests, report.Failures)
+  }
+
+  remediated := report.Suites[0].Cases[1]
+  if remediated.Failure != nil || !strings.Contains(remediated.SystemOut, `"public" => "private"`) {
+    t.Errorf("remediated setting should pass with the change as output, got %+v", remediated)
+  }
+}
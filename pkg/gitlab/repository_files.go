@@ -0,0 +1,250 @@
+package gitlab
+
+import (
+  "context"
+  "encoding/base64"
+  "fmt"
+  "net/http"
+
+  "github.com/xanzy/go-gitlab"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/config"
+)
+
+const defaultRepositoryFilesCommitMessage = "Add required repository files"
+
+// EnsureRepositoryFiles ensures that all files configured in repository_files exist
+// on the default branch of the project, either by committing them directly or by
+// opening a merge request.
+func (m *ProjectManager) EnsureRepositoryFiles(ctx context.Context, project gitlab.Project, dryrun bool) error {
+  // Exit if nothing to configure
+  if m.config.RepositoryFiles == nil || len(m.config.RepositoryFiles.Files) == 0 {
+    m.logger.Debugf("No repository_files section provided in config")
+    return nil
+  }
+
+  if project.DefaultBranch == "" {
+    return fmt.Errorf("project %s has no default branch to commit repository files to", project.PathWithNamespace)
+  }
+
+  m.logger.Debugf("Ensuring repository files of project %s ...", project.PathWithNamespace)
+
+  actions, drift, err := m.getRepositoryFileActions(ctx, project)
+  if err != nil {
+    return err
+  }
+
+  if len(actions) == 0 {
+    m.logger.Debugf("No action required.")
+    return nil
+  }
+
+  settings := m.config.RepositoryFiles
+  opt := &gitlab.CreateCommitOptions{
+    Branch:        gitlab.String(project.DefaultBranch),
+    CommitMessage: gitlab.String(defaultRepositoryFilesCommitMessage),
+    Actions:       actions,
+  }
+  if settings.CommitMessage != "" {
+    opt.CommitMessage = gitlab.String(settings.CommitMessage)
+  }
+  if settings.AuthorName != "" {
+    opt.AuthorName = gitlab.String(settings.AuthorName)
+  }
+  if settings.AuthorEmail != "" {
+    opt.AuthorEmail = gitlab.String(settings.AuthorEmail)
+  }
+
+  m.logger.Debugf("---[ HTTP Payload for EnsureRepositoryFiles ]---\n")
+  m.logger.Debugf("%+v\n", opt)
+
+  committed := true
+  switch settings.Method {
+  case config.RepositoryFilesMethodMergeRequest:
+    committed, err = m.commitAsMergeRequest(ctx, project, opt, settings.Branch, "repository files", dryrun)
+    if err != nil {
+      return err
+    }
+  default:
+    if dryrun {
+      m.logger.Infof("DRYRUN: Skipped executing API call [CreateCommit]")
+      break
+    }
+
+    _, _, err := m.commitsClient.CreateCommit(project.ID, opt, gitlab.WithContext(ctx))
+    m.audit(project.PathWithNamespace, fmt.Sprintf("POST projects/%d/repository/commits", project.ID), repositoryFilesDrift(opt), err)
+    if err != nil {
+      return fmt.Errorf("failed to commit repository files to project %s: %v", project.PathWithNamespace, err)
+    }
+  }
+
+  // Dryruns record the planned changes, a still open merge request none
+  if committed {
+    m.addDrift(project.PathWithNamespace, "repository_files", drift)
+  }
+
+  m.logger.Debugf("Ensuring repository files of project %s done.", project.PathWithNamespace)
+
+  return nil
+}
+
+// commitAsMergeRequest commits onto the given branch and opens a merge request into the default
+// branch, unless one is already open, and returns whether it committed (or would in a dryrun).
+// A branch left over from an earlier merge request is recreated from the default branch. what
+// names the committed changes in logs and errors.
+func (m *ProjectManager) commitAsMergeRequest(ctx context.Context, project gitlab.Project, opt *gitlab.CreateCommitOptions, branch string, what string, dryrun bool) (bool, error) {
+  openMRs, _, err := m.mergeRequestsClient.ListProjectMergeRequests(project.ID, &gitlab.ListProjectMergeRequestsOptions{
+    State:        gitlab.String("opened"),
+    SourceBranch: gitlab.String(branch),
+    TargetBranch: gitlab.String(project.DefaultBranch),
+  }, gitlab.WithContext(ctx))
+  if err != nil {
+    return false, fmt.Errorf("failed to list merge requests of project %s: %v", project.PathWithNamespace, err)
+  }
+  if len(openMRs) > 0 {
+    m.logger.Infof("Merge request !%d for %s of project %s is still open.", openMRs[0].IID, what, project.PathWithNamespace)
+    return false, nil
+  }
+
+  if dryrun {
+    m.logger.Infof("DRYRUN: Skipped executing API call [CreateCommit]")
+    m.logger.Infof("DRYRUN: Skipped executing API call [CreateMergeRequest]")
+    return true, nil
+  }
+
+  if err := m.deleteLeftoverBranch(ctx, project, branch); err != nil {
+    return false, err
+  }
+
+  opt.Branch = gitlab.String(branch)
+  opt.StartBranch = gitlab.String(project.DefaultBranch)
+  _, _, err = m.commitsClient.CreateCommit(project.ID, opt, gitlab.WithContext(ctx))
+  m.audit(project.PathWithNamespace, fmt.Sprintf("POST projects/%d/repository/commits", project.ID), repositoryFilesDrift(opt), err)
+  if err != nil {
+    return false, fmt.Errorf("failed to commit %s to branch %s of project %s: %v", what, branch, project.PathWithNamespace, err)
+  }
+
+  mr, _, err := m.mergeRequestsClient.CreateMergeRequest(project.ID, &gitlab.CreateMergeRequestOptions{
+    Title:              opt.CommitMessage,
+    SourceBranch:       gitlab.String(branch),
+    TargetBranch:       gitlab.String(project.DefaultBranch),
+    RemoveSourceBranch: gitlab.Bool(true),
+  }, gitlab.WithContext(ctx))
+  m.audit(project.PathWithNamespace, fmt.Sprintf("POST projects/%d/merge_requests", project.ID), map[string]settingDrift{
+    "source_branch": {To: branch},
+    "target_branch": {To: project.DefaultBranch},
+  }, err)
+  if err != nil {
+    return true, fmt.Errorf("failed to open merge request for %s of project %s: %v", what, project.PathWithNamespace, err)
+  }
+
+  m.logger.Infof("Opened merge request !%d for %s of project %s.", mr.IID, what, project.PathWithNamespace)
+
+  return true, nil
+}
+
+// deleteLeftoverBranch deletes the branch if it exists, e.g. after its merge request was closed
+// without merging, so the next commit starts it afresh from the default branch
+func (m *ProjectManager) deleteLeftoverBranch(ctx context.Context, project gitlab.Project, branch string) error {
+  _, resp, err := m.branchesClient.GetBranch(project.ID, branch, gitlab.WithContext(ctx))
+  if err != nil {
+    if resp != nil && resp.StatusCode == http.StatusNotFound {
+      return nil
+    }
+    return fmt.Errorf("failed to check for branch %s of project %s: %v", branch, project.PathWithNamespace, err)
+  }
+
+  m.logger.Debugf("Recreating leftover branch %s of project %s.", branch, project.PathWithNamespace)
+
+  _, err = m.branchesClient.DeleteBranch(project.ID, branch, gitlab.WithContext(ctx))
+  m.audit(project.PathWithNamespace, fmt.Sprintf("DELETE projects/%d/repository/branches", project.ID), map[string]settingDrift{
+    "branch": {From: branch},
+  }, err)
+  if err != nil {
+    return fmt.Errorf("failed to delete leftover branch %s of project %s: %v", branch, project.PathWithNamespace, err)
+  }
+
+  return nil
+}
+
+// repositoryFilesDrift lists the commit actions of opt by file path for the audit log
+func repositoryFilesDrift(opt *gitlab.CreateCommitOptions) map[string]settingDrift {
+  drift := make(map[string]settingDrift)
+  for _, action := range opt.Actions {
+    drift[action.FilePath] = settingDrift{To: string(action.Action)}
+  }
+
+  return drift
+}
+
+// getRepositoryFileActions compares the configured files with the default branch and returns the
+// required commit actions, along with the current and desired content of each file they touch
+func (m *ProjectManager) getRepositoryFileActions(ctx context.Context, project gitlab.Project) ([]*gitlab.CommitAction, map[string]settingDrift, error) {
+  var actions []*gitlab.CommitAction
+  drift := make(map[string]settingDrift)
+
+  for _, f := range m.config.RepositoryFiles.Files {
+    current, exists, err := m.getRepositoryFileContent(ctx, project, f.Path, project.DefaultBranch)
+    if err != nil {
+      return nil, nil, err
+    }
+
+    switch {
+    case !exists:
+      m.logger.Debugf("File %s is missing.", f.Path)
+      actions = append(actions, &gitlab.CommitAction{
+        Action:   gitlab.FileCreate,
+        FilePath: f.Path,
+        Content:  f.Content,
+      })
+      drift[f.Path] = settingDrift{From: nil, To: f.Content}
+    case f.Overwrite && current != f.Content:
+      m.logger.Debugf("File %s differs from the configured content.", f.Path)
+      actions = append(actions, &gitlab.CommitAction{
+        Action:   gitlab.FileUpdate,
+        FilePath: f.Path,
+        Content:  f.Content,
+      })
+      drift[f.Path] = settingDrift{From: current, To: f.Content}
+    default:
+      m.logger.Debugf("File %s is present.", f.Path)
+    }
+  }
+
+  return actions, drift, nil
+}
+
+// getRepositoryFileContent returns the decoded content of the file at the given ref, and whether it exists
+func (m *ProjectManager) getRepositoryFileContent(ctx context.Context, project gitlab.Project, path string, ref string) (string, bool, error) {
+  file, resp, err := m.repositoryFilesClient.GetFile(project.ID, path, &gitlab.GetFileOptions{Ref: gitlab.String(ref)}, gitlab.WithContext(ctx))
+  if err != nil {
+    if resp != nil && resp.StatusCode == http.StatusNotFound {
+      return "", false, nil
+    }
+    return "", false, fmt.Errorf("failed to get file %s of project %s: %v", path, project.PathWithNamespace, err)
+  }
+
+  if file.Encoding != "base64" {
+    return file.Content, true, nil
+  }
+
+  content, err := base64.StdEncoding.DecodeString(file.Content)
+  if err != nil {
+    return "", true, fmt.Errorf("failed to decode file %s of project %s: %v", path, project.PathWithNamespace, err)
+  }
+
+  return string(content), true, nil
+}
+
+// repositoryFileExists returns whether the file exists at the given ref, requesting only its metadata
+func (m *ProjectManager) repositoryFileExists(ctx context.Context, project gitlab.Project, path string, ref string) (bool, error) {
+  _, resp, err := m.repositoryFilesClient.GetFileMetaData(project.ID, path, &gitlab.GetFileMetaDataOptions{Ref: gitlab.String(ref)}, gitlab.WithContext(ctx))
+  if err != nil {
+    if resp != nil && resp.StatusCode == http.StatusNotFound {
+      return false, nil
+    }
+    return false, fmt.Errorf("failed to get file %s of project %s: %v", path, project.PathWithNamespace, err)
+  }
+
+  return true, nil
+}
@@ -0,0 +1,90 @@
+package gitlab
+
+import (
+  "context"
+  "net/http"
+  "reflect"
+  "testing"
+
+  "github.com/xanzy/go-gitlab"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/config"
+)
+
+func newTestCommitOptions() *gitlab.CreateCommitOptions {
+  return &gitlab.CreateCommitOptions{
+    CommitMessage: gitlab.String("Update CODEOWNERS"),
+    Actions: []*gitlab.CommitAction{
+      {Action: gitlab.FileUpdate, FilePath: "CODEOWNERS", Content: "* @example/maintainers\n"},
+    },
+  }
+}
+
+func TestCommitAsMergeRequestAlreadyOpen(t *testing.T) {
+  mux := http.NewServeMux()
+  mux.HandleFunc("/api/v4/projects/1/merge_requests", func(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodGet {
+      t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+    }
+    w.Write([]byte(`[{"id": 10, "iid": 3, "source_branch": "enforcer/files", "target_branch": "master"}]`))
+  })
+  mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+    t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+    http.NotFound(w, r)
+  })
+
+  manager, shutdown := newTestProjectManager(t, mux, &config.Config{})
+  defer shutdown()
+
+  project := gitlab.Project{ID: 1, PathWithNamespace: "example/api", DefaultBranch: "master"}
+  committed, err := manager.commitAsMergeRequest(context.Background(), project, newTestCommitOptions(), "enforcer/files", "repository files", false)
+  if err != nil {
+    t.Fatalf("commitAsMergeRequest() failed: %v", err)
+  }
+  if committed {
+    t.Errorf("Expected nothing to be committed while a merge request is open")
+  }
+}
+
+func TestCommitAsMergeRequestRecreatesLeftoverBranch(t *testing.T) {
+  var requests []string
+
+  mux := http.NewServeMux()
+  mux.HandleFunc("/api/v4/projects/1/merge_requests", func(w http.ResponseWriter, r *http.Request) {
+    requests = append(requests, r.Method+" merge_requests")
+    if r.Method == http.MethodGet {
+      w.Write([]byte(`[]`))
+      return
+    }
+    w.Write([]byte(`{"id": 11, "iid": 4}`))
+  })
+  mux.HandleFunc("/api/v4/projects/1/repository/branches/enforcer/files", func(w http.ResponseWriter, r *http.Request) {
+    requests = append(requests, r.Method+" branch")
+    if r.Method == http.MethodGet {
+      w.Write([]byte(`{"name": "enforcer/files"}`))
+      return
+    }
+    w.WriteHeader(http.StatusNoContent)
+  })
+  mux.HandleFunc("/api/v4/projects/1/repository/commits", func(w http.ResponseWriter, r *http.Request) {
+    requests = append(requests, r.Method+" commits")
+    w.Write([]byte(`{"id": "abc"}`))
+  })
+
+  manager, shutdown := newTestProjectManager(t, mux, &config.Config{})
+  defer shutdown()
+
+  project := gitlab.Project{ID: 1, PathWithNamespace: "example/api", DefaultBranch: "master"}
+  committed, err := manager.commitAsMergeRequest(context.Background(), project, newTestCommitOptions(), "enforcer/files", "repository files", false)
+  if err != nil {
+    t.Fatalf("commitAsMergeRequest() failed: %v", err)
+  }
+  if !committed {
+    t.Errorf("Expected the files to be committed")
+  }
+
+  expected := []string{"GET merge_requests", "GET branch", "DELETE branch", "POST commits", "POST merge_requests"}
+  if !reflect.DeepEqual(requests, expected) {
+    t.Errorf("Expected requests %v, but got %v", expected, requests)
+  }
+}
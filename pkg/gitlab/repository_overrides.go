@@ -0,0 +1,81 @@
+package gitlab
+
+import (
+  "context"
+
+  "github.com/xanzy/go-gitlab"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/config"
+)
+
+// policyFor returns the effective policy of the project, including the matching rules, the
+// whitelisted settings of the override file in its default branch and the desired settings of
+// rego policies, without the settings the project is exempted from. The result is cached per project.
+func (m *ProjectManager) policyFor(ctx context.Context, project gitlab.Project) (*config.Policy, error) {
+  m.mu.Lock()
+  policy, ok := m.policies[project.PathWithNamespace]
+  m.mu.Unlock()
+  if ok {
+    return policy, nil
+  }
+
+  policy, err := m.config.PolicyFor(project.PathWithNamespace)
+  if err != nil {
+    return nil, err
+  }
+
+  policy, err = m.applyRules(ctx, project, policy)
+  if err != nil {
+    return nil, err
+  }
+
+  if m.config.RepositoryOverrides != nil && project.DefaultBranch != "" {
+    policy = m.applyRepositoryOverride(ctx, project, policy)
+  }
+
+  policy, err = m.applyRegoPolicies(ctx, project, policy)
+  if err != nil {
+    return nil, err
+  }
+
+  policy, err = m.applyExemptions(project, policy)
+  if err != nil {
+    return nil, err
+  }
+
+  m.mu.Lock()
+  m.policies[project.PathWithNamespace] = policy
+  m.mu.Unlock()
+
+  return policy, nil
+}
+
+// applyRepositoryOverride merges the whitelisted settings of the override file in the default
+// branch of the project on top of policy. An override file which can't be read or decoded is
+// ignored with a warning, so it can't take the project out of the central policy.
+func (m *ProjectManager) applyRepositoryOverride(ctx context.Context, project gitlab.Project, policy *config.Policy) *config.Policy {
+  path := m.config.RepositoryOverrides.Path
+
+  content, exists, err := m.getRepositoryFileContent(ctx, project, path, project.DefaultBranch)
+  if err != nil {
+    m.logger.Warnf("Ignoring the override file: %v", err)
+    return policy
+  }
+  if !exists {
+    return policy
+  }
+
+  m.logger.Debugf("Applying %s of project %s", path, project.PathWithNamespace)
+
+  overridden, rejected, err := m.config.ApplyRepositoryOverride(policy, []byte(content))
+  if err != nil {
+    m.logger.Warnf("Ignoring %s of project %s: %v", path, project.PathWithNamespace, err)
+    return policy
+  }
+
+  for _, key := range rejected {
+    m.logger.Warnf("Ignoring %s in %s of project %s: not allowed to be overridden", key, path, project.PathWithNamespace)
+  }
+
+  return overridden
+}
@@ -0,0 +1,47 @@
+package gitlab
+
+import (
+  "context"
+  "encoding/base64"
+  "fmt"
+  "net/http"
+  "testing"
+
+  "github.com/xanzy/go-gitlab"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/config"
+)
+
+func TestPolicyForIgnoresBrokenRepositoryOverride(t *testing.T) {
+  requested := false
+
+  mux := http.NewServeMux()
+  mux.HandleFunc("/api/v4/projects/1/repository/files/.gitlab-settings.yml", func(w http.ResponseWriter, r *http.Request) {
+    requested = true
+    content := base64.StdEncoding.EncodeToString([]byte("project_settings: [merge_method: merge"))
+    fmt.Fprintf(w, `{"file_path": ".gitlab-settings.yml", "encoding": "base64", "content": %q}`, content)
+  })
+
+  cfg := &config.Config{
+    ProjectSettings: &gitlab.EditProjectOptions{MergeMethod: gitlab.MergeMethod(gitlab.FastForwardMerge)},
+    RepositoryOverrides: &config.RepositoryOverrides{
+      Path:    config.DefaultRepositoryOverridesPath,
+      Allowed: []string{"project_settings.merge_method"},
+    },
+  }
+  manager, shutdown := newTestProjectManager(t, mux, cfg)
+  defer shutdown()
+
+  project := gitlab.Project{ID: 1, PathWithNamespace: "example/api", DefaultBranch: "master"}
+  policy, err := manager.policyFor(context.Background(), project)
+  if err != nil {
+    t.Fatalf("policyFor() failed: %v", err)
+  }
+  if !requested {
+    t.Fatalf("Expected the override file to be read")
+  }
+
+  if policy.ProjectSettings == nil || policy.ProjectSettings.MergeMethod == nil || *policy.ProjectSettings.MergeMethod != gitlab.FastForwardMerge {
+    t.Errorf("Expected the central merge_method ff to be enforced, got %+v", policy.ProjectSettings)
+  }
+}
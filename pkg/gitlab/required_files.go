@@ -0,0 +1,68 @@
+package gitlab
+
+import (
+  "context"
+
+  "github.com/xanzy/go-gitlab"
+)
+
+// AuditRequiredFiles checks the default branch of the project for the files listed
+// in compliance.required_files and records their presence.
+func (m *ProjectManager) AuditRequiredFiles(ctx context.Context, project gitlab.Project) error {
+  if m.config.Compliance == nil || len(m.config.Compliance.RequiredFiles) == 0 {
+    m.logger.Debugf("No compliance.required_files provided in config")
+    return nil
+  }
+
+  m.logger.Debugf("Auditing required files of project %s ...", project.PathWithNamespace)
+
+  audit := make(map[string]bool)
+
+  for _, path := range m.config.Compliance.RequiredFiles {
+    // Empty repositories have no default branch and therefore no files
+    if project.DefaultBranch == "" {
+      audit[path] = false
+      continue
+    }
+
+    exists, err := m.repositoryFileExists(ctx, project, path, project.DefaultBranch)
+    if err != nil {
+      return err
+    }
+
+    m.logger.Debugf("File %s present: %t", path, exists)
+    audit[path] = exists
+  }
+
+  m.mu.Lock()
+  m.RequiredFilesAudit[project.PathWithNamespace] = audit
+  m.mu.Unlock()
+
+  m.logger.Debugf("Auditing required files of project %s done.", project.PathWithNamespace)
+
+  return nil
+}
+
+// requiredFileState returns the printable audit state of a single required file
+func (m *ProjectManager) requiredFileState(project string, path string) string {
+  present, ok := m.RequiredFilesAudit[project][path]
+  switch {
+  case !ok:
+    return "NOT AUDITED"
+  case present:
+    return "present"
+  default:
+    return "MISSING"
+  }
+}
+
+// requiredFilesResult returns PASS if all required files are present in the project, FAIL otherwise
+func (m *ProjectManager) requiredFilesResult(project string) string {
+  for _, path := range m.config.Compliance.RequiredFiles {
+    if !m.RequiredFilesAudit[project][path] {
+      return "FAIL"
+    }
+  }
+
+  return "PASS"
+}
@@ -0,0 +1,42 @@
+package gitlab
+
+import (
+  "context"
+  "net/http"
+  "reflect"
+  "testing"
+
+  "github.com/xanzy/go-gitlab"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/config"
+)
+
+func TestAuditRequiredFiles(t *testing.T) {
+  mux := http.NewServeMux()
+  mux.HandleFunc("/api/v4/projects/1/repository/files/", func(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodHead {
+      t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+    }
+    if r.URL.Query().Get("ref") != "main" {
+      t.Errorf("Expected the default branch as ref, but got %q", r.URL.Query().Get("ref"))
+    }
+    if r.URL.Path != "/api/v4/projects/1/repository/files/README.md" {
+      http.NotFound(w, r)
+    }
+  })
+
+  manager, shutdown := newTestProjectManager(t, mux, &config.Config{
+    Compliance: &config.ComplianceSettings{RequiredFiles: []string{"README.md", "CODEOWNERS"}},
+  })
+  defer shutdown()
+
+  project := gitlab.Project{ID: 1, PathWithNamespace: "example/api", DefaultBranch: "main"}
+  if err := manager.AuditRequiredFiles(context.Background(), project); err != nil {
+    t.Fatalf("AuditRequiredFiles() failed: %v", err)
+  }
+
+  expected := map[string]bool{"README.md": true, "CODEOWNERS": false}
+  if !reflect.DeepEqual(manager.RequiredFilesAudit["example/api"], expected) {
+    t.Errorf("Expected audit %v, but got %v", expected, manager.RequiredFilesAudit["example/api"])
+  }
+}
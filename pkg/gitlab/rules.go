@@ -0,0 +1,103 @@
+package gitlab
+
+import (
+  "context"
+  "fmt"
+  "net/http"
+  "strings"
+  "time"
+
+  "github.com/xanzy/go-gitlab"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/config"
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/internal/stringslice"
+)
+
+// applyRules merges the policies of all rules matching the project, in order, on top of policy
+func (m *ProjectManager) applyRules(ctx context.Context, project gitlab.Project, policy *config.Policy) (*config.Policy, error) {
+  for i, rule := range m.config.Rules {
+    matches, err := m.matchesCondition(ctx, project, rule.When)
+    if err != nil {
+      return nil, err
+    }
+    if !matches {
+      continue
+    }
+
+    m.logger.Debugf("Applying rule %d (%s) to project %s", i, rule.Name, project.PathWithNamespace)
+
+    rulePolicy := rule.Policy
+    policy, err = policy.Merge(&rulePolicy)
+    if err != nil {
+      return nil, fmt.Errorf("failed to apply rule %d (%s) to project %s: %v", i, rule.Name, project.PathWithNamespace, err)
+    }
+  }
+
+  return policy, nil
+}
+
+// matchesCondition returns whether the project matches all fields set in the condition
+func (m *ProjectManager) matchesCondition(ctx context.Context, project gitlab.Project, when config.RuleCondition) (bool, error) {
+  if len(when.Visibility) > 0 && !stringslice.Contains(string(project.Visibility), when.Visibility) {
+    return false, nil
+  }
+
+  if len(when.Topics) > 0 && !containsAny(project.TagList, when.Topics) {
+    return false, nil
+  }
+
+  if when.Path != "" && !stringslice.Match(when.Path, project.PathWithNamespace) {
+    return false, nil
+  }
+
+  if when.MinInactiveDays > 0 || when.MaxInactiveDays > 0 {
+    if project.LastActivityAt == nil {
+      return false, nil
+    }
+
+    inactiveDays := int(time.Since(*project.LastActivityAt).Hours() / 24)
+    if when.MinInactiveDays > 0 && inactiveDays < when.MinInactiveDays {
+      return false, nil
+    }
+    if when.MaxInactiveDays > 0 && inactiveDays > when.MaxInactiveDays {
+      return false, nil
+    }
+  }
+
+  if len(when.Languages) > 0 {
+    languages, err := m.getProjectLanguages(ctx, project)
+    if err != nil {
+      return false, err
+    }
+
+    matches := false
+    for language := range languages {
+      for _, l := range when.Languages {
+        if strings.EqualFold(language, l) {
+          matches = true
+        }
+      }
+    }
+    if !matches {
+      return false, nil
+    }
+  }
+
+  return true, nil
+}
+
+// getProjectLanguages returns the languages of the project with their share in percent
+// https://docs.gitlab.com/ee/api/projects.html#languages
+func (m *ProjectManager) getProjectLanguages(ctx context.Context, project gitlab.Project) (map[string]float64, error) {
+  req, err := m.apiClient.NewRequest(http.MethodGet, fmt.Sprintf("projects/%d/languages", project.ID), nil, []gitlab.OptionFunc{gitlab.WithContext(ctx)})
+  if err != nil {
+    return nil, fmt.Errorf("failed to create request for languages of project %s: %v", project.PathWithNamespace, err)
+  }
+
+  languages := make(map[string]float64)
+  if _, err := m.apiClient.Do(req, &languages); err != nil {
+    return nil, fmt.Errorf("failed to get languages of project %s: %v", project.PathWithNamespace, err)
+  }
+
+  return languages, nil
+}
@@ -0,0 +1,211 @@
+package gitlab
+
+import (
+  "encoding/json"
+  "fmt"
+  "io"
+  "reflect"
+  "sort"
+
+  "github.com/iancoleman/strcase"
+)
+
+// OutputFormatSARIF renders the compliance audit as SARIF 2.1.0 log
+const OutputFormatSARIF = "sarif"
+
+const (
+  sarifVersion = "2.1.0"
+  sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+type sarifLog struct {
+  Version string     `json:"version"`
+  Schema  string     `json:"$schema"`
+  Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+  Tool    sarifTool     `json:"tool"`
+  Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+  Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+  Name           string      `json:"name"`
+  InformationURI string      `json:"informationUri"`
+  Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+  ID               string       `json:"id"`
+  ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+  RuleID    string          `json:"ruleId"`
+  Level     string          `json:"level"`
+  Message   sarifMessage    `json:"message"`
+  Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+  Text string `json:"text"`
+}
+
+type sarifLocation struct {
+  PhysicalLocation sarifPhysicalLocation  `json:"physicalLocation"`
+  LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifPhysicalLocation struct {
+  ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+  URI string `json:"uri"`
+}
+
+type sarifLogicalLocation struct {
+  FullyQualifiedName string `json:"fullyQualifiedName"`
+  Kind               string `json:"kind"`
+}
+
+// GenerateComplianceSARIF writes every non-compliant mandatory setting and missing required file
+// found by the compliance audit to w as SARIF log, one result per project and finding
+func (m *ProjectManager) GenerateComplianceSARIF(w io.Writer) error {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  run := sarifRun{
+    Tool: sarifTool{
+      Driver: sarifDriver{
+        Name:           "gitlab-settings-enforcer",
+        InformationURI: "https://github.com/erinkerNCS/gitlab-settings-enforcer",
+      },
+    },
+    Results: []sarifResult{},
+  }
+
+  // Create sorted list of projects
+  var project_names []string
+  for project_name := range m.ProjectSettingsOriginal {
+    project_names = append(project_names, project_name)
+  }
+  sort.Strings(project_names)
+
+  // Create sorted list of rules, one per mandatory setting and required file
+  var subsections []string
+  for subsection := range m.config.Compliance.Mandatory {
+    subsections = append(subsections, subsection)
+  }
+  sort.Strings(subsections)
+
+  var mandatory [][2]string
+  for _, subsection := range subsections {
+    var settings []string
+    for setting := range m.config.Compliance.Mandatory[subsection] {
+      settings = append(settings, setting)
+    }
+    sort.Strings(settings)
+
+    for _, setting := range settings {
+      mandatory = append(mandatory, [2]string{subsection, setting})
+      run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+        ID:               subsection + "." + setting,
+        ShortDescription: sarifMessage{Text: fmt.Sprintf("%s.%s must be %v", subsection, setting, m.config.Compliance.Mandatory[subsection][setting])},
+      })
+    }
+  }
+
+  required_files := append([]string{}, m.config.Compliance.RequiredFiles...)
+  sort.Strings(required_files)
+  for _, path := range required_files {
+    run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+      ID:               "required_files." + path,
+      ShortDescription: sarifMessage{Text: fmt.Sprintf("%s must exist on the default branch", path)},
+    })
+  }
+
+  // Record findings
+  for _, name := range project_names {
+    location := m.sarifLocation(name)
+
+    for _, key := range mandatory {
+      subsection, setting := key[0], key[1]
+      expected := m.config.Compliance.Mandatory[subsection][setting]
+
+      current, ok := m.complianceSettingValue(subsection, name, setting)
+      if ok && fmt.Sprintf("%v", current) == fmt.Sprintf("%v", expected) {
+        continue
+      }
+
+      run.Results = append(run.Results, sarifResult{
+        RuleID:    subsection + "." + setting,
+        Level:     "error",
+        Message:   sarifMessage{Text: fmt.Sprintf("%s.%s of project %s is %v, expected %v", subsection, setting, name, current, expected)},
+        Locations: []sarifLocation{location},
+      })
+    }
+
+    for _, path := range required_files {
+      if m.RequiredFilesAudit[name][path] {
+        continue
+      }
+
+      run.Results = append(run.Results, sarifResult{
+        RuleID:    "required_files." + path,
+        Level:     "error",
+        Message:   sarifMessage{Text: fmt.Sprintf("%s of project %s is %s", path, name, m.requiredFileState(name, path))},
+        Locations: []sarifLocation{location},
+      })
+    }
+  }
+
+  body, err := json.MarshalIndent(sarifLog{Version: sarifVersion, Schema: sarifSchema, Runs: []sarifRun{run}}, "", "  ")
+  if err != nil {
+    return fmt.Errorf("failed to marshal sarif log: %v", err)
+  }
+
+  _, err = fmt.Fprintf(w, "%s\n", body)
+  return err
+}
+
+// sarifLocation returns the location of findings of the given project, pointing to its web URL
+func (m *ProjectManager) sarifLocation(project string) sarifLocation {
+  uri := project
+  if settings := m.ProjectSettingsOriginal[project]; settings != nil && settings.WebURL != "" {
+    uri = settings.WebURL
+  }
+
+  return sarifLocation{
+    PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: uri}},
+    LogicalLocations: []sarifLogicalLocation{{FullyQualifiedName: project, Kind: "module"}},
+  }
+}
+
+// complianceSettingValue returns the audited value of a setting of the project, if it is a valid setting
+func (m *ProjectManager) complianceSettingValue(subsection string, project string, setting string) (interface{}, bool) {
+  var structure reflect.Value
+  switch subsection {
+  case "approval_settings":
+    structure = reflect.ValueOf(m.ApprovalSettingsOriginal[project])
+  case "project_settings":
+    structure = reflect.ValueOf(m.ProjectSettingsOriginal[project])
+  default:
+    return nil, false
+  }
+
+  if structure.IsNil() {
+    return nil, false
+  }
+
+  field := structure.Elem().FieldByName(strcase.ToCamel(setting))
+  if !field.IsValid() {
+    return "NOT VALID SETTING", false
+  }
+
+  return field.Interface(), true
+}
@@ -0,0 +1,126 @@
+package gitlab
+
+import (
+  "context"
+  "encoding/json"
+  "fmt"
+  "io"
+  "path"
+  "sort"
+  "time"
+
+  "github.com/xanzy/go-gitlab"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/config"
+)
+
+// scaffoldProjectSpecificSettings are the project settings describing the exemplar project
+// itself, which are written commented out as enforcing them on every project is rarely wanted
+var scaffoldProjectSpecificSettings = map[string]string{
+  "name":                                        "identifies the project",
+  "path":                                        "identifies the project",
+  "description":                                 "describes the project",
+  "default_branch":                              "differs between projects, see create_default_branch",
+  "tag_list":                                    "describes the project",
+  "ci_config_path":                              "depends on the repository layout",
+  "build_coverage_regex":                        "depends on the test tooling of the project",
+  "import_url":                                  "only used when importing",
+  "mirror":                                      "only used by mirrored projects",
+  "mirror_user_id":                              "only used by mirrored projects",
+  "mirror_trigger_builds":                       "only used by mirrored projects",
+  "only_mirror_protected_branches":              "only used by mirrored projects",
+  "mirror_overwrites_diverged_branches":         "only used by mirrored projects",
+  "external_authorization_classification_label": "depends on the data of the project",
+}
+
+// Scaffold writes a starter config enforcing the settings, approval configuration and branch
+// protections of the given exemplar project on the group containing it
+func (m *ProjectManager) Scaffold(ctx context.Context, w io.Writer, project gitlab.Project, now time.Time) error {
+  snapshot, err := m.SnapshotProject(ctx, project)
+  if err != nil {
+    return err
+  }
+
+  projectSettings := &gitlab.EditProjectOptions{}
+  if err := fromJSONMap(snapshot.ProjectSettings, projectSettings); err != nil {
+    return fmt.Errorf("invalid project settings of project %s: %v", project.PathWithNamespace, err)
+  }
+  projectValues, err := toJSONMap(projectSettings)
+  if err != nil {
+    return err
+  }
+
+  approvalSettings := &gitlab.ChangeApprovalConfigurationOptions{}
+  if err := fromJSONMap(snapshot.ApprovalSettings, approvalSettings); err != nil {
+    return fmt.Errorf("invalid approval settings of project %s: %v", project.PathWithNamespace, err)
+  }
+  approvalValues, err := toJSONMap(approvalSettings)
+  if err != nil {
+    return err
+  }
+
+  fmt.Fprintf(w, "# Generated from project %s at %s.\n", project.PathWithNamespace, now.UTC().Format(time.RFC3339))
+  fmt.Fprintf(w, "# Review every setting before enforcing it on all projects of the group. Commented out\n")
+  fmt.Fprintf(w, "# settings are specific to the exemplar project and usually shouldn't be enforced.\n")
+  fmt.Fprintf(w, "group_name: %s\n", scaffoldValue(path.Dir(project.PathWithNamespace)))
+
+  if len(projectValues) > 0 {
+    fmt.Fprintf(w, "\nproject_settings:\n")
+    for _, setting := range sortedKeys(projectValues) {
+      if reason, ok := scaffoldProjectSpecificSettings[setting]; ok {
+        fmt.Fprintf(w, "  # %s: %s  # %s\n", setting, scaffoldValue(projectValues[setting]), reason)
+        continue
+      }
+      fmt.Fprintf(w, "  %s: %s\n", setting, scaffoldValue(projectValues[setting]))
+    }
+  }
+
+  if len(approvalValues) > 0 {
+    fmt.Fprintf(w, "\napproval_settings:\n")
+    for _, setting := range sortedKeys(approvalValues) {
+      fmt.Fprintf(w, "  %s: %s\n", setting, scaffoldValue(approvalValues[setting]))
+    }
+  }
+
+  if len(snapshot.ProtectedBranches) > 0 {
+    fmt.Fprintf(w, "\nprotected_branches:\n")
+    for _, b := range snapshot.ProtectedBranches {
+      fmt.Fprintf(w, "  - name: %s\n", scaffoldValue(b.Name))
+      fmt.Fprintf(w, "    push_access_level: %s\n", scaffoldAccessLevel(b.PushAccessLevels))
+      fmt.Fprintf(w, "    merge_access_level: %s\n", scaffoldAccessLevel(b.MergeAccessLevels))
+    }
+  }
+
+  return nil
+}
+
+// scaffoldValue encodes the value as JSON, which YAML parses as the same value
+func scaffoldValue(value interface{}) string {
+  encoded, err := json.Marshal(value)
+  if err != nil {
+    return "null"
+  }
+
+  return string(encoded)
+}
+
+// scaffoldAccessLevel returns the config alias of the first access level
+func scaffoldAccessLevel(levels []*gitlab.BranchAccessDescription) string {
+  level := firstAccessLevel(levels)
+  if level == nil {
+    return config.AccessLevelNoOne
+  }
+
+  return string(config.AccessLevelOf(*level))
+}
+
+// sortedKeys returns the keys of the map in lexical order
+func sortedKeys(values map[string]interface{}) []string {
+  keys := make([]string, 0, len(values))
+  for key := range values {
+    keys = append(keys, key)
+  }
+  sort.Strings(keys)
+
+  return keys
+}
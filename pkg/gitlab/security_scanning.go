@@ -0,0 +1,195 @@
+package gitlab
+
+import (
+  "context"
+  "fmt"
+  "io"
+  "regexp"
+  "sort"
+  "strings"
+
+  "github.com/xanzy/go-gitlab"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/config"
+)
+
+const (
+  gitlabCIFile                         = ".gitlab-ci.yml"
+  defaultSecurityScanningCommitMessage = "Enable security scanning"
+)
+
+// scannerTemplates are the CI templates of GitLab enabling each scanner, the first one is added
+var scannerTemplates = map[string][]string{
+  config.ScannerSAST:               {"Security/SAST.gitlab-ci.yml", "Jobs/SAST.gitlab-ci.yml"},
+  config.ScannerSecretDetection:    {"Security/Secret-Detection.gitlab-ci.yml", "Jobs/Secret-Detection.gitlab-ci.yml"},
+  config.ScannerDependencyScanning: {"Security/Dependency-Scanning.gitlab-ci.yml", "Jobs/Dependency-Scanning.gitlab-ci.yml"},
+}
+
+// topLevelInclude matches an include keyword of a CI configuration
+var topLevelInclude = regexp.MustCompile(`(?m)^include:`)
+
+// EnsureSecurityScanning records which of the scanners configured in security_scanning the
+// .gitlab-ci.yml of the project includes. With security_scanning.enforce, the missing templates
+// are added by committing (or proposing) an include block. Files including other files already
+// can't be extended safely and are reported as violation instead.
+func (m *ProjectManager) EnsureSecurityScanning(ctx context.Context, project gitlab.Project, dryrun bool) error {
+  settings := m.config.SecurityScanning
+  if settings == nil || len(settings.Scanners) == 0 {
+    m.logger.Debugf("No security_scanning provided in config")
+    return nil
+  }
+
+  // Empty repositories have no pipelines to scan in
+  if project.DefaultBranch == "" {
+    m.recordScanningCoverage(project, nil)
+    return nil
+  }
+
+  m.logger.Debugf("Ensuring security scanning of project %s ...", project.PathWithNamespace)
+
+  content, exists, err := m.getRepositoryFileContent(ctx, project, gitlabCIFile, project.DefaultBranch)
+  if err != nil {
+    return err
+  }
+
+  coverage := make(map[string]bool)
+  var missing []string
+  for _, scanner := range settings.Scanners {
+    coverage[scanner] = includesTemplate(content, scannerTemplates[scanner])
+    if !coverage[scanner] {
+      missing = append(missing, scanner)
+    }
+  }
+  m.recordScanningCoverage(project, coverage)
+
+  if len(missing) == 0 || !settings.Enforce {
+    return nil
+  }
+
+  if topLevelInclude.MatchString(content) {
+    m.addViolation(project.PathWithNamespace, "security_scanning: %s includes other files, add the templates of %s manually", gitlabCIFile, strings.Join(missing, ", "))
+    return nil
+  }
+
+  block := "include:\n"
+  for _, scanner := range missing {
+    block += "  - template: " + scannerTemplates[scanner][0] + "\n"
+  }
+
+  action := &gitlab.CommitAction{Action: gitlab.FileCreate, FilePath: gitlabCIFile, Content: block}
+  if exists {
+    if content != "" && !strings.HasSuffix(content, "\n") {
+      content += "\n"
+    }
+    action.Action = gitlab.FileUpdate
+    action.Content = content + "\n" + block
+  }
+
+  opt := &gitlab.CreateCommitOptions{
+    Branch:        gitlab.String(project.DefaultBranch),
+    CommitMessage: gitlab.String(defaultSecurityScanningCommitMessage),
+    Actions:       []*gitlab.CommitAction{action},
+  }
+  if settings.CommitMessage != "" {
+    opt.CommitMessage = gitlab.String(settings.CommitMessage)
+  }
+
+  committed := true
+  switch settings.Method {
+  case config.RepositoryFilesMethodMergeRequest:
+    committed, err = m.commitAsMergeRequest(ctx, project, opt, settings.Branch, "security scanning", dryrun)
+    if err != nil {
+      return err
+    }
+  default:
+    if dryrun {
+      m.logSkippedCall(project, "CreateCommit", opt)
+      break
+    }
+
+    _, _, err := m.commitsClient.CreateCommit(project.ID, opt, gitlab.WithContext(ctx))
+    m.audit(project.PathWithNamespace, fmt.Sprintf("POST projects/%d/repository/commits", project.ID), repositoryFilesDrift(opt), err)
+    if err != nil {
+      return fmt.Errorf("failed to commit security scanning to project %s: %v", project.PathWithNamespace, err)
+    }
+  }
+
+  // Dryruns record the planned changes, a still open merge request none
+  if committed {
+    for _, scanner := range missing {
+      m.addChange(project.PathWithNamespace, "security_scanning", scanner, false, true)
+    }
+  }
+
+  m.logger.Debugf("Ensuring security scanning of project %s done.", project.PathWithNamespace)
+
+  return nil
+}
+
+// includesTemplate returns whether the CI configuration includes any of the templates
+func includesTemplate(content string, templates []string) bool {
+  for _, template := range templates {
+    if strings.Contains(content, template) {
+      return true
+    }
+  }
+
+  return false
+}
+
+// recordScanningCoverage records which scanners the project runs, nil if it has no repository
+func (m *ProjectManager) recordScanningCoverage(project gitlab.Project, coverage map[string]bool) {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  m.ScanningCoverage[project.PathWithNamespace] = coverage
+}
+
+// GenerateScanningCoverageReport writes the number of projects running each configured scanner and
+// the projects missing any of them to w. Nothing is written if no project was audited.
+func (m *ProjectManager) GenerateScanningCoverageReport(w io.Writer) error {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  if len(m.ScanningCoverage) == 0 {
+    return nil
+  }
+
+  var projects []string
+  for project := range m.ScanningCoverage {
+    projects = append(projects, project)
+  }
+  sort.Strings(projects)
+
+  fmt.Fprintf(w, "\nSECURITY SCANNING COVERAGE\n")
+
+  for _, scanner := range m.config.SecurityScanning.Scanners {
+    covered := 0
+    for _, project := range projects {
+      if m.ScanningCoverage[project][scanner] {
+        covered++
+      }
+    }
+    fmt.Fprintf(w, "  %s: %d of %d project(s)\n", scanner, covered, len(projects))
+  }
+
+  for _, project := range projects {
+    coverage := m.ScanningCoverage[project]
+    if coverage == nil {
+      fmt.Fprintf(w, "  %s: empty repository\n", project)
+      continue
+    }
+
+    var missing []string
+    for _, scanner := range m.config.SecurityScanning.Scanners {
+      if !coverage[scanner] {
+        missing = append(missing, scanner)
+      }
+    }
+    if len(missing) > 0 {
+      fmt.Fprintf(w, "  %s: missing %s\n", project, strings.Join(missing, ", "))
+    }
+  }
+
+  return nil
+}
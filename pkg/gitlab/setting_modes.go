@@ -0,0 +1,60 @@
+package gitlab
+
+import (
+  "encoding/json"
+  "fmt"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/config"
+)
+
+// applySettingModes unmarshals the desired settings of the given section into dst, leaving out
+// every default-only setting which already has a value in current and every setting whose
+// current value is allowed
+func (m *ProjectManager) applySettingModes(projectPath string, section string, policy *config.Policy, desired interface{}, current interface{}, dst interface{}) error {
+  desiredMap, err := toJSONMap(desired)
+  if err != nil {
+    return fmt.Errorf("failed to convert desired %s: %v", section, err)
+  }
+
+  currentMap, err := toJSONMap(current)
+  if err != nil {
+    return fmt.Errorf("failed to convert current %s: %v", section, err)
+  }
+
+  m.applyAllowedValues(projectPath, section, policy, desiredMap, currentMap)
+
+  for setting := range desiredMap {
+    if policy.ModeFor(section, setting) != config.SettingModeDefaultOnly {
+      continue
+    }
+
+    if !isUnsetValue(currentMap[setting]) {
+      m.logger.Debugf("Keeping current value %v of default-only setting %s.%s", currentMap[setting], section, setting)
+      delete(desiredMap, setting)
+    }
+  }
+
+  b, err := json.Marshal(desiredMap)
+  if err != nil {
+    return fmt.Errorf("failed to convert %s to json: %v", section, err)
+  }
+
+  return json.Unmarshal(b, dst)
+}
+
+// isUnsetValue reports whether a JSON decoded value is empty. Booleans and numbers
+// always count as set, as GitLab has no notion of an unset one.
+func isUnsetValue(v interface{}) bool {
+  switch value := v.(type) {
+  case nil:
+    return true
+  case string:
+    return value == ""
+  case []interface{}:
+    return len(value) == 0
+  case map[string]interface{}:
+    return len(value) == 0
+  default:
+    return false
+  }
+}
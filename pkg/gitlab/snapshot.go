@@ -0,0 +1,310 @@
+package gitlab
+
+import (
+  "context"
+  "encoding/json"
+  "fmt"
+  "net/http"
+  "reflect"
+  "strings"
+  "time"
+
+  "github.com/xanzy/go-gitlab"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/storage"
+)
+
+// Snapshot holds the settings of projects at a point in time, keyed by project path.
+// Rollback files are snapshots of the original values of the settings changed by a sync run.
+type Snapshot struct {
+  CreatedAt time.Time                  `json:"created_at"`
+  Projects  map[string]ProjectSnapshot `json:"projects"`
+}
+
+// ProjectSnapshot holds the settings of a single project, keyed by their API names
+type ProjectSnapshot struct {
+  ProjectSettings   map[string]interface{}    `json:"project_settings,omitempty"`
+  ApprovalSettings  map[string]interface{}    `json:"approval_settings,omitempty"`
+  ProtectedBranches []*gitlab.ProtectedBranch `json:"protected_branches,omitempty"`
+}
+
+// NewRollback returns the snapshot of the original project and approval settings of the change log.
+// Only settings of EditProjectOptions respectively ChangeApprovalConfigurationOptions are kept,
+// others (e.g. "namespace.name" or "last_activity_at") can't be changed by the API.
+func NewRollback(changes ChangeLog, now time.Time) *Snapshot {
+  s := &Snapshot{
+    CreatedAt: now,
+    Projects:  make(map[string]ProjectSnapshot),
+  }
+
+  projectFields := optionFields(gitlab.EditProjectOptions{})
+  approvalFields := optionFields(gitlab.ChangeApprovalConfigurationOptions{})
+
+  for project, subsections := range changes {
+    settings := ProjectSnapshot{
+      ProjectSettings:  originalValues(subsections["project_settings"], projectFields),
+      ApprovalSettings: originalValues(subsections["approval_settings"], approvalFields),
+    }
+
+    if len(settings.ProjectSettings) > 0 || len(settings.ApprovalSettings) > 0 {
+      s.Projects[project] = settings
+    }
+  }
+
+  return s
+}
+
+// originalValues returns the previous values of the given changes of the given fields, keyed by setting
+func originalValues(changes map[string]map[string]interface{}, fields map[string]bool) map[string]interface{} {
+  values := make(map[string]interface{})
+  for setting, change := range changes {
+    if fields[setting] {
+      values[setting] = change["From"]
+    }
+  }
+
+  return values
+}
+
+// optionFields returns the JSON names of the fields of the given options struct
+func optionFields(options interface{}) map[string]bool {
+  fields := make(map[string]bool)
+
+  t := reflect.TypeOf(options)
+  for i := 0; i < t.NumField(); i++ {
+    name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+    if name != "" && name != "-" {
+      fields[name] = true
+    }
+  }
+
+  return fields
+}
+
+// LoadSnapshot reads the snapshot (or rollback) file at the given path, which may be remote
+func LoadSnapshot(path string) (*Snapshot, error) {
+  b, err := storage.ReadFile(path)
+  if err != nil {
+    return nil, fmt.Errorf("failed to read snapshot file %q: %v", path, err)
+  }
+
+  s := &Snapshot{}
+  if err := json.Unmarshal(b, s); err != nil {
+    return nil, fmt.Errorf("failed to unmarshal snapshot file %q: %v", path, err)
+  }
+
+  return s, nil
+}
+
+// Save writes the snapshot to the file at the given path
+func (s *Snapshot) Save(path string) error {
+  b, err := json.MarshalIndent(s, "", "  ")
+  if err != nil {
+    return fmt.Errorf("failed to marshal snapshot: %v", err)
+  }
+
+  if err := storage.WriteFile(path, b, 0644); err != nil {
+    return fmt.Errorf("failed to write snapshot file %q: %v", path, err)
+  }
+
+  return nil
+}
+
+// SnapshotProject returns the current project settings, approval settings and protected
+// branches of the project
+func (m *ProjectManager) SnapshotProject(ctx context.Context, project gitlab.Project) (ProjectSnapshot, error) {
+  var snapshot ProjectSnapshot
+
+  projectSettings, err := m.GetProjectSettings(ctx, project)
+  if err != nil {
+    return snapshot, err
+  }
+  if snapshot.ProjectSettings, err = toJSONMap(projectSettings); err != nil {
+    return snapshot, fmt.Errorf("failed to convert project settings of project %s: %v", project.PathWithNamespace, err)
+  }
+
+  approvalSettings, err := m.GetProjectApprovalSettings(ctx, project)
+  if err != nil {
+    return snapshot, err
+  }
+  if snapshot.ApprovalSettings, err = toJSONMap(approvalSettings); err != nil {
+    return snapshot, fmt.Errorf("failed to convert approval settings of project %s: %v", project.PathWithNamespace, err)
+  }
+
+  if snapshot.ProtectedBranches, err = m.listProtectedBranches(ctx, project.ID); err != nil {
+    return snapshot, fmt.Errorf("failed to list protected branches of project %s: %v", project.PathWithNamespace, err)
+  }
+
+  return snapshot, nil
+}
+
+// RestoreProject re-applies the settings of the snapshot to the project at the given path.
+// Settings already at their snapshot value are left alone, as are settings and protected
+// branches missing from the snapshot. Destructive changes are refused like by sync.
+func (m *ProjectManager) RestoreProject(ctx context.Context, path string, snapshot ProjectSnapshot, dryrun bool) error {
+  project, _, err := m.projectsClient.GetProject(path, &gitlab.GetProjectOptions{}, gitlab.WithContext(ctx))
+  if err != nil {
+    return fmt.Errorf("failed to get project %s: %v", path, err)
+  }
+
+  if len(snapshot.ProjectSettings) > 0 {
+    options := &gitlab.EditProjectOptions{}
+    if err := fromJSONMap(snapshot.ProjectSettings, options); err != nil {
+      return fmt.Errorf("invalid project settings of project %s: %v", path, err)
+    }
+
+    drift, err := computeDrift(options, project)
+    if err != nil {
+      return err
+    }
+    if err := m.guardDestructive(path, "project_settings", drift, dryrun); err != nil {
+      return err
+    }
+
+    switch {
+    case len(drift) == 0:
+      m.logger.Infof("Project settings of project %s are already restored.", path)
+    case dryrun:
+      m.logSkippedCall(*project, "EditProject", options)
+      m.addDrift(path, "project_settings", drift)
+    default:
+      _, _, err := m.projectsClient.EditProject(project.ID, options, gitlab.WithContext(ctx))
+      m.audit(path, fmt.Sprintf("PUT projects/%d", project.ID), drift, err)
+      if err != nil {
+        return fmt.Errorf("failed to restore project settings of project %s: %v", path, err)
+      }
+      m.addDrift(path, "project_settings", drift)
+    }
+  }
+
+  if len(snapshot.ApprovalSettings) > 0 {
+    options := &gitlab.ChangeApprovalConfigurationOptions{}
+    if err := fromJSONMap(snapshot.ApprovalSettings, options); err != nil {
+      return fmt.Errorf("invalid approval settings of project %s: %v", path, err)
+    }
+
+    current, err := m.GetProjectApprovalSettings(ctx, *project)
+    if err != nil {
+      return err
+    }
+
+    drift, err := computeDrift(options, current)
+    if err != nil {
+      return err
+    }
+    if err := m.guardDestructive(path, "approval_settings", drift, dryrun); err != nil {
+      return err
+    }
+
+    switch {
+    case len(drift) == 0:
+      m.logger.Infof("Approval settings of project %s are already restored.", path)
+    case dryrun:
+      m.logSkippedCall(*project, "ChangeApprovalConfiguration", options)
+      m.addDrift(path, "approval_settings", drift)
+    default:
+      _, _, err := m.projectsClient.ChangeApprovalConfiguration(project.ID, options, gitlab.WithContext(ctx))
+      m.audit(path, fmt.Sprintf("POST projects/%d/approvals", project.ID), drift, err)
+      if err != nil {
+        return fmt.Errorf("failed to restore approval settings of project %s: %v", path, err)
+      }
+      m.addDrift(path, "approval_settings", drift)
+    }
+  }
+
+  if len(snapshot.ProtectedBranches) > 0 {
+    if err := m.restoreProtectedBranches(ctx, *project, snapshot.ProtectedBranches, dryrun); err != nil {
+      return err
+    }
+  }
+
+  return nil
+}
+
+// restoreProtectedBranches (re)protects the given branches of the project whose access levels differ
+func (m *ProjectManager) restoreProtectedBranches(ctx context.Context, project gitlab.Project, branches []*gitlab.ProtectedBranch, dryrun bool) error {
+  current, err := m.listProtectedBranches(ctx, project.ID)
+  if err != nil {
+    return fmt.Errorf("failed to list protected branches of project %s: %v", project.PathWithNamespace, err)
+  }
+
+  currentLevels := make(map[string][2]*gitlab.AccessLevelValue)
+  for _, b := range current {
+    currentLevels[b.Name] = [2]*gitlab.AccessLevelValue{firstAccessLevel(b.PushAccessLevels), firstAccessLevel(b.MergeAccessLevels)}
+  }
+
+  for _, b := range branches {
+    opt := &gitlab.ProtectRepositoryBranchesOptions{
+      Name:             gitlab.String(b.Name),
+      PushAccessLevel:  firstAccessLevel(b.PushAccessLevels),
+      MergeAccessLevel: firstAccessLevel(b.MergeAccessLevels),
+    }
+
+    levels, protected := currentLevels[b.Name]
+    drift, err := computeDrift(
+      map[string]*gitlab.AccessLevelValue{b.Name + ".push_access_level": opt.PushAccessLevel, b.Name + ".merge_access_level": opt.MergeAccessLevel},
+      map[string]*gitlab.AccessLevelValue{b.Name + ".push_access_level": levels[0], b.Name + ".merge_access_level": levels[1]},
+    )
+    if err != nil {
+      return err
+    }
+    if len(drift) == 0 {
+      continue
+    }
+    if err := m.guardDestructive(project.PathWithNamespace, "protected_branches", drift, dryrun); err != nil {
+      return err
+    }
+
+    if dryrun {
+      m.logSkippedCall(project, "ProtectRepositoryBranches", opt)
+      m.addDrift(project.PathWithNamespace, "protected_branches", drift)
+      continue
+    }
+
+    if protected {
+      if err := m.updateBranchProtection(ctx, project, opt, drift); err != nil {
+        return err
+      }
+    } else {
+      _, _, err = m.protectedBranchesClient.ProtectRepositoryBranches(project.ID, opt, gitlab.WithContext(ctx))
+      m.audit(project.PathWithNamespace, fmt.Sprintf("POST projects/%d/protected_branches", project.ID), drift, err)
+      if err != nil {
+        return fmt.Errorf("failed to protect branch %s: %v", b.Name, err)
+      }
+    }
+    m.addDrift(project.PathWithNamespace, "protected_branches", drift)
+  }
+
+  return nil
+}
+
+// listProtectedBranches returns all protected branches of the project
+func (m *ProjectManager) listProtectedBranches(ctx context.Context, projectID int) ([]*gitlab.ProtectedBranch, error) {
+  var branches []*gitlab.ProtectedBranch
+
+  opt := &gitlab.ListProtectedBranchesOptions{PerPage: 100, Page: 1}
+  for {
+    page, resp, err := m.protectedBranchesClient.ListProtectedBranches(projectID, opt, gitlab.WithContext(ctx))
+    if err != nil {
+      if resp != nil && resp.StatusCode == http.StatusNotFound {
+        return nil, nil
+      }
+      return nil, err
+    }
+    branches = append(branches, page...)
+
+    if resp.NextPage == 0 {
+      return branches, nil
+    }
+    opt.Page = resp.NextPage
+  }
+}
+
+// firstAccessLevel returns the access level of the first of the given access descriptions, if any
+func firstAccessLevel(levels []*gitlab.BranchAccessDescription) *gitlab.AccessLevelValue {
+  if len(levels) == 0 {
+    return nil
+  }
+
+  return gitlab.AccessLevel(levels[0].AccessLevel)
+}
@@ -0,0 +1,58 @@
+package gitlab
+
+import (
+  "context"
+  "net/http"
+  "reflect"
+  "testing"
+  "time"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/config"
+)
+
+func TestNewRollbackKeepsOptionSettings(t *testing.T) {
+  changes := ChangeLog{}
+  changes.Add("example/api", "project_settings", "merge_method", "merge", "ff")
+  changes.Add("example/api", "project_settings", "last_activity_at", "2019-01-01", "2019-01-02")
+  changes.Add("example/api", "project_settings", "namespace.name", "example", "platform")
+  changes.Add("example/api", "approval_settings", "reset_approvals_on_push", false, true)
+  changes.Add("example/api", "protected_branches", "master", nil, "maintainer")
+  changes.Add("example/web", "project_settings", "star_count", 1, 2)
+
+  rollback := NewRollback(changes, time.Now())
+
+  want := map[string]ProjectSnapshot{
+    "example/api": {
+      ProjectSettings:  map[string]interface{}{"merge_method": "merge"},
+      ApprovalSettings: map[string]interface{}{"reset_approvals_on_push": false},
+    },
+  }
+  if !reflect.DeepEqual(rollback.Projects, want) {
+    t.Errorf("NewRollback() = %#v, want %#v", rollback.Projects, want)
+  }
+}
+
+func TestRestoreProjectRefusesDestructiveChanges(t *testing.T) {
+  mux := http.NewServeMux()
+  mux.HandleFunc("/api/v4/projects/example/api", func(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodGet {
+      t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+    }
+    w.Write([]byte(`{"id": 1, "path_with_namespace": "example/api", "visibility": "private"}`))
+  })
+  mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+    t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+    http.NotFound(w, r)
+  })
+
+  manager, shutdown := newTestProjectManager(t, mux, &config.Config{})
+  defer shutdown()
+
+  snapshot := ProjectSnapshot{ProjectSettings: map[string]interface{}{"visibility": "public"}}
+  if err := manager.RestoreProject(context.Background(), "example/api", snapshot, false); err == nil {
+    t.Errorf("Expected restoring public visibility to be refused without --allow-destructive")
+  }
+  if len(manager.destructive["example/api"]) != 1 || !manager.destructive["example/api"][0].Refused {
+    t.Errorf("Expected the refused change to be recorded, got %+v", manager.destructive["example/api"])
+  }
+}
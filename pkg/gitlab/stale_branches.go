@@ -0,0 +1,156 @@
+package gitlab
+
+import (
+  "context"
+  "fmt"
+  "io"
+  "sort"
+  "time"
+
+  "github.com/xanzy/go-gitlab"
+)
+
+// defaultStaleBranchMaxAgeDays is the age of the last commit of stale branches, unless configured
+const defaultStaleBranchMaxAgeDays = 90
+
+// StaleBranch is a branch without commits within stale_branches.max_age_days
+type StaleBranch struct {
+  Name       string    `json:"name"`
+  LastCommit time.Time `json:"last_commit"`
+  Deleted    bool      `json:"deleted"`
+}
+
+// staleBranchMaxAge returns the configured age of the last commit of stale branches
+func (m *ProjectManager) staleBranchMaxAge() time.Duration {
+  days := m.config.StaleBranches.MaxAgeDays
+  if days == 0 {
+    days = defaultStaleBranchMaxAgeDays
+  }
+
+  return time.Duration(days) * 24 * time.Hour
+}
+
+// AuditStaleBranches records the branches of the project without commits within
+// stale_branches.max_age_days, leaving out protected branches, the default branch and the
+// excluded ones. With prune, the stale branches are deleted as well.
+func (m *ProjectManager) AuditStaleBranches(ctx context.Context, project gitlab.Project, prune bool, dryrun bool) error {
+  if m.config.StaleBranches == nil {
+    m.logger.Debugf("No stale_branches provided in config")
+    return nil
+  }
+
+  m.logger.Debugf("Auditing stale branches of project %s ...", project.PathWithNamespace)
+
+  branches, err := m.listBranches(ctx, project)
+  if err != nil {
+    return err
+  }
+
+  threshold := time.Now().Add(-m.staleBranchMaxAge())
+
+  var stale []StaleBranch
+  for _, b := range branches {
+    if b.Protected || b.Name == project.DefaultBranch || m.isExcludedStaleBranch(b.Name) {
+      continue
+    }
+    if b.Commit == nil || b.Commit.CommittedDate == nil || b.Commit.CommittedDate.After(threshold) {
+      continue
+    }
+
+    stale = append(stale, StaleBranch{Name: b.Name, LastCommit: *b.Commit.CommittedDate})
+  }
+  sort.Slice(stale, func(i, j int) bool {
+    return stale[i].LastCommit.Before(stale[j].LastCommit)
+  })
+
+  if prune {
+    for i, b := range stale {
+      if err := m.deleteStaleBranch(ctx, project, b.Name, dryrun); err != nil {
+        m.recordStaleBranches(project, stale)
+        return err
+      }
+      stale[i].Deleted = !dryrun
+    }
+  }
+
+  m.recordStaleBranches(project, stale)
+
+  m.logger.Debugf("Auditing stale branches of project %s done.", project.PathWithNamespace)
+
+  return nil
+}
+
+// isExcludedStaleBranch returns whether the branch matches a wildcard of stale_branches.exclude
+func (m *ProjectManager) isExcludedStaleBranch(name string) bool {
+  for _, pattern := range m.config.StaleBranches.Exclude {
+    if wildcardBranchMatch(pattern, name) {
+      return true
+    }
+  }
+
+  return false
+}
+
+// deleteStaleBranch deletes the branch of the project and records the deletion in the change log
+func (m *ProjectManager) deleteStaleBranch(ctx context.Context, project gitlab.Project, name string, dryrun bool) error {
+  if dryrun {
+    m.logger.Infof("DRYRUN: Skipped executing API call [DeleteBranch] of %s on project %s.", name, project.PathWithNamespace)
+  } else {
+    _, err := m.branchesClient.DeleteBranch(project.ID, name, gitlab.WithContext(ctx))
+    m.audit(project.PathWithNamespace, fmt.Sprintf("DELETE projects/%d/repository/branches", project.ID), map[string]settingDrift{
+      "branch": {From: name},
+    }, err)
+    if err != nil {
+      return fmt.Errorf("failed to delete stale branch %s of project %s: %v", name, project.PathWithNamespace, err)
+    }
+  }
+
+  // Dryruns record the planned deletion
+  m.addChange(project.PathWithNamespace, "branches", name+".exists", true, false)
+
+  return nil
+}
+
+// recordStaleBranches records the stale branches of the project for the stale branches report
+func (m *ProjectManager) recordStaleBranches(project gitlab.Project, stale []StaleBranch) {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  if len(stale) == 0 {
+    delete(m.StaleBranches, project.PathWithNamespace)
+    return
+  }
+  m.StaleBranches[project.PathWithNamespace] = stale
+}
+
+// GenerateStaleBranchesReport writes the stale branches of every project, oldest first, to w.
+// Nothing is written if no project has stale branches.
+func (m *ProjectManager) GenerateStaleBranchesReport(w io.Writer) error {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  if len(m.StaleBranches) == 0 {
+    return nil
+  }
+
+  var projects []string
+  for project := range m.StaleBranches {
+    projects = append(projects, project)
+  }
+  sort.Strings(projects)
+
+  fmt.Fprintf(w, "\nSTALE BRANCHES (no commits in %d days)\n", int(m.staleBranchMaxAge().Hours()/24))
+
+  for _, project := range projects {
+    fmt.Fprintf(w, "  %s\n", project)
+    for _, b := range m.StaleBranches[project] {
+      state := ""
+      if b.Deleted {
+        state = " DELETED"
+      }
+      fmt.Fprintf(w, "    %s: last commit %s%s\n", b.Name, b.LastCommit.Format("2006-01-02"), state)
+    }
+  }
+
+  return nil
+}
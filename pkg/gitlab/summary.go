@@ -0,0 +1,61 @@
+package gitlab
+
+import (
+  "encoding/json"
+  "fmt"
+  "io"
+  "time"
+)
+
+// RunSummary counts the results of a sync run
+type RunSummary struct {
+  Projects int           `json:"projects"`
+  Skipped  int           `json:"skipped"`
+  InSync   int           `json:"in_sync"`
+  Changed  int           `json:"changed"`
+  Failed   int           `json:"failed"`
+  APICalls int           `json:"api_calls"`
+  Elapsed  time.Duration `json:"-"`
+}
+
+// MarshalJSON implements json.Marshaler, writing the elapsed time in seconds
+func (s RunSummary) MarshalJSON() ([]byte, error) {
+  type summary RunSummary
+  return json.Marshal(struct {
+    summary
+    ElapsedSeconds float64 `json:"elapsed_seconds"`
+  }{summary(s), s.Elapsed.Seconds()})
+}
+
+// Add adds the counts of other to the summary, the elapsed time is kept
+func (s *RunSummary) Add(other RunSummary) {
+  s.Projects += other.Projects
+  s.Skipped += other.Skipped
+  s.InSync += other.InSync
+  s.Changed += other.Changed
+  s.Failed += other.Failed
+  s.APICalls += other.APICalls
+}
+
+// Render writes the summary as JSON for the json output format, as text for all others
+func (s RunSummary) Render(w io.Writer, format string) error {
+  if format == OutputFormatJSON {
+    body, err := json.MarshalIndent(map[string]RunSummary{"summary": s}, "", "  ")
+    if err != nil {
+      return fmt.Errorf("failed to marshal summary: %v", err)
+    }
+
+    _, err = fmt.Fprintf(w, "%s\n", body)
+    return err
+  }
+
+  fmt.Fprintf(w, "\nSUMMARY\n")
+  fmt.Fprintf(w, "  %-11s%d\n", "Projects:", s.Projects)
+  fmt.Fprintf(w, "  %-11s%d\n", "Skipped:", s.Skipped)
+  fmt.Fprintf(w, "  %-11s%d\n", "In sync:", s.InSync)
+  fmt.Fprintf(w, "  %-11s%d\n", "Changed:", s.Changed)
+  fmt.Fprintf(w, "  %-11s%d\n", "Failed:", s.Failed)
+  fmt.Fprintf(w, "  %-11s%d\n", "API calls:", s.APICalls)
+  _, err := fmt.Fprintf(w, "  %-11s%s\n\n", "Elapsed:", s.Elapsed.Round(time.Second))
+  return err
+}
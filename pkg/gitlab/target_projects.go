@@ -0,0 +1,124 @@
+package gitlab
+
+import (
+  "context"
+  "fmt"
+  "strconv"
+  "strings"
+
+  "github.com/xanzy/go-gitlab"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/internal/stringslice"
+)
+
+// GetProjectsByPattern fetches the projects matching the given patterns, ignoring the project
+// whitelist, blacklist and filters of the config. Exact paths are fetched directly, glob and
+// regular expression patterns are matched against the projects of the configured groups.
+func (m *ProjectManager) GetProjectsByPattern(ctx context.Context, patterns []string) ([]gitlab.Project, error) {
+  var repos []gitlab.Project
+  seen := make(map[int]bool)
+
+  var globs []string
+  for _, pattern := range patterns {
+    if err := stringslice.ValidatePattern(pattern); err != nil {
+      return nil, fmt.Errorf("invalid project pattern %q: %v", pattern, err)
+    }
+
+    if stringslice.IsPattern(pattern) {
+      globs = append(globs, pattern)
+      continue
+    }
+
+    project, _, err := m.projectsClient.GetProject(pattern, &gitlab.GetProjectOptions{}, gitlab.WithContext(ctx))
+    if err != nil {
+      return nil, fmt.Errorf("failed to fetch project %s: %v", pattern, err)
+    }
+    if !seen[project.ID] {
+      seen[project.ID] = true
+      repos = append(repos, *project)
+    }
+  }
+
+  if len(globs) == 0 {
+    return repos, nil
+  }
+
+  matches := func(p gitlab.Project) bool {
+    return stringslice.MatchAny(p.PathWithNamespace, globs)
+  }
+
+  projects, err := m.getNamespaceProjects(ctx, matches)
+  if err != nil {
+    return nil, err
+  }
+
+  for _, p := range projects {
+    if !seen[p.ID] {
+      seen[p.ID] = true
+      repos = append(repos, p)
+    }
+  }
+
+  return repos, nil
+}
+
+// IsProjectMatched returns whether the project belongs to one of the configured groups (or their
+// subgroups) or user namespaces or is listed in project_list, and passes the project whitelist,
+// blacklist and filters of the config. A group configured by group_id only is matched by the
+// path found by ResolveGroupPath.
+func (m *ProjectManager) IsProjectMatched(p gitlab.Project) bool {
+  if stringslice.Contains(p.PathWithNamespace, m.config.ProjectList) || stringslice.Contains(strconv.Itoa(p.ID), m.config.ProjectList) {
+    return m.isProjectSelected(p)
+  }
+
+  m.mu.Lock()
+  groupIDPath := m.groupIDPath
+  m.mu.Unlock()
+
+  var namespaces []string
+  for _, group := range m.config.GroupNames() {
+    if m.config.GroupName == "" && group == strconv.Itoa(m.config.GroupID) {
+      group = groupIDPath
+    }
+    if group != "" {
+      namespaces = append(namespaces, group)
+    }
+  }
+
+  for _, namespace := range append(namespaces, m.config.Users...) {
+    if strings.HasPrefix(p.PathWithNamespace, namespace+"/") {
+      return m.isProjectSelected(p)
+    }
+  }
+
+  return false
+}
+
+// GetGroupProjects fetches all projects of the given group and its subgroups, ignoring the
+// configured groups, project whitelist, blacklist and filters
+func (m *ProjectManager) GetGroupProjects(ctx context.Context, groupName string) ([]gitlab.Project, error) {
+  return m.getGroupProjects(ctx, groupName, func(gitlab.Project) bool { return true })
+}
+
+// getListedProjects fetches the projects listed in project_list by their path or ID, for which
+// selected returns true
+func (m *ProjectManager) getListedProjects(ctx context.Context, selected func(gitlab.Project) bool) ([]gitlab.Project, error) {
+  var repos []gitlab.Project
+  for _, listed := range m.config.ProjectList {
+    var pid interface{} = listed
+    if id, err := strconv.Atoi(listed); err == nil {
+      pid = id
+    }
+
+    project, _, err := m.projectsClient.GetProject(pid, &gitlab.GetProjectOptions{}, gitlab.WithContext(ctx))
+    if err != nil {
+      return nil, fmt.Errorf("failed to fetch project %s: %v", listed, err)
+    }
+
+    if selected(*project) {
+      repos = append(repos, *project)
+    }
+  }
+
+  return repos, nil
+}
@@ -0,0 +1,248 @@
+package gitlab
+
+import (
+  "bytes"
+  "io"
+  "io/ioutil"
+  "net"
+  "net/http"
+  "strconv"
+  "sync"
+  "time"
+
+  "github.com/sirupsen/logrus"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/config"
+)
+
+// Transport is an http.RoundTripper pacing the requests sent to GitLab according to the
+// configured rate limit and the RateLimit-* response headers, retrying throttled ones and
+// idempotent ones failing with a transient error.
+// It is safe for concurrent use, all requests share the same pacing state.
+type Transport struct {
+  base      http.RoundTripper
+  logger    *logrus.Entry
+  rateLimit config.RateLimit
+  retry     config.Retry
+
+  mu        sync.Mutex
+  nextSlot  time.Time
+  remaining int
+  resetAt   time.Time
+  requests  int
+}
+
+// NewTransport returns a Transport sending requests through base, http.DefaultTransport if nil
+func NewTransport(base http.RoundTripper, rateLimit config.RateLimit, retry config.Retry, logger *logrus.Entry) *Transport {
+  if base == nil {
+    base = http.DefaultTransport
+  }
+
+  return &Transport{
+    base:      base,
+    logger:    logger,
+    rateLimit: rateLimit,
+    retry:     retry,
+    remaining: -1,
+  }
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+  // Buffer the body, so it can be sent again on retries
+  var body []byte
+  if req.Body != nil {
+    b, err := ioutil.ReadAll(req.Body)
+    req.Body.Close()
+    if err != nil {
+      return nil, err
+    }
+    body = b
+  }
+
+  throttled, failed := 0, 0
+  for {
+    if err := t.wait(req, t.delay()); err != nil {
+      return nil, err
+    }
+
+    attemptReq := req.WithContext(req.Context())
+    if body != nil {
+      attemptReq.Body = ioutil.NopCloser(bytes.NewReader(body))
+    }
+
+    resp, err := t.base.RoundTrip(attemptReq)
+    if err != nil {
+      if !isIdempotent(req.Method) || !isTransientError(err) || req.Context().Err() != nil || failed+1 >= t.retry.MaxAttempts {
+        return nil, err
+      }
+
+      failed++
+      wait := t.backoff(failed)
+      t.logger.Warnf("Request %s %s failed: %v, retrying in %s (%d/%d)", req.Method, req.URL.Path, err, wait, failed+1, t.retry.MaxAttempts)
+
+      if err := t.wait(req, wait); err != nil {
+        return nil, err
+      }
+      continue
+    }
+
+    t.observe(resp)
+
+    var wait time.Duration
+    switch {
+    case resp.StatusCode == http.StatusTooManyRequests && throttled < t.rateLimit.MaxRetries:
+      wait = t.retryAfter(resp, throttled)
+      throttled++
+      t.logger.Warnf("Throttled by GitLab on %s %s, retrying in %s (%d/%d)", req.Method, req.URL.Path, wait, throttled, t.rateLimit.MaxRetries)
+    case isIdempotent(req.Method) && t.isRetryableStatus(resp.StatusCode) && failed+1 < t.retry.MaxAttempts:
+      failed++
+      wait = t.backoff(failed)
+      t.logger.Warnf("Request %s %s failed with status %d, retrying in %s (%d/%d)", req.Method, req.URL.Path, resp.StatusCode, wait, failed+1, t.retry.MaxAttempts)
+    default:
+      return resp, nil
+    }
+
+    io.Copy(ioutil.Discard, resp.Body)
+    resp.Body.Close()
+
+    if err := t.wait(req, wait); err != nil {
+      return nil, err
+    }
+  }
+}
+
+// Requests returns the number of requests sent so far, including retries
+func (t *Transport) Requests() int {
+  t.mu.Lock()
+  defer t.mu.Unlock()
+
+  return t.requests
+}
+
+// delay reserves the next request slot and returns how long to wait for it
+func (t *Transport) delay() time.Duration {
+  t.mu.Lock()
+  defer t.mu.Unlock()
+
+  t.requests++
+
+  now := time.Now()
+  var delay time.Duration
+
+  // Wait for the rate limit to reset once the remaining requests run low
+  if t.remaining >= 0 && t.remaining <= t.rateLimit.MinRemaining && t.resetAt.After(now) {
+    delay = t.capWait(t.resetAt.Sub(now))
+  }
+
+  if t.rateLimit.RequestsPerSecond > 0 {
+    slot := now.Add(delay)
+    if t.nextSlot.After(slot) {
+      slot = t.nextSlot
+    }
+    t.nextSlot = slot.Add(time.Duration(float64(time.Second) / t.rateLimit.RequestsPerSecond))
+    delay = slot.Sub(now)
+  }
+
+  return delay
+}
+
+// observe records the rate limit state sent by GitLab
+func (t *Transport) observe(resp *http.Response) {
+  remaining, err := strconv.Atoi(resp.Header.Get("RateLimit-Remaining"))
+  if err != nil {
+    return
+  }
+
+  t.mu.Lock()
+  defer t.mu.Unlock()
+
+  t.remaining = remaining
+  if reset, err := strconv.ParseInt(resp.Header.Get("RateLimit-Reset"), 10, 64); err == nil {
+    t.resetAt = time.Unix(reset, 0)
+  }
+}
+
+// retryAfter returns how long to wait before retrying a throttled request, as requested by
+// GitLab or growing exponentially with each attempt
+func (t *Transport) retryAfter(resp *http.Response, attempt int) time.Duration {
+  if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+    return t.capWait(time.Duration(seconds) * time.Second)
+  }
+
+  if reset, err := strconv.ParseInt(resp.Header.Get("RateLimit-Reset"), 10, 64); err == nil {
+    if wait := time.Until(time.Unix(reset, 0)); wait > 0 {
+      return t.capWait(wait)
+    }
+  }
+
+  return t.capWait(time.Second << uint(attempt))
+}
+
+// backoff returns the wait before the given retry of a failed request, doubling with each retry
+func (t *Transport) backoff(retry int) time.Duration {
+  wait := time.Duration(t.retry.InitialBackoffMillis) * time.Millisecond << uint(retry-1)
+
+  max := time.Duration(t.retry.MaxBackoffMillis) * time.Millisecond
+  if max > 0 && (wait > max || wait < 0) {
+    wait = max
+  }
+
+  return wait
+}
+
+// isRetryableStatus returns whether requests failing with the status code are retried
+func (t *Transport) isRetryableStatus(statusCode int) bool {
+  for _, code := range t.retry.RetryableStatusCodes {
+    if code == statusCode {
+      return true
+    }
+  }
+  return false
+}
+
+// isIdempotent returns whether sending a request of the method again has no further effect, so
+// it's retried even though GitLab may have processed the failed attempt
+func isIdempotent(method string) bool {
+  switch method {
+  case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+    return true
+  }
+  return false
+}
+
+// isTransientError returns whether a request failed due to a network error worth retrying
+func isTransientError(err error) bool {
+  if err == io.EOF || err == io.ErrUnexpectedEOF {
+    return true
+  }
+
+  netErr, ok := err.(net.Error)
+  return ok && (netErr.Timeout() || netErr.Temporary())
+}
+
+// capWait limits a wait to the configured maximum
+func (t *Transport) capWait(wait time.Duration) time.Duration {
+  max := time.Duration(t.rateLimit.MaxWaitSeconds) * time.Second
+  if max > 0 && wait > max {
+    return max
+  }
+  return wait
+}
+
+// wait sleeps for the given duration, or until the request is canceled
+func (t *Transport) wait(req *http.Request, d time.Duration) error {
+  if d <= 0 {
+    return nil
+  }
+
+  timer := time.NewTimer(d)
+  defer timer.Stop()
+
+  select {
+  case <-timer.C:
+    return nil
+  case <-req.Context().Done():
+    return req.Context().Err()
+  }
+}
@@ -0,0 +1,91 @@
+package gitlab
+
+import (
+  "io/ioutil"
+  "net/http"
+  "net/http/httptest"
+  "strings"
+  "testing"
+
+  "github.com/sirupsen/logrus"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/config"
+)
+
+// newTestTransport returns a Transport retrying without waiting, and the number of requests
+// the test server received, which answers each with the next of the given status codes
+func newTestTransport(statusCodes ...int) (*Transport, *httptest.Server, *int) {
+  received := 0
+  server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    code := statusCodes[len(statusCodes)-1]
+    if received < len(statusCodes) {
+      code = statusCodes[received]
+    }
+    received++
+    w.Header().Set("Retry-After", "0")
+    w.WriteHeader(code)
+  }))
+
+  logger := logrus.New()
+  logger.Out = ioutil.Discard
+
+  retry := config.DefaultRetry()
+  retry.InitialBackoffMillis = 1
+
+  return NewTransport(nil, config.DefaultRateLimit(), retry, logrus.NewEntry(logger)), server, &received
+}
+
+func TestTransportRetryPolicy(t *testing.T) {
+  tests := []struct {
+    method      string
+    statusCodes []int
+    want        int
+    requests    int
+  }{
+    {http.MethodGet, []int{http.StatusBadGateway, http.StatusOK}, http.StatusOK, 2},
+    {http.MethodPut, []int{http.StatusServiceUnavailable, http.StatusOK}, http.StatusOK, 2},
+    {http.MethodDelete, []int{http.StatusInternalServerError}, http.StatusInternalServerError, 3},
+    {http.MethodPost, []int{http.StatusBadGateway, http.StatusCreated}, http.StatusBadGateway, 1},
+    {http.MethodPost, []int{http.StatusTooManyRequests, http.StatusCreated}, http.StatusCreated, 2},
+    {http.MethodGet, []int{http.StatusNotFound}, http.StatusNotFound, 1},
+  }
+
+  for _, test := range tests {
+    transport, server, received := newTestTransport(test.statusCodes...)
+
+    req, err := http.NewRequest(test.method, server.URL, strings.NewReader("{}"))
+    if err != nil {
+      t.Fatalf("Failed to create request: %v", err)
+    }
+
+    resp, err := transport.RoundTrip(req)
+    server.Close()
+    if err != nil {
+      t.Errorf("%s answered with %v: unexpected error %v", test.method, test.statusCodes, err)
+      continue
+    }
+    resp.Body.Close()
+
+    if resp.StatusCode != test.want || *received != test.requests {
+      t.Errorf("%s answered with %v: expected status %d after %d requests, but got %d after %d",
+        test.method, test.statusCodes, test.want, test.requests, resp.StatusCode, *received)
+    }
+  }
+}
+
+func TestIsIdempotent(t *testing.T) {
+  tests := map[string]bool{
+    http.MethodGet:    true,
+    http.MethodHead:   true,
+    http.MethodPut:    true,
+    http.MethodDelete: true,
+    http.MethodPost:   false,
+    http.MethodPatch:  false,
+  }
+
+  for method, want := range tests {
+    if got := isIdempotent(method); got != want {
+      t.Errorf("isIdempotent(%q) = %t, want %t", method, got, want)
+    }
+  }
+}
@@ -1,7 +1,10 @@
 package gitlab
 
 import (
+  "context"
+  "fmt"
   "net/http"
+  "reflect"
 
   gitlab "github.com/xanzy/go-gitlab"
 )
@@ -12,6 +15,70 @@ type ProjectSettings struct {
   General  gitlab.Project          `json:"project_settings,omitempty"`
 }
 
+// ChangeLog stores applied changes, keyed by project, subsection and setting name.
+// Each change holds its previous ("From") and new ("To") value.
+type ChangeLog map[string]map[string]map[string]map[string]interface{}
+
+// Add records the change of a single setting. Pointers are dereferenced, so the values
+// and not their addresses are recorded.
+func (c ChangeLog) Add(project string, subsection string, setting string, from interface{}, to interface{}) {
+  if _, ok := c[project]; ! ok {
+    c[project] = make(map[string]map[string]map[string]interface{})
+  }
+  if _, ok := c[project][subsection]; ! ok {
+    c[project][subsection] = make(map[string]map[string]interface{})
+  }
+
+  c[project][subsection][setting] = map[string]interface{}{
+    "From": deref(from),
+    "To":   deref(to),
+  }
+}
+
+// deref follows pointers (and interfaces) to the value they point to, nil if any is nil
+func deref(v interface{}) interface{} {
+  rv := reflect.ValueOf(v)
+  for rv.IsValid() && (rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface) {
+    if rv.IsNil() {
+      return nil
+    }
+    rv = rv.Elem()
+  }
+
+  if !rv.IsValid() {
+    return nil
+  }
+  return rv.Interface()
+}
+
+// FormatValue formats a changed value for humans, with unset (nil) values as "<unset>"
+func FormatValue(v interface{}) string {
+  if v = deref(v); v == nil {
+    return "<unset>"
+  }
+
+  return fmt.Sprintf("%v", v)
+}
+
+// quoteValue formats a changed value like FormatValue, quoting set values
+func quoteValue(v interface{}) string {
+  if v = deref(v); v == nil {
+    return "<unset>"
+  }
+
+  return fmt.Sprintf("\"%v\"", v)
+}
+
+// apiClient performs raw requests for endpoints not (yet) covered by go-gitlab
+type apiClient interface {
+  NewRequest(method, path string, opt interface{}, options []gitlab.OptionFunc) (*http.Request, error)
+  Do(req *http.Request, v interface{}) (*gitlab.Response, error)
+}
+
+type graphqlClient interface {
+  Query(ctx context.Context, query string, variables map[string]interface{}, v interface{}) error
+}
+
 type groupsClient interface {
   GetGroup(gid interface{}, options ...gitlab.OptionFunc) (*gitlab.Group, *gitlab.Response, error)
   ListGroupProjects(gid interface{}, opt *gitlab.ListGroupProjectsOptions, options ...gitlab.OptionFunc) ([]*gitlab.Project, *gitlab.Response, error)
@@ -28,31 +95,36 @@ type projectsClient interface {
 type protectedBranchesClient interface {
   ProtectRepositoryBranches(pid interface{}, opt *gitlab.ProtectRepositoryBranchesOptions, options ...gitlab.OptionFunc) (*gitlab.ProtectedBranch, *gitlab.Response, error)
   UnprotectRepositoryBranches(pid interface{}, branch string, options ...gitlab.OptionFunc) (*gitlab.Response, error)
-  // ListProtectedBranches(pid interface{}, opt *gitlab.ListProtectedBranchesOptions, options ...gitlab.OptionFunc) ([]*gitlab.ProtectedBranch, *gitlab.Response, error)
+  ListProtectedBranches(pid interface{}, opt *gitlab.ListProtectedBranchesOptions, options ...gitlab.OptionFunc) ([]*gitlab.ProtectedBranch, *gitlab.Response, error)
 }
 
 type branchesClient interface {
   CreateBranch(pid interface{}, opt *gitlab.CreateBranchOptions, options ...gitlab.OptionFunc) (*gitlab.Branch, *gitlab.Response, error)
   GetBranch(pid interface{}, branch string, options ...gitlab.OptionFunc) (*gitlab.Branch, *gitlab.Response, error)
+  ListBranches(pid interface{}, opt *gitlab.ListBranchesOptions, options ...gitlab.OptionFunc) ([]*gitlab.Branch, *gitlab.Response, error)
+  DeleteBranch(pid interface{}, branch string, options ...gitlab.OptionFunc) (*gitlab.Response, error)
 }
 
-var (
-  listGroupProjectOps = &gitlab.ListGroupProjectsOptions{
-    ListOptions: gitlab.ListOptions{
-      PerPage: 100,
-    },
-  }
+type repositoryFilesClient interface {
+  GetRawFile(pid interface{}, fileName string, opt *gitlab.GetRawFileOptions, options ...gitlab.OptionFunc) ([]byte, *gitlab.Response, error)
+  GetFile(pid interface{}, fileName string, opt *gitlab.GetFileOptions, options ...gitlab.OptionFunc) (*gitlab.File, *gitlab.Response, error)
+  GetFileMetaData(pid interface{}, fileName string, opt *gitlab.GetFileMetaDataOptions, options ...gitlab.OptionFunc) (*gitlab.File, *gitlab.Response, error)
+}
 
-  listSubgroupOps = &gitlab.ListSubgroupsOptions{
-    ListOptions: gitlab.ListOptions{
-      PerPage: 100,
-    },
-  }
+type commitsClient interface {
+  CreateCommit(pid interface{}, opt *gitlab.CreateCommitOptions, options ...gitlab.OptionFunc) (*gitlab.Commit, *gitlab.Response, error)
+}
 
-  addIncludeSubgroups = gitlab.OptionFunc(func(req *http.Request) error {
-    v := req.URL.Query()
-    v.Add("include_subgroups", "true")
-    req.URL.RawQuery = v.Encode()
-    return nil
-  })
-)
+type mergeRequestsClient interface {
+  CreateMergeRequest(pid interface{}, opt *gitlab.CreateMergeRequestOptions, options ...gitlab.OptionFunc) (*gitlab.MergeRequest, *gitlab.Response, error)
+  ListProjectMergeRequests(pid interface{}, opt *gitlab.ListProjectMergeRequestsOptions, options ...gitlab.OptionFunc) ([]*gitlab.MergeRequest, *gitlab.Response, error)
+  UpdateMergeRequest(pid interface{}, mergeRequest int, opt *gitlab.UpdateMergeRequestOptions, options ...gitlab.OptionFunc) (*gitlab.MergeRequest, *gitlab.Response, error)
+}
+
+// addIncludeSubgroups makes ListGroupProjects include the projects of all subgroups
+func addIncludeSubgroups(req *http.Request) error {
+  v := req.URL.Query()
+  v.Add("include_subgroups", "true")
+  req.URL.RawQuery = v.Encode()
+  return nil
+}
@@ -0,0 +1,86 @@
+package gitlab
+
+import (
+  "fmt"
+  "io"
+  "net/http"
+  "sort"
+  "strings"
+
+  "github.com/xanzy/go-gitlab"
+)
+
+// unavailableExamples limits the projects listed per feature in the unavailable features report
+const unavailableExamples = 5
+
+// FeatureUnavailableError reports a feature whose endpoint answered 403 or 404, as on instances
+// or groups without the license (GitLab Premium or Ultimate) or version offering it
+type FeatureUnavailableError struct {
+  Feature    string
+  Target     string
+  StatusCode int
+}
+
+// Error implements error
+func (e *FeatureUnavailableError) Error() string {
+  return fmt.Sprintf("%s unavailable for %s (HTTP %d), it may require GitLab Premium or Ultimate", e.Feature, e.Target, e.StatusCode)
+}
+
+// unavailableFeature returns a FeatureUnavailableError if the response answered 403 or 404, nil otherwise
+func unavailableFeature(feature string, target string, resp *gitlab.Response) error {
+  if resp == nil || (resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusNotFound) {
+    return nil
+  }
+
+  return &FeatureUnavailableError{Feature: feature, Target: target, StatusCode: resp.StatusCode}
+}
+
+// skipUnavailable records the feature as unavailable and returns true if err is a
+// FeatureUnavailableError, which is logged as warning instead of failing the project
+func (m *ProjectManager) skipUnavailable(err error) bool {
+  unavailable, ok := err.(*FeatureUnavailableError)
+  if !ok {
+    return false
+  }
+
+  m.logger.Warnf("Skipping %s of %s: %v", unavailable.Feature, unavailable.Target, unavailable)
+
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  m.unavailable[unavailable.Feature] = append(m.unavailable[unavailable.Feature], unavailable.Target)
+
+  return true
+}
+
+// GenerateUnavailableReport writes the features skipped as unavailable, with the number of
+// affected projects or groups, to w. Nothing is written if all features were available.
+func (m *ProjectManager) GenerateUnavailableReport(w io.Writer) error {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  if len(m.unavailable) == 0 {
+    return nil
+  }
+
+  var features []string
+  for feature := range m.unavailable {
+    features = append(features, feature)
+  }
+  sort.Strings(features)
+
+  fmt.Fprintf(w, "\nUNAVAILABLE FEATURES (skipped, may require GitLab Premium or Ultimate)\n")
+
+  for _, feature := range features {
+    targets := append([]string{}, m.unavailable[feature]...)
+    sort.Strings(targets)
+
+    examples := targets
+    if len(examples) > unavailableExamples {
+      examples = append(examples[:unavailableExamples:unavailableExamples], "...")
+    }
+    fmt.Fprintf(w, "  %s: %d project(s) or group(s): %s\n", feature, len(targets), strings.Join(examples, ", "))
+  }
+
+  return nil
+}
@@ -0,0 +1,57 @@
+package gitlab
+
+import (
+  "context"
+  "fmt"
+  "net/http"
+  "net/url"
+
+  "github.com/xanzy/go-gitlab"
+)
+
+// getUserProjects fetches the projects owned by the user with the given username, e.g. projects
+// of bots or administrators kept in their personal namespace
+// https://docs.gitlab.com/ee/api/projects.html#list-user-projects
+func (m *ProjectManager) getUserProjects(ctx context.Context, username string, selected func(gitlab.Project) bool) ([]gitlab.Project, error) {
+  var repos []gitlab.Project
+
+  m.logger.Debugf("Fetching projects of user %s ...", username)
+
+  path := fmt.Sprintf("users/%s/projects", url.PathEscape(username))
+  opt := &gitlab.ListOptions{
+    Page:    1,
+    PerPage: 100,
+  }
+  for {
+    req, err := m.apiClient.NewRequest(http.MethodGet, path, opt, []gitlab.OptionFunc{gitlab.WithContext(ctx)})
+    if err != nil {
+      return nil, fmt.Errorf("failed to create request for projects of user %s: %v", username, err)
+    }
+
+    var projects []gitlab.Project
+    resp, err := m.apiClient.Do(req, &projects)
+    if err != nil {
+      return nil, fmt.Errorf("failed to fetch GitLab projects of user %s: %v", username, err)
+    }
+
+    for _, p := range projects {
+      if !selected(p) {
+        continue
+      }
+
+      repos = append(repos, p)
+    }
+
+    // Exit the loop when we've seen all pages.
+    if resp.NextPage == 0 {
+      break
+    }
+
+    // Update the page number to get the next page.
+    opt.Page = resp.NextPage
+  }
+
+  m.logger.Debugf("Fetching projects of user %s done. Retrieved %d.", username, len(repos))
+
+  return repos, nil
+}
@@ -0,0 +1,90 @@
+package gitlab
+
+import (
+  "encoding/json"
+  "fmt"
+  "io"
+)
+
+// verifyApplied compares the requested settings with the settings read back after applying
+// them. GitLab silently ignores settings unavailable on the tier or version of the instance,
+// those are recorded as requested but not applied.
+func (m *ProjectManager) verifyApplied(project string, subsection string, requested interface{}, current interface{}) error {
+  remaining, err := computeDrift(requested, current)
+  if err != nil {
+    return err
+  }
+
+  for setting, drift := range remaining {
+    m.addNotApplied(project, subsection, setting, drift)
+  }
+
+  return nil
+}
+
+// addNotApplied records a setting GitLab accepted but didn't apply, with the value still found
+// as "From" and the requested one as "To"
+func (m *ProjectManager) addNotApplied(project string, subsection string, setting string, drift settingDrift) {
+  m.logger.Warnf("GitLab accepted but didn't apply %s.%s of project %s (requested %s, found %s), it may be unavailable on the tier or version of the instance.",
+    subsection, setting, project, FormatValue(drift.To), FormatValue(drift.From))
+
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  m.notApplied.Add(project, subsection, setting, drift.From, drift.To)
+}
+
+// NotApplied returns a copy of the settings requested but not applied, keyed like the change log
+func (m *ProjectManager) NotApplied() ChangeLog {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  notApplied := make(ChangeLog)
+  for project, subsections := range m.notApplied {
+    for subsection, settings := range subsections {
+      for setting, values := range settings {
+        notApplied.Add(project, subsection, setting, values["From"], values["To"])
+      }
+    }
+  }
+
+  return notApplied
+}
+
+// GenerateNotAppliedReport writes the settings requested but not applied to w, as JSON for the
+// json format and as text otherwise. Nothing is written if all settings were applied.
+func (m *ProjectManager) GenerateNotAppliedReport(w io.Writer, format string) error {
+  notApplied := m.NotApplied()
+  if len(notApplied) == 0 {
+    return nil
+  }
+
+  if format == OutputFormatJSON {
+    body, err := json.MarshalIndent(notApplied, "", "  ")
+    if err != nil {
+      return fmt.Errorf("failed to marshal not applied settings: %v", err)
+    }
+
+    _, err = fmt.Fprintf(w, "%s\n", body)
+    return err
+  }
+
+  fmt.Fprintf(w, "\nREQUESTED BUT NOT APPLIED\n")
+
+  for _, name := range notApplied.projectNames() {
+    fmt.Fprintf(w, "  %s\n", name)
+
+    for _, subsection := range notApplied.subsections(name) {
+      for _, setting := range notApplied.settings(name, subsection) {
+        values := notApplied[name][subsection][setting]
+        fmt.Fprintf(w, "    %s.%s: requested %s, found %s\n", subsection, setting, quoteValue(values["To"]), quoteValue(values["From"]))
+      }
+    }
+
+    if _, err := fmt.Fprintf(w, "\n"); err != nil {
+      return err
+    }
+  }
+
+  return nil
+}
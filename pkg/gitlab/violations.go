@@ -0,0 +1,80 @@
+package gitlab
+
+import (
+  "fmt"
+  "io"
+  "sort"
+)
+
+// addViolation records a policy violation of the project which is reported but not remediated
+func (m *ProjectManager) addViolation(projectPath string, format string, args ...interface{}) {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  m.Violations[projectPath] = append(m.Violations[projectPath], fmt.Sprintf(format, args...))
+}
+
+// addChange records an applied change of a subsystem in the changelog
+func (m *ProjectManager) addChange(project string, subsection string, setting string, from interface{}, to interface{}) {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  m.changes.Add(project, subsection, setting, from, to)
+}
+
+// addDrift records the drifted settings of a subsystem in the changelog, e.g. the planned
+// changes of a dryrun
+func (m *ProjectManager) addDrift(project string, subsection string, drift map[string]settingDrift) {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  for setting, values := range drift {
+    m.changes.Add(project, subsection, setting, values.From, values.To)
+  }
+}
+
+// ProjectViolations returns a copy of the policy violations found per project
+func (m *ProjectManager) ProjectViolations() map[string][]string {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  violations := make(map[string][]string, len(m.Violations))
+  for project, found := range m.Violations {
+    violations[project] = append([]string{}, found...)
+  }
+
+  return violations
+}
+
+// GenerateViolationsReport writes the policy violations found per project to w
+func (m *ProjectManager) GenerateViolationsReport(w io.Writer) error {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+
+  if len(m.Violations) == 0 {
+    return nil
+  }
+
+  var project_names []string
+  for project_name := range m.Violations {
+    project_names = append(project_names, project_name)
+  }
+  sort.Strings(project_names)
+
+  fmt.Fprintf(w, "\nPOLICY VIOLATIONS\n")
+
+  for _, name := range project_names {
+    fmt.Fprintf(w, "  %s\n", name)
+
+    violations := append([]string{}, m.Violations[name]...)
+    sort.Strings(violations)
+
+    for _, violation := range violations {
+      fmt.Fprintf(w, "    %s\n", violation)
+    }
+
+    fmt.Fprintf(w, "\n")
+  }
+
+  return nil
+}
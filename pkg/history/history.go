@@ -0,0 +1,315 @@
+package history
+
+import (
+  "database/sql"
+  "encoding/json"
+  "fmt"
+  "time"
+
+  // Registers the sqlite3 driver
+  _ "github.com/mattn/go-sqlite3"
+)
+
+// Outcomes of a project in a run
+const (
+  OutcomeInSync  = "in_sync"
+  OutcomeChanged = "changed"
+  OutcomeFailed  = "failed"
+  OutcomeSkipped = "skipped"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS runs (
+  id          INTEGER PRIMARY KEY AUTOINCREMENT,
+  command     TEXT    NOT NULL,
+  started_at  TEXT    NOT NULL,
+  duration_ms INTEGER NOT NULL,
+  dryrun      INTEGER NOT NULL,
+  failed      INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS project_results (
+  run_id      INTEGER NOT NULL REFERENCES runs(id),
+  project     TEXT    NOT NULL,
+  outcome     TEXT    NOT NULL,
+  duration_ms INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS changes (
+  run_id     INTEGER NOT NULL REFERENCES runs(id),
+  project    TEXT    NOT NULL,
+  subsection TEXT    NOT NULL,
+  setting    TEXT    NOT NULL,
+  from_value TEXT,
+  to_value   TEXT
+);
+CREATE INDEX IF NOT EXISTS project_results_run ON project_results(run_id);
+CREATE INDEX IF NOT EXISTS changes_project ON changes(project, setting);
+`
+
+// Run is a recorded run of the sync command
+type Run struct {
+  ID        int64           `json:"id"`
+  Command   string          `json:"command"`
+  StartedAt time.Time       `json:"started_at"`
+  Duration  time.Duration   `json:"-"`
+  Dryrun    bool            `json:"dryrun"`
+  Failed    bool            `json:"failed"`
+  Projects  []ProjectResult `json:"projects,omitempty"`
+  Changes   []Change        `json:"changes,omitempty"`
+
+  // Counts of the project outcomes, filled by Runs
+  ProjectCount int `json:"project_count"`
+  ChangedCount int `json:"changed_count"`
+  FailedCount  int `json:"failed_count"`
+}
+
+// ProjectResult is the outcome of a single project in a run
+type ProjectResult struct {
+  Project  string        `json:"project"`
+  Outcome  string        `json:"outcome"`
+  Duration time.Duration `json:"-"`
+}
+
+// Change is a setting changed (or, in dryruns, found drifted) by a run
+type Change struct {
+  RunID      int64       `json:"run_id"`
+  StartedAt  time.Time   `json:"started_at"`
+  Dryrun     bool        `json:"dryrun"`
+  Project    string      `json:"project"`
+  Subsection string      `json:"subsection"`
+  Setting    string      `json:"setting"`
+  From       interface{} `json:"from"`
+  To         interface{} `json:"to"`
+}
+
+// MarshalJSON implements json.Marshaler, writing the duration in seconds
+func (r Run) MarshalJSON() ([]byte, error) {
+  type run Run
+  return json.Marshal(struct {
+    run
+    DurationSeconds float64 `json:"duration_seconds"`
+  }{run(r), r.Duration.Seconds()})
+}
+
+// MarshalJSON implements json.Marshaler, writing the duration in seconds
+func (p ProjectResult) MarshalJSON() ([]byte, error) {
+  type result ProjectResult
+  return json.Marshal(struct {
+    result
+    DurationSeconds float64 `json:"duration_seconds"`
+  }{result(p), p.Duration.Seconds()})
+}
+
+// ChangeFilter selects recorded changes. Empty fields match all changes.
+type ChangeFilter struct {
+  Project string
+  // Setting matches the setting name with or without its subsection, e.g. merge_method or
+  // project_settings.merge_method
+  Setting string
+  RunID   int64
+  Limit   int
+}
+
+// Store records runs in a SQLite database
+type Store struct {
+  db *sql.DB
+}
+
+// Open opens (or creates) the history database at the given path
+func Open(path string) (*Store, error) {
+  db, err := sql.Open("sqlite3", path)
+  if err != nil {
+    return nil, fmt.Errorf("failed to open history database %q: %v", path, err)
+  }
+
+  if _, err := db.Exec(schema); err != nil {
+    db.Close()
+    return nil, fmt.Errorf("failed to create history database %q: %v", path, err)
+  }
+
+  return &Store{db: db}, nil
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+  return s.db.Close()
+}
+
+// Record stores the run with its project results and changes, setting its ID
+func (s *Store) Record(run *Run) error {
+  tx, err := s.db.Begin()
+  if err != nil {
+    return fmt.Errorf("failed to record run: %v", err)
+  }
+
+  if err := record(tx, run); err != nil {
+    tx.Rollback()
+    return fmt.Errorf("failed to record run: %v", err)
+  }
+
+  if err := tx.Commit(); err != nil {
+    return fmt.Errorf("failed to record run: %v", err)
+  }
+
+  return nil
+}
+
+// record inserts the run within the transaction
+func record(tx *sql.Tx, run *Run) error {
+  result, err := tx.Exec(
+    `INSERT INTO runs (command, started_at, duration_ms, dryrun, failed) VALUES (?, ?, ?, ?, ?)`,
+    run.Command, run.StartedAt.UTC().Format(time.RFC3339Nano), milliseconds(run.Duration), run.Dryrun, run.Failed,
+  )
+  if err != nil {
+    return err
+  }
+  if run.ID, err = result.LastInsertId(); err != nil {
+    return err
+  }
+
+  for _, p := range run.Projects {
+    if _, err := tx.Exec(
+      `INSERT INTO project_results (run_id, project, outcome, duration_ms) VALUES (?, ?, ?, ?)`,
+      run.ID, p.Project, p.Outcome, milliseconds(p.Duration),
+    ); err != nil {
+      return err
+    }
+  }
+
+  for _, c := range run.Changes {
+    from, err := json.Marshal(c.From)
+    if err != nil {
+      return err
+    }
+    to, err := json.Marshal(c.To)
+    if err != nil {
+      return err
+    }
+
+    if _, err := tx.Exec(
+      `INSERT INTO changes (run_id, project, subsection, setting, from_value, to_value) VALUES (?, ?, ?, ?, ?, ?)`,
+      run.ID, c.Project, c.Subsection, c.Setting, string(from), string(to),
+    ); err != nil {
+      return err
+    }
+  }
+
+  return nil
+}
+
+// Runs returns the latest runs with the counts of their project outcomes, newest first
+func (s *Store) Runs(limit int) ([]Run, error) {
+  rows, err := s.db.Query(`
+SELECT r.id, r.command, r.started_at, r.duration_ms, r.dryrun, r.failed,
+  COUNT(p.project),
+  COALESCE(SUM(CASE WHEN p.outcome = ? THEN 1 ELSE 0 END), 0),
+  COALESCE(SUM(CASE WHEN p.outcome = ? THEN 1 ELSE 0 END), 0)
+FROM runs r LEFT JOIN project_results p ON p.run_id = r.id
+GROUP BY r.id
+ORDER BY r.id DESC
+LIMIT ?`, OutcomeChanged, OutcomeFailed, limit)
+  if err != nil {
+    return nil, fmt.Errorf("failed to query runs: %v", err)
+  }
+  defer rows.Close()
+
+  var runs []Run
+  for rows.Next() {
+    var run Run
+    var startedAt string
+    var duration int64
+    if err := rows.Scan(&run.ID, &run.Command, &startedAt, &duration, &run.Dryrun, &run.Failed, &run.ProjectCount, &run.ChangedCount, &run.FailedCount); err != nil {
+      return nil, fmt.Errorf("failed to read runs: %v", err)
+    }
+    run.StartedAt, _ = time.Parse(time.RFC3339Nano, startedAt)
+    run.Duration = time.Duration(duration) * time.Millisecond
+
+    runs = append(runs, run)
+  }
+
+  return runs, rows.Err()
+}
+
+// ProjectResults returns the project outcomes of the run
+func (s *Store) ProjectResults(runID int64) ([]ProjectResult, error) {
+  rows, err := s.db.Query(`SELECT project, outcome, duration_ms FROM project_results WHERE run_id = ? ORDER BY project`, runID)
+  if err != nil {
+    return nil, fmt.Errorf("failed to query project results: %v", err)
+  }
+  defer rows.Close()
+
+  var results []ProjectResult
+  for rows.Next() {
+    var result ProjectResult
+    var duration int64
+    if err := rows.Scan(&result.Project, &result.Outcome, &duration); err != nil {
+      return nil, fmt.Errorf("failed to read project results: %v", err)
+    }
+    result.Duration = time.Duration(duration) * time.Millisecond
+
+    results = append(results, result)
+  }
+
+  return results, rows.Err()
+}
+
+// Changes returns the recorded changes matching the filter, newest first
+func (s *Store) Changes(filter ChangeFilter) ([]Change, error) {
+  query := `
+SELECT c.run_id, r.started_at, r.dryrun, c.project, c.subsection, c.setting, c.from_value, c.to_value
+FROM changes c JOIN runs r ON r.id = c.run_id
+WHERE (? = '' OR c.project = ?)
+  AND (? = '' OR c.setting = ? OR c.subsection || '.' || c.setting = ?)
+  AND (? = 0 OR c.run_id = ?)
+ORDER BY c.run_id DESC, c.project, c.subsection, c.setting`
+  args := []interface{}{
+    filter.Project, filter.Project,
+    filter.Setting, filter.Setting, filter.Setting,
+    filter.RunID, filter.RunID,
+  }
+  if filter.Limit > 0 {
+    query += "\nLIMIT ?"
+    args = append(args, filter.Limit)
+  }
+
+  rows, err := s.db.Query(query, args...)
+  if err != nil {
+    return nil, fmt.Errorf("failed to query changes: %v", err)
+  }
+  defer rows.Close()
+
+  var changes []Change
+  for rows.Next() {
+    var change Change
+    var startedAt string
+    var from, to sql.NullString
+    if err := rows.Scan(&change.RunID, &startedAt, &change.Dryrun, &change.Project, &change.Subsection, &change.Setting, &from, &to); err != nil {
+      return nil, fmt.Errorf("failed to read changes: %v", err)
+    }
+    change.StartedAt, _ = time.Parse(time.RFC3339Nano, startedAt)
+    change.From = decodeValue(from)
+    change.To = decodeValue(to)
+
+    changes = append(changes, change)
+  }
+
+  return changes, rows.Err()
+}
+
+// decodeValue decodes a JSON encoded value, nil if unset
+func decodeValue(value sql.NullString) interface{} {
+  if !value.Valid {
+    return nil
+  }
+
+  var v interface{}
+  if err := json.Unmarshal([]byte(value.String), &v); err != nil {
+    return value.String
+  }
+
+  return v
+}
+
+// milliseconds returns the duration in whole milliseconds
+func milliseconds(d time.Duration) int64 {
+  return int64(d / time.Millisecond)
+}
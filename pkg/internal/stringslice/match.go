@@ -0,0 +1,76 @@
+package stringslice
+
+import (
+  "regexp"
+  "strings"
+)
+
+// Match returns whether elem matches the given pattern. Patterns enclosed in slashes
+// (e.g. /^team-a\/.*$/) are regular expressions, patterns containing * or ? are globs,
+// where * matches within a single path segment and ** matches across segments.
+// All other patterns must match exactly.
+func Match(pattern string, elem string) bool {
+  re, err := compile(pattern)
+  if err != nil {
+    return false
+  }
+
+  if re == nil {
+    return pattern == elem
+  }
+
+  return re.MatchString(elem)
+}
+
+// MatchAny returns whether elem matches any of the given patterns
+func MatchAny(elem string, patterns []string) bool {
+  for _, p := range patterns {
+    if Match(p, elem) {
+      return true
+    }
+  }
+
+  return false
+}
+
+// IsPattern returns whether pattern is a regular expression or glob rather than an exact value
+func IsPattern(pattern string) bool {
+  re, err := compile(pattern)
+  return err != nil || re != nil
+}
+
+// ValidatePattern returns an error if the pattern is an invalid regular expression
+func ValidatePattern(pattern string) error {
+  _, err := compile(pattern)
+  return err
+}
+
+// compile returns the regular expression of regex and glob patterns, nil for exact patterns
+func compile(pattern string) (*regexp.Regexp, error) {
+  if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+    return regexp.Compile(pattern[1 : len(pattern)-1])
+  }
+
+  if !strings.ContainsAny(pattern, "*?") {
+    return nil, nil
+  }
+
+  var expr strings.Builder
+  expr.WriteString("^")
+  for i := 0; i < len(pattern); i++ {
+    switch {
+    case strings.HasPrefix(pattern[i:], "**"):
+      expr.WriteString(".*")
+      i++
+    case pattern[i] == '*':
+      expr.WriteString("[^/]*")
+    case pattern[i] == '?':
+      expr.WriteString("[^/]")
+    default:
+      expr.WriteString(regexp.QuoteMeta(string(pattern[i])))
+    }
+  }
+  expr.WriteString("$")
+
+  return regexp.Compile(expr.String())
+}
@@ -0,0 +1,68 @@
+package stringslice
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+  tests := []struct {
+    pattern string
+    elem    string
+    match   bool
+  }{
+    {"team-a/project", "team-a/project", true},
+    {"team-a/project", "team-a/project-2", false},
+    {"team-a/*", "team-a/project", true},
+    {"team-a/*", "team-a/sub/project", false},
+    {"team-a/**", "team-a/sub/project", true},
+    {"team-?/project", "team-b/project", true},
+    {"team-a.b/*", "team-aXb/project", false},
+    {"/^team-(a|b)/.*-api$/", "team-b/sub/user-api", true},
+    {"/^team-(a|b)/.*-api$/", "team-c/user-api", false},
+    {"/[/", "/[/", false},
+  }
+
+  for _, test := range tests {
+    if Match(test.pattern, test.elem) != test.match {
+      t.Errorf("Expected Match(%q, %q) to return %t", test.pattern, test.elem, test.match)
+    }
+  }
+}
+
+func TestMatchAny(t *testing.T) {
+  patterns := []string{"team-a/*", "team-b/project"}
+
+  if !MatchAny("team-a/project", patterns) {
+    t.Errorf("Expected MatchAny to return true as a pattern matches the element, but it returned false")
+  }
+
+  if MatchAny("team-c/project", patterns) {
+    t.Errorf("Expected MatchAny to return false as no pattern matches the element, but it returned true")
+  }
+}
+
+func TestValidatePattern(t *testing.T) {
+  if err := ValidatePattern("/^team-a/.*$/"); err != nil {
+    t.Errorf("Expected valid regular expression to pass validation, but got %v", err)
+  }
+
+  if err := ValidatePattern("/[/"); err == nil {
+    t.Errorf("Expected invalid regular expression to fail validation, but it passed")
+  }
+}
+
+func TestIsPattern(t *testing.T) {
+  tests := []struct {
+    pattern   string
+    isPattern bool
+  }{
+    {"team-a/project", false},
+    {"team-a/*", true},
+    {"team-?/project", true},
+    {"/^team-a/.*$/", true},
+  }
+
+  for _, test := range tests {
+    if IsPattern(test.pattern) != test.isPattern {
+      t.Errorf("Expected IsPattern(%q) to return %t", test.pattern, test.isPattern)
+    }
+  }
+}
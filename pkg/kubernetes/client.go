@@ -0,0 +1,128 @@
+package kubernetes
+
+import (
+  "bytes"
+  "context"
+  "crypto/tls"
+  "crypto/x509"
+  "encoding/json"
+  "errors"
+  "fmt"
+  "io/ioutil"
+  "net"
+  "net/http"
+  "os"
+  "path/filepath"
+  "strings"
+  "time"
+)
+
+// serviceAccountDir holds the credentials mounted into every pod
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// requestTimeout limits the time a single request to the API server may take
+const requestTimeout = 30 * time.Second
+
+var errNotInCluster = errors.New("not running in a Kubernetes cluster, KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT must be set")
+
+// Client talks to the Kubernetes API server with the service account of the pod
+type Client struct {
+  httpClient *http.Client
+  host       string
+  tokenFile  string
+}
+
+// NewInClusterClient returns a client for the API server of the cluster the pod runs in
+func NewInClusterClient() (*Client, error) {
+  host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+  if host == "" || port == "" {
+    return nil, errNotInCluster
+  }
+
+  ca, err := ioutil.ReadFile(filepath.Join(serviceAccountDir, "ca.crt"))
+  if err != nil {
+    return nil, fmt.Errorf("failed to read service account CA: %v", err)
+  }
+  pool := x509.NewCertPool()
+  if !pool.AppendCertsFromPEM(ca) {
+    return nil, fmt.Errorf("no certificates found in service account CA")
+  }
+
+  return &Client{
+    httpClient: &http.Client{
+      Timeout:   requestTimeout,
+      Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+    },
+    host:      "https://" + net.JoinHostPort(host, port),
+    tokenFile: filepath.Join(serviceAccountDir, "token"),
+  }, nil
+}
+
+// Namespace returns the namespace of the pod
+func (c *Client) Namespace() (string, error) {
+  b, err := ioutil.ReadFile(filepath.Join(serviceAccountDir, "namespace"))
+  if err != nil {
+    return "", fmt.Errorf("failed to read service account namespace: %v", err)
+  }
+
+  return strings.TrimSpace(string(b)), nil
+}
+
+// Get decodes the resource at the given API path into v
+func (c *Client) Get(ctx context.Context, path string, v interface{}) error {
+  return c.do(ctx, http.MethodGet, path, "", nil, v)
+}
+
+// MergePatch applies the JSON merge patch to the resource at the given API path
+func (c *Client) MergePatch(ctx context.Context, path string, patch interface{}) error {
+  body, err := json.Marshal(patch)
+  if err != nil {
+    return fmt.Errorf("failed to marshal patch: %v", err)
+  }
+
+  return c.do(ctx, http.MethodPatch, path, "application/merge-patch+json", body, nil)
+}
+
+// do sends a request to the API server, decoding the response into v if given
+func (c *Client) do(ctx context.Context, method string, path string, contentType string, body []byte, v interface{}) error {
+  // Projected service account tokens are rotated, so the token is read for every request
+  token, err := ioutil.ReadFile(c.tokenFile)
+  if err != nil {
+    return fmt.Errorf("failed to read service account token: %v", err)
+  }
+
+  req, err := http.NewRequest(method, c.host+path, bytes.NewReader(body))
+  if err != nil {
+    return fmt.Errorf("failed to create request: %v", err)
+  }
+  req = req.WithContext(ctx)
+  req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+  req.Header.Set("Accept", "application/json")
+  if contentType != "" {
+    req.Header.Set("Content-Type", contentType)
+  }
+
+  resp, err := c.httpClient.Do(req)
+  if err != nil {
+    return fmt.Errorf("failed to %s %s: %v", method, path, err)
+  }
+  defer resp.Body.Close()
+
+  respBody, err := ioutil.ReadAll(resp.Body)
+  if err != nil {
+    return fmt.Errorf("failed to read response of %s %s: %v", method, path, err)
+  }
+
+  if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+    return fmt.Errorf("failed to %s %s: unexpected status code %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(respBody)))
+  }
+
+  if v == nil {
+    return nil
+  }
+  if err := json.Unmarshal(respBody, v); err != nil {
+    return fmt.Errorf("failed to decode response of %s %s: %v", method, path, err)
+  }
+
+  return nil
+}
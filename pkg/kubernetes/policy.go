@@ -0,0 +1,100 @@
+package kubernetes
+
+import (
+  "context"
+  "encoding/json"
+  "fmt"
+  "time"
+)
+
+// API group, version and resource of the GitLabSettingsPolicy custom resource
+const (
+  PolicyGroup    = "settings-enforcer.gitlab.io"
+  PolicyVersion  = "v1alpha1"
+  PolicyResource = "gitlabsettingspolicies"
+)
+
+// Condition types of the status of policies
+const (
+  ConditionReady     = "Ready"
+  ConditionCompliant = "Compliant"
+)
+
+// Policy is a GitLabSettingsPolicy, whose spec is a config of the enforcer
+type Policy struct {
+  Metadata ObjectMeta      `json:"metadata"`
+  Spec     json.RawMessage `json:"spec"`
+  Status   PolicyStatus    `json:"status"`
+}
+
+// ObjectMeta holds the metadata of a policy used by the operator
+type ObjectMeta struct {
+  Name       string `json:"name"`
+  Namespace  string `json:"namespace"`
+  Generation int64  `json:"generation"`
+}
+
+// PolicyStatus is the status of a policy reported by the operator
+type PolicyStatus struct {
+  ObservedGeneration int64                  `json:"observedGeneration,omitempty"`
+  LastRunTime        *time.Time             `json:"lastRunTime,omitempty"`
+  Summary            map[string]interface{} `json:"summary,omitempty"`
+  Conditions         []Condition            `json:"conditions,omitempty"`
+}
+
+// Condition is a condition of the status of a policy
+type Condition struct {
+  Type               string    `json:"type"`
+  Status             string    `json:"status"`
+  Reason             string    `json:"reason"`
+  Message            string    `json:"message"`
+  LastTransitionTime time.Time `json:"lastTransitionTime"`
+}
+
+// SetCondition sets the condition of the given type, keeping its transition time if its
+// status didn't change
+func (s *PolicyStatus) SetCondition(condition Condition) {
+  for i, existing := range s.Conditions {
+    if existing.Type != condition.Type {
+      continue
+    }
+
+    if existing.Status == condition.Status {
+      condition.LastTransitionTime = existing.LastTransitionTime
+    }
+    s.Conditions[i] = condition
+    return
+  }
+
+  s.Conditions = append(s.Conditions, condition)
+}
+
+type policyList struct {
+  Items []Policy `json:"items"`
+}
+
+// ListPolicies returns the policies of the namespace, or of all namespaces if it is empty
+func (c *Client) ListPolicies(ctx context.Context, namespace string) ([]Policy, error) {
+  var list policyList
+  if err := c.Get(ctx, policiesPath(namespace), &list); err != nil {
+    return nil, err
+  }
+
+  return list.Items, nil
+}
+
+// UpdatePolicyStatus replaces the status of the policy
+func (c *Client) UpdatePolicyStatus(ctx context.Context, policy Policy) error {
+  path := fmt.Sprintf("%s/%s/status", policiesPath(policy.Metadata.Namespace), policy.Metadata.Name)
+  return c.MergePatch(ctx, path, map[string]interface{}{"status": policy.Status})
+}
+
+// policiesPath returns the API path of the policies of the namespace, or of all namespaces if
+// it is empty
+func policiesPath(namespace string) string {
+  if namespace == "" {
+    return fmt.Sprintf("/apis/%s/%s/%s", PolicyGroup, PolicyVersion, PolicyResource)
+  }
+
+  return fmt.Sprintf("/apis/%s/%s/namespaces/%s/%s", PolicyGroup, PolicyVersion, namespace, PolicyResource)
+}
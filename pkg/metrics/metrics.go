@@ -0,0 +1,151 @@
+package metrics
+
+import (
+  "fmt"
+  "net/http"
+  "time"
+
+  "github.com/prometheus/client_golang/prometheus"
+  "github.com/prometheus/client_golang/prometheus/promhttp"
+  "github.com/prometheus/client_golang/prometheus/push"
+  dto "github.com/prometheus/client_model/go"
+  "github.com/sirupsen/logrus"
+)
+
+const namespace = "gitlab_settings_enforcer"
+
+// Metrics collects the Prometheus metrics of a single run
+type Metrics struct {
+  registry           *prometheus.Registry
+  started            time.Time
+  projectsScanned    prometheus.Gauge
+  projectsDrifted    prometheus.Gauge
+  settingsDrifted    prometheus.Gauge
+  settingsRemediated prometheus.Gauge
+  apiRequests        *prometheus.CounterVec
+  errors             prometheus.Counter
+  duration           prometheus.Gauge
+  lastSuccess        prometheus.Gauge
+  succeeded          bool
+}
+
+// New returns a new Metrics instance, starting the run duration now
+func New() *Metrics {
+  m := &Metrics{
+    registry: prometheus.NewRegistry(),
+    started:  time.Now(),
+    projectsScanned: prometheus.NewGauge(prometheus.GaugeOpts{
+      Namespace: namespace,
+      Name:      "projects_scanned",
+      Help:      "Number of projects processed by the last run.",
+    }),
+    projectsDrifted: prometheus.NewGauge(prometheus.GaugeOpts{
+      Namespace: namespace,
+      Name:      "projects_drifted",
+      Help:      "Number of projects whose settings drifted from the config.",
+    }),
+    settingsDrifted: prometheus.NewGauge(prometheus.GaugeOpts{
+      Namespace: namespace,
+      Name:      "settings_drifted",
+      Help:      "Number of settings which drifted from the config.",
+    }),
+    settingsRemediated: prometheus.NewGauge(prometheus.GaugeOpts{
+      Namespace: namespace,
+      Name:      "settings_remediated",
+      Help:      "Number of drifted settings changed back to the config and applied by GitLab (zero in dryruns).",
+    }),
+    apiRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+      Namespace: namespace,
+      Name:      "api_requests_total",
+      Help:      "Number of requests sent to GitLab, including retries.",
+    }, []string{"code", "method"}),
+    errors: prometheus.NewCounter(prometheus.CounterOpts{
+      Namespace: namespace,
+      Name:      "errors_total",
+      Help:      "Number of errors encountered.",
+    }),
+    duration: prometheus.NewGauge(prometheus.GaugeOpts{
+      Namespace: namespace,
+      Name:      "run_duration_seconds",
+      Help:      "Duration of the last run.",
+    }),
+    lastSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+      Namespace: namespace,
+      Name:      "last_success_timestamp_seconds",
+      Help:      "Unix time of the last run finished without errors.",
+    }),
+  }
+
+  m.registry.MustRegister(
+    m.projectsScanned,
+    m.projectsDrifted,
+    m.settingsDrifted,
+    m.settingsRemediated,
+    m.apiRequests,
+    m.errors,
+    m.duration,
+    m.lastSuccess,
+  )
+
+  return m
+}
+
+// InstrumentTransport counts the requests sent by next, by status code and method
+func (m *Metrics) InstrumentTransport(next http.RoundTripper) http.RoundTripper {
+  return promhttp.InstrumentRoundTripperCounter(m.apiRequests, next)
+}
+
+// Levels implements logrus.Hook
+func (m *Metrics) Levels() []logrus.Level {
+  return []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel}
+}
+
+// Fire implements logrus.Hook, counting every logged error
+func (m *Metrics) Fire(*logrus.Entry) error {
+  m.errors.Inc()
+  return nil
+}
+
+// ObserveRun records the results of the run and its duration. The drifted settings include the
+// ones GitLab didn't apply, the remediated settings only the applied ones.
+func (m *Metrics) ObserveRun(scanned int, driftedProjects int, driftedSettings int, remediatedSettings int, failed bool) {
+  m.projectsScanned.Set(float64(scanned))
+  m.projectsDrifted.Set(float64(driftedProjects))
+  m.settingsDrifted.Set(float64(driftedSettings))
+  m.settingsRemediated.Set(float64(remediatedSettings))
+
+  now := time.Now()
+  m.duration.Set(now.Sub(m.started).Seconds())
+  if !failed {
+    m.lastSuccess.Set(float64(now.Unix()))
+    m.succeeded = true
+  }
+}
+
+// Push sends all metrics to the Pushgateway at the given URL, replacing the pushed metrics
+// of the same name of the job. The last success timestamp is left out until a run succeeded,
+// keeping the timestamp pushed by the previous successful run.
+func (m *Metrics) Push(url string, job string) error {
+  if err := push.New(url, job).Gatherer(prometheus.GathererFunc(m.gather)).Add(); err != nil {
+    return fmt.Errorf("failed to push metrics to %s: %v", url, err)
+  }
+
+  return nil
+}
+
+// gather collects the registered metrics, without the last success timestamp if no run succeeded
+func (m *Metrics) gather() ([]*dto.MetricFamily, error) {
+  families, err := m.registry.Gather()
+  if m.succeeded {
+    return families, err
+  }
+
+  gathered := families[:0]
+  for _, family := range families {
+    if family.GetName() != namespace+"_last_success_timestamp_seconds" {
+      gathered = append(gathered, family)
+    }
+  }
+
+  return gathered, err
+}
@@ -0,0 +1,99 @@
+package notify
+
+import (
+  "context"
+  "crypto/tls"
+  "fmt"
+  "net"
+  "net/smtp"
+  "strconv"
+  "strings"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/config"
+)
+
+// EmailNotifier sends the digest as plain text email over SMTP
+type EmailNotifier struct {
+  config config.EmailConfig
+}
+
+// NewEmailNotifier returns a new EmailNotifier sending through the given server
+func NewEmailNotifier(config config.EmailConfig) *EmailNotifier {
+  return &EmailNotifier{config: config}
+}
+
+// Notify implements Notifier
+func (n *EmailNotifier) Notify(ctx context.Context, digest Digest) error {
+  message := "From: " + n.config.From + "\r\n"
+  message += "To: " + strings.Join(n.config.To, ", ") + "\r\n"
+  message += "Subject: " + digest.Title() + "\r\n"
+  message += "Content-Type: text/plain; charset=\"utf-8\"\r\n"
+  message += "\r\n"
+  message += strings.Join(digest.Lines(), "\r\n") + "\r\n"
+
+  addr := n.config.Server + ":" + strconv.Itoa(n.config.Port)
+  if err := n.send(ctx, addr, []byte(message)); err != nil {
+    return fmt.Errorf("failed to send digest email via %s: %v", addr, err)
+  }
+
+  return nil
+}
+
+// send delivers the message like smtp.SendMail, but bound to the deadline of ctx. STARTTLS is used
+// if the server offers it and required with StartTLS, authentication only with a username.
+func (n *EmailNotifier) send(ctx context.Context, addr string, message []byte) error {
+  var dialer net.Dialer
+  conn, err := dialer.DialContext(ctx, "tcp", addr)
+  if err != nil {
+    return err
+  }
+  defer conn.Close()
+
+  if deadline, ok := ctx.Deadline(); ok {
+    if err := conn.SetDeadline(deadline); err != nil {
+      return err
+    }
+  }
+
+  client, err := smtp.NewClient(conn, n.config.Server)
+  if err != nil {
+    return err
+  }
+  defer client.Close()
+
+  if ok, _ := client.Extension("STARTTLS"); ok {
+    if err := client.StartTLS(&tls.Config{ServerName: n.config.Server}); err != nil {
+      return err
+    }
+  } else if n.config.StartTLS {
+    return fmt.Errorf("server doesn't support STARTTLS")
+  }
+
+  if n.config.Username != "" {
+    if err := client.Auth(smtp.PlainAuth("", n.config.Username, n.config.Password, n.config.Server)); err != nil {
+      return err
+    }
+  }
+
+  if err := client.Mail(n.config.From); err != nil {
+    return err
+  }
+  for _, to := range n.config.To {
+    if err := client.Rcpt(to); err != nil {
+      return err
+    }
+  }
+
+  w, err := client.Data()
+  if err != nil {
+    return err
+  }
+  if _, err := w.Write(message); err != nil {
+    return err
+  }
+  if err := w.Close(); err != nil {
+    return err
+  }
+
+  return client.Quit()
+}
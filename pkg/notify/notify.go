@@ -0,0 +1,100 @@
+package notify
+
+import (
+  "context"
+  "fmt"
+  "net/http"
+  "sort"
+  "strings"
+  "time"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/config"
+  gl "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/gitlab"
+)
+
+// Notifier delivers the digest of a run to a single backend
+type Notifier interface {
+  Notify(ctx context.Context, digest Digest) error
+}
+
+// Digest summarizes the results of a run
+type Digest struct {
+  Command  string
+  Dryrun   bool
+  Failed   bool
+  Started  time.Time
+  Finished time.Time
+  Changes  gl.ChangeLog
+  Summary  *gl.RunSummary
+}
+
+// New returns the notifiers of all backends configured in the given notifications section
+func New(notifications *config.Notifications, httpClient *http.Client) []Notifier {
+  var notifiers []Notifier
+  if notifications == nil {
+    return notifiers
+  }
+
+  if notifications.Email != nil {
+    notifiers = append(notifiers, NewEmailNotifier(*notifications.Email))
+  }
+  if notifications.Teams != nil {
+    notifiers = append(notifiers, NewTeamsNotifier(httpClient, notifications.Teams.WebhookURL))
+  }
+  for _, webhook := range notifications.Webhooks {
+    notifiers = append(notifiers, NewWebhookNotifier(httpClient, webhook))
+  }
+
+  return notifiers
+}
+
+// Title returns a one line summary of the run
+func (d Digest) Title() string {
+  status := "succeeded"
+  if d.Failed {
+    status = "failed"
+  }
+
+  dryrun := ""
+  if d.Dryrun {
+    dryrun = " (dryrun)"
+  }
+
+  return fmt.Sprintf("gitlab-settings-enforcer %s%s %s: %d project(s) changed", d.Command, dryrun, status, len(d.Changes))
+}
+
+// Lines returns the changes of the run, one line per changed setting, grouped by project
+func (d Digest) Lines() []string {
+  lines := []string{
+    fmt.Sprintf("Started: %s, duration: %s", d.Started.Format(time.RFC1123), d.Finished.Sub(d.Started).Round(time.Second)),
+  }
+  if s := d.Summary; s != nil {
+    lines = append(lines, fmt.Sprintf("Projects: %d, skipped: %d, in sync: %d, changed: %d, failed: %d, API calls: %d", s.Projects, s.Skipped, s.InSync, s.Changed, s.Failed, s.APICalls))
+  }
+
+  var project_names []string
+  for project_name := range d.Changes {
+    project_names = append(project_names, project_name)
+  }
+  sort.Strings(project_names)
+
+  for _, name := range project_names {
+    lines = append(lines, "", name)
+
+    var keys []string
+    for subsection, settings := range d.Changes[name] {
+      for setting := range settings {
+        keys = append(keys, subsection+"."+setting)
+      }
+    }
+    sort.Strings(keys)
+
+    for _, key := range keys {
+      parts := strings.SplitN(key, ".", 2)
+      change := d.Changes[name][parts[0]][parts[1]]
+      lines = append(lines, fmt.Sprintf("  %s: %s => %s", key, gl.FormatValue(change["From"]), gl.FormatValue(change["To"])))
+    }
+  }
+
+  return lines
+}
@@ -0,0 +1,79 @@
+package notify
+
+import (
+  "bytes"
+  "context"
+  "encoding/json"
+  "fmt"
+  "net/http"
+  "strings"
+)
+
+// TeamsNotifier posts the digest as message card to a Microsoft Teams incoming webhook
+type TeamsNotifier struct {
+  httpClient *http.Client
+  webhookURL string
+}
+
+type teamsMessageCard struct {
+  Type       string `json:"@type"`
+  Context    string `json:"@context"`
+  Summary    string `json:"summary"`
+  ThemeColor string `json:"themeColor"`
+  Title      string `json:"title"`
+  Text       string `json:"text"`
+}
+
+// NewTeamsNotifier returns a new TeamsNotifier posting to the given webhook
+func NewTeamsNotifier(httpClient *http.Client, webhookURL string) *TeamsNotifier {
+  if httpClient == nil {
+    httpClient = http.DefaultClient
+  }
+
+  return &TeamsNotifier{
+    httpClient: httpClient,
+    webhookURL: webhookURL,
+  }
+}
+
+// Notify implements Notifier
+func (n *TeamsNotifier) Notify(ctx context.Context, digest Digest) error {
+  color := "2EB886"
+  if digest.Failed {
+    color = "D93F0B"
+  }
+
+  // Teams renders the text as markdown, which needs blank lines between paragraphs
+  card := teamsMessageCard{
+    Type:       "MessageCard",
+    Context:    "https://schema.org/extensions",
+    Summary:    digest.Title(),
+    ThemeColor: color,
+    Title:      digest.Title(),
+    Text:       strings.Join(digest.Lines(), "\n\n"),
+  }
+
+  body, err := json.Marshal(card)
+  if err != nil {
+    return fmt.Errorf("failed to marshal teams message: %v", err)
+  }
+
+  req, err := http.NewRequest(http.MethodPost, n.webhookURL, bytes.NewReader(body))
+  if err != nil {
+    return fmt.Errorf("failed to create teams request: %v", err)
+  }
+  req = req.WithContext(ctx)
+  req.Header.Set("Content-Type", "application/json")
+
+  resp, err := n.httpClient.Do(req)
+  if err != nil {
+    return fmt.Errorf("failed to post digest to teams: %v", err)
+  }
+  defer resp.Body.Close()
+
+  if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+    return fmt.Errorf("teams webhook returned unexpected status code %d", resp.StatusCode)
+  }
+
+  return nil
+}
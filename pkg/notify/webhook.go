@@ -0,0 +1,108 @@
+package notify
+
+import (
+  "bytes"
+  "context"
+  "crypto/hmac"
+  "crypto/sha256"
+  "encoding/hex"
+  "encoding/json"
+  "fmt"
+  "net/http"
+  "time"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/config"
+  gl "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/gitlab"
+)
+
+// SignatureHeader carries the hex encoded HMAC-SHA256 of the webhook body, prefixed with "sha256="
+const SignatureHeader = "X-Gitlab-Settings-Enforcer-Signature"
+
+// WebhookNotifier posts the run results as JSON to an arbitrary HTTP endpoint
+type WebhookNotifier struct {
+  httpClient *http.Client
+  config     config.WebhookNotification
+}
+
+type webhookPayload struct {
+  Command         string         `json:"command"`
+  Dryrun          bool           `json:"dryrun"`
+  Status          string         `json:"status"`
+  Started         time.Time      `json:"started"`
+  Finished        time.Time      `json:"finished"`
+  DurationSeconds float64        `json:"duration_seconds"`
+  Changes         gl.ChangeLog   `json:"changes"`
+  Summary         *gl.RunSummary `json:"summary,omitempty"`
+}
+
+// NewWebhookNotifier returns a new WebhookNotifier posting to the configured endpoint
+func NewWebhookNotifier(httpClient *http.Client, config config.WebhookNotification) *WebhookNotifier {
+  if httpClient == nil {
+    httpClient = http.DefaultClient
+  }
+
+  return &WebhookNotifier{
+    httpClient: httpClient,
+    config:     config,
+  }
+}
+
+// Notify implements Notifier
+func (n *WebhookNotifier) Notify(ctx context.Context, digest Digest) error {
+  status := "succeeded"
+  if digest.Failed {
+    status = "failed"
+  }
+
+  changes := digest.Changes
+  if changes == nil {
+    changes = gl.ChangeLog{}
+  }
+
+  body, err := json.Marshal(webhookPayload{
+    Command:         digest.Command,
+    Dryrun:          digest.Dryrun,
+    Status:          status,
+    Started:         digest.Started,
+    Finished:        digest.Finished,
+    DurationSeconds: digest.Finished.Sub(digest.Started).Seconds(),
+    Changes:         changes,
+    Summary:         digest.Summary,
+  })
+  if err != nil {
+    return fmt.Errorf("failed to marshal webhook payload: %v", err)
+  }
+
+  req, err := http.NewRequest(http.MethodPost, n.config.URL, bytes.NewReader(body))
+  if err != nil {
+    return fmt.Errorf("failed to create webhook request: %v", err)
+  }
+  req = req.WithContext(ctx)
+  req.Header.Set("Content-Type", "application/json")
+  for name, value := range n.config.Headers {
+    req.Header.Set(name, value)
+  }
+
+  if n.config.Secret != "" {
+    req.Header.Set(SignatureHeader, "sha256="+sign(body, n.config.Secret))
+  }
+
+  resp, err := n.httpClient.Do(req)
+  if err != nil {
+    return fmt.Errorf("failed to post run results to webhook %s: %v", req.URL.Host, err)
+  }
+  defer resp.Body.Close()
+
+  if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+    return fmt.Errorf("webhook %s returned unexpected status code %d", req.URL.Host, resp.StatusCode)
+  }
+
+  return nil
+}
+
+// sign returns the hex encoded HMAC-SHA256 of body using secret as key
+func sign(body []byte, secret string) string {
+  mac := hmac.New(sha256.New, []byte(secret))
+  mac.Write(body)
+  return hex.EncodeToString(mac.Sum(nil))
+}
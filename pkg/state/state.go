@@ -0,0 +1,176 @@
+package state
+
+import (
+  "encoding/json"
+  "fmt"
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "sync"
+  "time"
+
+  "github.com/erinkerNCS/gitlab-settings-enforcer/pkg/storage"
+)
+
+// State is persisted between runs of the sync command
+type State struct {
+  mu   sync.Mutex
+  path string
+
+  StartedAt     time.Time            `json:"started_at"`
+  Completed     map[string]time.Time `json:"completed"`
+  LastRun       time.Time            `json:"last_run"`
+  LastFullSweep time.Time            `json:"last_full_sweep"`
+
+  // Applied holds the settings last applied per project, keyed by "<subsection>.<setting>"
+  Applied map[string]map[string]interface{} `json:"applied"`
+}
+
+// Load reads the state file at the given path, which may be remote. A missing file results in
+// an empty state.
+func Load(path string) (*State, error) {
+  s := &State{
+    path:      path,
+    Completed: make(map[string]time.Time),
+    Applied:   make(map[string]map[string]interface{}),
+  }
+
+  b, err := storage.ReadFile(path)
+  if storage.IsNotExist(err) {
+    return s, nil
+  } else if err != nil {
+    return nil, fmt.Errorf("failed to read state file %q: %v", path, err)
+  }
+
+  if err := json.Unmarshal(b, s); err != nil {
+    return nil, fmt.Errorf("failed to unmarshal state file %q: %v", path, err)
+  }
+  if s.Completed == nil {
+    s.Completed = make(map[string]time.Time)
+  }
+  if s.Applied == nil {
+    s.Applied = make(map[string]map[string]interface{})
+  }
+
+  return s, nil
+}
+
+// Reset forgets the completed projects of the previous run and starts a new one
+func (s *State) Reset(now time.Time) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  s.StartedAt = now
+  s.Completed = make(map[string]time.Time)
+}
+
+// IsCompleted returns whether the project has been enforced successfully in the current run
+func (s *State) IsCompleted(project string) bool {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  _, ok := s.Completed[project]
+  return ok
+}
+
+// Complete records the project as enforced successfully and persists the state
+func (s *State) Complete(project string, now time.Time) error {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  s.Completed[project] = now
+  return s.save()
+}
+
+// SetApplied records the settings applied to the project, keeping the settings applied before
+// which aren't given (e.g. of subsystems skipped in this run). They are persisted with the
+// completion of the project.
+func (s *State) SetApplied(project string, settings map[string]interface{}) {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  merged := make(map[string]interface{}, len(s.Applied[project])+len(settings))
+  for setting, value := range s.Applied[project] {
+    merged[setting] = value
+  }
+  for setting, value := range settings {
+    merged[setting] = value
+  }
+  s.Applied[project] = merged
+}
+
+// AppliedSettings returns a copy of the settings last applied per project
+func (s *State) AppliedSettings() map[string]map[string]interface{} {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  applied := make(map[string]map[string]interface{}, len(s.Applied))
+  for project, settings := range s.Applied {
+    applied[project] = settings
+  }
+
+  return applied
+}
+
+// Path returns the path of the state file
+func (s *State) Path() string {
+  return s.path
+}
+
+// Finish records the current run as finished successfully and persists the state. Following
+// incremental runs only enforce projects active since the start of this run.
+func (s *State) Finish(fullSweep bool) error {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  s.LastRun = s.StartedAt
+  if fullSweep {
+    s.LastFullSweep = s.StartedAt
+  }
+
+  return s.save()
+}
+
+// Save persists the state
+func (s *State) Save() error {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  return s.save()
+}
+
+// save writes the state to a temporary file first, so an interrupted write never corrupts it.
+// Remote objects are replaced at once anyway.
+func (s *State) save() error {
+  b, err := json.MarshalIndent(s, "", "  ")
+  if err != nil {
+    return fmt.Errorf("failed to marshal state: %v", err)
+  }
+
+  if storage.IsRemote(s.path) {
+    if err := storage.WriteFile(s.path, b, 0644); err != nil {
+      return fmt.Errorf("failed to write state file %q: %v", s.path, err)
+    }
+    return nil
+  }
+
+  tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+  if err != nil {
+    return fmt.Errorf("failed to write state file %q: %v", s.path, err)
+  }
+  defer os.Remove(tmp.Name())
+
+  if _, err := tmp.Write(b); err != nil {
+    tmp.Close()
+    return fmt.Errorf("failed to write state file %q: %v", s.path, err)
+  }
+  if err := tmp.Close(); err != nil {
+    return fmt.Errorf("failed to write state file %q: %v", s.path, err)
+  }
+
+  if err := os.Rename(tmp.Name(), s.path); err != nil {
+    return fmt.Errorf("failed to write state file %q: %v", s.path, err)
+  }
+
+  return nil
+}
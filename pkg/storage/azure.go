@@ -0,0 +1,71 @@
+package storage
+
+import (
+  "bytes"
+  "context"
+  "fmt"
+  "net/http"
+  "os"
+  "strings"
+)
+
+// azureBackend stores objects as block blobs in an Azure Blob Storage container, authorized
+// by a shared access signature
+type azureBackend struct {
+  account   string
+  container string
+  sasToken  string
+}
+
+// newAzureBackend returns the backend of the "<account>/<container>" given, authorized by the
+// SAS token given by AZURE_STORAGE_SAS_TOKEN
+func newAzureBackend(container string) (backend, error) {
+  parts := strings.SplitN(container, "/", 2)
+  if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+    return nil, fmt.Errorf("invalid azblob container %q, expected <account>/<container>", container)
+  }
+
+  b := &azureBackend{
+    account:   parts[0],
+    container: parts[1],
+    sasToken:  strings.TrimPrefix(os.Getenv("AZURE_STORAGE_SAS_TOKEN"), "?"),
+  }
+  if b.sasToken == "" {
+    return nil, fmt.Errorf("azblob storage requires AZURE_STORAGE_SAS_TOKEN")
+  }
+
+  return b, nil
+}
+
+func (b *azureBackend) get(ctx context.Context, key string) ([]byte, error) {
+  req, err := b.request(ctx, http.MethodGet, key, nil)
+  if err != nil {
+    return nil, err
+  }
+
+  return do(req)
+}
+
+func (b *azureBackend) put(ctx context.Context, key string, data []byte) error {
+  req, err := b.request(ctx, http.MethodPut, key, data)
+  if err != nil {
+    return err
+  }
+  req.Header.Set("X-Ms-Blob-Type", "BlockBlob")
+
+  _, err = do(req)
+  return err
+}
+
+// request returns a request for the blob, authorized by the SAS token in its query
+func (b *azureBackend) request(ctx context.Context, method string, key string, body []byte) (*http.Request, error) {
+  url := fmt.Sprintf("https://%s.blob.core.windows.net/%s/%s?%s", b.account, b.container, escapePath(key), b.sasToken)
+
+  req, err := http.NewRequest(method, url, bytes.NewReader(body))
+  if err != nil {
+    return nil, fmt.Errorf("failed to create storage request: %v", err)
+  }
+  req.Header.Set("X-Ms-Version", "2020-04-08")
+
+  return req.WithContext(ctx), nil
+}
@@ -0,0 +1,176 @@
+package storage
+
+import (
+  "bytes"
+  "context"
+  "crypto/hmac"
+  "crypto/sha256"
+  "encoding/hex"
+  "fmt"
+  "net/http"
+  "net/url"
+  "os"
+  "sort"
+  "strings"
+  "time"
+)
+
+// s3Backend stores objects in an S3 bucket, or any bucket speaking the S3 API with AWS
+// signature version 4 (e.g. Google Cloud Storage with HMAC keys, MinIO)
+type s3Backend struct {
+  endpoint     *url.URL
+  bucket       string
+  region       string
+  accessKey    string
+  secretKey    string
+  sessionToken string
+  pathStyle    bool
+}
+
+// newS3Backend returns the backend of an S3 bucket, configured by the usual AWS_* env vars.
+// STORAGE_S3_ENDPOINT points it to an S3 compatible service instead, using path-style URLs.
+func newS3Backend(bucket string) (backend, error) {
+  b := &s3Backend{
+    bucket:       bucket,
+    region:       os.Getenv("AWS_REGION"),
+    accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+    secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+    sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+  }
+  if b.region == "" {
+    b.region = "us-east-1"
+  }
+  if b.accessKey == "" || b.secretKey == "" {
+    return nil, fmt.Errorf("s3 storage requires AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+  }
+
+  endpoint := "https://s3." + b.region + ".amazonaws.com"
+  if custom := os.Getenv("STORAGE_S3_ENDPOINT"); custom != "" {
+    endpoint, b.pathStyle = custom, true
+  }
+
+  var err error
+  if b.endpoint, err = url.Parse(endpoint); err != nil {
+    return nil, fmt.Errorf("invalid s3 endpoint %q: %v", endpoint, err)
+  }
+
+  return b, nil
+}
+
+// newGCSBackend returns the backend of a Google Cloud Storage bucket, accessed through its
+// S3 compatible XML API with the HMAC key given by GCS_HMAC_ACCESS_ID and GCS_HMAC_SECRET
+func newGCSBackend(bucket string) (backend, error) {
+  b := &s3Backend{
+    endpoint:  &url.URL{Scheme: "https", Host: "storage.googleapis.com"},
+    bucket:    bucket,
+    region:    "auto",
+    accessKey: os.Getenv("GCS_HMAC_ACCESS_ID"),
+    secretKey: os.Getenv("GCS_HMAC_SECRET"),
+    pathStyle: true,
+  }
+  if b.accessKey == "" || b.secretKey == "" {
+    return nil, fmt.Errorf("gs storage requires GCS_HMAC_ACCESS_ID and GCS_HMAC_SECRET")
+  }
+
+  return b, nil
+}
+
+func (b *s3Backend) get(ctx context.Context, key string) ([]byte, error) {
+  req, err := b.request(ctx, http.MethodGet, key, nil)
+  if err != nil {
+    return nil, err
+  }
+
+  return do(req)
+}
+
+func (b *s3Backend) put(ctx context.Context, key string, data []byte) error {
+  req, err := b.request(ctx, http.MethodPut, key, data)
+  if err != nil {
+    return err
+  }
+
+  _, err = do(req)
+  return err
+}
+
+// request returns a signed request for the object
+func (b *s3Backend) request(ctx context.Context, method string, key string, body []byte) (*http.Request, error) {
+  host := b.endpoint.Host
+  objectPath := "/" + escapePath(key)
+  if b.pathStyle {
+    objectPath = strings.TrimSuffix(b.endpoint.Path, "/") + "/" + b.bucket + objectPath
+  } else {
+    host = b.bucket + "." + host
+  }
+
+  req, err := http.NewRequest(method, b.endpoint.Scheme+"://"+host, bytes.NewReader(body))
+  if err != nil {
+    return nil, fmt.Errorf("failed to create storage request: %v", err)
+  }
+  req = req.WithContext(ctx)
+  // Opaque keeps the escaping the signature is computed over
+  req.URL.Opaque = "//" + host + objectPath
+
+  b.sign(req, host, objectPath, body, time.Now().UTC())
+
+  return req, nil
+}
+
+// sign adds the AWS signature version 4 headers to the request
+func (b *s3Backend) sign(req *http.Request, host string, objectPath string, body []byte, now time.Time) {
+  payloadHash := sha256.Sum256(body)
+  amzDate := now.Format("20060102T150405Z")
+  date := now.Format("20060102")
+
+  req.Header.Set("X-Amz-Date", amzDate)
+  req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+  if b.sessionToken != "" {
+    req.Header.Set("X-Amz-Security-Token", b.sessionToken)
+  }
+
+  headers := map[string]string{"host": host}
+  for name := range req.Header {
+    headers[strings.ToLower(name)] = strings.TrimSpace(req.Header.Get(name))
+  }
+
+  var names []string
+  for name := range headers {
+    names = append(names, name)
+  }
+  sort.Strings(names)
+
+  var canonicalHeaders strings.Builder
+  for _, name := range names {
+    canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+  }
+  signedHeaders := strings.Join(names, ";")
+
+  canonicalRequest := strings.Join([]string{
+    req.Method,
+    objectPath,
+    "",
+    canonicalHeaders.String(),
+    signedHeaders,
+    hex.EncodeToString(payloadHash[:]),
+  }, "\n")
+
+  scope := date + "/" + b.region + "/s3/aws4_request"
+  requestHash := sha256.Sum256([]byte(canonicalRequest))
+  stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+  key := hmacSHA256([]byte("AWS4"+b.secretKey), date)
+  key = hmacSHA256(key, b.region)
+  key = hmacSHA256(key, "s3")
+  key = hmacSHA256(key, "aws4_request")
+  signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+  req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", b.accessKey, scope, signedHeaders, signature))
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data with the given key
+func hmacSHA256(key []byte, data string) []byte {
+  mac := hmac.New(sha256.New, key)
+  mac.Write([]byte(data))
+  return mac.Sum(nil)
+}
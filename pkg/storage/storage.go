@@ -0,0 +1,182 @@
+package storage
+
+import (
+  "context"
+  "errors"
+  "fmt"
+  "io/ioutil"
+  "net/http"
+  "os"
+  "path"
+  "path/filepath"
+  "strings"
+  "time"
+)
+
+// requestTimeout limits the time a single request to a remote backend may take
+const requestTimeout = 60 * time.Second
+
+// ErrNotExist is returned when reading a remote object which doesn't exist
+var ErrNotExist = errors.New("object does not exist")
+
+// httpClient is used by all remote backends
+var httpClient = &http.Client{Timeout: requestTimeout}
+
+// backend stores objects in a remote bucket or container
+type backend interface {
+  get(ctx context.Context, key string) ([]byte, error)
+  put(ctx context.Context, key string, data []byte) error
+}
+
+// schemes maps the URL schemes of remote paths to the constructor of their backend, which is
+// given the bucket (or account and container) of the URL
+var schemes = map[string]func(bucket string) (backend, error){
+  "s3":     newS3Backend,
+  "gs":     newGCSBackend,
+  "azblob": newAzureBackend,
+}
+
+// IsRemote returns whether the path is the URL of an object in a remote backend, e.g.
+// s3://bucket/key, gs://bucket/key or azblob://account/container/key
+func IsRemote(p string) bool {
+  scheme, _, ok := splitURL(p)
+  if !ok {
+    return false
+  }
+
+  _, ok = schemes[scheme]
+  return ok
+}
+
+// ReadFile reads the local file or remote object at the given path
+func ReadFile(p string) ([]byte, error) {
+  if !IsRemote(p) {
+    return ioutil.ReadFile(p)
+  }
+
+  b, key, err := open(p)
+  if err != nil {
+    return nil, err
+  }
+
+  ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+  defer cancel()
+
+  return b.get(ctx, key)
+}
+
+// WriteFile writes data to the local file or remote object at the given path, replacing it
+func WriteFile(p string, data []byte, perm os.FileMode) error {
+  if !IsRemote(p) {
+    return ioutil.WriteFile(p, data, perm)
+  }
+
+  b, key, err := open(p)
+  if err != nil {
+    return err
+  }
+
+  ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+  defer cancel()
+
+  return b.put(ctx, key, data)
+}
+
+// IsNotExist returns whether the error reports a missing local file or remote object
+func IsNotExist(err error) bool {
+  return os.IsNotExist(err) || err == ErrNotExist
+}
+
+// Join joins the directory (or remote prefix) and the file name
+func Join(dir string, name string) string {
+  if !IsRemote(dir) {
+    return filepath.Join(dir, name)
+  }
+
+  scheme, rest, _ := splitURL(dir)
+  return scheme + "://" + path.Join(rest, name)
+}
+
+// open returns the backend of the remote path and the key of the object within it
+func open(p string) (backend, string, error) {
+  scheme, rest, _ := splitURL(p)
+
+  // Azure blobs are addressed by account and container
+  parts := 2
+  if scheme == "azblob" {
+    parts = 3
+  }
+
+  elems := strings.SplitN(rest, "/", parts)
+  if len(elems) < parts || elems[len(elems)-1] == "" {
+    return nil, "", fmt.Errorf("invalid storage URL %q, the object key is missing", p)
+  }
+
+  b, err := schemes[scheme](strings.Join(elems[:parts-1], "/"))
+  if err != nil {
+    return nil, "", err
+  }
+
+  return b, elems[parts-1], nil
+}
+
+// splitURL splits the path into its URL scheme and the rest
+func splitURL(p string) (string, string, bool) {
+  i := strings.Index(p, "://")
+  if i <= 0 {
+    return "", "", false
+  }
+
+  return p[:i], p[i+3:], true
+}
+
+// escapePath percent-encodes all but the unreserved characters and slashes of the path, as
+// required by the request signatures
+func escapePath(p string) string {
+  var b strings.Builder
+  for i := 0; i < len(p); i++ {
+    c := p[i]
+    if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') || strings.IndexByte("-._~/", c) >= 0 {
+      b.WriteByte(c)
+      continue
+    }
+    fmt.Fprintf(&b, "%%%02X", c)
+  }
+
+  return b.String()
+}
+
+// do sends the request, returning the response body of successful requests and ErrNotExist
+// for missing objects
+func do(req *http.Request) ([]byte, error) {
+  object := objectURL(req)
+
+  resp, err := httpClient.Do(req)
+  if err != nil {
+    return nil, fmt.Errorf("failed to %s %s: %v", req.Method, object, err)
+  }
+  defer resp.Body.Close()
+
+  body, err := ioutil.ReadAll(resp.Body)
+  if err != nil {
+    return nil, fmt.Errorf("failed to read response of %s %s: %v", req.Method, object, err)
+  }
+
+  if resp.StatusCode == http.StatusNotFound && req.Method == http.MethodGet {
+    return nil, ErrNotExist
+  }
+  if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+    return nil, fmt.Errorf("failed to %s %s: unexpected status code %d: %s", req.Method, object, resp.StatusCode, strings.TrimSpace(string(body)))
+  }
+
+  return body, nil
+}
+
+// objectURL returns the URL of the requested object without its query, which may hold secrets
+func objectURL(req *http.Request) string {
+  if req.URL.Opaque != "" {
+    return req.URL.Scheme + ":" + req.URL.Opaque
+  }
+
+  return req.URL.Scheme + "://" + req.URL.Host + req.URL.Path
+}
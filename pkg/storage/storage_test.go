@@ -0,0 +1,46 @@
+package storage
+
+import (
+  "testing"
+)
+
+func TestIsRemote(t *testing.T) {
+  tests := map[string]bool{
+    "state.json":                      false,
+    "/var/lib/enforcer/state.json":    false,
+    "https://example.com/state.json":  false,
+    "s3://bucket/state.json":          true,
+    "gs://bucket/state.json":          true,
+    "azblob://account/container/a/b": true,
+  }
+
+  for path, want := range tests {
+    if got := IsRemote(path); got != want {
+      t.Errorf("IsRemote(%q) = %t, want %t", path, got, want)
+    }
+  }
+}
+
+func TestJoin(t *testing.T) {
+  tests := []struct {
+    dir  string
+    name string
+    want string
+  }{
+    {"rollbacks", "rollback.json", "rollbacks/rollback.json"},
+    {"s3://bucket/rollbacks/", "rollback.json", "s3://bucket/rollbacks/rollback.json"},
+    {"azblob://account/container", "rollback.json", "azblob://account/container/rollback.json"},
+  }
+
+  for _, test := range tests {
+    if got := Join(test.dir, test.name); got != test.want {
+      t.Errorf("Join(%q, %q) = %q, want %q", test.dir, test.name, got, test.want)
+    }
+  }
+}
+
+func TestEscapePath(t *testing.T) {
+  if got, want := escapePath("runs/state file+1.json"), "runs/state%20file%2B1.json"; got != want {
+    t.Errorf("escapePath() = %q, want %q", got, want)
+  }
+}